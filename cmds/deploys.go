@@ -2,6 +2,7 @@ package main
 
 import (
 	"code.google.com/p/goauth2/oauth"
+	"context"
 	"fmt"
 	"github.com/codegangsta/cli"
 	"github.com/wm/go-flowdock/flowdock"
@@ -87,6 +88,7 @@ func main() {
 }
 
 func getAppDeployCount(q Query, client *flowdock.Client, channel chan AppDeployCount) {
+	ctx := context.Background()
 	var deployCount = map[string]int{}
 
 	go func() {
@@ -98,7 +100,7 @@ func getAppDeployCount(q Query, client *flowdock.Client, channel chan AppDeployC
 		opt.Search = "production to production"
 		opt.Event = "mail"
 
-		messages, _, err := client.Messages.List(q.Org, q.Flow, &opt)
+		messages, _, err := client.Messages.List(ctx, q.Org, q.Flow, &opt)
 
 		if err != nil {
 			log.Fatal("Get:", err)
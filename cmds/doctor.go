@@ -0,0 +1,139 @@
+// A command diagnosing why a bot is silent: it checks that the API answers,
+// which scopes the token was granted, that the stream of a flow connects,
+// and how much of the rate limit is left.
+//
+//	go run cmds/doctor.go -org iora -flow tech-stuff
+package main
+
+import (
+	"code.google.com/p/goauth2/oauth"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/wm/go-flowdock/auth"
+	"github.com/wm/go-flowdock/flowdock"
+	"os"
+	"time"
+)
+
+var (
+	org         = flag.String("org", "", "Organization of the flow to probe the stream of")
+	flow        = flag.String("flow", "", "Flow to probe the stream of")
+	streamProbe = flag.Duration("stream_probe", 5*time.Second, "How long the stream must stay up")
+)
+
+// check is a line of the report.
+type check struct {
+	name   string
+	err    error
+	detail string
+}
+
+func main() {
+	httpClient := auth.AuthenticationRequest()
+	token, _ := oauth.CacheFile("cache.json").Token()
+
+	client := flowdock.NewClient(httpClient)
+	ctx := context.Background()
+
+	var checks []check
+	checks = append(checks, health(ctx, client))
+	checks = append(checks, scopes(ctx, client)...)
+	if *org != "" && *flow != "" && token != nil {
+		checks = append(checks, stream(ctx, client, token.AccessToken))
+	} else {
+		checks = append(checks, check{name: "stream", detail: "skipped, set -org and -flow"})
+	}
+	checks = append(checks, rate(client))
+
+	failed := 0
+	for _, c := range checks {
+		status := "ok  "
+		if c.err != nil {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s %-16s %s", status, c.name, c.detail)
+		if c.err != nil {
+			fmt.Printf(" (%v)", c.err)
+		}
+		fmt.Println()
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		os.Exit(1)
+	}
+}
+
+// health checks that the API answers with the token.
+func health(ctx context.Context, client *flowdock.Client) check {
+	start := time.Now()
+	_, _, err := client.Flows.List(ctx, false, nil)
+	c := check{name: "api", err: err, detail: fmt.Sprintf("%s answered in %v", client.RestURL, time.Since(start).Round(time.Millisecond))}
+	var e *flowdock.Error
+	if errors.As(err, &e) && e.StatusCode() == 401 {
+		c.detail = "token refused, authorize again"
+	}
+	return c
+}
+
+// scopes checks the scopes of the token, by calling an endpoint needing
+// each of them.
+func scopes(ctx context.Context, client *flowdock.Client) []check {
+	probes := []struct {
+		scope string
+		call  func() error
+	}{
+		{"flow", func() error { _, _, err := client.Flows.List(ctx, true, nil); return err }},
+		{"profile", func() error { _, _, err := client.Users.List(ctx, nil); return err }},
+		{"manage", func() error { _, _, err := client.Organizations.All(ctx); return err }},
+	}
+
+	var checks []check
+	for _, p := range probes {
+		err := p.call()
+		detail := "granted"
+		if err != nil {
+			detail = "not granted or failing"
+		}
+		checks = append(checks, check{name: "scope " + p.scope, err: err, detail: detail})
+	}
+	return checks
+}
+
+// stream checks that the stream of the flow connects and stays up.
+func stream(ctx context.Context, client *flowdock.Client, token string) check {
+	ctx, cancel := context.WithTimeout(ctx, *streamProbe)
+	defer cancel()
+
+	name := fmt.Sprintf("stream %s/%s", *org, *flow)
+	_, s, err := client.Messages.Stream(ctx, token, *org, *flow)
+	if err != nil {
+		return check{name: name, err: err}
+	}
+	defer s.Close()
+
+	select {
+	case r := <-s.Reconnects():
+		return check{name: name, err: r.Err, detail: "connection lost"}
+	case <-s.Done():
+		if err := s.Err(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return check{name: name, err: err, detail: "connection refused"}
+		}
+	}
+	return check{name: name, detail: fmt.Sprintf("up for %v", *streamProbe)}
+}
+
+// rate reports the rate limit left, as of the last response.
+func rate(client *flowdock.Client) check {
+	r := client.Rate()
+	if r.Limit == 0 {
+		return check{name: "rate limit", detail: "not reported by the API"}
+	}
+	c := check{name: "rate limit", detail: fmt.Sprintf("%d of %d requests left, reset at %v", r.Remaining, r.Limit, r.Reset.Format(time.RFC3339))}
+	if r.Remaining == 0 {
+		c.err = fmt.Errorf("exhausted until %v", r.Reset.Format(time.RFC3339))
+	}
+	return c
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/wm/go-flowdock/auth"
 	"github.com/wm/go-flowdock/flowdock"
@@ -26,8 +27,9 @@ func main() {
 }
 
 func flowsCreate(org, name string, client *flowdock.Client) {
+	ctx := context.Background()
 	opt := &flowdock.FlowsCreateOptions{Name: name}
-	_, _, err := client.Flows.Create(org, opt)
+	_, _, err := client.Flows.Create(ctx, org, opt)
 	if err != nil {
 		log.Fatal("Get:", err)
 	}
@@ -35,9 +37,10 @@ func flowsCreate(org, name string, client *flowdock.Client) {
 }
 
 func flowsUpdate(org, name string, client *flowdock.Client) {
+	ctx := context.Background()
 	disable := true
 	flow := &flowdock.Flow{Disabled: &disable}
-	flow, _, err := client.Flows.Update(org, name, flow)
+	flow, _, err := client.Flows.Update(ctx, org, name, flow)
 	displayFlowData(*flow)
 	if err != nil {
 		log.Fatal("Get:", err)
@@ -45,7 +48,8 @@ func flowsUpdate(org, name string, client *flowdock.Client) {
 }
 
 func flowsGet(org, name string, client *flowdock.Client) {
-	flow, _, err := client.Flows.Get(org, name)
+	ctx := context.Background()
+	flow, _, err := client.Flows.Get(ctx, org, name)
 	if err != nil {
 		log.Fatal("Get:", err)
 	}
@@ -53,7 +57,8 @@ func flowsGet(org, name string, client *flowdock.Client) {
 }
 
 func flowsGetByID(id string, client *flowdock.Client) {
-	flow, _, err := client.Flows.GetByID(id)
+	ctx := context.Background()
+	flow, _, err := client.Flows.GetByID(ctx, id)
 
 	if err != nil {
 		log.Fatal("Get:", err)
@@ -62,8 +67,9 @@ func flowsGetByID(id string, client *flowdock.Client) {
 }
 
 func flowsList(client *flowdock.Client) {
+	ctx := context.Background()
 	opt := flowdock.FlowsListOptions{User: true}
-	flows, _, err := client.Flows.List(true, &opt)
+	flows, _, err := client.Flows.List(ctx, true, &opt)
 
 	if err != nil {
 		log.Fatal("Get:", err)
@@ -80,8 +86,9 @@ func displayFlowData(flow flowdock.Flow) {
 }
 
 func messageList(client *flowdock.Client) {
+	ctx := context.Background()
 	opt := flowdock.MessagesListOptions{Limit: 100, Event: "message, comment"}
-	messages, _, err := client.Messages.List("iora", "egg", &opt)
+	messages, _, err := client.Messages.List(ctx, "iora", "egg", &opt)
 
 	if err != nil {
 		log.Fatal("Get:", err)
@@ -93,16 +100,22 @@ func messageList(client *flowdock.Client) {
 }
 
 func displayMessageData(msg flowdock.Message) {
-	fmt.Println("MSG:", *msg.ID, *msg.Event, msg.Content())
+	content, err := msg.ContentE()
+	if err != nil {
+		fmt.Println("MSG:", *msg.ID, *msg.Event, "malformed content:", err)
+		return
+	}
+	fmt.Println("MSG:", *msg.ID, *msg.Event, content)
 }
 
 func messagesCreate(client *flowdock.Client) *flowdock.Message {
+	ctx := context.Background()
 	opt := &flowdock.MessagesCreateOptions{FlowID: "iora:egg",
 		Event:   "message",
 		Content: "Howdy-Doo @dd #awesome",
 		Tags:    []string{"test", ":#api:", "@wm"},
 	}
-	m, _, err := client.Messages.Create(opt)
+	m, _, err := client.Messages.Create(ctx, opt)
 	if err != nil {
 		log.Fatal("Get:", err)
 	}
@@ -112,12 +125,13 @@ func messagesCreate(client *flowdock.Client) *flowdock.Message {
 }
 
 func messagesComment(client *flowdock.Client, messageID int) {
+	ctx := context.Background()
 	opt := &flowdock.MessagesCreateOptions{FlowID: "iora:egg",
 		MessageID: messageID,
 		Event:     "comment",
 		Content:   "Commenting yo!",
 	}
-	m, _, err := client.Messages.CreateComment(opt)
+	m, _, err := client.Messages.CreateComment(ctx, opt)
 	if err != nil {
 		log.Fatal("Get:", err)
 	}
@@ -126,6 +140,7 @@ func messagesComment(client *flowdock.Client, messageID int) {
 
 // TODO: needs to be fixed (load token from file)
 func inboxMessage(client *flowdock.Client) *flowdock.Message {
+	ctx := context.Background()
 	opt := &flowdock.InboxCreateOptions{
 		Source:      "go-flowdock",
 		FromName:    "TeamCity CI",
@@ -153,7 +168,7 @@ func inboxMessage(client *flowdock.Client) *flowdock.Message {
 		`,
 		Tags: []string{"fail", "CI", "87"},
 	}
-	m, _, err := client.Inbox.Create("SOME_TOKEN", opt)
+	m, _, err := client.Inbox.Create(ctx, "SOME_TOKEN", opt)
 	if err != nil {
 		log.Fatal("Get:", err)
 	}
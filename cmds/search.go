@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/wm/go-flowdock/auth"
 	"github.com/wm/go-flowdock/flowdock"
@@ -18,6 +19,7 @@ func main() {
 }
 
 func messageSearch(tags *[]string, event, search *string, client *flowdock.Client) {
+	ctx := context.Background()
 	opt := flowdock.MessagesListOptions{Limit: 100, TagMode: "and"}
 	if tags != nil {
 		opt.Tags = *tags
@@ -28,7 +30,7 @@ func messageSearch(tags *[]string, event, search *string, client *flowdock.Clien
 	if event != nil {
 		opt.Event = *event
 	}
-	messages, _, err := client.Messages.List("iora", "tech-stuff", &opt)
+	messages, _, err := client.Messages.List(ctx, "iora", "tech-stuff", &opt)
 
 	if err != nil {
 		log.Fatal("Get:", err)
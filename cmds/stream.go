@@ -2,6 +2,7 @@ package main
 
 import (
 	"code.google.com/p/goauth2/oauth"
+	"context"
 	"fmt"
 	"github.com/wm/go-flowdock/auth"
 	"github.com/wm/go-flowdock/flowdock"
@@ -21,24 +22,32 @@ func main() {
 }
 
 func messageStream(client *flowdock.Client, token string) {
-	stream, es, _ := client.Messages.Stream(token, "iora", "tech-stuff")
-	stream1, es1, _ := client.Messages.Stream(token, "iora", "technical-discussions")
+	ctx := context.Background()
+	stream, es, _ := client.Messages.Stream(ctx, token, "iora", "tech-stuff")
+	stream1, es1, _ := client.Messages.Stream(ctx, token, "iora", "technical-discussions")
 	defer es.Close()
 	defer es1.Close()
 
 	for {
 		select {
-		case msg := <-stream:
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
 			displayMessageData(msg, "wc")
-		case msg1 := <-stream1:
+		case msg1, ok := <-stream1:
+			if !ok {
+				return
+			}
 			displayMessageData(msg1, "td")
 		}
 	}
 }
 
 func messageList(client *flowdock.Client) {
+	ctx := context.Background()
 	opt := flowdock.MessagesListOptions{Limit: 100}
-	messages, _, err := client.Messages.List("iora", "tech-stuff", &opt)
+	messages, _, err := client.Messages.List(ctx, "iora", "tech-stuff", &opt)
 
 	if err != nil {
 		log.Fatal("Get:", err)
@@ -52,7 +61,12 @@ func messageList(client *flowdock.Client) {
 func displayMessageData(msg flowdock.Message, room string) {
 	events := []string{"user-edit", "file", "activity.user", "mail", "zendesk", "twitter", "tag-change"}
 	if stringNotInSlice(*msg.Event, events) {
-		fmt.Println("\nMSG:", room, *msg.ID, *msg.Event, msg.Content())
+		content, err := msg.ContentE()
+		if err != nil {
+			fmt.Println("\nMSG:", room, *msg.ID, *msg.Event, "malformed content:", err)
+			return
+		}
+		fmt.Println("\nMSG:", room, *msg.ID, *msg.Event, content)
 	}
 }
 
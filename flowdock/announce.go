@@ -0,0 +1,179 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultAnnounceWindow is how long an Announcer remembers announcements by
+// default.
+const DefaultAnnounceWindow = time.Hour
+
+// Announcer posts announcements, such as company-wide ones, to many flows at
+// once. An announcement posted to a flow within Window is not posted there
+// again, so that a job retried after a partial failure only posts to the
+// flows it missed. Announcements are compared by content and tags.
+type Announcer struct {
+	client *Client
+	store  Store
+
+	// Window is how long announcements are remembered.
+	Window time.Duration
+
+	// Tags are added to every announcement, so that they can be found
+	// across flows, such as "announcement".
+	Tags []string
+}
+
+// NewAnnouncer returns an Announcer posting through client, and
+// remembering announcements in store. A nil store keeps them in memory;
+// use a durable Store, such as a FileStore, to suppress duplicates across
+// runs.
+func NewAnnouncer(client *Client, store Store) *Announcer {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Announcer{client: client, store: store, Window: DefaultAnnounceWindow}
+}
+
+// AnnounceResult is the outcome of an announcement in one flow.
+type AnnounceResult struct {
+	Flow      FlowRef
+	Message   *Message // posted, or posted earlier for duplicates
+	Duplicate bool     // whether it was posted within the Window already
+	Err       error
+}
+
+// AnnounceReport consolidates the outcome of an announcement, with a result
+// per flow, in the order the flows were given.
+type AnnounceReport struct {
+	Results []AnnounceResult
+}
+
+// Posted returns the number of flows the announcement was posted to.
+func (r *AnnounceReport) Posted() int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Err == nil && !res.Duplicate {
+			n++
+		}
+	}
+	return n
+}
+
+// Duplicates returns the number of flows the announcement was posted to
+// earlier, and not again.
+func (r *AnnounceReport) Duplicates() int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Duplicate {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the results of the flows the announcement failed to be
+// posted to.
+func (r *AnnounceReport) Failed() []AnnounceResult {
+	var failed []AnnounceResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// String summarizes the report, such as "posted to 2 flows, 1 duplicate,
+// 0 failed".
+func (r *AnnounceReport) String() string {
+	s := fmt.Sprintf("posted to %d flows, %d duplicate, %d failed", r.Posted(), r.Duplicates(), len(r.Failed()))
+	for _, res := range r.Failed() {
+		s += fmt.Sprintf("; %v: %v", res.Flow, res.Err)
+	}
+	return s
+}
+
+// Announce posts the message opt to each of flows, concurrently on the
+// client's Workers, with the Tags of the Announcer added to its own. The
+// FlowID of opt is ignored. Flows the same announcement was posted to within
+// the Window are skipped, and reported as duplicates. Failures do not stop
+// the posting to the other flows: they are in the report, and returned in a
+// *BulkError identified by flow reference.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (a *Announcer) Announce(ctx context.Context, flows []FlowRef, opt *MessagesCreateOptions) (*AnnounceReport, error) {
+	msg := *opt
+	msg.FlowID = ""
+	if msg.Event == "" {
+		msg.Event = "message"
+	}
+	msg.Tags = append(Tags(nil), a.Tags...)
+	for _, tag := range opt.Tags {
+		if !containsTag(msg.Tags, tag) {
+			msg.Tags = append(msg.Tags, tag)
+		}
+	}
+	hash := contentHash([]byte(msg.Content + "\x00" + strings.Join(msg.Tags, ",")))
+
+	report := &AnnounceReport{Results: make([]AnnounceResult, len(flows))}
+	errs := a.client.Workers.Run(ctx, len(flows), func(ctx context.Context, i int) error {
+		res := &report.Results[i]
+		var err error
+		res.Message, res.Duplicate, err = a.post(ctx, flows[i], hash, msg)
+		return err
+	})
+	for i, err := range errs {
+		report.Results[i].Flow = flows[i]
+		report.Results[i].Err = err // such as the panics of tasks
+	}
+	return report, bulkErrors(errs, func(i int) string { return flows[i].String() })
+}
+
+// post posts msg, whose announcement hash is hash, to flow unless it was
+// posted there within the Window, and reports whether it was.
+func (a *Announcer) post(ctx context.Context, flow FlowRef, hash string, msg MessagesCreateOptions) (*Message, bool, error) {
+	if err := flow.validate(); err != nil {
+		return nil, false, err
+	}
+	key := fmt.Sprintf("announcements/%s/%s/%s", flow.Org, flow.Flow, hash)
+	if m, ok := a.lookup(key); ok {
+		return m, true, nil
+	}
+
+	m, _, err := a.client.Messages.createInFlow(ctx, string(flow.Org), flow.Flow, &msg)
+	if err != nil {
+		return nil, false, err
+	}
+	value, err := json.Marshal(m)
+	if err != nil {
+		return m, false, err
+	}
+	data, err := json.Marshal(cacheEntry{Fetched: Time{a.client.Clock.Now()}, Value: value})
+	if err != nil {
+		return m, false, err
+	}
+	return m, false, a.store.Set(key, data)
+}
+
+// lookup returns the message of the announcement stored under key, if it
+// was posted within the Window.
+func (a *Announcer) lookup(key string) (*Message, bool) {
+	data, ok, err := a.store.Get(key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || a.client.Clock.Now().Sub(entry.Fetched.Time) >= a.Window {
+		return nil, false
+	}
+	m := new(Message)
+	if err := json.Unmarshal(entry.Value, m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
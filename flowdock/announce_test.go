@@ -0,0 +1,73 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAnnouncer_Announce(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+
+	var mu sync.Mutex
+	posts := make(map[string]int)
+	for _, flow := range []string{"a", "b"} {
+		flow := flow
+		mux.HandleFunc("/flows/org/"+flow+"/messages", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, "POST")
+			testJSONValues(t, r, values{"event": "message", "content": "Office closed", "tags": "announcement,office"})
+			mu.Lock()
+			posts[flow]++
+			mu.Unlock()
+			fmt.Fprint(w, `{"id":1}`)
+		})
+	}
+
+	a := NewAnnouncer(client, nil)
+	a.Tags = []string{"announcement"}
+	flows := []FlowRef{{Org: "org", Flow: "a"}, {Org: "org", Flow: "b"}, {Org: "org", Flow: "missing"}}
+	opt := &MessagesCreateOptions{Content: "Office closed", Tags: []string{"office", "#Announcement"}, FlowID: "ignored"}
+
+	report, err := a.Announce(ctx, flows, opt)
+	if berr, ok := err.(*BulkError); !ok || len(berr.Errors) != 1 || berr.Errors[0].ID != "org/missing" {
+		t.Fatalf("Announcer.Announce returned %v, want a *BulkError for org/missing", err)
+	}
+	if report.Posted() != 2 || report.Duplicates() != 0 || len(report.Failed()) != 1 || report.Results[2].Flow != flows[2] {
+		t.Errorf("Announcer.Announce reported %v", report)
+	}
+
+	// retried: only the missed flow is posted to again
+	clock.Advance(time.Minute)
+	report, _ = a.Announce(ctx, flows, opt)
+	if report.Posted() != 0 || report.Duplicates() != 2 || *report.Results[0].Message.ID != 1 {
+		t.Errorf("Announcer.Announce reported %v on retry", report)
+	}
+
+	clock.Advance(DefaultAnnounceWindow)
+	report, _ = a.Announce(ctx, flows[:1], opt)
+	if report.Posted() != 1 {
+		t.Errorf("Announcer.Announce reported %v after the window", report)
+	}
+	if posts["a"] != 2 || posts["b"] != 1 {
+		t.Errorf("Announcer.Announce posted %v", posts)
+	}
+}
+
+func TestAnnounceReport_String(t *testing.T) {
+	r := &AnnounceReport{Results: []AnnounceResult{
+		{Flow: FlowRef{Org: "o", Flow: "a"}},
+		{Flow: FlowRef{Org: "o", Flow: "b"}, Duplicate: true},
+		{Flow: FlowRef{Org: "o", Flow: "c"}, Err: fmt.Errorf("boom")},
+	}}
+	if got, want := r.String(), "posted to 1 flows, 1 duplicate, 1 failed; o/c: boom"; got != want {
+		t.Errorf("AnnounceReport.String() = %q, want %q", got, want)
+	}
+}
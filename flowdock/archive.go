@@ -0,0 +1,222 @@
+package flowdock
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Archive is an index of the messages of exported flows, as written from
+// the fn of an Exporter, searched with Search for historical
+// investigations without requests to the API. The zero value is an empty
+// archive. An Archive is not safe for concurrent use.
+type Archive struct {
+	byKey    map[archiveKey]Message
+	messages []Message        // sorted by Sent, flow and ID
+	byUser   map[string][]int // user ID to indexes of messages
+	byTag    map[string][]int // normalized tag to indexes of messages
+}
+
+// archiveKey identifies a message of an Archive: IDs are only unique
+// within a flow.
+type archiveKey struct {
+	flow string
+	id   int
+}
+
+// ArchiveQuery selects messages of an Archive. The zero value selects
+// every message.
+type ArchiveQuery struct {
+	Flow  string         // ID of the flow of the messages
+	Users []string       // IDs of the authors, any of them
+	Tags  []string       // tags of the messages, all of them; "#" and case are ignored
+	Event string         // event of the messages, such as "comment"
+	Since time.Time      // sent at or after
+	Until time.Time      // sent before
+	Text  *regexp.Regexp // matching the String of the content
+	Limit int            // maximum number of messages, 0 for all
+}
+
+// LoadArchive returns an Archive of the JSON lines export of each reader.
+func LoadArchive(readers ...io.Reader) (*Archive, error) {
+	a := new(Archive)
+	for _, r := range readers {
+		if err := a.Add(r); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Add reads the messages of the JSON lines export of r into the archive.
+// A message already in the archive, as exported again, is replaced by its
+// last occurrence. Messages without an ID fail the whole export, which is
+// then not added.
+func (a *Archive) Add(r io.Reader) error {
+	snapshot, err := readSnapshot(r)
+	if err != nil {
+		return fmt.Errorf("flowdock: archive: %v", err)
+	}
+	if a.byKey == nil {
+		a.byKey = make(map[archiveKey]Message)
+	}
+	for id, m := range snapshot {
+		var flow string
+		if m.FlowID != nil {
+			flow = *m.FlowID
+		}
+		a.byKey[archiveKey{flow, id}] = m.Message
+	}
+	a.index()
+	return nil
+}
+
+// Len returns the number of messages of the archive.
+func (a *Archive) Len() int {
+	return len(a.messages)
+}
+
+// index sorts the messages and rebuilds the indexes by user and tag.
+func (a *Archive) index() {
+	a.messages = a.messages[:0]
+	for _, m := range a.byKey {
+		a.messages = append(a.messages, m)
+	}
+	sort.Slice(a.messages, func(i, j int) bool {
+		mi, mj := &a.messages[i], &a.messages[j]
+		if ti, tj := sentAt(mi), sentAt(mj); !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		if fi, fj := flowOf(mi), flowOf(mj); fi != fj {
+			return fi < fj
+		}
+		return *mi.ID < *mj.ID
+	})
+
+	a.byUser = make(map[string][]int)
+	a.byTag = make(map[string][]int)
+	for i := range a.messages {
+		m := &a.messages[i]
+		if m.UserID != nil {
+			a.byUser[*m.UserID] = append(a.byUser[*m.UserID], i)
+		}
+		if m.Tags != nil {
+			seen := make(map[string]bool)
+			for _, tag := range *m.Tags {
+				tag = normalizeTag(tag)
+				if !seen[tag] {
+					seen[tag] = true
+					a.byTag[tag] = append(a.byTag[tag], i)
+				}
+			}
+		}
+	}
+}
+
+// Search returns the messages of the archive selected by q, oldest first.
+// The indexes by user and tag narrow the messages looked at; the other
+// criteria are checked on each of them.
+func (a *Archive) Search(q *ArchiveQuery) []Message {
+	if q == nil {
+		q = new(ArchiveQuery)
+	}
+
+	var found []Message
+	for _, i := range a.candidates(q) {
+		m := &a.messages[i]
+		if !q.matches(m) {
+			continue
+		}
+		found = append(found, *m)
+		if q.Limit > 0 && len(found) == q.Limit {
+			break
+		}
+	}
+	return found
+}
+
+// candidates returns the sorted indexes of the messages which may match q,
+// from the most selective of its indexed criteria.
+func (a *Archive) candidates(q *ArchiveQuery) []int {
+	var best []int
+	indexed := false
+	if len(q.Users) > 0 {
+		seen := make(map[string]bool)
+		for _, user := range q.Users {
+			if !seen[user] {
+				seen[user] = true
+				best = append(best, a.byUser[user]...)
+			}
+		}
+		sort.Ints(best)
+		indexed = true
+	}
+	for _, tag := range q.Tags {
+		if ids := a.byTag[normalizeTag(tag)]; !indexed || len(ids) < len(best) {
+			best, indexed = ids, true
+		}
+	}
+	if indexed {
+		return best
+	}
+
+	all := make([]int, len(a.messages))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// matches reports whether m is selected by q.
+func (q *ArchiveQuery) matches(m *Message) bool {
+	if q.Flow != "" && flowOf(m) != q.Flow {
+		return false
+	}
+	if len(q.Users) > 0 && (m.UserID == nil || !containsString(q.Users, *m.UserID)) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if m.Tags == nil || !containsTag(*m.Tags, tag) {
+			return false
+		}
+	}
+	if q.Event != "" && (m.Event == nil || *m.Event != q.Event) {
+		return false
+	}
+	sent := sentAt(m)
+	if !q.Since.IsZero() && sent.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !sent.Before(q.Until) {
+		return false
+	}
+	if q.Text != nil && !q.Text.MatchString(m.contentText()) {
+		return false
+	}
+	return true
+}
+
+func sentAt(m *Message) time.Time {
+	if m.Sent == nil {
+		return time.Time{}
+	}
+	return m.Sent.Time
+}
+
+func flowOf(m *Message) string {
+	if m.FlowID == nil {
+		return ""
+	}
+	return *m.FlowID
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package flowdock
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchive_Search(t *testing.T) {
+	first := strings.NewReader(`{"id":1,"flow":"f","sent":1000,"user":"1","event":"message","content":"deploy started","tags":["#Deploy"]}
+{"id":2,"flow":"f","sent":2000,"user":"2","event":"message","content":"looks good"}
+{"id":3,"flow":"f","sent":3000,"user":"1","event":"comment","content":{"title":"t","text":"deploy failed"},"tags":["deploy","ops"]}
+`)
+	// another flow, with an ID of the first one, and message 2 exported again
+	second := strings.NewReader(`{"id":1,"flow":"g","sent":1500,"user":"2","event":"message","content":"other flow","tags":["ops"]}
+{"id":2,"flow":"f","sent":2000,"user":"2","event":"message","content":"looks great"}
+`)
+
+	archive, err := LoadArchive(first, second)
+	if err != nil {
+		t.Fatalf("LoadArchive returned error: %v", err)
+	}
+	if got := archive.Len(); got != 4 {
+		t.Errorf("Len = %d, want 4", got)
+	}
+
+	ids := func(messages []Message) string {
+		var s []string
+		for _, m := range messages {
+			s = append(s, fmt.Sprintf("%s/%d", *m.FlowID, *m.ID))
+		}
+		return strings.Join(s, " ")
+	}
+	tests := []struct {
+		query *ArchiveQuery
+		want  string
+	}{
+		{nil, "f/1 g/1 f/2 f/3"},
+		{&ArchiveQuery{Flow: "f"}, "f/1 f/2 f/3"},
+		{&ArchiveQuery{Users: []string{"2", "2"}}, "g/1 f/2"},
+		{&ArchiveQuery{Tags: []string{"DEPLOY"}}, "f/1 f/3"},
+		{&ArchiveQuery{Tags: []string{"#deploy", "ops"}}, "f/3"},
+		{&ArchiveQuery{Users: []string{"1"}, Tags: []string{"ops"}}, "f/3"},
+		{&ArchiveQuery{Event: "comment"}, "f/3"},
+		{&ArchiveQuery{Since: time.Unix(1, 500e6), Until: time.Unix(3, 0)}, "g/1 f/2"},
+		{&ArchiveQuery{Text: regexp.MustCompile(`^deploy`)}, "f/1 f/3"},
+		{&ArchiveQuery{Text: regexp.MustCompile(`great`)}, "f/2"},
+		{&ArchiveQuery{Tags: []string{"none"}}, ""},
+		{&ArchiveQuery{Limit: 2}, "f/1 g/1"},
+	}
+	for _, tt := range tests {
+		if got := ids(archive.Search(tt.query)); got != tt.want {
+			t.Errorf("Search(%+v) returned %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestArchive_Add_invalid(t *testing.T) {
+	var archive Archive
+	if err := archive.Add(strings.NewReader(`{"id":1,"flow":"f"}` + "\n" + `{"flow":"f"}`)); err == nil {
+		t.Errorf("Add returned no error for a message without an ID")
+	}
+	if archive.Len() != 0 {
+		t.Errorf("Add of an invalid export added %d messages", archive.Len())
+	}
+}
@@ -0,0 +1,223 @@
+package flowdock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Attachment is a file attached to a message, as recorded in the manifest
+// of an AttachmentCache.
+type Attachment struct {
+	Path        string `json:"path"`
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Hash        string `json:"hash"` // SHA-256 of the content, in hexadecimal
+}
+
+// AttachmentCache keeps the files attached to exported messages, those of
+// "file" events and the attachments of mails, in a Store. Files are stored
+// once under the hash of their content, whatever the messages they are
+// attached to, and a manifest maps each message to its files. Messages
+// whose files are all in the cache are not downloaded again, so that
+// repeated exports of overlapping ranges only download new files. Set it as
+// the Attachments of an Exporter, or fill it with Prefetch and
+// PrefetchAvatars before rendering messages with their images.
+type AttachmentCache struct {
+	store Store
+}
+
+// NewAttachmentCache returns an AttachmentCache keeping files in store. A
+// nil store keeps them in memory; use a durable Store, such as a FileStore,
+// to skip downloads across runs.
+func NewAttachmentCache(store Store) *AttachmentCache {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &AttachmentCache{store: store}
+}
+
+// Manifest returns the files attached to the message with ID id of the flow
+// named flow in the organization org, and whether they were cached.
+func (c *AttachmentCache) Manifest(org, flow string, id int) ([]Attachment, bool, error) {
+	data, ok, err := c.store.Get(attachmentManifestKey(org, flow, id))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var files []Attachment
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, false, err
+	}
+	return files, true, nil
+}
+
+// File returns the content of the file with the given hash, as found in a
+// manifest, and whether it was cached.
+func (c *AttachmentCache) File(hash string) ([]byte, bool, error) {
+	return c.store.Get(attachmentFileKey(hash))
+}
+
+// fetch downloads the files attached to m of org/flow through client, unless
+// they are all cached already, and records them in the manifest of m.
+func (c *AttachmentCache) fetch(ctx context.Context, client *Client, org, flow string, m *Message) error {
+	files := attachmentsOf(m)
+	if len(files) == 0 || m.ID == nil {
+		return nil
+	}
+	if c.cached(org, flow, *m.ID) {
+		return nil
+	}
+
+	for i := range files {
+		var buf bytes.Buffer
+		if _, err := client.Messages.Download(ctx, files[i].Path, &buf); err != nil {
+			return err
+		}
+		files[i].Hash = contentHash(buf.Bytes())
+		if err := c.store.Set(attachmentFileKey(files[i].Hash), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(attachmentManifestKey(org, flow, *m.ID), data)
+}
+
+// Prefetch downloads the files attached to messages of the flow named flow
+// in the organization org, concurrently on the client's Workers, so that
+// rendering the messages, with their inline images, is not held by the
+// network. Messages whose files are all cached are skipped. Failures do not
+// stop the other downloads and are reported in a *BulkError, identified by
+// message ID.
+func (c *AttachmentCache) Prefetch(ctx context.Context, client *Client, org, flow string, messages []Message) error {
+	errs := client.Workers.Run(ctx, len(messages), func(ctx context.Context, i int) error {
+		return c.fetch(ctx, client, org, flow, &messages[i])
+	})
+	return bulkErrors(errs, func(i int) string {
+		if messages[i].ID == nil {
+			return ""
+		}
+		return strconv.Itoa(*messages[i].ID)
+	})
+}
+
+// PrefetchAvatars downloads the avatars of users concurrently on the
+// client's Workers, for Avatar. Avatars are downloaded once per URL, and
+// those cached already are skipped. They are served by another host than
+// the API, so the requests carry no credentials. Failures do not stop the
+// other downloads and are reported in a *BulkError, identified by URL.
+func (c *AttachmentCache) PrefetchAvatars(ctx context.Context, client *Client, users []User) error {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, u := range users {
+		if u.Avatar == nil || *u.Avatar == "" || seen[*u.Avatar] {
+			continue
+		}
+		seen[*u.Avatar] = true
+		if _, ok, err := c.Avatar(*u.Avatar); err == nil && ok {
+			continue
+		}
+		urls = append(urls, *u.Avatar)
+	}
+
+	errs := client.Workers.Run(ctx, len(urls), func(ctx context.Context, i int) error {
+		return c.fetchAvatar(ctx, client, urls[i])
+	})
+	return bulkErrors(errs, func(i int) string { return urls[i] })
+}
+
+// Avatar returns the avatar found at url, such as the Avatar of a User, as
+// downloaded by PrefetchAvatars, and whether it was cached.
+func (c *AttachmentCache) Avatar(url string) ([]byte, bool, error) {
+	return c.store.Get(attachmentAvatarKey(url))
+}
+
+// fetchAvatar downloads the avatar found at url with the HTTP client of
+// client, and caches it.
+func (c *AttachmentCache) fetchAvatar(ctx context.Context, client *Client, url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := CheckResponse(resp); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return err
+	}
+	return c.store.Set(attachmentAvatarKey(url), buf.Bytes())
+}
+
+// cached reports whether the manifest of the message with ID id and all
+// its files are in the cache.
+func (c *AttachmentCache) cached(org, flow string, id int) bool {
+	files, ok, err := c.Manifest(org, flow, id)
+	if err != nil || !ok {
+		return false
+	}
+	for _, f := range files {
+		if _, ok, err := c.store.Get(attachmentFileKey(f.Hash)); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// attachmentsOf returns the files attached to m, without their hash.
+func attachmentsOf(m *Message) []Attachment {
+	var files []Attachment
+	if m.Event != nil && *m.Event == "file" {
+		content, _ := m.ContentE() // nil if malformed
+		if c, ok := content.(*FileContent); ok && c.Path != nil {
+			files = append(files, Attachment{
+				Path:        *c.Path,
+				FileName:    stringValue(c.FileName),
+				ContentType: stringValue(c.ContentType),
+			})
+		}
+	}
+	if mail, ok := m.Mail(); ok {
+		for _, a := range mail.Attachments {
+			if a.Path != nil {
+				files = append(files, Attachment{
+					Path:        *a.Path,
+					FileName:    stringValue(a.FileName),
+					ContentType: stringValue(a.ContentType),
+				})
+			}
+		}
+	}
+	return files
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func attachmentFileKey(hash string) string {
+	return "attachments/files/" + hash
+}
+
+func attachmentAvatarKey(url string) string {
+	return "attachments/avatars/" + contentHash([]byte(url))
+}
+
+func attachmentManifestKey(org, flow string, id int) string {
+	return fmt.Sprintf("attachments/manifests/%s/%s/%d", org, flow, id)
+}
@@ -0,0 +1,141 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestExporter_attachments(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"id":1,"event":"file","content":{"path":"/files/a/x.txt","file_name":"x.txt"}},
+			{"id":2,"event":"message","content":"hi"},
+			{"id":3,"event":"mail","content":{"attachments":[{"path":"/files/b/y.txt","file_name":"y.txt"}]}}
+		]`)
+	})
+	downloads := make(map[string]int)
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		downloads[r.URL.Path]++
+		fmt.Fprint(w, "same content")
+	})
+
+	cache := NewAttachmentCache(nil)
+	for run := 0; run < 2; run++ {
+		e := NewExporter(client, nil)
+		e.Attachments = cache
+		if err := e.ExportFlow(ctx, "o", "f", func(*Message) error { return nil }); err != nil {
+			t.Fatalf("Exporter.ExportFlow returned error: %v", err)
+		}
+	}
+	if want := map[string]int{"/files/a/x.txt": 1, "/files/b/y.txt": 1}; !reflect.DeepEqual(downloads, want) {
+		t.Errorf("Exporter.ExportFlow downloaded %v, want %v", downloads, want)
+	}
+
+	hash := contentHash([]byte("same content"))
+	files, ok, err := cache.Manifest("o", "f", 3)
+	if want := []Attachment{{Path: "/files/b/y.txt", FileName: "y.txt", Hash: hash}}; err != nil || !ok || !reflect.DeepEqual(files, want) {
+		t.Errorf("AttachmentCache.Manifest returned %+v, %v, %v, want %+v", files, ok, err, want)
+	}
+	if _, ok, _ := cache.Manifest("o", "f", 2); ok {
+		t.Errorf("AttachmentCache.Manifest returned a manifest for a message without files")
+	}
+	if data, ok, err := cache.File(hash); err != nil || !ok || string(data) != "same content" {
+		t.Errorf("AttachmentCache.File returned %q, %v, %v, want the content", data, ok, err)
+	}
+	if keys, _ := cache.store.Keys("attachments/files/"); len(keys) != 1 {
+		t.Errorf("AttachmentCache stored files %q, want the content once", keys)
+	}
+}
+
+func TestAttachmentCache_Prefetch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	downloads := make(map[string]int)
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downloads[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/files/missing" {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "content of "+r.URL.Path)
+	})
+
+	file := func(id int, path string) Message {
+		raw := json.RawMessage(fmt.Sprintf(`{"path":%q}`, path))
+		event := "file"
+		return Message{ID: &id, Event: &event, RawContent: &raw}
+	}
+	messages := []Message{file(1, "/files/a"), file(2, "/files/b"), file(3, "/files/missing")}
+
+	cache := NewAttachmentCache(nil)
+	for run := 0; run < 2; run++ {
+		err := cache.Prefetch(ctx, client, "o", "f", messages)
+		berr, ok := err.(*BulkError)
+		if !ok || len(berr.Errors) != 1 || berr.Errors[0].ID != "3" {
+			t.Fatalf("AttachmentCache.Prefetch returned %v, want a *BulkError for message 3", err)
+		}
+	}
+	if want := map[string]int{"/files/a": 1, "/files/b": 1, "/files/missing": 2}; !reflect.DeepEqual(downloads, want) {
+		t.Errorf("AttachmentCache.Prefetch downloaded %v, want %v", downloads, want)
+	}
+	files, ok, _ := cache.Manifest("o", "f", 2)
+	if !ok || len(files) != 1 {
+		t.Fatalf("AttachmentCache.Manifest returned %+v, %v", files, ok)
+	}
+	if data, _, _ := cache.File(files[0].Hash); string(data) != "content of /files/b" {
+		t.Errorf("AttachmentCache.File returned %q", data)
+	}
+}
+
+func TestAttachmentCache_PrefetchAvatars(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	var requests []string
+	avatars := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "" || r.URL.User != nil {
+			t.Errorf("avatar request carries credentials")
+		}
+		fmt.Fprint(w, "avatar "+r.URL.Path)
+	}))
+	defer avatars.Close()
+
+	a, b := avatars.URL+"/a.png", avatars.URL+"/b.png"
+	users := []User{{Avatar: &a}, {Avatar: &b}, {Avatar: &a}, {}}
+
+	cache := NewAttachmentCache(nil)
+	for run := 0; run < 2; run++ {
+		if err := cache.PrefetchAvatars(ctx, client, users); err != nil {
+			t.Fatalf("AttachmentCache.PrefetchAvatars returned error: %v", err)
+		}
+	}
+	sort.Strings(requests)
+	if want := []string{"/a.png", "/b.png"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("AttachmentCache.PrefetchAvatars requested %q, want %q", requests, want)
+	}
+	if data, ok, err := cache.Avatar(b); err != nil || !ok || string(data) != "avatar /b.png" {
+		t.Errorf("AttachmentCache.Avatar returned %q, %v, %v", data, ok, err)
+	}
+}
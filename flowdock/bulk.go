@@ -0,0 +1,110 @@
+package flowdock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// BulkItemError is the failure of one item of a bulk operation.
+type BulkItemError struct {
+	Index     int    // position of the item in the operation's input
+	ID        string // identifier of the item, when it has one
+	Err       error
+	Retryable bool // whether trying the item again may succeed
+}
+
+func (e BulkItemError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("%s: %v", e.ID, e.Err)
+	}
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// BulkError reports the items of a bulk operation that failed, while the
+// others succeeded.
+type BulkError struct {
+	Errors []BulkItemError
+}
+
+func (e *BulkError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, item := range e.Errors {
+		msgs[i] = item.Error()
+	}
+	return fmt.Sprintf("%d bulk items failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed items.
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, item := range e.Errors {
+		errs[i] = item.Err
+	}
+	return errs
+}
+
+// Retryable returns the failed items that may succeed if tried again.
+func (e *BulkError) Retryable() []BulkItemError {
+	var items []BulkItemError
+	for _, item := range e.Errors {
+		if item.Retryable {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RetryableIndexes returns the input positions of the failed items that may
+// succeed if tried again, to build the input of a second pass.
+func (e *BulkError) RetryableIndexes() []int {
+	var indexes []int
+	for _, item := range e.Retryable() {
+		indexes = append(indexes, item.Index)
+	}
+	return indexes
+}
+
+// add records the failure of an item.
+func (e *BulkError) add(index int, id string, err error) {
+	e.Errors = append(e.Errors, BulkItemError{
+		Index:     index,
+		ID:        id,
+		Err:       err,
+		Retryable: isRetryable(err),
+	})
+}
+
+// err returns e if any item failed, and nil otherwise.
+func (e *BulkError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// isRetryable reports whether a request that failed with err may succeed if
+// sent again: network failures, rate limiting and server errors. Requests
+// timing out on the deadline of the caller would time out again.
+func isRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		c := e.StatusCode()
+		return c == http.StatusTooManyRequests || c >= 500
+	}
+	var rateLimit *RateLimitError
+	if errors.As(err, &rateLimit) {
+		return true // not sent
+	}
+	var timeout *TimeoutError
+	if errors.As(err, &timeout) {
+		return !timeout.CallerDeadline
+	}
+
+	// failures of the http.Client are *url.Error values, which are
+	// net.Error values too
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
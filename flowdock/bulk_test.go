@@ -0,0 +1,54 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestBulkError_Retryable(t *testing.T) {
+	notFound := &ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	unavailable := &ErrorResponse{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}}
+	network := &url.Error{Op: "Get", URL: "/", Err: errors.New("connection reset")}
+
+	e := new(BulkError)
+	e.add(0, "1", notFound)
+	e.add(1, "2", unavailable)
+	e.add(2, "3", network)
+
+	if want := []int{1, 2}; !reflect.DeepEqual(e.RetryableIndexes(), want) {
+		t.Errorf("BulkError.RetryableIndexes() = %v, want %v", e.RetryableIndexes(), want)
+	}
+	if e.Error() == "" {
+		t.Errorf("Expected non-empty BulkError.Error()")
+	}
+	if !errors.Is(e, notFound) {
+		t.Errorf("errors.Is(BulkError, item error) = false, want true")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&ErrorResponse{Response: &http.Response{StatusCode: http.StatusTooManyRequests}}, true},
+		{&ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}, true},
+		{&ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden}}, false},
+		{fmt.Errorf("wrapped: %w", &Error{Response: &http.Response{StatusCode: http.StatusBadGateway}}), true},
+		{&url.Error{Op: "Get", URL: "/", Err: errors.New("EOF")}, true},
+		{errors.New("invalid character"), false},
+		{&RateLimitError{Rate: Rate{Limit: 1}, Request: &http.Request{}}, true},
+		{&TimeoutError{Class: ClassRead, Err: context.DeadlineExceeded}, true},
+		{&TimeoutError{Class: ClassRead, CallerDeadline: true, Err: context.DeadlineExceeded}, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultCacheTTL is how long a Cache reuses an entry by default.
+const DefaultCacheTTL = time.Hour
+
+// Cache memoizes user and flow lookups in a Store. Backed by a FileStore, it
+// lets short-lived programs reuse lookups made by previous runs instead of
+// fetching them again at startup.
+type Cache struct {
+	client *Client
+	store  Store
+
+	// TTL is how long an entry is reused before being fetched again.
+	TTL time.Duration
+
+	// FormerUsers makes User return a placeholder for users that no longer
+	// exist, instead of failing. Exports of old flows then get through
+	// messages of deleted users.
+	FormerUsers bool
+}
+
+// formerUserName is the name of the placeholders of deleted users.
+const formerUserName = "Former user"
+
+// cacheEntry is how values are kept in the Store.
+type cacheEntry struct {
+	Fetched Time            `json:"fetched"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// NewCache returns a Cache fetching through client and keeping entries in
+// store. A nil store keeps entries in memory.
+func NewCache(client *Client, store Store) *Cache {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Cache{client: client, store: store, TTL: DefaultCacheTTL}
+}
+
+// User returns the user with the given id, fetching it with ctx if needed.
+func (c *Cache) User(ctx context.Context, id int) (*User, error) {
+	user := new(User)
+	err := c.get(fmt.Sprintf("users/%d", id), user, func() (interface{}, error) {
+		u, _, err := c.client.Users.Get(ctx, id)
+		return u, err
+	})
+	if err != nil {
+		if c.FormerUsers && IsNotFound(err) {
+			return c.formerUser(id), nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// formerUser returns the placeholder of the deleted user with the given id,
+// known by its last cached nick if there is one.
+func (c *Cache) formerUser(id int) *User {
+	nick := fmt.Sprintf("former-user-%d", id)
+	if entry, ok := c.lookup(fmt.Sprintf("users/%d", id)); ok {
+		last := new(User)
+		if err := json.Unmarshal(entry.Value, last); err == nil && last.Nick != nil {
+			nick = *last.Nick
+		}
+	}
+
+	name := formerUserName
+	disabled := true
+	return &User{ID: &id, Nick: &nick, Name: &name, Disabled: &disabled}
+}
+
+// IsFormerUser reports whether u is a placeholder returned by a Cache for a
+// user that no longer exists.
+func IsFormerUser(u *User) bool {
+	return u.Name != nil && *u.Name == formerUserName &&
+		u.Disabled != nil && *u.Disabled
+}
+
+// Flow returns the flow named flow in the organization org, fetching it
+// with ctx if needed.
+func (c *Cache) Flow(ctx context.Context, org, flow string) (*Flow, error) {
+	f := new(Flow)
+	err := c.get(fmt.Sprintf("flows/%s/%s", org, flow), f, func() (interface{}, error) {
+		f, _, err := c.client.Flows.Get(ctx, org, flow)
+		return f, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// InvalidateUser drops the cached user with the given id.
+func (c *Cache) InvalidateUser(id int) error {
+	return c.store.Delete(fmt.Sprintf("users/%d", id))
+}
+
+// InvalidateFlow drops the cached flow named flow in the organization org.
+func (c *Cache) InvalidateFlow(org, flow string) error {
+	return c.store.Delete(fmt.Sprintf("flows/%s/%s", org, flow))
+}
+
+// get decodes the entry under key into v, calling fetch to refresh missing
+// or expired entries.
+func (c *Cache) get(key string, v interface{}, fetch func() (interface{}, error)) error {
+	if entry, ok := c.lookup(key); ok && c.clock().Now().Sub(entry.Fetched.Time) < c.TTL {
+		if err := json.Unmarshal(entry.Value, v); err == nil {
+			return nil
+		}
+	}
+
+	fetched, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(fetched)
+	if err != nil {
+		return err
+	}
+	if err := c.put(key, value); err != nil {
+		return err
+	}
+	return json.Unmarshal(value, v)
+}
+
+// lookup returns the entry stored under key, regardless of its age.
+func (c *Cache) lookup(key string) (*cacheEntry, bool) {
+	data, ok, err := c.store.Get(key)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	entry := new(cacheEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *Cache) put(key string, value []byte) error {
+	entry := cacheEntry{Fetched: Time{c.clock().Now()}, Value: value}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(key, data)
+}
+
+// clock returns the clock used to expire entries, the Client's one.
+func (c *Cache) clock() Clock {
+	if c.client.Clock == nil {
+		return SystemClock
+	}
+	return c.client.Clock
+}
@@ -0,0 +1,133 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCache_User(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	calls := 0
+	mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"id":1,"nick":"jackie"}`)
+	})
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	c := NewCache(client, nil)
+
+	for i := 0; i < 2; i++ {
+		user, err := c.User(ctx, 1)
+		if err != nil {
+			t.Fatalf("Cache.User returned error: %v", err)
+		}
+		if *user.Nick != "jackie" {
+			t.Errorf("Cache.User returned %+v, want jackie", user)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Cache.User fetched the user %d times, want 1", calls)
+	}
+
+	clock.Advance(c.TTL)
+	c.User(ctx, 1)
+	if calls != 2 {
+		t.Errorf("Cache.User did not refetch an expired entry")
+	}
+
+	c.InvalidateUser(1)
+	c.User(ctx, 1)
+	if calls != 3 {
+		t.Errorf("Cache.User did not refetch an invalidated entry")
+	}
+}
+
+func TestCache_Flow_persisted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	calls := 0
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"id":"org:flow"}`)
+	})
+
+	dir, err := ioutil.TempDir("", "flowdock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// two caches over the same directory, as two runs of a program
+	for i := 0; i < 2; i++ {
+		store, _ := NewFileStore(dir)
+		flow, err := NewCache(client, store).Flow(ctx, "org", "flow")
+		if err != nil {
+			t.Fatalf("Cache.Flow returned error: %v", err)
+		}
+		if *flow.ID != idOrgFlow {
+			t.Errorf("Cache.Flow returned %+v, want %v", flow, idOrgFlow)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Cache.Flow fetched the flow %d times, want 1", calls)
+	}
+}
+
+func TestCache_User_former(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	deleted := false
+	mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+		if deleted {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"nick":"jackie"}`)
+	})
+	mux.HandleFunc("/users/2", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	c := NewCache(client, nil)
+
+	if _, err := c.User(ctx, 2); !IsNotFound(err) {
+		t.Errorf("Cache.User returned %v for a deleted user, want a 404 error", err)
+	}
+
+	c.FormerUsers = true
+	c.User(ctx, 1)
+	deleted = true
+	clock.Advance(c.TTL)
+
+	tests := []struct {
+		id   int
+		nick string
+	}{
+		{1, "jackie"},
+		{2, "former-user-2"},
+	}
+	for _, tt := range tests {
+		user, err := c.User(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("Cache.User(%d) returned error: %v", tt.id, err)
+		}
+		if !IsFormerUser(user) || *user.ID != tt.id || *user.Nick != tt.nick {
+			t.Errorf("Cache.User(%d) = %+v, want a former user named %v", tt.id, user, tt.nick)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package flowdock
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultChaosRetryAfter is the Retry-After of the rate limited responses
+// of a ChaosTransport by default.
+const DefaultChaosRetryAfter = time.Second
+
+// ChaosTransport is an http.RoundTripper injecting faults in the requests
+// it sends, to test how a program using the library copes with a degraded
+// API: its retries, stream reconnections and rate limiting. Use it as the
+// Transport of the http.Client of a Client pointed at a test server, or,
+// carefully, at the API:
+//
+//	chaos := &flowdock.ChaosTransport{ServerErrorRate: 0.1, ResetRate: 0.05}
+//	client := flowdock.NewClient(&http.Client{Transport: chaos})
+//
+// Each request is first delayed with a probability of DelayRate, then
+// fails with a probability of ResetRate, ServerErrorRate or RateLimitRate,
+// whose sum must not exceed 1, or is sent. Failed requests are not sent.
+// A ChaosTransport is safe for concurrent use; set its fields before.
+type ChaosTransport struct {
+	// Transport sends the requests which are not failed. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// DelayRate is the probability of delaying a request, by a random
+	// duration up to Delay.
+	DelayRate float64
+	Delay     time.Duration
+
+	// ResetRate is the probability of failing a request as if the
+	// connection was reset by the server.
+	ResetRate float64
+
+	// ServerErrorRate is the probability of answering a request with a
+	// 503 Service Unavailable.
+	ServerErrorRate float64
+
+	// RateLimitRate is the probability of answering a request with a 429
+	// Too Many Requests, asking to retry after RetryAfter, rounded to the
+	// second. RetryAfter defaults to DefaultChaosRetryAfter.
+	RateLimitRate float64
+	RetryAfter    time.Duration
+
+	// Rand draws the faults. Defaults to a source seeded with the time;
+	// set a seeded one for reproducible runs.
+	Rand *rand.Rand
+
+	// Clock used for the delays. Defaults to SystemClock.
+	Clock Clock
+
+	mu    sync.Mutex
+	stats ChaosStats
+}
+
+// ChaosStats counts the faults injected by a ChaosTransport.
+type ChaosStats struct {
+	Requests     int // sent or failed
+	Delays       int
+	Resets       int
+	ServerErrors int
+	RateLimits   int
+}
+
+// Stats returns the faults injected so far.
+func (t *ChaosTransport) Stats() ChaosStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *ChaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay, fault := t.draw()
+	if delay > 0 {
+		clock := t.Clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		select {
+		case <-clock.After(delay):
+		case <-req.Context().Done():
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	switch fault {
+	case chaosReset:
+		closeBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	case chaosServerError:
+		closeBody(req)
+		return chaosResponse(req, http.StatusServiceUnavailable, nil), nil
+	case chaosRateLimit:
+		closeBody(req)
+		retryAfter := t.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = DefaultChaosRetryAfter
+		}
+		seconds := int((retryAfter + time.Second/2) / time.Second)
+		return chaosResponse(req, http.StatusTooManyRequests, http.Header{"Retry-After": {strconv.Itoa(seconds)}}), nil
+	}
+
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(req)
+}
+
+type chaosFault int
+
+const (
+	chaosNone chaosFault = iota
+	chaosReset
+	chaosServerError
+	chaosRateLimit
+)
+
+// draw returns the delay and the fault of the next request, and counts
+// them.
+func (t *ChaosTransport) draw() (time.Duration, chaosFault) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Rand == nil {
+		t.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	t.stats.Requests++
+
+	var delay time.Duration
+	if t.Delay > 0 && t.Rand.Float64() < t.DelayRate {
+		delay = time.Duration(t.Rand.Int63n(int64(t.Delay) + 1))
+		t.stats.Delays++
+	}
+
+	p := t.Rand.Float64()
+	switch {
+	case p < t.ResetRate:
+		t.stats.Resets++
+		return delay, chaosReset
+	case p < t.ResetRate+t.ServerErrorRate:
+		t.stats.ServerErrors++
+		return delay, chaosServerError
+	case p < t.ResetRate+t.ServerErrorRate+t.RateLimitRate:
+		t.stats.RateLimits++
+		return delay, chaosRateLimit
+	}
+	return delay, chaosNone
+}
+
+// chaosResponse returns an injected error response to req.
+func chaosResponse(req *http.Request, code int, header http.Header) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Type", "application/json")
+	body := fmt.Sprintf(`{"message":"chaos: injected %d %s"}`, code, http.StatusText(code))
+	return &http.Response{
+		Status:        strconv.Itoa(code) + " " + http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// closeBody closes the body of a request which is not sent, as
+// RoundTrippers must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,105 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChaosTransport_faults(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	served := 0
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		served++
+		fmt.Fprint(w, `[]`)
+	})
+
+	chaos := new(ChaosTransport)
+	client.client = &http.Client{Transport: chaos}
+
+	chaos.ServerErrorRate = 1
+	_, _, err := client.Users.List(ctx, nil)
+	if e, ok := err.(*Error); !ok || e.StatusCode() != http.StatusServiceUnavailable || IsMaintenance(err) {
+		t.Errorf("Users.List returned %v, want a 503", err)
+	}
+
+	chaos.ServerErrorRate, chaos.RateLimitRate, chaos.RetryAfter = 0, 1, 2*time.Second
+	_, resp, err := client.Users.List(ctx, nil)
+	if e, ok := err.(*Error); !ok || e.StatusCode() != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("Users.List returned %v, want a 429 to retry after 2s", err)
+	}
+
+	chaos.RateLimitRate, chaos.ResetRate = 0, 1
+	if _, _, err = client.Users.List(ctx, nil); err == nil || !isRetryable(err) {
+		t.Errorf("Users.List returned %v, want a transient connection error", err)
+	}
+
+	chaos.ResetRate = 0
+	if _, _, err = client.Users.List(ctx, nil); err != nil {
+		t.Errorf("Users.List returned error: %v", err)
+	}
+	if served != 1 {
+		t.Errorf("%d requests served, want only the one not failed", served)
+	}
+	want := ChaosStats{Requests: 4, Resets: 1, ServerErrors: 1, RateLimits: 1}
+	if got := chaos.Stats(); got != want {
+		t.Errorf("Stats returned %+v, want %+v", got, want)
+	}
+}
+
+func TestChaosTransport_delay(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	clock := NewFakeClock(time.Unix(0, 0))
+	chaos := &ChaosTransport{DelayRate: 1, Delay: time.Second, Clock: clock, Rand: rand.New(rand.NewSource(1))}
+	client.client = &http.Client{Transport: chaos}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Users.List(context.Background(), nil)
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Users.List returned %v before the delay", err)
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Users.List returned error: %v", err)
+	}
+}
+
+func TestChaosTransport_retried(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	chaos := &ChaosTransport{ResetRate: 0.2, ServerErrorRate: 0.2, Rand: rand.New(rand.NewSource(1))}
+	client.client = &http.Client{Transport: chaos}
+	client.Retry = &RetryPolicy{MaxAttempts: 10, Backoff: func(int) time.Duration { return 0 }}
+
+	for i := 0; i < 50; i++ {
+		if _, _, err := client.Users.List(context.Background(), nil); err != nil {
+			t.Fatalf("Users.List returned error despite retries: %v", err)
+		}
+	}
+	if stats := chaos.Stats(); stats.Resets == 0 || stats.ServerErrors == 0 {
+		t.Errorf("Stats returned %+v, want resets and server errors", stats)
+	}
+}
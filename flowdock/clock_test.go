@@ -0,0 +1,48 @@
+package flowdock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Date(2014, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	short := c.After(time.Second)
+	long := c.After(time.Minute)
+	if c.Waiters() != 2 {
+		t.Errorf("FakeClock.Waiters() = %v, want %v", c.Waiters(), 2)
+	}
+
+	c.Advance(2 * time.Second)
+
+	select {
+	case now := <-short:
+		if want := start.Add(2 * time.Second); !now.Equal(want) {
+			t.Errorf("FakeClock.After sent %v, want %v", now, want)
+		}
+	default:
+		t.Errorf("FakeClock.After(1s) did not fire after 2s")
+	}
+
+	select {
+	case <-long:
+		t.Errorf("FakeClock.After(1m) fired after 2s")
+	default:
+	}
+
+	if c.Waiters() != 1 {
+		t.Errorf("FakeClock.Waiters() = %v, want %v", c.Waiters(), 1)
+	}
+}
+
+func TestFakeClock_After_zero(t *testing.T) {
+	c := NewFakeClock(time.Now())
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Errorf("FakeClock.After(0) did not fire immediately")
+	}
+}
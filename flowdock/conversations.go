@@ -0,0 +1,124 @@
+package flowdock
+
+import (
+	"strconv"
+	"time"
+)
+
+// DefaultConversationGap is the silence after which a message without a
+// thread starts a new conversation.
+const DefaultConversationGap = 10 * time.Minute
+
+// Conversation is a thread of a flow: the message starting it and the
+// messages replying to it, in the order they were sent.
+type Conversation struct {
+	// ID is the thread id of the conversation, or the id of its starter
+	// for history older than threads.
+	ID      string
+	Starter *Message
+	Replies []*Message
+}
+
+// Messages returns the starter of the conversation followed by its replies.
+func (c *Conversation) Messages() []*Message {
+	return append([]*Message{c.Starter}, c.Replies...)
+}
+
+// Last returns the latest message of the conversation.
+func (c *Conversation) Last() *Message {
+	if len(c.Replies) == 0 {
+		return c.Starter
+	}
+	return c.Replies[len(c.Replies)-1]
+}
+
+// nonConversationEvents are the events which update other messages or the
+// flow rather than say something.
+var nonConversationEvents = map[string]bool{
+	"action":         true,
+	"message-edit":   true,
+	"emoji-reaction": true,
+	"tag-change":     true,
+	"user-edit":      true,
+	"activity.user":  true,
+}
+
+// Conversations segments msgs, in the order they were sent as returned by
+// MessagesService.List, into the conversations of the flow. Messages join
+// the conversation of their ThreadID, and comments the one of the message
+// they comment. Older messages, which have neither, join the previous
+// conversation without a thread unless they were sent more than gap after
+// its last message; a gap of 0 means DefaultConversationGap. Events which
+// don't say anything, such as tag changes, are left out.
+func Conversations(msgs []Message, gap time.Duration) []*Conversation {
+	if gap <= 0 {
+		gap = DefaultConversationGap
+	}
+
+	var (
+		convs    []*Conversation
+		threads  = make(map[string]*Conversation)
+		messages = make(map[int]*Conversation)
+		current  *Conversation // the latest conversation without a thread
+	)
+	start := func(id string, m *Message) *Conversation {
+		c := &Conversation{ID: id, Starter: m}
+		convs = append(convs, c)
+		return c
+	}
+
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Event != nil && nonConversationEvents[*m.Event] {
+			continue
+		}
+
+		var c *Conversation
+		switch {
+		case m.ThreadID != nil:
+			if c = threads[*m.ThreadID]; c == nil {
+				c = start(*m.ThreadID, m)
+				threads[*m.ThreadID] = c
+			} else {
+				c.Replies = append(c.Replies, m)
+			}
+		case m.MessageID != nil && messages[*m.MessageID] != nil:
+			c = messages[*m.MessageID]
+			c.Replies = append(c.Replies, m)
+		case current != nil && m.MessageID == nil && !after(m, current.Last(), gap):
+			c = current
+			c.Replies = append(c.Replies, m)
+		default:
+			// the parent of a comment may be older than msgs
+			c = start(messageKey(m), m)
+			if m.MessageID == nil {
+				current = c
+			}
+		}
+
+		if m.ID != nil {
+			messages[*m.ID] = c
+		}
+	}
+	return convs
+}
+
+// after reports whether m was sent more than gap after prev. Messages
+// without a sent time are never apart.
+func after(m, prev *Message, gap time.Duration) bool {
+	if m.Sent == nil || prev.Sent == nil {
+		return false
+	}
+	return m.Sent.Sub(prev.Sent.Time) > gap
+}
+
+// messageKey returns the id of m as a string, or its UUID when it has no id.
+func messageKey(m *Message) string {
+	switch {
+	case m.ID != nil:
+		return strconv.Itoa(*m.ID)
+	case m.UUID != nil:
+		return *m.UUID
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// conversationIDs returns the ids of the messages of each conversation.
+func conversationIDs(convs []*Conversation) map[string][]int {
+	ids := make(map[string][]int)
+	for _, c := range convs {
+		for _, m := range c.Messages() {
+			ids[c.ID] = append(ids[c.ID], *m.ID)
+		}
+	}
+	return ids
+}
+
+func TestConversations_threads(t *testing.T) {
+	var msgs []Message
+	json.Unmarshal([]byte(`[
+		{"id": 1, "event": "message", "thread_id": "a", "sent": 1317397485000},
+		{"id": 2, "event": "message", "thread_id": "b", "sent": 1317397486000},
+		{"id": 3, "event": "comment", "thread_id": "a", "message": 1, "sent": 1317397487000},
+		{"id": 4, "event": "tag-change", "thread_id": "a", "sent": 1317397488000},
+		{"id": 5, "event": "message", "thread_id": "b", "sent": 1317397489000}
+	]`), &msgs)
+
+	convs := Conversations(msgs, 0)
+	want := map[string][]int{"a": {1, 3}, "b": {2, 5}}
+	if got := conversationIDs(convs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Conversations returned %v, want %v", got, want)
+	}
+	if len(convs) != 2 || convs[0].ID != "a" || *convs[1].Last().ID != 5 {
+		t.Errorf("Conversations returned %+v, want threads a then b", convs)
+	}
+}
+
+func TestConversations_oldHistory(t *testing.T) {
+	var msgs []Message
+	json.Unmarshal([]byte(`[
+		{"id": 1, "event": "message", "sent": 1317397485000},
+		{"id": 2, "event": "message", "sent": 1317397545000},
+		{"id": 3, "event": "message", "sent": 1317401085000},
+		{"id": 4, "event": "comment", "message": 1, "sent": 1317401145000},
+		{"id": 5, "event": "message", "sent": 1317401205000},
+		{"id": 6, "event": "comment", "message": 0, "sent": 1317401265000}
+	]`), &msgs)
+
+	convs := Conversations(msgs, 10*time.Minute)
+	want := map[string][]int{"1": {1, 2, 4}, "3": {3, 5}, "6": {6}}
+	if got := conversationIDs(convs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Conversations returned %v, want %v", got, want)
+	}
+}
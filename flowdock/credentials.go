@@ -0,0 +1,191 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCredentials is returned when a CredentialsProvider has no token to
+// supply.
+var ErrNoCredentials = errors.New("flowdock: no credentials")
+
+// CredentialsProvider supplies the access token of a Client. It is consulted
+// for each API request and stream connection which isn't authorized
+// already, so that tokens can be kept in a secret manager and rotated
+// without restarting. Wrap slow providers with NewCachedCredentials.
+type CredentialsProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// CredentialsFunc is a CredentialsProvider calling a function, for instance
+// to fetch the token from a secret manager such as Vault.
+type CredentialsFunc func(ctx context.Context) (string, error)
+
+// Token implements the CredentialsProvider interface.
+func (f CredentialsFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// EnvCredentials returns a CredentialsProvider reading the token from the
+// environment variable name.
+func EnvCredentials(name string) CredentialsProvider {
+	return CredentialsFunc(func(context.Context) (string, error) {
+		token := strings.TrimSpace(os.Getenv(name))
+		if token == "" {
+			return "", fmt.Errorf("%v: $%s is not set", ErrNoCredentials, name)
+		}
+		return token, nil
+	})
+}
+
+// FileCredentials returns a CredentialsProvider reading the token from the
+// file at path, such as a secret mounted in a container. Surrounding white
+// space is ignored.
+func FileCredentials(path string) CredentialsProvider {
+	return CredentialsFunc(func(context.Context) (string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("%v: %s is empty", ErrNoCredentials, path)
+		}
+		return token, nil
+	})
+}
+
+// CachedCredentials is a CredentialsProvider reusing the token of another
+// one for a while.
+type CachedCredentials struct {
+	provider CredentialsProvider
+	ttl      time.Duration
+
+	// Clock defaults to SystemClock.
+	Clock Clock
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewCachedCredentials returns a CachedCredentials reusing the tokens of
+// provider for ttl. The Client invalidates the cached token when a request
+// is rejected with a 401, so that a rotated token is picked up at once.
+func NewCachedCredentials(provider CredentialsProvider, ttl time.Duration) *CachedCredentials {
+	return &CachedCredentials{provider: provider, ttl: ttl, Clock: SystemClock}
+}
+
+// Token implements the CredentialsProvider interface.
+func (c *CachedCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.Clock.Now()
+	if c.token != "" && now.Before(c.expires) {
+		return c.token, nil
+	}
+	token, err := c.provider.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, now.Add(c.ttl)
+	return token, nil
+}
+
+// Invalidate drops the cached token if it is token, the one rejected, so
+// that requests rejected together fetch a single new token. An empty token
+// drops the cached one whichever it is.
+func (c *CachedCredentials) Invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if token == "" || token == c.token {
+		c.token = ""
+	}
+}
+
+// invalidator is implemented by the CredentialsProviders caching tokens,
+// such as CachedCredentials, to drop a token rejected by the API.
+type invalidator interface {
+	Invalidate(token string)
+}
+
+// authorizeFromCredentials authorizes req with the token of the Client's
+// Credentials, unless req carries a token already.
+func (c *Client) authorizeFromCredentials(req *http.Request) error {
+	if c.Credentials == nil || req.Header.Get("Authorization") != "" ||
+		req.URL.Query().Get("access_token") != "" {
+		return nil
+	}
+
+	token, err := c.Credentials.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	c.AuthorizeStreamRequest(req, token)
+	return nil
+}
+
+// rejectedCredentials invalidates the token of the Client's Credentials, if
+// they cache it, after resp rejected it. It reports whether it did, in which
+// case a new token may be accepted.
+func (c *Client) rejectedCredentials(resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	if i, ok := c.Credentials.(invalidator); ok {
+		i.Invalidate(requestToken(resp.Request))
+		return true
+	}
+	return false
+}
+
+// requestToken returns the token req was authorized with by
+// AuthorizeStreamRequest, or "" if there is none.
+func requestToken(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	if token := req.URL.Query().Get("access_token"); token != "" {
+		return token
+	}
+	if token, _, ok := req.BasicAuth(); ok {
+		return token
+	}
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+}
+
+// doReauthorized sends req with doRetry and, when the token of the Client's
+// Credentials was rejected as expired and invalidated, sends it once more
+// with a new token. Requests carrying a token of their own are sent as is.
+func (c *Client) doReauthorized(req *http.Request, v interface{}) (*http.Response, error) {
+	_, invalidates := c.Credentials.(invalidator)
+	if !invalidates || req.Header.Get("Authorization") != "" ||
+		req.URL.Query().Get("access_token") != "" {
+		return c.doRetry(req, v)
+	}
+
+	// authorize clones, so that req is left without the rejected token
+	resp, err := c.doRetry(req.Clone(req.Context()), v)
+	if !IsTokenExpired(err) || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	c.Logf("%s %s: token expired, retrying with a new token", req.Method, req.URL)
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return resp, err
+		}
+		retry.Body = body
+	}
+	return c.doRetry(retry, v)
+}
@@ -0,0 +1,194 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClient_Credentials(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	valid := "token-1"
+	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+
+	fetched := 0
+	client.Credentials = NewCachedCredentials(CredentialsFunc(func(context.Context) (string, error) {
+		fetched++
+		return fmt.Sprintf("token-%d", fetched), nil
+	}), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Flows.List(ctx, false, nil); err != nil {
+			t.Errorf("Flows.List returned error: %v", err)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("Credentials fetched %d times, want 1", fetched)
+	}
+
+	// the token is rotated: the rejected one is dropped from the cache
+	valid = "token-2"
+	if _, _, err := client.Flows.List(ctx, false, nil); err == nil {
+		t.Errorf("Flows.List returned no error for a rotated token")
+	}
+	if _, _, err := client.Flows.List(ctx, false, nil); err != nil {
+		t.Errorf("Flows.List returned error after the rotation: %v", err)
+	}
+}
+
+func TestClient_Credentials_expired(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	valid, requests := "token-2", 0
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		testJSONValues(t, r, values{"event": "message", "content": "hi"})
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"token_expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	fetched := 0
+	client.Credentials = NewCachedCredentials(CredentialsFunc(func(context.Context) (string, error) {
+		fetched++
+		return fmt.Sprintf("token-%d", fetched), nil
+	}), time.Hour)
+
+	// the expired token is refreshed and the request sent again, once
+	opt := &MessagesCreateOptions{Event: "message", Content: "hi"}
+	if _, _, err := client.Messages.Create(ctx, opt); err != nil {
+		t.Errorf("Messages.Create returned error: %v", err)
+	}
+	if fetched != 2 || requests != 2 {
+		t.Errorf("Credentials fetched %d times for %d requests, want 2 and 2", fetched, requests)
+	}
+
+	valid, requests = "never", 0
+	if _, _, err := client.Messages.Create(ctx, opt); !IsTokenExpired(err) {
+		t.Errorf("Messages.Create returned %v, want an expired token error", err)
+	}
+	if requests != 2 {
+		t.Errorf("Messages.Create sent %d requests, want 2", requests)
+	}
+
+	// a token set by the caller is not replaced
+	requests = 0
+	req, _ := client.newMessageRequest("POST", "messages", opt)
+	req.Header.Set("Authorization", "Bearer mine")
+	if _, err := client.Do(ctx, req, nil); !IsTokenExpired(err) || requests != 1 {
+		t.Errorf("Do returned %v after %d requests, want an expired token error after 1", err, requests)
+	}
+}
+
+func TestCachedCredentials_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	fetched := 0
+	c := NewCachedCredentials(CredentialsFunc(func(context.Context) (string, error) {
+		fetched++
+		return fmt.Sprintf("token-%d", fetched), nil
+	}), time.Hour)
+
+	c.Token(ctx)
+	// two requests rejected token-1: the second rejection comes late
+	c.Invalidate("token-1")
+	if token, _ := c.Token(ctx); token != "token-2" {
+		t.Errorf("Token returned %q, want a new token", token)
+	}
+	c.Invalidate("token-1")
+	if token, _ := c.Token(ctx); token != "token-2" || fetched != 2 {
+		t.Errorf("Token returned %q after %d fetches, want token-2 after 2", token, fetched)
+	}
+
+	c.Invalidate("")
+	if token, _ := c.Token(ctx); token != "token-3" {
+		t.Errorf("Token returned %q, want a new token", token)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	for _, auth := range []StreamAuth{StreamAuthBearer, StreamAuthBasic, StreamAuthQuery} {
+		c := NewClient(nil)
+		c.StreamAuth = auth
+		req, _ := http.NewRequest("GET", "https://api.flowdock.com/flows", nil)
+		c.AuthorizeStreamRequest(req, "secret")
+		if got := requestToken(req); got != "secret" {
+			t.Errorf("requestToken returned %q with %v, want the token", got, auth)
+		}
+	}
+}
+
+func TestClient_Credentials_stream(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/o/f", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer secret")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"event\":\"message\",\"content\":\"hi\"}\n\n")
+		w.(responseWriter).Flush()
+		<-r.Context().Done()
+	})
+
+	client.Credentials = CredentialsFunc(func(context.Context) (string, error) {
+		return "secret", nil
+	})
+	msgs, stream, err := client.Messages.Stream(ctx, "", "o", "f")
+	if err != nil {
+		t.Fatalf("Messages.Stream returned error: %v", err)
+	}
+	defer stream.Close()
+	if m := <-msgs; m.Content().String() != "hi" {
+		t.Errorf("Messages.Stream sent %v, want hi", m.Content())
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	os.Setenv("FLOWDOCK_TEST_TOKEN", " env-token\n")
+	defer os.Unsetenv("FLOWDOCK_TEST_TOKEN")
+
+	if token, err := EnvCredentials("FLOWDOCK_TEST_TOKEN").Token(context.Background()); err != nil || token != "env-token" {
+		t.Errorf("EnvCredentials.Token returned %q, %v, want env-token", token, err)
+	}
+	if _, err := EnvCredentials("FLOWDOCK_TEST_MISSING").Token(context.Background()); err == nil {
+		t.Errorf("EnvCredentials.Token returned no error for a missing variable")
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowdock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	ioutil.WriteFile(path, []byte("file-token\n"), 0600)
+	if token, err := FileCredentials(path).Token(context.Background()); err != nil || token != "file-token" {
+		t.Errorf("FileCredentials.Token returned %q, %v, want file-token", token, err)
+	}
+
+	ioutil.WriteFile(path, nil, 0600)
+	if _, err := FileCredentials(path).Token(context.Background()); err == nil {
+		t.Errorf("FileCredentials.Token returned no error for an empty file")
+	}
+}
@@ -0,0 +1,67 @@
+package flowdock
+
+// Deprecation reports a deprecated field found in a Message.
+type Deprecation struct {
+	Field       string // JSON name of the deprecated field
+	Value       string
+	Replacement string // JSON names of the fields replacing it
+	Message     *Message
+}
+
+// Return the string version of a Deprecation, "app: use event and thread_id"
+func (d Deprecation) String() string {
+	return d.Field + ": use " + d.Replacement
+}
+
+// appEvents maps the legacy App values onto the Event of messages which have
+// none: "chat" messages are chat messages, and "influx" ones items of the
+// team inbox.
+var appEvents = map[string]string{
+	"chat":   "message",
+	"influx": "mail",
+}
+
+// Deprecations returns the deprecated fields set in m.
+func (m *Message) Deprecations() []Deprecation {
+	var ds []Deprecation
+	if m.App != nil {
+		ds = append(ds, Deprecation{
+			Field:       "app",
+			Value:       *m.App,
+			Replacement: "event and thread_id",
+			Message:     m,
+		})
+	}
+	return ds
+}
+
+// MigrateApp moves the deprecated App of m onto the event and thread model:
+// a message without an Event gets the one of its App, and App is cleared.
+// Threads are not assigned to legacy messages, which Conversations groups
+// by comment parent and time instead. MigrateApp reports whether m had an
+// App it knows of; unknown values are left untouched.
+func (m *Message) MigrateApp() bool {
+	if m.App == nil {
+		return false
+	}
+	event, ok := appEvents[*m.App]
+	if !ok {
+		return false
+	}
+	if m.Event == nil {
+		m.Event = &event
+	}
+	m.App = nil
+	return true
+}
+
+// checkDeprecated calls the Client's OnDeprecated with the deprecated fields
+// of m.
+func (c *Client) checkDeprecated(m *Message) {
+	if c.OnDeprecated == nil {
+		return
+	}
+	for _, d := range m.Deprecations() {
+		c.OnDeprecated(d)
+	}
+}
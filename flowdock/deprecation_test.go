@@ -0,0 +1,61 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMessage_MigrateApp(t *testing.T) {
+	tests := []struct {
+		json     string
+		migrated bool
+		event    string
+	}{
+		{`{"app":"chat","content":"hi"}`, true, "message"},
+		{`{"app":"influx","content":{"subject":"s"}}`, true, "mail"},
+		{`{"app":"chat","event":"comment"}`, true, "comment"},
+		{`{"app":"unknown","event":"message"}`, false, "message"},
+		{`{"event":"message"}`, false, "message"},
+	}
+
+	for _, tt := range tests {
+		m := new(Message)
+		json.Unmarshal([]byte(tt.json), m)
+		if got := m.MigrateApp(); got != tt.migrated {
+			t.Errorf("MigrateApp of %s returned %v, want %v", tt.json, got, tt.migrated)
+		}
+		if m.Event == nil || *m.Event != tt.event {
+			t.Errorf("MigrateApp of %s set Event %v, want %v", tt.json, m.Event, tt.event)
+		}
+		if tt.migrated && m.App != nil {
+			t.Errorf("MigrateApp of %s left App %v", tt.json, *m.App)
+		}
+	}
+}
+
+func TestMessagesService_List_deprecated(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"app":"chat"},{"id":2,"event":"message"}]`)
+	})
+
+	var found []string
+	client.OnDeprecated = func(d Deprecation) {
+		found = append(found, fmt.Sprintf("%d %s=%s (%v)", *d.Message.ID, d.Field, d.Value, d))
+	}
+	if _, _, err := client.Messages.List(ctx, "org", "flow", nil); err != nil {
+		t.Fatalf("Messages.List returned error: %v", err)
+	}
+
+	want := []string{"1 app=chat (app: use event and thread_id)"}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("OnDeprecated called with %q, want %q", found, want)
+	}
+}
@@ -0,0 +1,43 @@
+package flowdock
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// coreDeps are the only non standard packages the flowdock package may
+// import. Optional subsystems with heavier dependencies live in their own
+// packages, such as slackimport, so that programs only posting messages
+// don't pull them in.
+var coreDeps = map[string]bool{
+	"github.com/google/go-querystring/query": true,
+}
+
+func TestCoreDependencies(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			std := !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+			if !std && !coreDeps[path] {
+				t.Errorf("%s imports %s, move the code needing it to its own package", name, path)
+			}
+		}
+	}
+}
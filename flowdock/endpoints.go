@@ -0,0 +1,127 @@
+package flowdock
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// OrgEndpoint is where the API of an organization is served, when it is
+// not at the RestURL and StreamURL of the Client: an organization on a
+// custom domain, or on a regional endpoint.
+type OrgEndpoint struct {
+	RestURL   *url.URL
+	StreamURL *url.URL // nil streams from the StreamURL of the Client
+}
+
+// SetOrgEndpoint sends the requests of the organization with the
+// parameterized name org to restURL, and its streams to streamURL, or to
+// the Client's StreamURL if empty. The URLs get the user information of
+// the Client's ones, such as the token of NewClientWithToken, unless they
+// have their own. Set endpoints before making requests.
+func (c *Client) SetOrgEndpoint(org, restURL, streamURL string) error {
+	var e OrgEndpoint
+	var err error
+	if e.RestURL, err = parseEndpoint(restURL, c.RestURL); err != nil {
+		return err
+	}
+	if streamURL != "" {
+		if e.StreamURL, err = parseEndpoint(streamURL, c.StreamURL); err != nil {
+			return err
+		}
+	}
+	if c.OrgEndpoints == nil {
+		c.OrgEndpoints = make(map[string]OrgEndpoint)
+	}
+	c.OrgEndpoints[org] = e
+	return nil
+}
+
+// parseEndpoint parses the base URL s, giving it the user information of
+// base when it has none.
+func parseEndpoint(s string, base *url.URL) (*url.URL, error) {
+	if !strings.HasSuffix(s, "/") {
+		s += "/" // relative paths resolve below it
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil && base != nil {
+		u.User = base.User
+	}
+	return u, nil
+}
+
+// ErrMixedEndpoints is returned for the requests of several flows, such as
+// the streams of StreamFlows, whose organizations are served by different
+// OrgEndpoints: a request can only be sent to one of them.
+var ErrMixedEndpoints = errors.New("flowdock: the flows of the request are served by several endpoints")
+
+// orgOf returns the organization the API path p is about: the one of
+// flows/{org}/{flow}/... and organizations/{org}, or "" for the others.
+func orgOf(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "flows":
+		return unescape(parts[1])
+	case len(parts) == 2 && parts[0] == "organizations" && parts[1] != "find":
+		return unescape(parts[1])
+	}
+	return ""
+}
+
+// orgsOf returns the organizations the relative URL rel is about: the one
+// of its path, or those of the "org/flow" list of its filter parameter for
+// the streams of several flows. The organization "" stands for the others.
+func orgsOf(rel *url.URL) []string {
+	if strings.Trim(rel.Path, "/") != "flows" || rel.Query().Get("filter") == "" {
+		return []string{orgOf(rel.Path)}
+	}
+	var orgs []string
+	for _, filter := range strings.Split(rel.Query().Get("filter"), ",") {
+		org := ""
+		if i := strings.Index(filter, "/"); i >= 0 {
+			org = filter[:i]
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs
+}
+
+// endpointURL returns the base URL of the request of the relative URL rel,
+// as chosen by endpoint for each of its organizations, and
+// ErrMixedEndpoints if they don't agree.
+func endpointURL(rel *url.URL, endpoint func(org string) *url.URL) (*url.URL, error) {
+	var base *url.URL
+	for _, org := range orgsOf(rel) {
+		u := endpoint(org)
+		if base != nil && u.String() != base.String() {
+			return nil, ErrMixedEndpoints
+		}
+		base = u
+	}
+	return base, nil
+}
+
+// restURL returns the base URL of the REST request of the relative URL
+// rel: the RestURL of its organization's endpoint, or of the Client.
+func (c *Client) restURL(rel *url.URL) (*url.URL, error) {
+	return endpointURL(rel, func(org string) *url.URL {
+		if e, ok := c.OrgEndpoints[org]; ok && e.RestURL != nil {
+			return e.RestURL
+		}
+		return c.RestURL
+	})
+}
+
+// streamURL returns the base URL of the stream request of the relative
+// URL rel: the StreamURL of its organizations' endpoint, or of the Client.
+func (c *Client) streamURL(rel *url.URL) (*url.URL, error) {
+	return endpointURL(rel, func(org string) *url.URL {
+		if e, ok := c.OrgEndpoints[org]; ok && e.StreamURL != nil {
+			return e.StreamURL
+		}
+		return c.StreamURL
+	})
+}
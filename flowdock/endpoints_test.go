@@ -0,0 +1,140 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOrgOf(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"flows/org/flow", "org"},
+		{"/flows/org/flow/messages/1", "org"},
+		{"flows/my%20org/flow", "my org"},
+		{"organizations/org", "org"},
+		{"flows", ""},
+		{"flows/all", ""},
+		{"flows/find", ""},
+		{"organizations", ""},
+		{"organizations/find", ""},
+		{"users/1", ""},
+		{"private/1/messages", ""},
+	}
+	for _, tt := range tests {
+		if got := orgOf(tt.path); got != tt.want {
+			t.Errorf("orgOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestClient_OrgEndpoints(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	euMux := http.NewServeMux()
+	eu := httptest.NewServer(euMux)
+	defer eu.Close()
+
+	var served []string
+	euMux.HandleFunc("/api/flows/eu/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "eu "+r.URL.Path)
+		fmt.Fprint(w, `[]`)
+	})
+	euMux.HandleFunc("/api/organizations/eu", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "eu "+r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/flows/us/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "default "+r.URL.Path)
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, "default "+r.URL.Path)
+		fmt.Fprint(w, `[]`)
+	})
+
+	if err := client.SetOrgEndpoint("eu", eu.URL+"/api", ""); err != nil {
+		t.Fatalf("SetOrgEndpoint returned error: %v", err)
+	}
+	client.Messages.List(ctx, "eu", "flow", nil)
+	client.Organizations.GetByParameterizedName(ctx, "eu")
+	client.Messages.List(ctx, "us", "flow", nil)
+	client.Users.List(ctx, nil)
+
+	want := []string{
+		"eu /api/flows/eu/flow/messages",
+		"eu /api/organizations/eu",
+		"default /flows/us/flow/messages",
+		"default /users",
+	}
+	if fmt.Sprint(served) != fmt.Sprint(want) {
+		t.Errorf("requests served as %q, want %q", served, want)
+	}
+
+	// streams of the organization go to the Client's StreamURL by default
+	req, _ := client.NewStreamRequest("GET", "flows/eu/flow", nil)
+	if got, want := req.URL.String(), streamServer.URL+"/flows/eu/flow"; got != want {
+		t.Errorf("NewStreamRequest URL = %v, want %v", got, want)
+	}
+}
+
+func TestClient_SetOrgEndpoint(t *testing.T) {
+	c := NewClientWithToken(nil, "token")
+	if err := c.SetOrgEndpoint("eu", "https://api.eu.example.com", "https://stream.eu.example.com/"); err != nil {
+		t.Fatalf("SetOrgEndpoint returned error: %v", err)
+	}
+
+	req, _ := c.NewRequest("GET", "flows/eu/flow", nil)
+	if got, want := req.URL.String(), "https://token@api.eu.example.com/flows/eu/flow"; got != want {
+		t.Errorf("NewRequest URL = %v, want %v", got, want)
+	}
+	req, _ = c.NewStreamRequest("GET", "flows/eu/flow", nil)
+	if got, want := req.URL.String(), "https://token@stream.eu.example.com/flows/eu/flow"; got != want {
+		t.Errorf("NewStreamRequest URL = %v, want %v", got, want)
+	}
+	if op := c.operation(req, true); op.Endpoint != "flows/{org}/{flow}" || op.Org != "eu" {
+		t.Errorf("operation returned %+v", op)
+	}
+
+	c.OrgEndpoints["other"] = OrgEndpoint{RestURL: &url.URL{Scheme: "https", Host: "api.other.example.com"}}
+	req, _ = c.NewRequest("GET", "organizations/other", nil)
+	if got, want := req.URL.Host, "api.other.example.com"; got != want {
+		t.Errorf("NewRequest host = %v, want %v", got, want)
+	}
+
+	if err := c.SetOrgEndpoint("bad", "://", ""); err == nil {
+		t.Errorf("SetOrgEndpoint returned no error for an invalid URL")
+	}
+}
+
+func TestClient_OrgEndpoints_filter(t *testing.T) {
+	c := NewClientWithToken(nil, "token")
+	if err := c.SetOrgEndpoint("eu", "https://api.eu.example.com", "https://stream.eu.example.com/"); err != nil {
+		t.Fatalf("SetOrgEndpoint returned error: %v", err)
+	}
+	stream := func(filters ...FlowFilter) (*http.Request, error) {
+		u, err := c.addOptions("flows", &StreamOptions{Filter: filters})
+		if err != nil {
+			t.Fatalf("addOptions returned error: %v", err)
+		}
+		return c.NewStreamRequest("GET", u, nil)
+	}
+
+	req, err := stream(FlowFilter{Org: "eu", Flow: "a"}, FlowFilter{Org: "eu", Flow: "b"})
+	if err != nil || req.URL.Host != "stream.eu.example.com" {
+		t.Errorf("NewStreamRequest returned %v and %v, want a request to the eu endpoint", req, err)
+	}
+	req, err = stream(FlowFilter{Org: "us", Flow: "a"}, FlowFilter{Org: "other", Flow: "b"})
+	if err != nil || req.URL.Host != c.StreamURL.Host {
+		t.Errorf("NewStreamRequest returned %v and %v, want a request to the default endpoint", req, err)
+	}
+	if _, err := stream(FlowFilter{Org: "eu", Flow: "a"}, FlowFilter{Org: "us", Flow: "b"}); err != ErrMixedEndpoints {
+		t.Errorf("NewStreamRequest returned %v, want ErrMixedEndpoints", err)
+	}
+}
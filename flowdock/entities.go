@@ -0,0 +1,151 @@
+package flowdock
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EntityKind is the kind of a VCS entity mentioned in a message.
+type EntityKind string
+
+const (
+	EntityIssue       EntityKind = "issue"
+	EntityPullRequest EntityKind = "pull_request"
+	EntityCommit      EntityKind = "commit"
+)
+
+// Entity is a VCS entity mentioned in a message: an issue or pull request
+// number, or a commit SHA.
+type Entity struct {
+	Kind   EntityKind
+	Repo   string // "owner/name", when known
+	Number int    // of issues and pull requests
+	SHA    string // of commits
+	Text   string // the matched text
+}
+
+// EntityPattern finds entities of a kind. Its Regexp captures the entity
+// in groups named "repo", "number" and "sha".
+type EntityPattern struct {
+	Kind   EntityKind
+	Regexp *regexp.Regexp
+}
+
+// DefaultEntityPatterns find GitHub URLs, owner/name#123 and #123
+// references, and commit SHAs.
+var DefaultEntityPatterns = []EntityPattern{
+	{EntityPullRequest, regexp.MustCompile(`github\.com/(?P<repo>[\w.-]+/[\w.-]+)/pulls?/(?P<number>\d+)`)},
+	{EntityIssue, regexp.MustCompile(`github\.com/(?P<repo>[\w.-]+/[\w.-]+)/issues/(?P<number>\d+)`)},
+	{EntityCommit, regexp.MustCompile(`github\.com/(?P<repo>[\w.-]+/[\w.-]+)/commits?/(?P<sha>[0-9a-f]{7,40})\b`)},
+	{EntityIssue, regexp.MustCompile(`\b(?P<repo>[\w.-]+/[\w.-]+)#(?P<number>\d+)\b`)},
+	{EntityIssue, regexp.MustCompile(`(?:^|[^\w/])#(?P<number>\d+)\b`)},
+	{EntityCommit, regexp.MustCompile(`\b(?P<sha>[0-9a-f]{7,40})\b`)},
+}
+
+// EntityExtractor finds the VCS entities mentioned in messages.
+type EntityExtractor struct {
+	// Patterns are tried in order. Text matched by a pattern is not
+	// matched by the following ones.
+	Patterns []EntityPattern
+}
+
+// NewEntityExtractor returns an EntityExtractor using patterns, or
+// DefaultEntityPatterns if none are given.
+func NewEntityExtractor(patterns ...EntityPattern) *EntityExtractor {
+	if len(patterns) == 0 {
+		patterns = DefaultEntityPatterns
+	}
+	return &EntityExtractor{Patterns: patterns}
+}
+
+// Extract returns the entities mentioned in text, in order of pattern then
+// position, without duplicates.
+func (x *EntityExtractor) Extract(text string) []Entity {
+	var (
+		entities []Entity
+		taken    [][]int
+		seen     = make(map[Entity]bool)
+	)
+	for _, p := range x.Patterns {
+		for _, loc := range p.Regexp.FindAllStringSubmatchIndex(text, -1) {
+			if overlaps(taken, loc[0], loc[1]) {
+				continue
+			}
+			e, ok := p.entity(text, loc)
+			if !ok {
+				continue
+			}
+			taken = append(taken, loc[:2])
+
+			key := e
+			key.Text = ""
+			if !seen[key] {
+				seen[key] = true
+				entities = append(entities, e)
+			}
+		}
+	}
+	return entities
+}
+
+// Message returns the entities mentioned in m: in the URLs of VCS events,
+// and in the text of other messages. Messages with malformed content have
+// none.
+func (x *EntityExtractor) Message(m *Message) []Entity {
+	if m.RawContent == nil {
+		return nil
+	}
+
+	c, err := m.ContentE()
+	if err != nil {
+		return nil
+	}
+	vcs, ok := c.(*VcsContent)
+	if !ok {
+		return x.Extract(c.String())
+	}
+
+	var urls []string
+	for _, u := range []*string{vcs.CompareURL, vcs.PullRequest.URL, vcs.Issue.URL} {
+		if u != nil {
+			urls = append(urls, *u)
+		}
+	}
+	return x.Extract(strings.Join(urls, " "))
+}
+
+// entity returns the entity matched at loc in text.
+func (p *EntityPattern) entity(text string, loc []int) (Entity, bool) {
+	e := Entity{Kind: p.Kind, Text: strings.TrimLeft(text[loc[0]:loc[1]], " \t\n(")}
+	for i, name := range p.Regexp.SubexpNames() {
+		if name == "" || loc[2*i] < 0 {
+			continue
+		}
+		value := text[loc[2*i]:loc[2*i+1]]
+		switch name {
+		case "repo":
+			e.Repo = value
+		case "number":
+			e.Number, _ = strconv.Atoi(value)
+		case "sha":
+			e.SHA = value
+		}
+	}
+
+	// decimal numbers are not SHAs
+	if e.Kind == EntityCommit && strings.Trim(e.SHA, "0123456789") == "" {
+		return e, false
+	}
+	return e, true
+}
+
+// overlaps reports whether [start, end) overlaps any of spans.
+func overlaps(spans [][]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestEntityExtractor_Extract(t *testing.T) {
+	text := "Fixed by https://github.com/wm/go-flowdock/pull/12 in deadbeef1, " +
+		"see wm/go-flowdock#3 and #4 (#4 again), not 1234567 or a#5"
+
+	got := NewEntityExtractor().Extract(text)
+	want := []Entity{
+		{Kind: EntityPullRequest, Repo: "wm/go-flowdock", Number: 12, Text: "github.com/wm/go-flowdock/pull/12"},
+		{Kind: EntityIssue, Repo: "wm/go-flowdock", Number: 3, Text: "wm/go-flowdock#3"},
+		{Kind: EntityIssue, Number: 4, Text: "#4"},
+		{Kind: EntityCommit, SHA: "deadbeef1", Text: "deadbeef1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract returned %+v, want %+v", got, want)
+	}
+}
+
+func TestEntityExtractor_custom(t *testing.T) {
+	x := NewEntityExtractor(EntityPattern{EntityIssue, regexp.MustCompile(`\bJIRA-(?P<number>\d+)`)})
+
+	got := x.Extract("blocked on JIRA-42, #7")
+	want := []Entity{{Kind: EntityIssue, Number: 42, Text: "JIRA-42"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract returned %+v, want %+v", got, want)
+	}
+}
+
+func TestEntityExtractor_Message(t *testing.T) {
+	raw := json.RawMessage(`{
+		"event": "pull_request",
+		"pull_request": {"url": "https://github.com/wm/go-flowdock/pull/9"},
+		"repository": {"name": "go-flowdock"}
+	}`)
+	event := "vcs"
+	m := &Message{Event: &event, RawContent: &raw}
+
+	got := NewEntityExtractor().Message(m)
+	want := []Entity{{Kind: EntityPullRequest, Repo: "wm/go-flowdock", Number: 9, Text: "github.com/wm/go-flowdock/pull/9"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Message returned %+v, want %+v", got, want)
+	}
+
+	raw = json.RawMessage(`"closes #1"`)
+	event = "message"
+	if got := NewEntityExtractor().Message(m); len(got) != 1 || got[0].Number != 1 {
+		t.Errorf("Message returned %+v for a chat message, want issue 1", got)
+	}
+}
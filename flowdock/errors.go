@@ -0,0 +1,115 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// IsNotFound reports whether err is, or wraps, an *Error with a 404 Not
+// Found status.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is, or wraps, an *Error with a 401
+// Unauthorized status: the token is missing or invalid.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsTokenExpired reports whether err is, or wraps, an *Error with a 401
+// Unauthorized status because the token expired, as told by the
+// "token_expired" error of its body or its WWW-Authenticate header.
+func IsTokenExpired(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode() != http.StatusUnauthorized {
+		return false
+	}
+	var body errorBody
+	if json.Unmarshal(e.Data, &body) == nil &&
+		(body.Error == "token_expired" || body.Message == "token_expired") {
+		return true
+	}
+	return strings.Contains(e.Response.Header.Get("WWW-Authenticate"), "expired")
+}
+
+// IsForbidden reports whether err is, or wraps, an *Error with a 403
+// Forbidden status: the token lacks access to the resource.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether err is, or wraps, an *Error with a 429 Too
+// Many Requests status, or a *RateLimitError.
+func IsRateLimited(err error) bool {
+	var e *RateLimitError
+	return hasStatus(err, http.StatusTooManyRequests) || errors.As(err, &e)
+}
+
+// IsValidation reports whether err is, or wraps, an *Error with field
+// validation errors.
+func IsValidation(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && len(e.Errors) > 0
+}
+
+func hasStatus(err error, code int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode() == code
+}
+
+// errorBody is the JSON body of a Flowdock API error. Its errors are either
+// an object of messages by field, or a list of field errors.
+type errorBody struct {
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Errors  json.RawMessage `json:"errors"`
+}
+
+// parse fills the Message and Errors of r from its Data, when it holds a
+// Flowdock error body.
+func (r *Error) parse() {
+	var body errorBody
+	if json.Unmarshal(r.Data, &body) != nil {
+		return
+	}
+	r.Message = body.Message
+
+	var byField map[string]json.RawMessage
+	if json.Unmarshal(body.Errors, &byField) == nil {
+		fields := make([]string, 0, len(byField))
+		for field := range byField {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			var msgs []string
+			var msg string
+			if json.Unmarshal(byField[field], &msgs) != nil && json.Unmarshal(byField[field], &msg) == nil {
+				msgs = []string{msg}
+			}
+			for _, msg := range msgs {
+				r.Errors = append(r.Errors, FieldError{Field: field, Message: msg})
+			}
+		}
+		return
+	}
+
+	var list []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	}
+	if json.Unmarshal(body.Errors, &list) == nil {
+		for _, item := range list {
+			msg := item.Message
+			if msg == "" {
+				msg = item.Code
+			}
+			r.Errors = append(r.Errors, FieldError{Field: item.Field, Message: msg})
+		}
+	}
+}
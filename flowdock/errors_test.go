@@ -0,0 +1,91 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckResponse_fieldErrors(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusUnprocessableEntity,
+		Body: ioutil.NopCloser(strings.NewReader(`{"message":"Validation error",
+			"errors":{"tags":"is invalid","content":["can't be blank","is too short"]}}`)),
+	}
+	err := CheckResponse(res).(*Error)
+
+	if err.Message != "Validation error" || err.StatusCode() != http.StatusUnprocessableEntity {
+		t.Errorf("Error = %q with status %d, want the message of the body", err.Message, err.StatusCode())
+	}
+	want := []FieldError{
+		{Field: "content", Message: "can't be blank"},
+		{Field: "content", Message: "is too short"},
+		{Field: "tags", Message: "is invalid"},
+	}
+	if !reflect.DeepEqual(err.Errors, want) {
+		t.Errorf("Error.Errors = %v, want %v", err.Errors, want)
+	}
+	if !IsValidation(err) {
+		t.Errorf("IsValidation returned false for %v", err)
+	}
+}
+
+func TestCheckResponse_notJSON(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadGateway,
+		Body:       ioutil.NopCloser(strings.NewReader("<html>bad gateway</html>")),
+	}
+	err := CheckResponse(res).(*Error)
+
+	if err.Message != "" || err.Errors != nil || string(err.Data) != "<html>bad gateway</html>" {
+		t.Errorf("Error = %#v, want the body in Data only", err)
+	}
+}
+
+func TestErrorHelpers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	for _, code := range []int{401, 403, 404, 429} {
+		code := code
+		mux.HandleFunc(fmt.Sprintf("/flows/org/%d", code), func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, `{"message":"failed"}`)
+		})
+	}
+
+	tests := []struct {
+		flow  string
+		is    func(error) bool
+		other func(error) bool
+	}{
+		{"401", IsUnauthorized, IsForbidden},
+		{"401", IsUnauthorized, IsTokenExpired},
+		{"403", IsForbidden, IsUnauthorized},
+		{"404", IsNotFound, IsRateLimited},
+		{"429", IsRateLimited, IsNotFound},
+	}
+	for _, tt := range tests {
+		_, _, err := client.Flows.Get(ctx, "org", tt.flow)
+		if !tt.is(err) || tt.other(err) {
+			t.Errorf("helpers misclassified the error %v", err)
+		}
+		if wrapped := fmt.Errorf("wrapped: %w", err); !tt.is(wrapped) {
+			t.Errorf("helpers did not see through the wrapping of %v", err)
+		}
+		if e, ok := err.(*Error); !ok || e.Message != "failed" {
+			t.Errorf("Flows.Get returned %#v, want an *Error with its message", err)
+		}
+	}
+
+	if IsNotFound(nil) || IsNotFound(ErrStreamClosed) {
+		t.Errorf("IsNotFound returned true for a nil or non-API error")
+	}
+}
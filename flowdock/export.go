@@ -0,0 +1,225 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultExportPageSize is the number of messages an Exporter lists
+	// per request by default.
+	DefaultExportPageSize = 100
+
+	// DefaultExportBackoff is the delay before an Exporter retries a page
+	// which failed temporarily, unless the API asks for another one with
+	// a Retry-After header. It doubles on each failure, up to
+	// DefaultExportMaxBackoff.
+	DefaultExportBackoff    = 5 * time.Second
+	DefaultExportMaxBackoff = 5 * time.Minute
+
+	// DefaultExportMaxRetries is how many temporary failures in a row an
+	// Exporter tolerates by default.
+	DefaultExportMaxRetries = 10
+)
+
+// Exporter exports the history of flows, oldest message first. After each
+// page it checkpoints the ID of the last exported message of the flow in a
+// Store, the cursor store, so that an export stopped by rate limiting or a
+// crash resumes where it stopped when run again. Messages of the page in
+// progress during a crash are exported again. Messages older than the
+// HistoryFloor of the client are skipped, and never exported: exports start
+// at the floor, as the walks of MessagesService.Iterate oldest first do.
+type Exporter struct {
+	client *Client
+	store  Store
+
+	// PageSize is the number of messages listed per request. Defaults to
+	// DefaultExportPageSize.
+	PageSize int
+
+	// Backoff and MaxBackoff bound the delay before retrying temporary
+	// failures, such as rate limiting. They default to
+	// DefaultExportBackoff and DefaultExportMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// MaxRetries is how many temporary failures in a row ExportFlow
+	// tolerates before returning the last one. Defaults to
+	// DefaultExportMaxRetries. Requests refused for maintenance don't
+	// count: ExportFlow waits for the maintenance window instead.
+	MaxRetries int
+
+	// Attachments, if set, receives the files attached to the exported
+	// messages, downloaded before the messages are passed to fn.
+	Attachments *AttachmentCache
+}
+
+// NewExporter returns an Exporter listing messages through client and
+// keeping its cursors in store. A nil store keeps them in memory, which
+// only lets an export resume within the same process.
+func NewExporter(client *Client, store Store) *Exporter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Exporter{
+		client:     client,
+		store:      store,
+		PageSize:   DefaultExportPageSize,
+		Backoff:    DefaultExportBackoff,
+		MaxBackoff: DefaultExportMaxBackoff,
+		MaxRetries: DefaultExportMaxRetries,
+	}
+}
+
+// ExportFlow calls fn for each message of the flow named flow in the
+// organization org sent after the cursor of the flow, in order. It returns
+// once the history is exhausted, or with the error of fn or of the download
+// of attachments, which stops the export after the last message fn
+// accepted, or with the error of ctx. Its requests have PriorityLow,
+// unless ctx has another priority.
+func (e *Exporter) ExportFlow(ctx context.Context, org, flow string, fn func(*Message) error) error {
+	ctx = withDefaultPriority(ctx, PriorityLow)
+	cursor, err := e.Cursor(org, flow)
+	if err != nil {
+		return err
+	}
+	if e.client.HistoryFloor > 0 {
+		if cursor, err = e.client.Messages.floorID(ctx, org, flow, cursor, 0); err != nil {
+			return err
+		}
+	}
+
+	backoff, retries := e.Backoff, 0
+	for {
+		opt := &MessagesListOptions{SinceID: cursor, Limit: e.PageSize, Sort: "asc"}
+		page, _, err := e.client.Messages.list(ctx, org, flow, opt)
+		if err != nil {
+			if ctx.Err() != nil || !isRetryable(err) || retries >= e.MaxRetries {
+				return err
+			}
+			wait := retryAfter(err, backoff)
+			maintenance := false
+			if d, ok := maintenanceBackoff(err, DefaultMaintenanceBackoff); ok {
+				wait, maintenance = d, true
+			}
+			e.client.Logf("export of %s/%s failed, retrying in %v: %v", org, flow, wait, err)
+			select {
+			case <-e.client.Clock.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if maintenance {
+				continue // waited out, without giving up
+			}
+			retries++
+			if backoff *= 2; backoff > e.MaxBackoff {
+				backoff = e.MaxBackoff
+			}
+			continue
+		}
+		backoff, retries = e.Backoff, 0
+
+		last, floor := cursor, e.client.historyFloor()
+		for i := range page {
+			if !beforeFloor(&page[i], floor) {
+				if err := e.export(ctx, org, flow, &page[i], fn); err != nil {
+					if last != cursor {
+						e.checkpoint(org, flow, last)
+					}
+					return err
+				}
+			}
+			if page[i].ID != nil {
+				last = *page[i].ID
+			}
+		}
+		if last == cursor {
+			return nil
+		}
+		if err := e.checkpoint(org, flow, last); err != nil {
+			return err
+		}
+		if len(page) < e.PageSize {
+			return nil
+		}
+		cursor = last
+	}
+}
+
+// ExportFlows runs ExportFlow for each of flows, concurrently on the
+// client's Workers, calling fn with the flow of each message. fn is called
+// concurrently for messages of different flows, and in order for the
+// messages of a flow. The failures of some flows do not stop the export of
+// the others and are reported in a *BulkError, identified by their flow
+// reference; running ExportFlows again resumes them.
+func (e *Exporter) ExportFlows(ctx context.Context, flows []FlowRef, fn func(FlowRef, *Message) error) error {
+	errs := e.client.Workers.Run(ctx, len(flows), func(ctx context.Context, i int) error {
+		ref := flows[i]
+		if err := ref.validate(); err != nil {
+			return err
+		}
+		return e.ExportFlow(ctx, string(ref.Org), ref.Flow, func(m *Message) error {
+			return fn(ref, m)
+		})
+	})
+	return bulkErrors(errs, func(i int) string { return flows[i].String() })
+}
+
+// export passes m to fn, once its attachments are downloaded.
+func (e *Exporter) export(ctx context.Context, org, flow string, m *Message, fn func(*Message) error) error {
+	if e.Attachments != nil {
+		if err := e.Attachments.fetch(ctx, e.client, org, flow, m); err != nil {
+			return err
+		}
+	}
+	return fn(m)
+}
+
+// Cursor returns the ID of the last exported message of the flow named
+// flow in the organization org, or 0 if none was.
+func (e *Exporter) Cursor(org, flow string) (int, error) {
+	data, ok, err := e.store.Get(exportKey(org, flow))
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.Atoi(string(data))
+}
+
+// Reset drops the cursor of the flow named flow in the organization org, so
+// that the next export starts over.
+func (e *Exporter) Reset(org, flow string) error {
+	return e.store.Delete(exportKey(org, flow))
+}
+
+func (e *Exporter) checkpoint(org, flow string, id int) error {
+	return e.store.Set(exportKey(org, flow), []byte(strconv.Itoa(id)))
+}
+
+func exportKey(org, flow string) string {
+	return fmt.Sprintf("export/%s/%s", org, flow)
+}
+
+// retryAfter returns the delay the API asked for with the Retry-After
+// header of the response of err, in seconds, or until the reset of the rate
+// limit which kept err from being sent, or def when there is none.
+func retryAfter(err error, def time.Duration) time.Duration {
+	var rateLimit *RateLimitError
+	if errors.As(err, &rateLimit) {
+		if d := time.Until(rateLimit.Rate.Reset); d > 0 {
+			return d
+		}
+		return def
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Response == nil {
+		return def
+	}
+	seconds, convErr := strconv.Atoi(e.Response.Header.Get("Retry-After"))
+	if convErr != nil || seconds < 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
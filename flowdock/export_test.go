@@ -0,0 +1,229 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dayMillis is a day in milliseconds, the unit of sent times: the messages
+// of the test histories are sent on the day of their ID after the Epoch.
+const dayMillis = 24 * 60 * 60 * 1000
+
+// handleHistory serves the messages 1 to n of flows/o/f in pages, failing
+// the requests listed in fail with the given status.
+func handleHistory(t *testing.T, n int, fail map[int]int) *int {
+	requests := new(int)
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		*requests++
+		if status, ok := fail[*requests]; ok {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		since, _ := strconv.Atoi(r.FormValue("since_id"))
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+		var ids []int
+		switch sort := r.FormValue("sort"); sort {
+		case "asc":
+			for id := since + 1; id <= n && id <= since+limit; id++ {
+				ids = append(ids, id)
+			}
+		case "": // newest first, as the search of the HistoryFloor walks
+			until, _ := strconv.Atoi(r.FormValue("until_id"))
+			if until == 0 {
+				until = n + 1
+			}
+			for id := until - 1; id > since && len(ids) < limit; id-- {
+				ids = append(ids, id)
+			}
+		default:
+			t.Errorf("Request sort = %q, want asc", sort)
+		}
+
+		fmt.Fprint(w, "[")
+		for i, id := range ids {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%d,"sent":%d}`, id, id*dayMillis)
+		}
+		fmt.Fprint(w, "]")
+	})
+	return requests
+}
+
+func TestExporter_ExportFlow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	requests := handleHistory(t, 5, map[int]int{2: http.StatusTooManyRequests})
+
+	e := NewExporter(client, nil)
+	e.PageSize = 2
+	var ids []int
+	err := e.ExportFlow(ctx, "o", "f", func(m *Message) error {
+		ids = append(ids, *m.ID)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Exporter.ExportFlow returned error: %v", err)
+	}
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Exporter.ExportFlow exported %v, want %v", ids, want)
+	}
+	if *requests != 4 {
+		t.Errorf("Exporter.ExportFlow made %d requests, want 4", *requests)
+	}
+	if cursor, _ := e.Cursor("o", "f"); cursor != 5 {
+		t.Errorf("Exporter.Cursor returned %d, want 5", cursor)
+	}
+}
+
+func TestExporter_ExportFlow_historyFloor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	requests := handleHistory(t, 5, nil)
+	client.Clock = NewFakeClock(time.Unix(0, 0).Add(10 * 24 * time.Hour))
+	client.HistoryFloor = 7*24*time.Hour + 12*time.Hour
+
+	e := NewExporter(client, nil)
+	e.PageSize = 2
+	var ids []int
+	err := e.ExportFlow(ctx, "o", "f", func(m *Message) error {
+		ids = append(ids, *m.ID)
+		return nil
+	})
+	if want := []int{3, 4, 5}; err != nil || !reflect.DeepEqual(ids, want) {
+		t.Errorf("Exporter.ExportFlow exported %v and returned %v, want %v", ids, err, want)
+	}
+	if *requests != 3 {
+		t.Errorf("Exporter.ExportFlow made %d requests, want 3: one to find the floor, two from there", *requests)
+	}
+	if cursor, _ := e.Cursor("o", "f"); cursor != 5 {
+		t.Errorf("Exporter.Cursor returned %d, want 5", cursor)
+	}
+}
+
+func TestExporter_ExportFlow_resume(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	handleHistory(t, 5, nil)
+
+	e := NewExporter(client, nil)
+	e.PageSize = 2
+	stop := errors.New("stop")
+	var ids []int
+	export := func(m *Message) error {
+		if *m.ID == 4 && len(ids) < 4 {
+			return stop
+		}
+		ids = append(ids, *m.ID)
+		return nil
+	}
+
+	if err := e.ExportFlow(ctx, "o", "f", export); err != stop {
+		t.Errorf("Exporter.ExportFlow returned %v, want %v", err, stop)
+	}
+	if cursor, _ := e.Cursor("o", "f"); cursor != 3 {
+		t.Errorf("Exporter.Cursor returned %d, want 3", cursor)
+	}
+	ids = append(ids, 0)
+	if err := e.ExportFlow(ctx, "o", "f", export); err != nil {
+		t.Errorf("Exporter.ExportFlow returned error: %v", err)
+	}
+	if want := []int{1, 2, 3, 0, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Exporter.ExportFlow exported %v, want %v", ids, want)
+	}
+
+	if err := e.Reset("o", "f"); err != nil {
+		t.Errorf("Exporter.Reset returned error: %v", err)
+	}
+	if cursor, _ := e.Cursor("o", "f"); cursor != 0 {
+		t.Errorf("Exporter.Cursor returned %d after Reset, want 0", cursor)
+	}
+}
+
+func TestExporter_ExportFlow_maxRetries(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	fail := make(map[int]int)
+	for i := 1; i <= 3; i++ {
+		fail[i] = http.StatusServiceUnavailable
+	}
+	fail[4] = http.StatusNotFound
+	requests := handleHistory(t, 5, fail)
+
+	e := NewExporter(client, nil)
+	e.MaxRetries = 2
+	err := e.ExportFlow(ctx, "o", "f", func(*Message) error { return nil })
+	if err == nil || *requests != 3 {
+		t.Errorf("Exporter.ExportFlow returned %v after %d requests, want an error after 3", err, *requests)
+	}
+}
+
+func TestExporter_ExportFlows(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	handleHistory(t, 3, nil)
+
+	var mu sync.Mutex
+	var exported []string
+	e := NewExporter(client, nil)
+	flows := []FlowRef{{Org: "o", Flow: "missing"}, {Org: "o", Flow: "f"}, {Org: "o"}}
+	err := e.ExportFlows(ctx, flows, func(ref FlowRef, m *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		exported = append(exported, fmt.Sprintf("%v#%d", ref, *m.ID))
+		return nil
+	})
+
+	if want := []string{"o/f#1", "o/f#2", "o/f#3"}; !reflect.DeepEqual(exported, want) {
+		t.Errorf("Exporter.ExportFlows exported %v, want %v", exported, want)
+	}
+	berr, ok := err.(*BulkError)
+	if !ok || len(berr.Errors) != 2 || berr.Errors[0].ID != "o/missing" || berr.Errors[1].Err != ErrInvalidFlowRef {
+		t.Fatalf("Exporter.ExportFlows returned %v, want a BulkError for o/missing and o/", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "30")
+
+	tests := []struct {
+		err  error
+		want time.Duration
+	}{
+		{&ErrorResponse{Response: resp}, 30 * time.Second},
+		{&ErrorResponse{Response: &http.Response{Header: http.Header{}}}, time.Second},
+		{errors.New("network"), time.Second},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.err, time.Second); got != tt.want {
+			t.Errorf("retryAfter(%v) returned %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	limited := &RateLimitError{Rate: Rate{Limit: 1, Reset: time.Now().Add(time.Minute)}, Request: &http.Request{URL: &url.URL{}}}
+	if got := retryAfter(limited, time.Second); got <= 50*time.Second || got > time.Minute {
+		t.Errorf("retryAfter(%v) returned %v, want the time until the reset", limited, got)
+	}
+}
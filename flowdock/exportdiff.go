@@ -0,0 +1,101 @@
+package flowdock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ExportDiff is the difference between two snapshots of the export of a
+// flow, as returned by DiffExports. Messages are sorted by ID.
+type ExportDiff struct {
+	Added   []Message     // in the new snapshot only
+	Edited  []MessageEdit // in both snapshots, with different contents
+	Deleted []Message     // in the old snapshot only
+}
+
+// MessageEdit is a message changed between two snapshots.
+type MessageEdit struct {
+	Old, New Message
+}
+
+// Empty reports whether the snapshots hold the same messages, unchanged.
+func (d *ExportDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Edited) == 0 && len(d.Deleted) == 0
+}
+
+// DiffExports compares two snapshots of the export of a flow, the old one
+// read from from and the new one from to. Snapshots are JSON lines: one
+// JSON encoded Message per line, as written from the fn of an Exporter. It
+// reports the messages added and deleted between them, identified by their
+// IDs, and the messages edited: those whose JSON representation differs,
+// such as their content or tags. It verifies that an archive is complete
+// and unchanged, for compliance. A message found more than once in a
+// snapshot, as exported again after a crash, is taken at its last
+// occurrence. Messages without an ID fail the comparison.
+func DiffExports(from, to io.Reader) (*ExportDiff, error) {
+	before, err := readSnapshot(from)
+	if err != nil {
+		return nil, fmt.Errorf("flowdock: old snapshot: %v", err)
+	}
+	after, err := readSnapshot(to)
+	if err != nil {
+		return nil, fmt.Errorf("flowdock: new snapshot: %v", err)
+	}
+
+	diff := new(ExportDiff)
+	for id, a := range after {
+		b, ok := before[id]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, a.Message)
+		case !bytes.Equal(a.data, b.data):
+			diff.Edited = append(diff.Edited, MessageEdit{Old: b.Message, New: a.Message})
+		}
+	}
+	for id, b := range before {
+		if _, ok := after[id]; !ok {
+			diff.Deleted = append(diff.Deleted, b.Message)
+		}
+	}
+
+	sortMessages(diff.Added)
+	sortMessages(diff.Deleted)
+	sort.Slice(diff.Edited, func(i, j int) bool { return *diff.Edited[i].New.ID < *diff.Edited[j].New.ID })
+	return diff, nil
+}
+
+// snapshotMessage is a message of a snapshot, with its canonical JSON
+// encoding, so that formatting differences are not taken for edits.
+type snapshotMessage struct {
+	Message
+	data []byte
+}
+
+// readSnapshot reads the messages of the JSON lines of r, by ID.
+func readSnapshot(r io.Reader) (map[int]snapshotMessage, error) {
+	messages := make(map[int]snapshotMessage)
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var m Message
+		if err := dec.Decode(&m); err == io.EOF {
+			return messages, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("message %d: %v", n, err)
+		}
+		if m.ID == nil {
+			return nil, fmt.Errorf("message %d has no ID", n)
+		}
+		data, err := json.Marshal(&m)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %v", n, err)
+		}
+		messages[*m.ID] = snapshotMessage{Message: m, data: data}
+	}
+}
+
+func sortMessages(messages []Message) {
+	sort.Slice(messages, func(i, j int) bool { return *messages[i].ID < *messages[j].ID })
+}
@@ -0,0 +1,59 @@
+package flowdock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffExports(t *testing.T) {
+	old := strings.Join([]string{
+		`{"id":1,"event":"message","content":"hello"}`,
+		`{"id":2,"event":"message","content":"typo"}`,
+		`{"id":3,"event":"message","content":"gone"}`,
+		`{"id":4,"event":"message","content":"same","tags":["a"]}`,
+	}, "\n")
+	new := strings.Join([]string{
+		`{"id":1, "event": "message", "content": "hello"}`, // formatted differently
+		`{"id":2,"event":"message","content":"fixed"}`,
+		`{"id":4,"event":"message","content":"same","tags":["a","b"]}`,
+		`{"id":5,"event":"message","content":"new"}`,
+		`{"id":5,"event":"message","content":"new"}`, // exported again
+	}, "\n")
+
+	diff, err := DiffExports(strings.NewReader(old), strings.NewReader(new))
+	if err != nil {
+		t.Fatalf("DiffExports returned error: %v", err)
+	}
+	if len(diff.Added) != 1 || *diff.Added[0].ID != 5 {
+		t.Errorf("DiffExports added %+v, want message 5", diff.Added)
+	}
+	if len(diff.Deleted) != 1 || *diff.Deleted[0].ID != 3 {
+		t.Errorf("DiffExports deleted %+v, want message 3", diff.Deleted)
+	}
+	if len(diff.Edited) != 2 || *diff.Edited[0].New.ID != 2 || *diff.Edited[1].New.ID != 4 {
+		t.Fatalf("DiffExports edited %+v, want messages 2 and 4", diff.Edited)
+	}
+	if e := diff.Edited[0]; e.Old.Content().String() != "typo" || e.New.Content().String() != "fixed" {
+		t.Errorf("DiffExports edited %q into %q, want typo into fixed", e.Old.Content(), e.New.Content())
+	}
+	if diff.Empty() {
+		t.Errorf("ExportDiff.Empty returned true")
+	}
+
+	diff, err = DiffExports(strings.NewReader(old), strings.NewReader(old))
+	if err != nil || !diff.Empty() {
+		t.Errorf("DiffExports of a snapshot with itself returned %+v, %v, want no difference", diff, err)
+	}
+}
+
+func TestDiffExports_invalid(t *testing.T) {
+	tests := []string{
+		`{"id":1}` + "\n" + `{"event":"message"}`,
+		`{"id":1}` + "\n" + `not json`,
+	}
+	for _, snapshot := range tests {
+		if _, err := DiffExports(strings.NewReader(`{"id":1}`), strings.NewReader(snapshot)); err == nil {
+			t.Errorf("DiffExports(%q) returned no error", snapshot)
+		}
+	}
+}
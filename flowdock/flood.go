@@ -0,0 +1,167 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// FloodRate is a message rate: Messages within Per.
+type FloodRate struct {
+	Messages int
+	Per      time.Duration
+}
+
+// Flood reports a user or source exceeding its FloodRate.
+type Flood struct {
+	User    string // ID of the user, if the flood is a user's
+	Source  string // external user name or app, if the flood is a source's
+	Count   int    // messages within the FloodRate's Per
+	Message *Message
+}
+
+// Return the string version of a Flood, "user 1: 20 messages"
+func (f Flood) String() string {
+	if f.Source != "" {
+		return fmt.Sprintf("source %s: %d messages", f.Source, f.Count)
+	}
+	return fmt.Sprintf("user %s: %d messages", f.User, f.Count)
+}
+
+// FloodDetector flags the users and sources, such as runaway integrations
+// posting as an external user, which send messages faster than their
+// FloodRate. Set it as the Client's FloodDetector to observe the streamed
+// messages, before the Client's Mute drops them. It is safe for concurrent
+// use.
+type FloodDetector struct {
+	// UserRate and SourceRate are the rates above which a user or a source
+	// is flooding. A zero rate never floods.
+	UserRate   FloodRate
+	SourceRate FloodRate
+
+	// OnFlood is called, from the stream delivering the message, with the
+	// first message of each flood. A flood ends once the rate drops
+	// below the threshold.
+	OnFlood func(Flood)
+
+	// Clock defaults to SystemClock.
+	Clock Clock
+
+	mu       sync.Mutex
+	sent     map[string][]time.Time
+	flooding map[string]bool
+}
+
+// NewFloodDetector returns a FloodDetector calling onFlood when a user sends
+// more than rate.Messages within rate.Per. Set its SourceRate to flag
+// sources too.
+func NewFloodDetector(rate FloodRate, onFlood func(Flood)) *FloodDetector {
+	return &FloodDetector{
+		UserRate: rate,
+		OnFlood:  onFlood,
+		Clock:    SystemClock,
+		sent:     make(map[string][]time.Time),
+		flooding: make(map[string]bool),
+	}
+}
+
+// Observe records m, and reports whether its sender is flooding. Activity
+// events, such as edits and tag changes, are not counted.
+func (d *FloodDetector) Observe(m *Message) bool {
+	if m.Event != nil && nonConversationEvents[*m.Event] {
+		return false
+	}
+
+	var f Flood
+	var key string
+	var rate FloodRate
+	switch {
+	case m.ExternalUserName != nil && *m.ExternalUserName != "":
+		f.Source, key, rate = *m.ExternalUserName, "source/"+*m.ExternalUserName, d.SourceRate
+	case m.App != nil && *m.App != "":
+		f.Source, key, rate = *m.App, "source/"+*m.App, d.SourceRate
+	case m.UserID != nil:
+		f.User, key, rate = *m.UserID, "user/"+*m.UserID, d.UserRate
+	default:
+		return false
+	}
+	if rate.Messages <= 0 {
+		return false
+	}
+
+	f.Count, f.Message = d.count(key, rate), m
+	if f.Count <= rate.Messages {
+		return false
+	}
+	if d.start(key) && d.OnFlood != nil {
+		d.OnFlood(f)
+	}
+	return true
+}
+
+// count records a message of key, and returns the number of messages of key
+// within rate.Per.
+func (d *FloodDetector) count(key string, rate FloodRate) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.Clock.Now()
+	sent := d.sent[key]
+	for len(sent) > 0 && now.Sub(sent[0]) >= rate.Per {
+		sent = sent[1:]
+	}
+	sent = append(sent, now)
+	d.sent[key] = sent
+	if len(sent) <= rate.Messages {
+		delete(d.flooding, key)
+	}
+	return len(sent)
+}
+
+// start marks key as flooding, and reports whether its flood just started.
+func (d *FloodDetector) start(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.flooding[key] {
+		return false
+	}
+	d.flooding[key] = true
+	return true
+}
+
+// MuteFlooders returns an OnFlood callback muting the flooding users with
+// mute. Sources post as a shared user, and are not muted.
+func MuteFlooders(mute *Mute) func(Flood) {
+	return func(f Flood) {
+		if f.User != "" {
+			mute.MuteUser(f.User)
+		}
+	}
+}
+
+// LogFloods returns an OnFlood callback logging the floods to l.
+func LogFloods(l *log.Logger) func(Flood) {
+	return func(f Flood) {
+		l.Printf("flood detected: %v", f)
+	}
+}
+
+// AlertFloods returns an OnFlood callback posting the floods to the flow of
+// the moderators. Failures to post are logged by client.
+func AlertFloods(client *Client, moderators FlowRef) func(Flood) {
+	return func(f Flood) {
+		where := ""
+		if f.Message != nil && f.Message.FlowID != nil {
+			where = " in flow " + *f.Message.FlowID
+		}
+		_, _, err := client.Messages.createInFlow(context.Background(), string(moderators.Org), moderators.Flow, &MessagesCreateOptions{
+			Event:   "message",
+			Content: fmt.Sprintf("Flood detected%s: %v", where, f),
+		})
+		if err != nil {
+			client.Logf("failed to alert of %v: %v", f, err)
+		}
+	}
+}
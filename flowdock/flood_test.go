@@ -0,0 +1,108 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func floodMessage(data string) *Message {
+	m := new(Message)
+	json.Unmarshal([]byte(data), m)
+	return m
+}
+
+func TestFloodDetector_Observe(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	var floods []string
+	d := NewFloodDetector(FloodRate{Messages: 2, Per: time.Minute}, func(f Flood) {
+		floods = append(floods, f.String())
+	})
+	d.SourceRate = FloodRate{Messages: 1, Per: time.Minute}
+	d.Clock = clock
+
+	user := `{"event":"message","user":"1","content":"hi"}`
+	for i, want := range []bool{false, false, true, true} {
+		if got := d.Observe(floodMessage(user)); got != want {
+			t.Errorf("FloodDetector.Observe of message %d = %v, want %v", i, got, want)
+		}
+	}
+	if d.Observe(floodMessage(`{"event":"tag-change","user":"1"}`)) {
+		t.Errorf("FloodDetector.Observe flagged a tag change")
+	}
+
+	clock.Advance(time.Minute)
+	if d.Observe(floodMessage(user)) {
+		t.Errorf("FloodDetector.Observe flagged a message after the flood ended")
+	}
+	d.Observe(floodMessage(user))
+	d.Observe(floodMessage(user))
+
+	bot := `{"event":"message","user":"0","external_user_name":"ci","content":"build"}`
+	d.Observe(floodMessage(bot))
+	if !d.Observe(floodMessage(bot)) {
+		t.Errorf("FloodDetector.Observe did not flag the source")
+	}
+
+	want := []string{"user 1: 3 messages", "user 1: 3 messages", "source ci: 2 messages"}
+	if !reflect.DeepEqual(floods, want) {
+		t.Errorf("FloodDetector.OnFlood called with %q, want %q", floods, want)
+	}
+}
+
+func TestMessagesService_Stream_flood(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprint(w, "data: {\"event\":\"message\",\"user\":\"2\",\"content\":\"noise\"}\n\n")
+		}
+		fmt.Fprint(w, "data: {\"event\":\"message\",\"user\":\"1\",\"content\":\"signal\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	client.Mute = NewMute()
+	client.FloodDetector = NewFloodDetector(FloodRate{Messages: 1, Per: time.Hour}, MuteFlooders(client.Mute))
+
+	stream, es, err := client.Messages.Stream(ctx, "token", "org", "flow")
+	if err != nil {
+		t.Fatalf("Messages.Stream returned error: %v", err)
+	}
+	defer es.Close()
+
+	if msg := <-stream; msg.Content().String() != "noise" {
+		t.Errorf("Messages.Stream delivered %v, want the first message", msg.Content())
+	}
+	if msg := <-stream; msg.Content().String() != "signal" {
+		t.Errorf("Messages.Stream delivered %v, want the flooding user muted", msg.Content())
+	}
+}
+
+func TestAlertFloods(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/mods/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{
+			"event":   "message",
+			"content": "Flood detected in flow f1: source ci: 5 messages",
+		})
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	flowID := "f1"
+	AlertFloods(client, OrgID("org").Flow("mods"))(Flood{
+		Source:  "ci",
+		Count:   5,
+		Message: &Message{FlowID: &flowID},
+	})
+}
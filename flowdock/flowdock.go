@@ -2,16 +2,22 @@
 package flowdock
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/google/go-querystring/query"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -24,6 +30,23 @@ const (
 	defaultMediaType = "application/json"
 )
 
+// StreamAuth selects how the access token is passed to the streaming API.
+type StreamAuth int
+
+const (
+	// StreamAuthBearer sends the token in a bearer Authorization header.
+	StreamAuthBearer StreamAuth = iota
+
+	// StreamAuthBasic sends the token as the user name of a basic
+	// Authorization header, as done for personal API tokens.
+	StreamAuthBasic
+
+	// StreamAuthQuery sends the token in the access_token query parameter.
+	// The token then shows up in the URL. It is redacted from the logs and
+	// errors of the library, but may still be logged by proxies.
+	StreamAuthQuery
+)
+
 // A Client manages communication with the Flowdock API.
 type Client struct {
 	// HTTP client used to communicate with the API.
@@ -35,17 +58,140 @@ type Client struct {
 	// Streaming URL for API requests.
 	StreamURL *url.URL
 
+	// OrgEndpoints, if set, maps the parameterized names of organizations
+	// to their endpoints, for organizations served elsewhere than RestURL
+	// and StreamURL, so that one Client reaches organizations of several
+	// domains or regions. The requests of the paths naming an
+	// organization, flows/{org}/... and organizations/{org}, go to its
+	// endpoint; the others, such as users or flows/find, to RestURL. See
+	// SetOrgEndpoint.
+	OrgEndpoints map[string]OrgEndpoint
+
 	// User agent used when communicating with the Flowdock API.
 	UserAgent string
 
 	Log *log.Logger
 
+	// Redactor, if set, removes secrets of your own from the URLs and
+	// requests logged or embedded in errors, after Redact.
+	Redactor Redactor
+
+	// StreamAuth is how tokens are passed to the streaming API. Defaults to
+	// StreamAuthBearer.
+	StreamAuth StreamAuth
+
+	// Credentials, if set, supplies the token of the requests and stream
+	// connections which aren't authorized otherwise. The token is passed as
+	// selected by StreamAuth.
+	Credentials CredentialsProvider
+
+	// Mute, if set, drops the streamed messages it silences.
+	Mute *Mute
+
+	// FloodDetector, if set, observes the streamed messages to flag the
+	// users and sources flooding flows.
+	FloodDetector *FloodDetector
+
+	// OnDeprecated, if set, is called with the deprecated fields of the
+	// listed, fetched and streamed messages, to find the code paths which
+	// still rely on them. See Message.MigrateApp.
+	OnDeprecated func(Deprecation)
+
+	// UploadDedup, if set, keeps identical files from being uploaded
+	// again to the same flow.
+	UploadDedup *UploadDedup
+
+	// MaxEventSize is the maximum size, in bytes, of a single streamed
+	// event. Larger events are discarded. Defaults to DefaultMaxEventSize,
+	// or MaxResponseBytes if smaller.
+	MaxEventSize int
+
+	// MaxResponseBytes, if set, is the maximum size of an API response
+	// body. Reading past it fails with ErrResponseTooLarge, so that
+	// oversized responses can't exhaust the memory of long-running
+	// programs. Streamed events are bounded by it too.
+	MaxResponseBytes int64
+
+	// Clock used by the time dependent parts of the client, such as stream
+	// reconnection delays. Defaults to SystemClock.
+	Clock Clock
+
+	// HistoryFloor, if set, is the age beyond which the messages of flows
+	// are withheld from the lists of messages: MessagesService.List,
+	// Iterate and ListAll, ThreadsService.ListMessages, and Exporters. It
+	// enforces data retention policies, such as never reading messages
+	// older than 90 days, whatever the callers ask for. Walks newest first
+	// stop at the floor, and walks oldest first start there. Messages
+	// without a sent time are not withheld, nor messages asked for by ID,
+	// nor the messages of Archives, which are read from exports rather
+	// than from the API.
+	HistoryFloor time.Duration
+
+	// Timeouts bounds the duration of the requests of each EndpointClass,
+	// including the reading of their response. Classes without a timeout
+	// are only bounded by the http.Client. A shorter deadline of the
+	// context of a request bounds it instead; requests timing out either
+	// way fail with a *TimeoutError telling which.
+	Timeouts map[EndpointClass]time.Duration
+
+	// HedgeAfter, if set, sends a read request a second time when it got
+	// no response after that delay, and uses the first response. It cuts
+	// the tail latency of lookups on latency-sensitive paths, such as the
+	// resolution of users while processing a stream.
+	HedgeAfter time.Duration
+
+	// Tracer, if set, observes the API requests and stream connections,
+	// for instance to record them as tracing spans.
+	Tracer Tracer
+
+	// LegacyMessageQuery sends the options of the requests creating and
+	// editing messages as URL query parameters, as done before they were
+	// sent as a JSON body. Query strings break on long contents.
+	LegacyMessageQuery bool
+
+	// Limiter, if set, paces the API requests. Stream connections are not
+	// paced. Tag the context of requests with WithPriority to have a
+	// RateLimiter send them before, or after, the others.
+	Limiter Limiter
+
+	// RateLimitPolicy is what requests do while the rate limit reported
+	// by the API is exhausted. Defaults to RateLimitIgnore. See Rate.
+	RateLimitPolicy RateLimitPolicy
+
+	rateMu sync.Mutex
+	rate   Rate // of the last response reporting it
+
+	// Retry, if set, sends the requests which failed transiently again,
+	// such as the rate limited ones. Requests are not retried by default.
+	Retry *RetryPolicy
+
+	// Workers runs the requests of the concurrent helpers, such as
+	// MessagesService.DeleteBulk. A nil Workers runs DefaultWorkers
+	// requests at once.
+	Workers *WorkerPool
+
+	// QueryEncoder, if set, encodes the options of the requests as query
+	// parameters instead of DefaultQueryEncoder.
+	QueryEncoder QueryEncoder
+
+	// RestartOnPanic, if set, keeps the goroutines of the library calling
+	// user code, such as the reader of a Stream running Mute or
+	// OnDeprecated, or StreamManager.Serve running its handler, going
+	// after a panic of that code: the message being processed is dropped
+	// and the next one processed. Otherwise the panic ends them. Either
+	// way, the panic is recovered and reported as a *PanicError.
+	RestartOnPanic bool
+
 	// Services used for talking to different parts of the Flowdock API.
 	Flows         *FlowsService
 	Messages      *MessagesService
 	Users         *UsersService
 	Organizations *OrganizationsService
 	Inbox         *InboxService
+	Integrations  *IntegrationsService
+	Invitations   *InvitationsService
+	Sources       *SourcesService
+	Threads       *ThreadsService
 }
 
 func newClient(httpClient *http.Client, baseURL, streamURL *url.URL) *Client {
@@ -55,13 +201,18 @@ func newClient(httpClient *http.Client, baseURL, streamURL *url.URL) *Client {
 		StreamURL: streamURL,
 		UserAgent: userAgent,
 		Log:       log.New(os.Stderr, "[flowdock]", log.Llongfile|log.Ltime),
+		Clock:     SystemClock,
 	}
 
 	c.Flows = &FlowsService{client: c}
 	c.Messages = &MessagesService{client: c}
 	c.Inbox = &InboxService{client: c}
+	c.Integrations = &IntegrationsService{client: c}
 	c.Users = &UsersService{client: c}
 	c.Organizations = &OrganizationsService{client: c}
+	c.Invitations = &InvitationsService{client: c}
+	c.Sources = &SourcesService{client: c}
+	c.Threads = &ThreadsService{client: c}
 	return c
 }
 
@@ -89,13 +240,17 @@ func NewClientWithToken(httpClient *http.Client, token string) *Client {
 	return newClient(httpClient, baseURL, streamURL)
 }
 
-func (c *Client) baseRequest(method, urlStr string, baseURL url.URL, body interface{}) (*http.Request, error) {
+func (c *Client) baseRequest(method, urlStr string, baseURL func(*url.URL) (*url.URL, error), body interface{}) (*http.Request, error) {
 	rel, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
 
-	u := baseURL.ResolveReference(rel)
+	base, err := baseURL(rel)
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(rel)
 
 	buf := new(bytes.Buffer)
 	if body != nil {
@@ -110,101 +265,316 @@ func (c *Client) baseRequest(method, urlStr string, baseURL url.URL, body interf
 		return nil, err
 	}
 
+	if body != nil {
+		req.Header.Add("Content-Type", defaultMediaType)
+	}
 	req.Header.Add("Accept", defaultMediaType)
 	req.Header.Add("User-Agent", c.UserAgent)
 	return req, nil
 }
 
 // NewRequest creates an API request. A relative URL can be provided in urlStr,
-// in which case it is resolved relative to the RestURL of the Client.
+// in which case it is resolved relative to the RestURL of the Client, or of
+// the OrgEndpoints of the organization it names.
 // Relative URLs should always be specified without a preceding slash. If
 // specified, the value pointed to by body is JSON encoded and included as the
 // request body.
 func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	return c.baseRequest(method, urlStr, *c.RestURL, body)
+	return c.baseRequest(method, urlStr, c.restURL, body)
 }
 
 // NewStreamRequest creates an API request. A relative URL can be provided in urlStr,
-// in which case it is resolved relative to the StreamURL of the Client.
+// in which case it is resolved relative to the StreamURL of the Client, or
+// of the OrgEndpoints of the organization it names, or of the organizations
+// of its filter parameter, which fails with ErrMixedEndpoints when they
+// have different ones.
 // Relative URLs should always be specified without a preceding slash. If
 // specified, the value pointed to by body is JSON encoded and included as the
 // request body.
 func (c *Client) NewStreamRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	return c.baseRequest(method, urlStr, *c.StreamURL, body)
+	return c.baseRequest(method, urlStr, c.streamURL, body)
+}
+
+// NewUploadRequest creates an upload request. A relative URL can be provided
+// in urlStr, in which case it is resolved relative to the RestURL of the
+// Client, or of the OrgEndpoints of the organization it names. The body is
+// sent as is, with the given media type as its Content-Type.
+func (c *Client) NewUploadRequest(urlStr string, body io.Reader, mediaType string) (*http.Request, error) {
+	rel, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := c.restURL(rel)
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(rel)
+	req, err := http.NewRequest("POST", u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", mediaType)
+	req.Header.Add("Accept", defaultMediaType)
+	req.Header.Add("User-Agent", c.UserAgent)
+	return req, nil
+}
+
+// AuthorizeStreamRequest adds token to a request made with NewStreamRequest,
+// as selected by the Client's StreamAuth. An empty token leaves the request
+// untouched, for clients authenticated by their http.Client.
+func (c *Client) AuthorizeStreamRequest(req *http.Request, token string) {
+	if token == "" {
+		return
+	}
+
+	switch c.StreamAuth {
+	case StreamAuthBasic:
+		req.SetBasicAuth(token, "")
+	case StreamAuthQuery:
+		q := req.URL.Query()
+		q.Set("access_token", token)
+		req.URL.RawQuery = q.Encode()
+	default:
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 }
 
 // Do sends an API request and returns the API response. The API response is
 // decoded and stored in the value pointed to by v, or returned as an error if
-// an API error has occurred.
-func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	resp, err := c.client.Do(req)
-	if err != nil {
+// an API error has occurred. If v implements the io.Writer interface, the raw
+// response body is written to it instead. Responses without content, such as
+// 204 No Content ones, succeed without touching v; see IsEmptyResponse.
+//
+// The request is bound to ctx: canceling it, or reaching its deadline,
+// aborts the request. ctx must be non-nil. Transient failures are retried
+// as selected by the Client's Retry.
+func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+	if ctx == nil {
+		return nil, errNonNilContext
+	}
+	req = req.WithContext(ctx)
+
+	if c.Tracer == nil {
+		return c.doReauthorized(req, v)
+	}
+
+	req, end := c.Tracer.Start(req, c.operation(req, false))
+	resp, err := c.doReauthorized(req, v)
+	end(resp, err)
+	return resp, err
+}
+
+func (c *Client) do(req *http.Request, v interface{}) (*http.Response, error) {
+	// the timeout of the class is bounded by the deadline of the caller,
+	// and timeouts are reported with both
+	b := c.budget(req)
+	if d := c.Timeouts[b.class]; d > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.checkRate(req); err != nil {
 		return nil, err
 	}
 
+	if err := c.authorizeFromCredentials(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, c.timeoutError(req, b, c.redactError(err))
+	}
+	c.updateRate(resp)
+
 	defer func() { _ = resp.Body.Close() }()
 
+	if c.MaxResponseBytes > 0 {
+		if resp.ContentLength > c.MaxResponseBytes {
+			return resp, ErrResponseTooLarge
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, n: c.MaxResponseBytes}
+	}
+
 	err = CheckResponse(resp)
 	if err != nil {
+		c.rejectedCredentials(resp)
 		// even though there was an error, we still return the response
 		// in case the caller wants to inspect it further
-		return resp, err
+		return resp, c.redactError(err)
 	}
 
-	if v != nil {
-		err = json.NewDecoder(resp.Body).Decode(v)
+	body := bufio.NewReader(resp.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		// 202 and 204 responses, among others, have no content to
+		// decode; their Body is set to http.NoBody
+		resp.Body.Close()
+		resp.Body = http.NoBody
+		return resp, nil
 	}
-	return resp, err
+
+	switch v := v.(type) {
+	case nil:
+	case io.Writer:
+		_, err = io.Copy(v, body)
+	default:
+		err = json.NewDecoder(body).Decode(v)
+	}
+	return resp, c.timeoutError(req, b, err)
 }
 
-// An ErrorResponse reports the errors caused by an API request.
-//
-type ErrorResponse struct {
+// IsEmptyResponse reports whether resp, returned by Client.Do, had no
+// content. The value passed to Do is then left untouched.
+func IsEmptyResponse(resp *http.Response) bool {
+	return resp != nil && resp.Body == http.NoBody
+}
+
+// An Error reports the failure of an API request which got a response with
+// a status code outside the 200 range. The Flowdock error body is kept in
+// Data, and parsed into Message and Errors when it is JSON. Use errors.As to
+// get it from the errors of the client, or the helpers like IsNotFound.
+type Error struct {
 	Response *http.Response // HTTP response
 	Data     []byte         // the error details
+
+	// Message is the message of the error body.
+	Message string
+
+	// Errors are the validation errors of the fields of the request.
+	Errors []FieldError
+
+	// Maintenance is set when the API refused the request because it is
+	// under maintenance. See ErrMaintenance.
+	Maintenance *Maintenance
+
+	redact Redactor // set by the Client, Redact otherwise
+}
+
+// ErrorResponse is the former name of Error.
+type ErrorResponse = Error
+
+// FieldError is the validation error of a field of an API request.
+type FieldError struct {
+	Field   string
+	Message string
 }
 
-func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf("%v %v: %d %s",
+func (e FieldError) String() string {
+	return e.Field + " " + e.Message
+}
+
+func (r *Error) Error() string {
+	redact := r.redact
+	if redact == nil {
+		redact = Redact
+	}
+	return redact(fmt.Sprintf("%v %v: %d %s",
 		r.Response.Request.Method, r.Response.Request.URL,
-		r.Response.StatusCode, r.Data)
+		r.Response.StatusCode, r.Data))
+}
+
+// StatusCode returns the status code of the response, or 0 if there is none.
+func (r *Error) StatusCode() int {
+	if r.Response == nil {
+		return 0
+	}
+	return r.Response.StatusCode
 }
 
 // CheckResponse checks the API response for errors, and returns them if
 // present.  A response is considered an error if it has a status code outside
 // the 200 range.  API error responses are expected to have either no response
-// body, or a JSON response body that maps to ErrorResponse.  Any other
-// response body will be silently ignored.
+// body, or a JSON response body that maps to Error.  Any other response body
+// is kept in Data only.
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; 200 <= c && c <= 299 {
 		return nil
 	}
-	errorResponse := &ErrorResponse{Response: r}
+	errorResponse := &Error{Response: r}
 	data, err := ioutil.ReadAll(r.Body)
 	if err == nil && data != nil {
 		errorResponse.Data = data
+		errorResponse.parse()
+		errorResponse.parseMaintenance()
 	}
 	return errorResponse
 }
 
-// addOptions adds the parameters in opt as URL query parameters to s. opt must
-// be a struct whose fields may contain "url" tags.
-func addOptions(s string, opt interface{}) (string, error) {
-	v := reflect.ValueOf(opt)
-	if v.Kind() == reflect.Ptr && v.IsNil() {
-		return s, nil
-	}
+// errNonNilContext is returned by Do when given a nil context.
+var errNonNilContext = errors.New("flowdock: context must be non-nil")
 
-	u, err := url.Parse(s)
-	if err != nil {
-		return s, err
+// ErrResponseTooLarge is returned when a response body exceeds the Client's
+// MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("flowdock: response exceeds MaxResponseBytes")
+
+// limitedBody fails with ErrResponseTooLarge once more than n bytes are
+// read.
+type limitedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		// only fail when there is more to read
+		var one [1]byte
+		for {
+			n, err := b.ReadCloser.Read(one[:])
+			if n > 0 {
+				return 0, ErrResponseTooLarge
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
 	}
+	n, err := b.ReadCloser.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
 
-	qs, err := query.Values(opt)
-	if err != nil {
-		return s, err
+// maxEventSize returns the maximum size of a streamed event.
+func (c *Client) maxEventSize() int {
+	size := int64(c.MaxEventSize)
+	if size <= 0 {
+		size = DefaultMaxEventSize
+	}
+	if c.MaxResponseBytes > 0 && c.MaxResponseBytes < size {
+		size = c.MaxResponseBytes
 	}
+	return int(size)
+}
 
-	u.RawQuery = qs.Encode()
-	return u.String(), nil
+// jsonFieldNames returns the JSON names of the exported fields of struct type
+// t, as set by their "json" tags, with their position in the encoding of t.
+func jsonFieldNames(t reflect.Type) map[string]int {
+	names := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names[name] = len(names)
+	}
+	return names
 }
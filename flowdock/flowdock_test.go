@@ -1,7 +1,9 @@
 package flowdock
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -53,6 +55,12 @@ func testMethod(t *testing.T, r *http.Request, want string) {
 	}
 }
 
+func testHeader(t *testing.T, r *http.Request, header string, want string) {
+	if value := r.Header.Get(header); want != value {
+		t.Errorf("Header %s = %s, want: %s", header, value, want)
+	}
+}
+
 type responseWriter interface {
 	http.ResponseWriter
 	http.Flusher
@@ -73,6 +81,39 @@ func testFormValues(t *testing.T, r *http.Request, values values) {
 	}
 }
 
+// jsonValues returns the fields of the JSON object in the body of r as
+// parameters, lists being comma-separated, for the requests sending their
+// options as JSON.
+func jsonValues(t *testing.T, r *http.Request) url.Values {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("Request body is not a JSON object: %v", err)
+	}
+	form := url.Values{}
+	for k, v := range body {
+		if list, ok := v.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			v = strings.Join(items, ",")
+		}
+		form.Set(k, fmt.Sprint(v))
+	}
+	return form
+}
+
+func testJSONValues(t *testing.T, r *http.Request, values values) {
+	want := url.Values{}
+	for k, v := range values {
+		want.Add(k, v)
+	}
+
+	if got := jsonValues(t, r); !reflect.DeepEqual(want, got) {
+		t.Errorf("Request body = %v, want %v", got, want)
+	}
+}
+
 func TestNewClient(t *testing.T) {
 	c := NewClient(nil)
 
@@ -123,6 +164,33 @@ func TestNewRequest(t *testing.T) {
 	}
 }
 
+func TestAuthorizeStreamRequest(t *testing.T) {
+	c := NewClient(nil)
+
+	tests := []struct {
+		auth          StreamAuth
+		authorization string
+		query         string
+	}{
+		{StreamAuthBearer, "Bearer t", ""},
+		{StreamAuthBasic, "Basic dDo=", ""},
+		{StreamAuthQuery, "", "access_token=t"},
+	}
+
+	for _, tt := range tests {
+		c.StreamAuth = tt.auth
+		req, _ := c.NewStreamRequest("GET", "flows/org/flow", nil)
+		c.AuthorizeStreamRequest(req, "t")
+
+		if got := req.Header.Get("Authorization"); got != tt.authorization {
+			t.Errorf("AuthorizeStreamRequest(%v) Authorization = %q, want %q", tt.auth, got, tt.authorization)
+		}
+		if got := req.URL.RawQuery; got != tt.query {
+			t.Errorf("AuthorizeStreamRequest(%v) query = %q, want %q", tt.auth, got, tt.query)
+		}
+	}
+}
+
 func TestNewRequest_invalidJSON(t *testing.T) {
 	c := NewClient(nil)
 
@@ -158,6 +226,7 @@ func TestDo(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	type foo struct {
 		A string
 	}
@@ -171,7 +240,7 @@ func TestDo(t *testing.T) {
 
 	req, _ := client.NewRequest("GET", "/", nil)
 	body := new(foo)
-	client.Do(req, body)
+	client.Do(ctx, req, body)
 
 	want := &foo{"a"}
 	if !reflect.DeepEqual(body, want) {
@@ -183,30 +252,58 @@ func TestDo_httpError(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Bad Request", 400)
 	})
 
 	req, _ := client.NewRequest("GET", "/", nil)
-	_, err := client.Do(req, nil)
+	_, err := client.Do(ctx, req, nil)
 
 	if err == nil {
 		t.Error("Expected HTTP 400 error.")
 	}
 }
 
+func TestDo_nilContext(t *testing.T) {
+	setup()
+	defer teardown()
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(nil, req, nil); err != errNonNilContext {
+		t.Errorf("Do returned %v, want errNonNilContext", err)
+	}
+}
+
+func TestDo_canceledContext(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request sent despite the canceled context")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do returned %v, want context.Canceled", err)
+	}
+}
+
 // Test handling of an error caused by the internal http client's Do()
 // function.
 func TestDo_redirectLoop(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/", http.StatusFound)
 	})
 
 	req, _ := client.NewRequest("GET", "/", nil)
-	_, err := client.Do(req, nil)
+	_, err := client.Do(ctx, req, nil)
 
 	if err == nil {
 		t.Error("Expected error to be returned.")
@@ -216,6 +313,117 @@ func TestDo_redirectLoop(t *testing.T) {
 	}
 }
 
+func TestDo_emptyResponse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	type foo struct {
+		A string
+	}
+
+	for _, status := range []int{http.StatusOK, http.StatusAccepted, http.StatusNoContent} {
+		status := status
+		path := fmt.Sprintf("/%d", status)
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+
+		req, _ := client.NewRequest("DELETE", path, nil)
+		body := &foo{"unchanged"}
+		resp, err := client.Do(ctx, req, body)
+		if err != nil {
+			t.Errorf("Do returned error %v for status %d", err, status)
+		}
+		if !IsEmptyResponse(resp) {
+			t.Errorf("IsEmptyResponse returned false for status %d, want true", status)
+		}
+		if want := (&foo{"unchanged"}); !reflect.DeepEqual(body, want) {
+			t.Errorf("Response body = %v, want %v", body, want)
+		}
+	}
+}
+
+func TestIsEmptyResponse(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"A":"a"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		t.Errorf("Do returned error %v", err)
+	}
+	if IsEmptyResponse(resp) {
+		t.Errorf("IsEmptyResponse returned true, want false")
+	}
+	if IsEmptyResponse(nil) {
+		t.Errorf("IsEmptyResponse(nil) returned true, want false")
+	}
+}
+
+func TestDo_maxResponseBytes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"A":"a"}`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"A":"%s"}`, strings.Repeat("a", 100))
+	})
+	mux.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"A":"`)
+		w.(http.Flusher).Flush() // no Content-Length
+		fmt.Fprintf(w, `%s"}`, strings.Repeat("a", 100))
+	})
+	client.MaxResponseBytes = 16
+
+	type foo struct {
+		A string
+	}
+	tests := []struct {
+		path string
+		want error
+	}{
+		{"small", nil},
+		{"large", ErrResponseTooLarge},
+		{"chunked", ErrResponseTooLarge},
+	}
+	for _, tt := range tests {
+		req, _ := client.NewRequest("GET", tt.path, nil)
+		if _, err := client.Do(ctx, req, new(foo)); err != tt.want {
+			t.Errorf("Do(%v) returned %v, want %v", tt.path, err, tt.want)
+		}
+	}
+}
+
+func TestClient_maxEventSize(t *testing.T) {
+	tests := []struct {
+		event    int
+		response int64
+		want     int
+	}{
+		{0, 0, DefaultMaxEventSize},
+		{100, 0, 100},
+		{0, 100, 100},
+		{100, 50, 50},
+		{50, 100, 50},
+	}
+	for _, tt := range tests {
+		c := NewClient(nil)
+		c.MaxEventSize, c.MaxResponseBytes = tt.event, tt.response
+		if got := c.maxEventSize(); got != tt.want {
+			t.Errorf("maxEventSize with %d, %d = %d, want %d", tt.event, tt.response, got, tt.want)
+		}
+	}
+}
+
 func TestCheckResponse(t *testing.T) {
 	res := &http.Response{
 		Request:    &http.Request{},
@@ -233,6 +441,8 @@ func TestCheckResponse(t *testing.T) {
 		Response: res,
 		Data: []byte(`{"message":"m", 
                         "errors": [{"resource": "r", "field": "f", "code": "c"}]}`),
+		Message: "m",
+		Errors:  []FieldError{{Field: "f", Message: "c"}},
 	}
 	if !reflect.DeepEqual(err, want) {
 		t.Errorf("Error = %#v, want %#v", err, want)
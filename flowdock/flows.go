@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -26,16 +27,33 @@ type Flow struct {
 	WebURL            *string       `json:"web_url,omitempty"`
 	JoinURL           *string       `json:"join_url,omitempty"`
 	AccessMode        *string       `json:"access_mode,omitempty"`
+	Description       *string       `json:"description,omitempty"`
+	Email             *string       `json:"email,omitempty"`
+	APIToken          *string       `json:"api_token,omitempty"`
+	FlowAdmin         *bool         `json:"flow_admin,omitempty"`
 	Organization      *Organization `json:"organization,omitempty"`
 	Users             *[]User       `json:"users,omitempty"`
 }
 
+// The access modes of a flow, in Flow.AccessMode, selecting who may join it.
+const (
+	// FlowAccessInvitation only lets invited users join the flow.
+	FlowAccessInvitation = "invitation"
+
+	// FlowAccessLink lets anyone with the join link of the flow join it.
+	FlowAccessLink = "link"
+
+	// FlowAccessOrganization lets every member of the organization join
+	// the flow.
+	FlowAccessOrganization = "organization"
+)
+
 // FlowsListOptions specifies the optional parameters to the FlowsService.List
 // method.
 type FlowsListOptions struct {
 	// User a boolean value (1/0) that controls whether a list of users should
 	// be included with each flow.
-	User bool `url:"user,omitempty"`
+	User bool `url:"users,int,omitempty"`
 }
 
 // FlowsGetOptions specifies the optional parameters to the FlowsService.Get
@@ -53,14 +71,14 @@ type FlowsCreateOptions struct {
 // Lists the flows that the authenticated user is a member of.
 //
 // Flowdock API docs: https://www.flowdock.com/api/flows
-func (s *FlowsService) List(all bool, opt *FlowsListOptions) ([]Flow, *http.Response, error) {
+func (s *FlowsService) List(ctx context.Context, all bool, opt *FlowsListOptions) ([]Flow, *http.Response, error) {
 	u := "flows"
 
 	if all {
 		u += "/all"
 	}
 
-	u, err := addOptions(u, opt)
+	u, err := s.client.addOptions(u, opt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -71,7 +89,7 @@ func (s *FlowsService) List(all bool, opt *FlowsListOptions) ([]Flow, *http.Resp
 	}
 
 	flows := new([]Flow)
-	resp, err := s.client.Do(req, flows)
+	resp, err := s.client.Do(ctx, req, flows)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -84,7 +102,7 @@ func (s *FlowsService) List(all bool, opt *FlowsListOptions) ([]Flow, *http.Resp
 // list of flows.
 //
 // Flowdock API docs: https://www.flowdock.com/api/flows
-func (s *FlowsService) Get(org, flowName string) (*Flow, *http.Response, error) {
+func (s *FlowsService) Get(ctx context.Context, org, flowName string) (*Flow, *http.Response, error) {
 	u := fmt.Sprintf("flows/%v/%v", org, flowName)
 
 	req, err := s.client.NewRequest("GET", u, nil)
@@ -93,7 +111,7 @@ func (s *FlowsService) Get(org, flowName string) (*Flow, *http.Response, error)
 	}
 
 	flow := new(Flow)
-	resp, err := s.client.Do(req, flow)
+	resp, err := s.client.Do(ctx, req, flow)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -106,9 +124,9 @@ func (s *FlowsService) Get(org, flowName string) (*Flow, *http.Response, error)
 // list of flows.
 //
 // Flowdock API docs: https://www.flowdock.com/api/flows
-func (s *FlowsService) GetByID(id string) (*Flow, *http.Response, error) {
+func (s *FlowsService) GetByID(ctx context.Context, id string) (*Flow, *http.Response, error) {
 	u := "flows/find"
-	u, err := addOptions(u, FlowsGetOptions{ID: id})
+	u, err := s.client.addOptions(u, FlowsGetOptions{ID: id})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -119,7 +137,7 @@ func (s *FlowsService) GetByID(id string) (*Flow, *http.Response, error) {
 	}
 
 	flow := new(Flow)
-	resp, err := s.client.Do(req, flow)
+	resp, err := s.client.Do(ctx, req, flow)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -130,17 +148,20 @@ func (s *FlowsService) GetByID(id string) (*Flow, *http.Response, error) {
 // Create a flow for the specified organization
 //
 // Flowdock API docs: https://www.flowdock.com/api/flows
-func (s *FlowsService) Create(orgName string, opt *FlowsCreateOptions) (*Flow, *http.Response, error) {
+func (s *FlowsService) Create(ctx context.Context, orgName string, opt *FlowsCreateOptions) (*Flow, *http.Response, error) {
 	u := fmt.Sprintf("flows/%v", orgName)
 
-	u, err := addOptions(u, opt)
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
 	req, err := s.client.NewRequest("POST", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	flow := new(Flow)
-	resp, err := s.client.Do(req, flow)
+	resp, err := s.client.Do(ctx, req, flow)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -151,7 +172,7 @@ func (s *FlowsService) Create(orgName string, opt *FlowsCreateOptions) (*Flow, *
 // Update a flow.
 //
 // Flowdock API docs: https://www.flowdock.com/api/flows
-func (s *FlowsService) Update(orgName, flowName string, flow *Flow) (*Flow, *http.Response, error) {
+func (s *FlowsService) Update(ctx context.Context, orgName, flowName string, flow *Flow) (*Flow, *http.Response, error) {
 	u := fmt.Sprintf("flows/%v/%v", orgName, flowName)
 	req, err := s.client.NewRequest("PUT", u, flow)
 	if err != nil {
@@ -159,10 +180,126 @@ func (s *FlowsService) Update(orgName, flowName string, flow *Flow) (*Flow, *htt
 	}
 
 	flow = new(Flow)
-	resp, err := s.client.Do(req, flow)
+	resp, err := s.client.Do(ctx, req, flow)
 	if err != nil {
 		return nil, resp, err
 	}
 
 	return flow, resp, err
 }
+
+// Rename renames a flow. Its parameterized name, found in its URLs, is
+// left as it was.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) Rename(ctx context.Context, org, flowName, name string) (*Flow, *http.Response, error) {
+	return s.Update(ctx, org, flowName, &Flow{Name: &name})
+}
+
+// Reopen opens a flow closed with Close again.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) Reopen(ctx context.Context, org, flowName string) (*Flow, *http.Response, error) {
+	open := true
+	return s.Update(ctx, org, flowName, &Flow{Open: &open})
+}
+
+// SetAccessMode sets who may join a flow, as one of FlowAccessInvitation,
+// FlowAccessLink or FlowAccessOrganization. FlowAccessInvitation disables
+// joining the flow without an invitation.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) SetAccessMode(ctx context.Context, org, flowName, mode string) (*Flow, *http.Response, error) {
+	return s.Update(ctx, org, flowName, &Flow{AccessMode: &mode})
+}
+
+// GetDescription returns the description of a flow, "" if it has none.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) GetDescription(ctx context.Context, org, flowName string) (string, *http.Response, error) {
+	flow, resp, err := s.Get(ctx, org, flowName)
+	if err != nil {
+		return "", resp, err
+	}
+	if flow.Description == nil {
+		return "", resp, nil
+	}
+	return *flow.Description, resp, nil
+}
+
+// SetDescription replaces the description of a flow, leaving its other
+// settings as they are.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) SetDescription(ctx context.Context, org, flowName, description string) (*Flow, *http.Response, error) {
+	return s.Update(ctx, org, flowName, &Flow{Description: &description})
+}
+
+// SyncDescription sets the description of a flow to description unless it
+// already is, for bots keeping it in sync with an external source of truth,
+// such as an on-call rotation, without updating the flow on every run. It
+// reports whether the description was updated.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) SyncDescription(ctx context.Context, org, flowName, description string) (bool, *http.Response, error) {
+	current, resp, err := s.GetDescription(ctx, org, flowName)
+	if err != nil || current == description {
+		return false, resp, err
+	}
+	if _, resp, err = s.SetDescription(ctx, org, flowName, description); err != nil {
+		return false, resp, err
+	}
+	return true, resp, nil
+}
+
+// FlowsCloseOptions specifies the optional parameters to the
+// FlowsService.Close method.
+type FlowsCloseOptions struct {
+	// Announce posts a last message in the flow before closing it.
+	Announce bool
+
+	// Message is the announcement. Defaults to a notice that the flow is
+	// archived, pointing to MovedTo if set.
+	Message string
+
+	// MovedTo names where the conversation continues, such as a flow.
+	MovedTo string
+}
+
+// Close closes a flow, after announcing it in the flow if opt asks to. If
+// the flow can't be closed, the announcement is deleted again, so that the
+// flow is left as it was.
+//
+// Flowdock API docs: https://www.flowdock.com/api/flows
+func (s *FlowsService) Close(ctx context.Context, org, flowName string, opt *FlowsCloseOptions) (*Flow, *http.Response, error) {
+	var announcement *Message
+	if opt != nil && opt.Announce {
+		m, resp, err := s.client.Messages.createInFlow(ctx, org, flowName, &MessagesCreateOptions{
+			Event:   "message",
+			Content: opt.announcement(),
+		})
+		if err != nil {
+			return nil, resp, err
+		}
+		announcement = m
+	}
+
+	closed := false
+	flow, resp, err := s.Update(ctx, org, flowName, &Flow{Open: &closed})
+	if err != nil && announcement != nil && announcement.ID != nil {
+		if _, delErr := s.client.Messages.Delete(ctx, org, flowName, *announcement.ID); delErr != nil {
+			err = fmt.Errorf("%v; the announcement was left in the flow: %v", err, delErr)
+		}
+	}
+	return flow, resp, err
+}
+
+func (opt *FlowsCloseOptions) announcement() string {
+	switch {
+	case opt.Message != "":
+		return opt.Message
+	case opt.MovedTo != "":
+		return fmt.Sprintf("This flow is being archived, please go to %s.", opt.MovedTo)
+	}
+	return "This flow is being archived."
+}
@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,12 +19,13 @@ func TestFlowsService_List(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `[{"id":"1"}, {"id":"2"}]`)
 	})
 
-	flows, _, err := client.Flows.List(false, nil)
+	flows, _, err := client.Flows.List(ctx, false, nil)
 	if err != nil {
 		t.Errorf("Flows.List returned error: %v", err)
 	}
@@ -38,13 +40,15 @@ func TestFlowsService_List_all(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/all", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"users": "1"})
 		fmt.Fprint(w, `[{"id":"1"}, {"id":"2"}]`)
 	})
 
 	opt := FlowsListOptions{User: true}
-	flows, _, err := client.Flows.List(true, &opt)
+	flows, _, err := client.Flows.List(ctx, true, &opt)
 	if err != nil {
 		t.Errorf("Flows.List returned error: %v", err)
 	}
@@ -56,9 +60,10 @@ func TestFlowsService_List_all(t *testing.T) {
 }
 
 func TestFlowsService_List_invalidOpt(t *testing.T) {
+	ctx := context.Background()
 	opt := new(FlowsListOptions)
 
-	_, _, err := client.Flows.List(true, opt)
+	_, _, err := client.Flows.List(ctx, true, opt)
 	if err == nil {
 		t.Errorf("Flows.List expected an error")
 	}
@@ -68,12 +73,13 @@ func TestFlowsService_Get(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/orgname/flowname", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `{"id":"1"}`)
 	})
 
-	flow, _, err := client.Flows.Get("orgname", "flowname")
+	flow, _, err := client.Flows.Get(ctx, "orgname", "flowname")
 	if err != nil {
 		t.Errorf("Flows.Get returned error: %v", err)
 	}
@@ -88,13 +94,14 @@ func TestFlowsService_GetByID(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/find", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		testFormValues(t, r, values{"id": "orgname:flowname"})
 		fmt.Fprint(w, `{"id":"1"}`)
 	})
 
-	flow, _, err := client.Flows.GetByID("orgname:flowname")
+	flow, _, err := client.Flows.GetByID(ctx, "orgname:flowname")
 	if err != nil {
 		t.Errorf("Flows.Get returned error: %v", err)
 	}
@@ -109,6 +116,7 @@ func TestFlowsService_Create(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/org", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
 		testFormValues(t, r, values{"name": "flow"})
@@ -116,7 +124,7 @@ func TestFlowsService_Create(t *testing.T) {
 	})
 
 	opt := FlowsCreateOptions{Name: "flow"}
-	flow, _, err := client.Flows.Create("org", &opt)
+	flow, _, err := client.Flows.Create(ctx, "org", &opt)
 	if err != nil {
 		t.Errorf("Flows.Create returned error: %v", err)
 	}
@@ -131,6 +139,7 @@ func TestFlowsService_Update(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	truth := true
 	input := &Flow{Open: &truth}
 
@@ -145,7 +154,7 @@ func TestFlowsService_Update(t *testing.T) {
 		fmt.Fprint(w, `{"id":"org:flow"}`)
 	})
 
-	flow, _, err := client.Flows.Update("org", "flow", input)
+	flow, _, err := client.Flows.Update(ctx, "org", "flow", input)
 	if err != nil {
 		t.Errorf("Flows.Update returned error: %v", err)
 	}
@@ -155,3 +164,134 @@ func TestFlowsService_Update(t *testing.T) {
 		t.Errorf("Flows.Update returned %+v, want %+v", flow, want)
 	}
 }
+
+func TestFlowsService_updateHelpers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var got []map[string]interface{}
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		v := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&v)
+		got = append(got, v)
+		fmt.Fprint(w, `{"id":"org:flow"}`)
+	})
+
+	client.Flows.Rename(ctx, "org", "flow", "New name")
+	client.Flows.Reopen(ctx, "org", "flow")
+	client.Flows.SetAccessMode(ctx, "org", "flow", FlowAccessInvitation)
+
+	want := []map[string]interface{}{
+		{"name": "New name"},
+		{"open": true},
+		{"access_mode": "invitation"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Request bodies = %v, want %v", got, want)
+	}
+}
+
+func TestFlowsService_SyncDescription(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	description := "On call: alice"
+	var puts int
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			puts++
+			v := new(Flow)
+			json.NewDecoder(r.Body).Decode(v)
+			want := &Flow{Description: &description}
+			if !reflect.DeepEqual(v, want) {
+				t.Errorf("Request body = %+v, want %+v", v, want)
+			}
+			return
+		}
+		testMethod(t, r, "GET")
+		fmt.Fprintf(w, `{"id":"org:flow","description":%q}`, "On call: alice")
+	})
+
+	got, _, err := client.Flows.GetDescription(ctx, "org", "flow")
+	if err != nil || got != description {
+		t.Errorf("Flows.GetDescription returned %q, %v, want %q", got, err, description)
+	}
+
+	changed, _, err := client.Flows.SyncDescription(ctx, "org", "flow", description)
+	if err != nil || changed || puts != 0 {
+		t.Errorf("Flows.SyncDescription returned %v, %v after %d updates, want no update", changed, err, puts)
+	}
+
+	description = "On call: bob"
+	changed, _, err = client.Flows.SyncDescription(ctx, "org", "flow", description)
+	if err != nil || !changed || puts != 1 {
+		t.Errorf("Flows.SyncDescription returned %v, %v after %d updates, want one update", changed, err, puts)
+	}
+}
+
+func TestFlowsService_Close_announce(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var calls []string
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{
+			"event":   "message",
+			"content": "This flow is being archived, please go to org/new.",
+		})
+		calls = append(calls, "announce")
+		fmt.Fprint(w, `{"id":5}`)
+	})
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		v := new(Flow)
+		json.NewDecoder(r.Body).Decode(v)
+		if v.Open == nil || *v.Open {
+			t.Errorf("Request body = %+v, want open false", v)
+		}
+		calls = append(calls, "close")
+		fmt.Fprint(w, `{"id":"org:flow","open":false}`)
+	})
+
+	flow, _, err := client.Flows.Close(ctx, "org", "flow", &FlowsCloseOptions{Announce: true, MovedTo: "org/new"})
+	if err != nil {
+		t.Fatalf("Flows.Close returned error: %v", err)
+	}
+	if *flow.Open {
+		t.Errorf("Flows.Close returned %+v, want a closed flow", flow)
+	}
+	if want := []string{"announce", "close"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Flows.Close made calls %v, want %v", calls, want)
+	}
+}
+
+func TestFlowsService_Close_rollback(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	deleted := false
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":5}`)
+	})
+	mux.HandleFunc("/flows/org/flow/messages/5", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		deleted = true
+	})
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", 403)
+	})
+
+	_, _, err := client.Flows.Close(ctx, "org", "flow", &FlowsCloseOptions{Announce: true})
+	if err == nil {
+		t.Errorf("Flows.Close returned no error")
+	}
+	if !deleted {
+		t.Errorf("Flows.Close did not delete the announcement")
+	}
+}
@@ -0,0 +1,110 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// LostFields compares the JSON data of an API response with v, the value
+// it was decoded into, and returns the paths of the fields of data which
+// encoding v doesn't give back, such as "users[0].website". Fields that are
+// null or hold a zero value may be dropped by omitempty and are not
+// reported.
+//
+// Together with captured API responses, it guards the models against
+// fields they silently drop; the tests of this package check the captures
+// of testdata/golden this way, and the ones of your own can be checked
+// too.
+func LostFields(data []byte, v interface{}) ([]string, error) {
+	var want interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var got interface{}
+	if err := json.Unmarshal(encoded, &got); err != nil {
+		return nil, err
+	}
+
+	var lost []string
+	diffJSON("", want, got, &lost)
+	return lost, nil
+}
+
+// diffJSON appends to lost the paths under path of the values of want which
+// got doesn't hold.
+func diffJSON(path string, want, got interface{}, lost *[]string) {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			*lost = append(*lost, pathOrRoot(path))
+			return
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub := key
+			if path != "" {
+				sub = path + "." + key
+			}
+			if value, ok := g[key]; ok {
+				diffJSON(sub, w[key], value, lost)
+			} else if !isZeroJSON(w[key]) {
+				*lost = append(*lost, sub)
+			}
+		}
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok && got == nil && len(w) == 0 {
+			return // a nil slice
+		}
+		if !ok || len(g) != len(w) {
+			*lost = append(*lost, pathOrRoot(path))
+			return
+		}
+		for i := range w {
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], lost)
+		}
+	default:
+		if !reflect.DeepEqual(want, got) && !(isZeroJSON(want) && got == nil) {
+			*lost = append(*lost, pathOrRoot(path))
+		}
+	}
+}
+
+// isZeroJSON reports whether the decoded JSON value v is null or a zero
+// value.
+func isZeroJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
@@ -0,0 +1,139 @@
+package flowdock
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// goldenEndpoints are the endpoints whose captured responses, in
+// testdata/golden, must decode without losing fields.
+var goldenEndpoints = []struct {
+	file string
+	path string
+	call func() (interface{}, error)
+}{
+	{"flows.json", "/flows", func() (interface{}, error) {
+		v, _, err := client.Flows.List(context.Background(), false, nil)
+		return v, err
+	}},
+	{"flow.json", "/flows/example/main", func() (interface{}, error) {
+		v, _, err := client.Flows.Get(context.Background(), "example", "main")
+		return v, err
+	}},
+	{"flow.json", "/flows/find", func() (interface{}, error) {
+		v, _, err := client.Flows.GetByID(context.Background(), "a1b2c3d4e5f6a7b8c9d0")
+		return v, err
+	}},
+	{"messages.json", "/flows/example/main/messages", func() (interface{}, error) {
+		v, _, err := client.Messages.List(context.Background(), "example", "main", nil)
+		return v, err
+	}},
+	{"message.json", "/flows/example/main/messages/3816534", func() (interface{}, error) {
+		v, _, err := client.Messages.Get(context.Background(), "example", "main", 3816534)
+		return v, err
+	}},
+	{"comment.json", "/comments", func() (interface{}, error) {
+		v, _, err := client.Messages.CreateComment(context.Background(), &MessagesCreateOptions{MessageID: 3816534})
+		return v, err
+	}},
+	{"users.json", "/users", func() (interface{}, error) {
+		v, _, err := client.Users.List(context.Background(), nil)
+		return v, err
+	}},
+	{"flow_users.json", "/users/example/main/users", func() (interface{}, error) {
+		v, _, err := client.Users.ListByFlow(context.Background(), "example", "main", nil)
+		return v, err
+	}},
+	{"user.json", "/users/9", func() (interface{}, error) {
+		v, _, err := client.Users.Get(context.Background(), 9)
+		return v, err
+	}},
+	{"organizations.json", "/organizations", func() (interface{}, error) {
+		v, _, err := client.Organizations.All(context.Background())
+		return v, err
+	}},
+	{"organization.json", "/organizations/example", func() (interface{}, error) {
+		v, _, err := client.Organizations.GetByParameterizedName(context.Background(), "example")
+		return v, err
+	}},
+	{"organization.json", "/organizations/find", func() (interface{}, error) {
+		v, _, err := client.Organizations.GetByID(context.Background(), 54321)
+		return v, err
+	}},
+}
+
+func readGolden(t *testing.T, file string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "golden", file))
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	return data
+}
+
+func TestGolden_endpoints(t *testing.T) {
+	for _, e := range goldenEndpoints {
+		setup()
+		data := readGolden(t, e.file)
+		mux.HandleFunc(e.path, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(data)
+		})
+
+		v, err := e.call()
+		teardown()
+		if err != nil {
+			t.Errorf("%s: call returned error: %v", e.path, err)
+			continue
+		}
+		lost, err := LostFields(data, v)
+		if err != nil || len(lost) > 0 {
+			t.Errorf("%s: decoding %s lost fields %v (error %v)", e.path, e.file, lost, err)
+		}
+	}
+}
+
+func TestGolden_messageContent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	data := readGolden(t, "messages.json")
+	mux.HandleFunc("/flows/example/main/messages", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+
+	msgs, _, err := client.Messages.List(ctx, "example", "main", nil)
+	if err != nil {
+		t.Fatalf("Messages.List returned error: %v", err)
+	}
+	for _, m := range msgs {
+		content := m.Content()
+		if _, ok := content.(*JsonContent); ok {
+			t.Errorf("message %d: Content has no type for event %q", *m.ID, *m.Event)
+			continue
+		}
+		lost, err := LostFields(*m.RawContent, content)
+		if err != nil || len(lost) > 0 {
+			t.Errorf("message %d: decoding %T lost fields %v (error %v)", *m.ID, content, lost, err)
+		}
+	}
+}
+
+func TestLostFields(t *testing.T) {
+	type user struct {
+		Nick *string `json:"nick,omitempty"`
+	}
+	data := []byte(`{"nick":"ollie","website":"https://example.com","status":null,"admin":false,"tags":[]}`)
+
+	v := new(user)
+	lost, err := LostFields(data, v)
+	if err != nil {
+		t.Fatalf("LostFields returned error: %v", err)
+	}
+	if want := []string{"nick", "website"}; !reflect.DeepEqual(lost, want) {
+		t.Errorf("LostFields returned %v, want %v", lost, want)
+	}
+}
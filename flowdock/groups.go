@@ -0,0 +1,217 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// groupNameRegexp matches the names of groups, mentioned as "@name".
+var groupNameRegexp = regexp.MustCompile(`^[\w.-]*\w$`)
+
+// Groups maps the names of user groups, such as "backend", to the IDs of
+// their members, in a Store. Flowdock has no groups of its own: Expand
+// replaces the mentions of a group, such as "@backend", with mentions of
+// its members before a message is sent. Names ignore case and a leading
+// "@". Groups is safe for concurrent use.
+type Groups struct {
+	store Store
+	mu    sync.Mutex // serializes the updates of members
+}
+
+// NewGroups returns the Groups kept in store. A nil store keeps them in
+// memory; use Load to fill it from a configuration file.
+func NewGroups(store Store) *Groups {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Groups{store: store}
+}
+
+// Set sets the members of the group name, replacing its previous ones.
+func (g *Groups) Set(name string, userIDs ...int) error {
+	key, err := groupKey(name)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.set(key, userIDs)
+}
+
+// Add adds members to the group name, creating it if needed.
+func (g *Groups) Add(name string, userIDs ...int) error {
+	return g.update(name, func(members map[int]bool) {
+		for _, id := range userIDs {
+			members[id] = true
+		}
+	})
+}
+
+// Remove removes members from the group name.
+func (g *Groups) Remove(name string, userIDs ...int) error {
+	return g.update(name, func(members map[int]bool) {
+		for _, id := range userIDs {
+			delete(members, id)
+		}
+	})
+}
+
+// Delete deletes the group name.
+func (g *Groups) Delete(name string) error {
+	key, err := groupKey(name)
+	if err != nil {
+		return err
+	}
+	return g.store.Delete(key)
+}
+
+// Members returns the sorted IDs of the members of the group name, and
+// whether the group exists.
+func (g *Groups) Members(name string) ([]int, bool, error) {
+	key, err := groupKey(name)
+	if err != nil {
+		return nil, false, err
+	}
+	return g.members(key)
+}
+
+// Names returns the sorted names of the groups, in lower case.
+func (g *Groups) Names() ([]string, error) {
+	keys, err := g.store.Keys("groups/")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = strings.TrimPrefix(key, "groups/")
+	}
+	return names, nil
+}
+
+// Load sets the groups of the JSON object read from r, mapping the names
+// of groups to the IDs of their members, such as:
+//
+//	{"backend": [1, 2], "oncall": [3]}
+//
+// Groups missing from the object are kept.
+func (g *Groups) Load(r io.Reader) error {
+	var config map[string][]int
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return fmt.Errorf("flowdock: groups: %v", err)
+	}
+	for name, members := range config {
+		if err := g.Set(name, members...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expand replaces the mentions of groups in content with mentions of their
+// members among users, the users of the flow the content is sent to, such
+// as "@backend" with "@alice @bob". Members already mentioned, or missing
+// from users, are skipped. Mentions of users, and of unknown groups, are
+// left as is.
+func (g *Groups) Expand(content string, users []User) (string, error) {
+	nicks := make(map[int]string)
+	for _, u := range users {
+		if u.ID != nil && u.Nick != nil {
+			nicks[*u.ID] = *u.Nick
+		}
+	}
+	mentioned := make(map[string]bool)
+	for _, m := range mentionRegexp.FindAllStringSubmatch(content, -1) {
+		mentioned[strings.ToLower(m[1])] = true
+	}
+
+	var err error
+	expanded := mentionRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		i := strings.Index(match, "@")
+		name := match[i+1:]
+		if _, ok := userByNick(users, name); ok || err != nil {
+			return match
+		}
+		key, _ := groupKey(name)
+		members, ok, e := g.members(key)
+		if e != nil || !ok {
+			err = e
+			return match
+		}
+
+		var mentions []string
+		for _, id := range members {
+			nick, ok := nicks[id]
+			if !ok || mentioned[strings.ToLower(nick)] {
+				continue
+			}
+			mentioned[strings.ToLower(nick)] = true
+			mentions = append(mentions, "@"+nick)
+		}
+		return match[:i] + strings.Join(mentions, " ")
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+func (g *Groups) update(name string, fn func(map[int]bool)) error {
+	key, err := groupKey(name)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ids, _, err := g.members(key)
+	if err != nil {
+		return err
+	}
+	members := make(map[int]bool)
+	for _, id := range ids {
+		members[id] = true
+	}
+	fn(members)
+
+	ids = ids[:0]
+	for id := range members {
+		ids = append(ids, id)
+	}
+	return g.set(key, ids)
+}
+
+func (g *Groups) members(key string) ([]int, bool, error) {
+	data, ok, err := g.store.Get(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var ids []int
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, false, fmt.Errorf("flowdock: %s: %v", key, err)
+	}
+	return ids, true, nil
+}
+
+func (g *Groups) set(key string, userIDs []int) error {
+	ids := append([]int{}, userIDs...)
+	sort.Ints(ids)
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return g.store.Set(key, data)
+}
+
+// groupKey returns the key of the group name in the Store.
+func groupKey(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(name, "@"))
+	if !groupNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("flowdock: invalid group name %q", name)
+	}
+	return "groups/" + name, nil
+}
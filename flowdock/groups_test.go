@@ -0,0 +1,57 @@
+package flowdock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	g := NewGroups(nil)
+	if err := g.Load(strings.NewReader(`{"Backend": [2, 1], "oncall": [3]}`)); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if err := g.Add("@backend", 4, 1); err != nil {
+		t.Fatalf("Add returned error: %v", err)
+	}
+	if err := g.Remove("oncall", 3); err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+
+	if members, ok, err := g.Members("BACKEND"); err != nil || !ok || !reflect.DeepEqual(members, []int{1, 2, 4}) {
+		t.Errorf("Members returned %v, %v, %v, want [1 2 4]", members, ok, err)
+	}
+	if members, ok, _ := g.Members("oncall"); !ok || len(members) != 0 {
+		t.Errorf("Members returned %v, %v, want an empty group", members, ok)
+	}
+	if names, _ := g.Names(); !reflect.DeepEqual(names, []string{"backend", "oncall"}) {
+		t.Errorf("Names returned %v", names)
+	}
+	if err := g.Delete("oncall"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := g.Members("oncall"); ok {
+		t.Errorf("Members returned a deleted group")
+	}
+	if err := g.Set("not a name", 1); err == nil {
+		t.Errorf("Set returned no error for an invalid name")
+	}
+}
+
+func TestGroups_Expand(t *testing.T) {
+	g := NewGroups(nil)
+	g.Set("backend", 1, 2, 3)
+	g.Set("ops", 4)
+
+	user := func(id int, nick string) User { return User{ID: &id, Nick: &nick} }
+	users := []User{user(1, "alice"), user(2, "bob"), user(4, "ops"), user(5, "carol")}
+
+	got, err := g.Expand("@Backend deploy is broken, cc @bob @ops @nobody", users)
+	if err != nil {
+		t.Fatalf("Expand returned error: %v", err)
+	}
+	// bob is mentioned already, 3 is not in the flow, and ops is a user
+	if want := "@alice deploy is broken, cc @bob @ops @nobody"; got != want {
+		t.Errorf("Expand returned %q, want %q", got, want)
+	}
+}
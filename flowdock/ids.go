@@ -0,0 +1,126 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidFlowRef is returned by the methods taking a FlowRef which is
+// missing its organization or flow name, or whose names contain a slash.
+var ErrInvalidFlowRef = errors.New("flowdock: invalid flow reference")
+
+// The typed identifiers below are accepted by the Ref variants of the
+// service methods, such as MessagesService.GetRef. Unlike the positional
+// string and int parameters of the other methods, they can't be swapped
+// without a compile error, and malformed ones are rejected before any
+// request is sent instead of failing with a 404.
+type (
+	// OrgID is the parameterized name of an organization, as found in
+	// its URLs.
+	OrgID string
+
+	// MessageID is the ID of a message in its flow.
+	MessageID int
+
+	// UserID is the ID of a user.
+	UserID int
+)
+
+// FlowRef designates a flow by the parameterized names of its organization
+// and of itself.
+type FlowRef struct {
+	Org  OrgID
+	Flow string
+}
+
+// Flow returns the reference to the flow named flow in the organization.
+func (o OrgID) Flow(flow string) FlowRef {
+	return FlowRef{Org: o, Flow: flow}
+}
+
+// ParseFlowRef parses a flow reference written "org/flow".
+func ParseFlowRef(s string) (FlowRef, error) {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return FlowRef{}, ErrInvalidFlowRef
+	}
+	ref := FlowRef{Org: OrgID(s[:i]), Flow: s[i+1:]}
+	return ref, ref.validate()
+}
+
+// String returns the reference written "org/flow".
+func (r FlowRef) String() string {
+	return string(r.Org) + "/" + r.Flow
+}
+
+// validate returns ErrInvalidFlowRef if r can't designate a flow.
+func (r FlowRef) validate() error {
+	if r.Org == "" || r.Flow == "" ||
+		strings.Contains(string(r.Org), "/") || strings.Contains(r.Flow, "/") {
+		return ErrInvalidFlowRef
+	}
+	return nil
+}
+
+// GetRef is Get for the flow ref.
+func (s *FlowsService) GetRef(ctx context.Context, ref FlowRef) (*Flow, *http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, nil, err
+	}
+	return s.Get(ctx, string(ref.Org), ref.Flow)
+}
+
+// ListRef is List for the flow ref.
+func (s *MessagesService) ListRef(ctx context.Context, ref FlowRef, opt *MessagesListOptions) ([]Message, *http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, nil, err
+	}
+	return s.List(ctx, string(ref.Org), ref.Flow, opt)
+}
+
+// GetRef is Get for the message id of the flow ref.
+func (s *MessagesService) GetRef(ctx context.Context, ref FlowRef, id MessageID) (*Message, *http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, nil, err
+	}
+	return s.Get(ctx, string(ref.Org), ref.Flow, int(id))
+}
+
+// EditRef is Edit for the message id of the flow ref.
+func (s *MessagesService) EditRef(ctx context.Context, ref FlowRef, id MessageID, opt *MessagesEditOptions) (*http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, err
+	}
+	return s.Edit(ctx, string(ref.Org), ref.Flow, int(id), opt)
+}
+
+// DeleteRef is Delete for the message id of the flow ref.
+func (s *MessagesService) DeleteRef(ctx context.Context, ref FlowRef, id MessageID) (*http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, err
+	}
+	return s.Delete(ctx, string(ref.Org), ref.Flow, int(id))
+}
+
+// StreamRef is Stream for the flow ref.
+func (s *MessagesService) StreamRef(ctx context.Context, token string, ref FlowRef) (<-chan Message, *Stream, error) {
+	if err := ref.validate(); err != nil {
+		return nil, nil, err
+	}
+	return s.Stream(ctx, token, string(ref.Org), ref.Flow)
+}
+
+// ListRef is ListByFlow for the flow ref.
+func (s *UsersService) ListRef(ctx context.Context, ref FlowRef, opt *ListOptions) ([]User, *http.Response, error) {
+	if err := ref.validate(); err != nil {
+		return nil, nil, err
+	}
+	return s.ListByFlow(ctx, string(ref.Org), ref.Flow, opt)
+}
+
+// GetRef is Get for the user id.
+func (s *UsersService) GetRef(ctx context.Context, id UserID) (*User, *http.Response, error) {
+	return s.Get(ctx, int(id))
+}
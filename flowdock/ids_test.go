@@ -0,0 +1,73 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestParseFlowRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want FlowRef
+		err  error
+	}{
+		{"org/flow", OrgID("org").Flow("flow"), nil},
+		{"org", FlowRef{}, ErrInvalidFlowRef},
+		{"/flow", FlowRef{Flow: "flow"}, ErrInvalidFlowRef},
+		{"org/flow/extra", FlowRef{Org: "org", Flow: "flow/extra"}, ErrInvalidFlowRef},
+	}
+	for _, tt := range tests {
+		got, err := ParseFlowRef(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("ParseFlowRef(%q) returned %+v, %v, want %+v, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+	if got := OrgID("org").Flow("flow").String(); got != "org/flow" {
+		t.Errorf("FlowRef.String returned %q, want %q", got, "org/flow")
+	}
+}
+
+func TestMessagesService_GetRef(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/messages/3", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":3}`)
+	})
+
+	m, _, err := client.Messages.GetRef(ctx, OrgID("org").Flow("flow"), MessageID(3))
+	if err != nil {
+		t.Errorf("Messages.GetRef returned error: %v", err)
+	}
+	if m == nil || m.ID == nil || *m.ID != 3 {
+		t.Errorf("Messages.GetRef returned %+v, want message 3", m)
+	}
+}
+
+func TestRefMethods_invalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request sent for an invalid FlowRef: %v", r.URL)
+	})
+
+	ref := FlowRef{Flow: "flow"}
+	if _, _, err := client.Flows.GetRef(ctx, ref); err != ErrInvalidFlowRef {
+		t.Errorf("Flows.GetRef returned %v, want ErrInvalidFlowRef", err)
+	}
+	if _, _, err := client.Messages.ListRef(ctx, ref, nil); err != ErrInvalidFlowRef {
+		t.Errorf("Messages.ListRef returned %v, want ErrInvalidFlowRef", err)
+	}
+	if _, err := client.Messages.DeleteRef(ctx, ref, 1); err != ErrInvalidFlowRef {
+		t.Errorf("Messages.DeleteRef returned %v, want ErrInvalidFlowRef", err)
+	}
+	if _, _, err := client.Users.ListRef(ctx, ref, nil); err != ErrInvalidFlowRef {
+		t.Errorf("Users.ListRef returned %v, want ErrInvalidFlowRef", err)
+	}
+}
@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -16,28 +17,31 @@ type InboxService struct {
 // InboxCreateOptions specifies the optional parameters to the
 // InboxCreate method.
 type InboxCreateOptions struct {
-	Source      string   `url:"source,omitempty"`
-	FromAddress string   `url:"from_address,omitempty"`
-	Subject     string   `url:"subject,omitempty"`
-	Content     string   `url:"content,omitempty"`
-	FromName    string   `url:"from_name,omitempty"`
-	ReplyTo     string   `url:"reply_to,omitempty"`
-	Project     string   `url:"project,omitempty"`
-	Tags        []string `url:"tags,comma,omitempty"`
-	Link        string   `url:"link,omitempty"`
+	Source      string `url:"source,omitempty"`
+	FromAddress string `url:"from_address,omitempty"`
+	Subject     string `url:"subject,omitempty"`
+	Content     string `url:"content,omitempty"`
+	FromName    string `url:"from_name,omitempty"`
+	ReplyTo     string `url:"reply_to,omitempty"`
+	Project     string `url:"project,omitempty"`
+	Tags        Tags   `url:"tags,omitempty"`
+	Link        string `url:"link,omitempty"`
 }
 
 // Create an Inbox mail message for the specified flow api token
 //
 // Flowdock API docs: https://www.flowdock.com/api/team-inbox
-func (s *InboxService) Create(flowApiToken string, opt *InboxCreateOptions) (*http.Response, error) {
+func (s *InboxService) Create(ctx context.Context, flowApiToken string, opt *InboxCreateOptions) (*http.Response, error) {
 	u := fmt.Sprintf("v1/messages/team_inbox/%v", flowApiToken)
 
-	u, err := addOptions(u, opt)
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, err
+	}
 	req, err := s.client.NewRequest("POST", u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.client.Do(req, nil)
+	return s.client.Do(ctx, req, nil)
 }
@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
@@ -10,6 +11,7 @@ func TestInboxService_Create(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/v1/messages/team_inbox/xxx", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
 		testFormValues(t, r, values{"subject": "a subject",
@@ -22,7 +24,7 @@ func TestInboxService_Create(t *testing.T) {
 		Subject: "a subject",
 		Content: "Howdy-Doo @Jackie #awesome",
 	}
-	_, err := client.Inbox.Create("xxx", &opt)
+	_, err := client.Inbox.Create(ctx, "xxx", &opt)
 	if err != nil {
 		t.Errorf("Messages.Create returned error: %v", err)
 	}
@@ -0,0 +1,303 @@
+package flowdock
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// IntegrationsService handles communication with the integration related
+// methods of the Flowdock API. Integrations post activities and discussions
+// into threads of a flow, authenticated by the flow_token of a source.
+//
+// Flowdock API docs: https://www.flowdock.com/api/integration-getting-started
+type IntegrationsService struct {
+	client *Client
+}
+
+// Author is who an integration message is displayed as posted by.
+type Author struct {
+	Name   *string `json:"name,omitempty"`
+	Avatar *string `json:"avatar,omitempty"`
+	Email  *string `json:"email,omitempty"`
+}
+
+// ThreadField is a label/value pair displayed in a thread's details.
+type ThreadField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// ThreadStatus is the colored status label of a thread.
+type ThreadStatus struct {
+	Color string `json:"color"`
+	Value string `json:"value"`
+}
+
+// ThreadAction is a link or a button displayed in a thread's details.
+type ThreadAction struct {
+	Type   string              `json:"@type"` // "ViewAction" or "UpdateAction"
+	Name   string              `json:"name"`
+	Target *ThreadActionTarget `json:"target,omitempty"`
+}
+
+// ThreadActionTarget is the URL a ThreadAction opens, or sends a request to.
+type ThreadActionTarget struct {
+	Type        string `json:"@type"` // "EntryPoint"
+	URLTemplate string `json:"urlTemplate"`
+	HTTPMethod  string `json:"httpMethod,omitempty"` // for an "UpdateAction"
+}
+
+// Thread represents the details of a Flowdock thread. Its ID and times are
+// set by the API, and ignored when posting integration messages.
+type Thread struct {
+	ID          *string         `json:"id,omitempty"`
+	Title       *string         `json:"title,omitempty"`
+	Body        *string         `json:"body,omitempty"`
+	Fields      *[]ThreadField  `json:"fields,omitempty"`
+	ExternalURL *string         `json:"external_url,omitempty"`
+	Status      *ThreadStatus   `json:"status,omitempty"`
+	Actions     *[]ThreadAction `json:"actions,omitempty"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty"`
+}
+
+// IntegrationCreateOptions specifies the parameters to the
+// IntegrationsService.Create method.
+type IntegrationCreateOptions struct {
+	FlowToken        string   `json:"flow_token"`
+	Event            string   `json:"event"` // "activity" or "discussion"
+	Author           *Author  `json:"author,omitempty"`
+	Title            string   `json:"title,omitempty"`
+	Body             string   `json:"body,omitempty"`
+	ExternalThreadID string   `json:"external_thread_id"`
+	Thread           *Thread  `json:"thread,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+// Create an integration message in the thread identified by
+// opt.ExternalThreadID, creating the thread if needed. The attributes of
+// opt.Thread that are set update the thread.
+//
+// Flowdock API docs: https://www.flowdock.com/api/production-integrations
+func (s *IntegrationsService) Create(ctx context.Context, opt *IntegrationCreateOptions) (*http.Response, error) {
+	_, resp, err := s.create(ctx, opt)
+	return resp, err
+}
+
+// create is Create, returning the message created as the API responds it,
+// empty if it doesn't.
+func (s *IntegrationsService) create(ctx context.Context, opt *IntegrationCreateOptions) (*Message, *http.Response, error) {
+	req, err := s.client.NewRequest("POST", "messages", opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := new(Message)
+	resp, err := s.client.Do(ctx, req, m)
+	if err != nil {
+		return nil, resp, err
+	}
+	return m, resp, nil
+}
+
+// DefaultMaxThreads is how many threads a ThreadUpdater keeps the state of
+// by default.
+const DefaultMaxThreads = 1000
+
+// ThreadUpdater sends integration messages while skipping the thread
+// attributes that did not change since the last message of the same thread.
+// Chatty sources, such as CI pipelines reporting every step, then only send
+// the updates that matter.
+type ThreadUpdater struct {
+	service *IntegrationsService
+
+	// Threads, if set, records the Flowdock thread of each external thread
+	// updated, as given by the responses of the API.
+	Threads *ThreadMap
+
+	// MaxThreads is how many threads the state is kept of. The least
+	// recently updated threads beyond are forgotten, and their next update
+	// is sent in full. Defaults to DefaultMaxThreads.
+	MaxThreads int
+
+	mu     sync.Mutex
+	sent   map[string]*list.Element // flow token and external thread ID => *sentThread
+	recent *list.List               // of *sentThread, most recently updated first
+}
+
+// sentThread is the state of a thread as last sent by a ThreadUpdater.
+type sentThread struct {
+	key    string
+	thread *Thread
+}
+
+// NewThreadUpdater returns a ThreadUpdater posting through client.
+func NewThreadUpdater(client *Client) *ThreadUpdater {
+	return &ThreadUpdater{
+		service:    client.Integrations,
+		MaxThreads: DefaultMaxThreads,
+		sent:       make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+// Update posts opt with only the thread attributes that differ from the last
+// update of the thread. When the thread is unchanged and opt carries no
+// activity of its own (no Title or Body), nothing is sent and the returned
+// response is nil.
+func (u *ThreadUpdater) Update(ctx context.Context, opt *IntegrationCreateOptions) (*http.Response, error) {
+	key := opt.FlowToken + "/" + opt.ExternalThreadID
+
+	var prev *Thread
+	u.mu.Lock()
+	if e, ok := u.sent[key]; ok {
+		prev = e.Value.(*sentThread).thread
+		u.recent.MoveToFront(e)
+	}
+	u.mu.Unlock()
+
+	delta := opt.Thread.diff(prev)
+	if delta == nil && opt.Title == "" && opt.Body == "" {
+		return nil, nil
+	}
+
+	minimal := *opt
+	minimal.Thread = delta
+	m, resp, err := u.service.create(ctx, &minimal)
+	if err != nil {
+		return resp, err
+	}
+	if u.Threads != nil && m.ThreadID != nil && *m.ThreadID != "" {
+		if err := u.Threads.Set(opt.FlowToken, opt.ExternalThreadID, *m.ThreadID); err != nil {
+			return resp, err
+		}
+	}
+
+	u.remember(key, prev.merge(delta))
+	return resp, nil
+}
+
+// remember records thread as the state of the thread key, forgetting the
+// least recently updated threads beyond MaxThreads.
+func (u *ThreadUpdater) remember(key string, thread *Thread) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if e, ok := u.sent[key]; ok {
+		e.Value.(*sentThread).thread = thread
+		u.recent.MoveToFront(e)
+		return
+	}
+	u.sent[key] = u.recent.PushFront(&sentThread{key: key, thread: thread})
+
+	max := u.MaxThreads
+	if max <= 0 {
+		max = DefaultMaxThreads
+	}
+	for u.recent.Len() > max {
+		oldest := u.recent.Back()
+		u.recent.Remove(oldest)
+		delete(u.sent, oldest.Value.(*sentThread).key)
+	}
+}
+
+// Forget drops the cached state of a thread so that its next update is sent
+// in full.
+func (u *ThreadUpdater) Forget(flowToken, externalThreadID string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := flowToken + "/" + externalThreadID
+	if e, ok := u.sent[key]; ok {
+		u.recent.Remove(e)
+		delete(u.sent, key)
+	}
+}
+
+// diff returns the attributes of t that are set and differ from prev, or nil
+// when there are none.
+func (t *Thread) diff(prev *Thread) *Thread {
+	if t == nil {
+		return nil
+	}
+	if prev == nil {
+		prev = new(Thread)
+	}
+
+	delta := new(Thread)
+	changed := false
+	if t.Title != nil && !reflect.DeepEqual(t.Title, prev.Title) {
+		delta.Title, changed = t.Title, true
+	}
+	if t.Body != nil && !reflect.DeepEqual(t.Body, prev.Body) {
+		delta.Body, changed = t.Body, true
+	}
+	if t.Fields != nil && !reflect.DeepEqual(t.Fields, prev.Fields) {
+		delta.Fields, changed = t.Fields, true
+	}
+	if t.ExternalURL != nil && !reflect.DeepEqual(t.ExternalURL, prev.ExternalURL) {
+		delta.ExternalURL, changed = t.ExternalURL, true
+	}
+	if t.Status != nil && !reflect.DeepEqual(t.Status, prev.Status) {
+		delta.Status, changed = t.Status, true
+	}
+	if t.Actions != nil && !reflect.DeepEqual(t.Actions, prev.Actions) {
+		delta.Actions, changed = t.Actions, true
+	}
+
+	if !changed {
+		return nil
+	}
+	return delta
+}
+
+// merge returns a copy of t updated with the attributes set in delta. The
+// attributes of delta are copied, so that the caller may change and reuse
+// them.
+func (t *Thread) merge(delta *Thread) *Thread {
+	merged := new(Thread)
+	if t != nil {
+		*merged = *t
+	}
+	if delta == nil {
+		return merged
+	}
+
+	if delta.Title != nil {
+		merged.Title = copyString(delta.Title)
+	}
+	if delta.Body != nil {
+		merged.Body = copyString(delta.Body)
+	}
+	if delta.Fields != nil {
+		fields := append([]ThreadField(nil), *delta.Fields...)
+		merged.Fields = &fields
+	}
+	if delta.ExternalURL != nil {
+		merged.ExternalURL = copyString(delta.ExternalURL)
+	}
+	if delta.Status != nil {
+		status := *delta.Status
+		merged.Status = &status
+	}
+	if delta.Actions != nil {
+		actions := append([]ThreadAction(nil), *delta.Actions...)
+		for i, a := range actions {
+			if a.Target != nil {
+				target := *a.Target
+				actions[i].Target = &target
+			}
+		}
+		merged.Actions = &actions
+	}
+	return merged
+}
+
+// copyString returns a copy of the string p points to.
+func copyString(p *string) *string {
+	v := *p
+	return &v
+}
@@ -0,0 +1,184 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIntegrationsService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	title := "Build #1"
+	input := &IntegrationCreateOptions{
+		FlowToken:        "token",
+		Event:            "activity",
+		Title:            "started",
+		ExternalThreadID: "build-1",
+		Thread:           &Thread{Title: &title},
+	}
+
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testHeader(t, r, "Content-Type", "application/json")
+
+		v := new(IntegrationCreateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+		if !reflect.DeepEqual(v, input) {
+			t.Errorf("Request body = %+v, want %+v", v, input)
+		}
+		fmt.Fprint(w, `{}`)
+	})
+
+	_, err := client.Integrations.Create(ctx, input)
+	if err != nil {
+		t.Errorf("Integrations.Create returned error: %v", err)
+	}
+}
+
+func TestThreadUpdater_Update(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var threads []*Thread
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		v := new(IntegrationCreateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+		threads = append(threads, v.Thread)
+		fmt.Fprint(w, `{}`)
+	})
+
+	title := "Build #1"
+	running := &ThreadStatus{Color: "yellow", Value: "running"}
+	passed := &ThreadStatus{Color: "green", Value: "passed"}
+	u := NewThreadUpdater(client)
+	send := func(status *ThreadStatus) *http.Response {
+		resp, err := u.Update(ctx, &IntegrationCreateOptions{
+			FlowToken:        "token",
+			Event:            "activity",
+			ExternalThreadID: "build-1",
+			Thread:           &Thread{Title: &title, Status: status},
+		})
+		if err != nil {
+			t.Fatalf("ThreadUpdater.Update returned error: %v", err)
+		}
+		return resp
+	}
+
+	send(running)
+	if resp := send(running); resp != nil {
+		t.Errorf("ThreadUpdater.Update sent an unchanged thread")
+	}
+	send(passed)
+
+	want := []*Thread{
+		{Title: &title, Status: running},
+		{Status: passed},
+	}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("ThreadUpdater.Update sent %+v, want %+v", threads, want)
+	}
+
+	u.Forget("token", "build-1")
+	send(passed)
+	if got := threads[len(threads)-1]; !reflect.DeepEqual(got, &Thread{Title: &title, Status: passed}) {
+		t.Errorf("ThreadUpdater.Update sent %+v after Forget, want the full thread", got)
+	}
+}
+
+func TestThreadUpdater_Update_reusedOptions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	sent := 0
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		fmt.Fprint(w, `{}`)
+	})
+
+	u := NewThreadUpdater(client)
+	status := &ThreadStatus{Color: "yellow", Value: "running"}
+	fields := []ThreadField{{Label: "step", Value: "build"}}
+	opt := &IntegrationCreateOptions{
+		FlowToken:        "token",
+		Event:            "activity",
+		ExternalThreadID: "build-1",
+		Thread:           &Thread{Status: status, Fields: &fields},
+	}
+	u.Update(ctx, opt)
+
+	// the caller updates the same options in place
+	status.Color, status.Value = "green", "passed"
+	fields[0].Value = "test"
+	if resp, err := u.Update(ctx, opt); resp == nil || err != nil || sent != 2 {
+		t.Errorf("ThreadUpdater.Update returned %v, %v after %d requests, want the change sent", resp, err, sent)
+	}
+}
+
+func TestThreadUpdater_MaxThreads(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	u := NewThreadUpdater(client)
+	u.MaxThreads = 2
+	title := "Build"
+	update := func(id string) *http.Response {
+		resp, _ := u.Update(ctx, &IntegrationCreateOptions{
+			FlowToken:        "token",
+			Event:            "activity",
+			ExternalThreadID: id,
+			Thread:           &Thread{Title: &title},
+		})
+		return resp
+	}
+	update("1")
+	update("2")
+	update("1")
+	update("3") // forgets 2, the least recently updated
+
+	if len(u.sent) != 2 || u.recent.Len() != 2 {
+		t.Errorf("ThreadUpdater kept %d threads, want 2", len(u.sent))
+	}
+	if update("1") != nil {
+		t.Errorf("ThreadUpdater.Update sent an unchanged thread kept in the state")
+	}
+	if update("2") == nil {
+		t.Errorf("ThreadUpdater.Update did not send a forgotten thread in full")
+	}
+}
+
+func TestThreadUpdater_Update_threads(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"thread_id":"abc"}`)
+	})
+
+	store := NewMemoryStore()
+	u := NewThreadUpdater(client)
+	u.Threads = NewThreadMap(store)
+	opt := &IntegrationCreateOptions{FlowToken: "token", Event: "activity", Title: "fired", ExternalThreadID: "alert-1"}
+	if _, err := u.Update(ctx, opt); err != nil {
+		t.Fatalf("ThreadUpdater.Update returned error: %v", err)
+	}
+
+	// the mapping outlives the updater
+	id, ok, err := NewThreadMap(store).ThreadID("token", "alert-1")
+	if err != nil || !ok || id != "abc" {
+		t.Errorf("ThreadMap.ThreadID returned %q, %v, %v, want abc", id, ok, err)
+	}
+}
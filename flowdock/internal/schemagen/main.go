@@ -0,0 +1,33 @@
+// Command schemagen writes the JSON Schemas of the flowdock models, as
+// returned by flowdock.Schemas, to the directory given as argument. It is
+// run by go generate in the flowdock package.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/wm/go-flowdock/flowdock"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: schemagen dir")
+	}
+	dir := os.Args[1]
+
+	schemas, err := flowdock.Schemas()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for name, data := range schemas {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// InvitationsService handles communication with the invitation related
+// methods of the Flowdock API: inviting users to flows by email, and
+// revoking pending invitations.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+type InvitationsService struct {
+	client *Client
+}
+
+// Invitation represents an invitation to a flow.
+type Invitation struct {
+	ID        *int       `json:"id,omitempty"`
+	State     *string    `json:"state,omitempty"` // "pending" or "accepted"
+	Email     *string    `json:"email,omitempty"`
+	FlowID    *string    `json:"flow,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// InvitationCreateOptions specifies the parameters to the
+// InvitationsService.Create method.
+type InvitationCreateOptions struct {
+	Email   string `json:"email"`
+	Message string `json:"message,omitempty"` // included in the invitation mail
+}
+
+// List the invitations to a flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+func (s *InvitationsService) List(ctx context.Context, org, flow string) ([]Invitation, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/invitations", org, flow)
+
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invitations := new([]Invitation)
+	resp, err := s.client.Do(ctx, req, invitations)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return *invitations, resp, err
+}
+
+// Get an invitation to a flow by its id.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+func (s *InvitationsService) Get(ctx context.Context, org, flow string, id int) (*Invitation, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/invitations/%v", org, flow, id)
+
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invitation := new(Invitation)
+	resp, err := s.client.Do(ctx, req, invitation)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return invitation, resp, err
+}
+
+// Create invites the user with the email of opt to a flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+func (s *InvitationsService) Create(ctx context.Context, org, flow string, opt *InvitationCreateOptions) (*Invitation, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/invitations", org, flow)
+
+	req, err := s.client.NewRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invitation := new(Invitation)
+	resp, err := s.client.Do(ctx, req, invitation)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return invitation, resp, err
+}
+
+// CreateBulk invites the users with the emails of opts to a flow,
+// concurrently on the client's Workers. The returned invitations are in the
+// order of opts, with nil for the invitations that failed. Failures do not
+// stop the other invitations and are reported in a *BulkError, identified
+// by their email.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+func (s *InvitationsService) CreateBulk(ctx context.Context, org, flow string, opts []*InvitationCreateOptions) ([]*Invitation, error) {
+	invitations := make([]*Invitation, len(opts))
+	errs := s.client.Workers.Run(ctx, len(opts), func(ctx context.Context, i int) error {
+		invitation, _, err := s.Create(ctx, org, flow, opts[i])
+		invitations[i] = invitation
+		return err
+	})
+	return invitations, bulkErrors(errs, func(i int) string { return opts[i].Email })
+}
+
+// Delete revokes a pending invitation to a flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/invitations
+func (s *InvitationsService) Delete(ctx context.Context, org, flow string, id int) (*http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/invitations/%v", org, flow, id)
+
+	req, err := s.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(ctx, req, nil)
+}
@@ -0,0 +1,130 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestInvitationsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/invitations", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":1,"state":"pending"},{"id":2,"state":"accepted"}]`)
+	})
+
+	invitations, _, err := client.Invitations.List(ctx, "org", "flow")
+	if err != nil {
+		t.Errorf("Invitations.List returned error: %v", err)
+	}
+	one, two, pending, accepted := 1, 2, "pending", "accepted"
+	want := []Invitation{{ID: &one, State: &pending}, {ID: &two, State: &accepted}}
+	if !reflect.DeepEqual(invitations, want) {
+		t.Errorf("Invitations.List returned %+v, want %+v", invitations, want)
+	}
+}
+
+func TestInvitationsService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/invitations/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":1,"email":"a@example.com","created_at":"2014-02-18T11:44:21Z"}`)
+	})
+
+	invitation, _, err := client.Invitations.Get(ctx, "org", "flow", 1)
+	if err != nil {
+		t.Fatalf("Invitations.Get returned error: %v", err)
+	}
+	if *invitation.Email != "a@example.com" || invitation.CreatedAt.Year() != 2014 {
+		t.Errorf("Invitations.Get returned %+v", invitation)
+	}
+}
+
+func TestInvitationsService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	input := &InvitationCreateOptions{Email: "a@example.com", Message: "join us"}
+	mux.HandleFunc("/flows/org/flow/invitations", func(w http.ResponseWriter, r *http.Request) {
+		v := new(InvitationCreateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		if !reflect.DeepEqual(v, input) {
+			t.Errorf("Request body = %+v, want %+v", v, input)
+		}
+		fmt.Fprint(w, `{"id":1,"state":"pending"}`)
+	})
+
+	invitation, _, err := client.Invitations.Create(ctx, "org", "flow", input)
+	if err != nil {
+		t.Fatalf("Invitations.Create returned error: %v", err)
+	}
+	if *invitation.ID != 1 || *invitation.State != "pending" {
+		t.Errorf("Invitations.Create returned %+v", invitation)
+	}
+}
+
+func TestInvitationsService_CreateBulk(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/invitations", func(w http.ResponseWriter, r *http.Request) {
+		v := new(InvitationCreateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		switch v.Email {
+		case "taken@example.com":
+			http.Error(w, `{"message":"already invited"}`, http.StatusUnprocessableEntity)
+		case "busy@example.com":
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{"id":1,"state":"pending"}`)
+		}
+	})
+
+	invitations, err := client.Invitations.CreateBulk(ctx, "org", "flow", []*InvitationCreateOptions{
+		{Email: "a@example.com"},
+		{Email: "taken@example.com"},
+		{Email: "busy@example.com"},
+	})
+	bulkErr, ok := err.(*BulkError)
+	if !ok {
+		t.Fatalf("Invitations.CreateBulk returned %v, want a *BulkError", err)
+	}
+	if len(invitations) != 3 || invitations[0] == nil || *invitations[0].ID != 1 || invitations[1] != nil || invitations[2] != nil {
+		t.Errorf("Invitations.CreateBulk returned %+v, want only the first invitation", invitations)
+	}
+	if len(bulkErr.Errors) != 2 || bulkErr.Errors[0].ID != "taken@example.com" || bulkErr.Errors[1].ID != "busy@example.com" {
+		t.Errorf("Invitations.CreateBulk returned errors %+v, want errors for the last two emails", bulkErr.Errors)
+	}
+	if want := []int{2}; !reflect.DeepEqual(bulkErr.RetryableIndexes(), want) {
+		t.Errorf("BulkError.RetryableIndexes() = %v, want %v", bulkErr.RetryableIndexes(), want)
+	}
+}
+
+func TestInvitationsService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/invitations/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+	})
+
+	if _, err := client.Invitations.Delete(ctx, "org", "flow", 1); err != nil {
+		t.Errorf("Invitations.Delete returned error: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package flowdock
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// likeTagPrefix starts the tags Flowdock records likes with, followed by
+// the ID of the user who liked the message.
+const likeTagPrefix = ":user:"
+
+// Like likes the message with the given ID on behalf of the user with the
+// ID userID, by adding the tag of the user to the message. Liking a
+// message twice does nothing. The tag is added with AddTags, which is not
+// atomic: tags edited at the same time may be lost.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) Like(ctx context.Context, org, flow string, id, userID int) (*http.Response, error) {
+	return s.AddTags(ctx, org, flow, id, likeTag(userID))
+}
+
+// Unlike removes the like of the user with the ID userID from the message
+// with the given ID, as Like adds it.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) Unlike(ctx context.Context, org, flow string, id, userID int) (*http.Response, error) {
+	return s.RemoveTags(ctx, org, flow, id, likeTag(userID))
+}
+
+func likeTag(userID int) string {
+	return likeTagPrefix + strconv.Itoa(userID)
+}
+
+// Likes returns the IDs of the users who liked m, from its tags.
+func (m *Message) Likes() []int {
+	if m.Tags == nil {
+		return nil
+	}
+	var users []int
+	for _, tag := range *m.Tags {
+		if !strings.HasPrefix(tag, likeTagPrefix) {
+			continue
+		}
+		if id, err := strconv.Atoi(strings.TrimPrefix(tag, likeTagPrefix)); err == nil {
+			users = append(users, id)
+		}
+	}
+	return users
+}
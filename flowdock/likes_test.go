@@ -0,0 +1,68 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMessagesService_Like(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	tags := []string{"deploy"}
+	var edits [][]string
+	mux.HandleFunc("/flows/org/flow/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			data, _ := json.Marshal(tags)
+			fmt.Fprintf(w, `{"id":1,"tags":%s}`, data)
+		case "PUT":
+			var body struct{ Tags []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Tags == nil {
+				t.Errorf("Request body has no tags")
+			}
+			tags = body.Tags
+			edits = append(edits, tags)
+		}
+	})
+
+	like := func(f func(context.Context, string, string, int, int) (*http.Response, error), user int) {
+		if _, err := f(ctx, "org", "flow", 1, user); err != nil {
+			t.Fatalf("returned error: %v", err)
+		}
+	}
+	like(client.Messages.Like, 2)
+	like(client.Messages.Like, 2) // already liked
+	like(client.Messages.Like, 3)
+	like(client.Messages.Unlike, 2)
+	like(client.Messages.Unlike, 4) // not liked
+
+	want := [][]string{
+		{"deploy", ":user:2"},
+		{"deploy", ":user:2", ":user:3"},
+		{"deploy", ":user:3"},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("Like and Unlike edited the tags as %q, want %q", edits, want)
+	}
+
+	// the last tag removed
+	tags = []string{":user:3"}
+	like(client.Messages.Unlike, 3)
+	if len(tags) != 0 {
+		t.Errorf("Unlike left the tags %q, want none", tags)
+	}
+}
+
+func TestMessage_Likes(t *testing.T) {
+	m := &Message{Tags: &[]string{"deploy", ":user:2", ":highlight:2", ":user:x", ":user:3"}}
+	if got, want := m.Likes(), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Likes returned %v, want %v", got, want)
+	}
+}
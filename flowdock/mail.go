@@ -0,0 +1,151 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// MailAddress is a sender or recipient of a mail.
+type MailAddress struct {
+	Address *string `json:"address"`
+	Name    *string `json:"name,omitempty"`
+}
+
+// Return the string version of a MailAddress, "Name <address>"
+func (a MailAddress) String() string {
+	var address string
+	if a.Address != nil {
+		address = *a.Address
+	}
+	if a.Name == nil || *a.Name == "" {
+		return address
+	}
+	return *a.Name + " <" + address + ">"
+}
+
+// MailAttachment is a file attached to a mail. Its Path can be passed to
+// MessagesService.Download.
+type MailAttachment struct {
+	Path        *string `json:"path"`
+	FileName    *string `json:"file_name"`
+	ContentType *string `json:"content_type"`
+	FileSize    *int    `json:"file_size"`
+	Disposition *string `json:"disposition,omitempty"` // "attachment" or "inline"
+}
+
+// MailContent represents a Message's Content when Message.Event is "mail":
+// a mail received by the team inbox, or a message posted to it with
+// InboxService.Create.
+type MailContent struct {
+	Subject     *string          `json:"subject"`
+	Content     *string          `json:"content"`
+	ContentType *string          `json:"content_type,omitempty"` // of Content
+	From        []MailAddress    `json:"from,omitempty"`
+	To          []MailAddress    `json:"to,omitempty"`
+	Cc          []MailAddress    `json:"cc,omitempty"`
+	Bcc         []MailAddress    `json:"bcc,omitempty"`
+	ReplyTo     []MailAddress    `json:"reply_to,omitempty"`
+	Attachments []MailAttachment `json:"attachments,omitempty"`
+
+	// fields of the messages posted with InboxService.Create
+	Source      *string `json:"source,omitempty"`
+	FromAddress *string `json:"from_address,omitempty"`
+	FromName    *string `json:"from_name,omitempty"`
+	Project     *string `json:"project,omitempty"`
+	Link        *string `json:"link,omitempty"`
+}
+
+// Return the string version of a MailContent
+//
+// It returns the *MailContent.Subject
+func (c *MailContent) String() string {
+	if c.Subject == nil {
+		return ""
+	}
+	return *c.Subject
+}
+
+// IsHTML reports whether the Content of the mail is HTML rather than plain
+// text.
+func (c *MailContent) IsHTML() bool {
+	return c.ContentType != nil && strings.HasPrefix(*c.ContentType, "text/html")
+}
+
+// Sender returns the address the mail comes from: its first From address,
+// or the FromAddress and FromName of messages posted to the team inbox.
+func (c *MailContent) Sender() MailAddress {
+	if len(c.From) > 0 {
+		return c.From[0]
+	}
+	return MailAddress{Address: c.FromAddress, Name: c.FromName}
+}
+
+// Mail returns the mail m reports, if m is a "mail" event.
+func (m *Message) Mail() (*MailContent, bool) {
+	if m.Event == nil || *m.Event != "mail" || m.RawContent == nil {
+		return nil, false
+	}
+	c := new(MailContent)
+	if err := json.Unmarshal(*m.RawContent, c); err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// Download writes the file found at path, such as the Path of a
+// MailAttachment or of a FileContent, to w.
+//
+// Flowdock API docs: https://www.flowdock.com/api/files
+func (s *MessagesService) Download(ctx context.Context, path string, w io.Writer) (*http.Response, error) {
+	req, err := s.client.NewRequest("GET", strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "*/*")
+	return s.client.Do(ctx, req, w)
+}
+
+// DownloadFile returns the content of the file found at path, such as the
+// Path of a FileContent, read as it is downloaded. The request is
+// authenticated as the other requests of the client, and redirects to the
+// storage of the file are followed. It fails at once if the API refuses the
+// download; errors of the transfer are returned by the reader. The reader
+// must be closed once done.
+//
+// Flowdock API docs: https://www.flowdock.com/api/files
+func (s *MessagesService) DownloadFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	started := make(chan struct{})
+	var once sync.Once
+	w := writerFunc(func(p []byte) (int, error) {
+		once.Do(func() { close(started) })
+		return pw.Write(p)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Download(ctx, path, w)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pr, nil
+}
+
+// writerFunc is a function implementing io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,110 @@
+package flowdock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestMessage_Mail(t *testing.T) {
+	raw := json.RawMessage(`{
+		"subject": "Quarterly report",
+		"content": "<p>Attached</p>",
+		"content_type": "text/html",
+		"from": [{"address": "ollie@example.com", "name": "Oliver Example"}],
+		"to": [{"address": "main@example.flowdock.com"}],
+		"attachments": [{"path": "/flows/o/f/files/x/report.pdf", "file_name": "report.pdf", "content_type": "application/pdf", "file_size": 3}]
+	}`)
+	event := "mail"
+	m := &Message{Event: &event, RawContent: &raw}
+
+	c, ok := m.Mail()
+	if !ok {
+		t.Fatal("Message.Mail returned false for a mail event")
+	}
+	if got := c.String(); got != "Quarterly report" {
+		t.Errorf("MailContent.String returned %q, want %q", got, "Quarterly report")
+	}
+	if !c.IsHTML() {
+		t.Errorf("MailContent.IsHTML returned false for text/html")
+	}
+	if got, want := c.Sender().String(), "Oliver Example <ollie@example.com>"; got != want {
+		t.Errorf("MailContent.Sender returned %q, want %q", got, want)
+	}
+	if got, want := c.To[0].String(), "main@example.flowdock.com"; got != want {
+		t.Errorf("MailAddress.String returned %q, want %q", got, want)
+	}
+	if len(c.Attachments) != 1 || *c.Attachments[0].FileName != "report.pdf" {
+		t.Errorf("MailContent.Attachments = %+v, want report.pdf", c.Attachments)
+	}
+	if _, ok := m.Content().(*MailContent); !ok {
+		t.Errorf("Message.Content returned %T, want *MailContent", m.Content())
+	}
+
+	event = "message"
+	if _, ok := m.Mail(); ok {
+		t.Errorf("Message.Mail returned true for a chat message")
+	}
+}
+
+func TestMailContent_Sender_inbox(t *testing.T) {
+	address, name := "ci@example.com", "CI"
+	c := &MailContent{FromAddress: &address, FromName: &name}
+	if got, want := c.Sender().String(), "CI <ci@example.com>"; got != want {
+		t.Errorf("MailContent.Sender returned %q, want %q", got, want)
+	}
+}
+
+func TestMessagesService_Download(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/o/f/files/x/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testHeader(t, r, "Accept", "*/*")
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF"))
+	})
+
+	var buf bytes.Buffer
+	_, err := client.Messages.Download(ctx, "/flows/o/f/files/x/report.pdf", &buf)
+	if err != nil {
+		t.Errorf("Messages.Download returned error: %v", err)
+	}
+	if got := buf.String(); got != "%PDF" {
+		t.Errorf("Messages.Download wrote %q, want %q", got, "%PDF")
+	}
+}
+
+func TestMessagesService_DownloadFile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/o/f/files/x/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer token")
+		http.Redirect(w, r, "/storage/report.pdf", http.StatusFound)
+	})
+	mux.HandleFunc("/storage/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("%PDF"))
+	})
+	client.Credentials = CredentialsFunc(func(context.Context) (string, error) { return "token", nil })
+
+	body, err := client.Messages.DownloadFile(ctx, "/flows/o/f/files/x/report.pdf")
+	if err != nil {
+		t.Fatalf("Messages.DownloadFile returned error: %v", err)
+	}
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil || string(data) != "%PDF" {
+		t.Errorf("Messages.DownloadFile read %q, %v, want %q", data, err, "%PDF")
+	}
+
+	if _, err := client.Messages.DownloadFile(ctx, "/flows/o/f/files/missing"); !IsNotFound(err) {
+		t.Errorf("Messages.DownloadFile returned %v, want a 404 error", err)
+	}
+}
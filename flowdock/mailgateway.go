@@ -0,0 +1,143 @@
+package flowdock
+
+import (
+	"context"
+	"html"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// DefaultMailSource is the Source of the team inbox messages a MailGateway
+// posts by default.
+const DefaultMailSource = "email"
+
+// Email is an inbound mail, as parsed by the mail server or library feeding
+// a MailGateway.
+type Email interface {
+	// Header returns the first value of the header named key, or "".
+	Header(key string) string
+
+	// Body returns the body of the mail and its media type, such as
+	// "text/plain" or "text/html".
+	Body() (body, contentType string)
+
+	// Attachments returns the files attached to the mail.
+	Attachments() []EmailAttachment
+}
+
+// EmailAttachment is a file attached to an Email.
+type EmailAttachment struct {
+	FileName    string
+	ContentType string
+	Content     io.Reader
+}
+
+// MailGateway posts inbound mails to the team inbox of a flow, for building
+// custom mail-to-flow gateways. The sender, subject and Reply-To address of
+// each mail are mapped to those of the inbox message, and its attachments
+// are uploaded to the flow as files.
+type MailGateway struct {
+	client *Client
+	token  string
+	flow   FlowRef
+
+	// Source is the source of the inbox messages, DefaultMailSource by
+	// default.
+	Source string
+
+	// Project and Tags, if set, are given to every inbox message.
+	Project string
+	Tags    []string
+}
+
+// NewMailGateway returns a MailGateway posting to the team inbox of the flow
+// whose API token is token. Attachments are uploaded to flow, which the
+// client must be authorized to post to.
+func NewMailGateway(client *Client, token string, flow FlowRef) *MailGateway {
+	return &MailGateway{client: client, token: token, flow: flow, Source: DefaultMailSource}
+}
+
+// Forward posts e to the team inbox, then uploads its attachments, with
+// ctx. It returns the messages of the uploaded attachments; the failures of
+// some of them are reported by a *BulkError once the others are uploaded.
+func (g *MailGateway) Forward(ctx context.Context, e Email) ([]*Message, error) {
+	if _, err := g.client.Inbox.Create(ctx, g.token, g.inboxOptions(e)); err != nil {
+		return nil, err
+	}
+
+	attachments := e.Attachments()
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	if err := g.flow.validate(); err != nil {
+		return nil, err
+	}
+
+	var msgs []*Message
+	berr := new(BulkError)
+	for i, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		m, _, err := g.client.Messages.uploadFile(ctx, string(g.flow.Org), g.flow.Flow, a.FileName, contentType, a.Content)
+		if err != nil {
+			berr.add(i, a.FileName, err)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, berr.err()
+}
+
+// inboxOptions maps e to the options of its team inbox message.
+func (g *MailGateway) inboxOptions(e Email) *InboxCreateOptions {
+	opt := &InboxCreateOptions{
+		Source:  g.Source,
+		Subject: decodeHeader(e.Header("Subject")),
+		Project: g.Project,
+		Tags:    g.Tags,
+	}
+	if opt.Source == "" {
+		opt.Source = DefaultMailSource
+	}
+
+	from := e.Header("From")
+	if addr, err := mail.ParseAddress(from); err == nil {
+		opt.FromAddress, opt.FromName = addr.Address, addr.Name
+	} else {
+		opt.FromAddress = strings.TrimSpace(from)
+	}
+	if addr, err := mail.ParseAddress(e.Header("Reply-To")); err == nil {
+		opt.ReplyTo = addr.Address
+	}
+
+	body, contentType := e.Body()
+	opt.Content = mailHTML(body, contentType)
+	return opt
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header value, which
+// is returned as is if they are malformed.
+func decodeHeader(v string) string {
+	dec := new(mime.WordDecoder)
+	decoded, err := dec.DecodeHeader(v)
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+// mailHTML returns body, of type contentType, as the HTML the team inbox
+// displays: HTML bodies are kept, and plain text ones are escaped with
+// their line breaks preserved.
+func mailHTML(body, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "text/html" {
+		return body
+	}
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	return strings.Replace(html.EscapeString(body), "\n", "<br>\n", -1)
+}
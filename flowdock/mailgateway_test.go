@@ -0,0 +1,138 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testEmail is an Email built from its fields.
+type testEmail struct {
+	headers     map[string]string
+	body        string
+	contentType string
+	attachments []EmailAttachment
+}
+
+func (e *testEmail) Header(key string) string       { return e.headers[key] }
+func (e *testEmail) Body() (string, string)         { return e.body, e.contentType }
+func (e *testEmail) Attachments() []EmailAttachment { return e.attachments }
+
+func TestMailGateway_Forward(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/v1/messages/team_inbox/xxx", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testFormValues(t, r, values{
+			"source":       "email",
+			"from_address": "ollie@example.com",
+			"from_name":    "Ollie Ä",
+			"reply_to":     "support@example.com",
+			"subject":      "Über deploy",
+			"content":      "a &lt; b<br>\nc",
+			"tags":         "mail",
+		})
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		file, header, err := r.FormFile("content")
+		if err != nil {
+			t.Fatalf("Request has no file content: %v", err)
+		}
+		if header.Filename != "log.txt" {
+			t.Errorf("Request filename = %v, want %v", header.Filename, "log.txt")
+		}
+		if ct := header.Header.Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("Request file Content-Type = %v, want text/plain", ct)
+		}
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != "log" {
+			t.Errorf("Request file content = %q, want %q", data, "log")
+		}
+		fmt.Fprint(w, `{"id":2,"event":"file"}`)
+	})
+
+	g := NewMailGateway(client, "xxx", OrgID("org").Flow("flow"))
+	g.Tags = []string{"mail"}
+	msgs, err := g.Forward(ctx, &testEmail{
+		headers: map[string]string{
+			"From":     "=?utf-8?q?Ollie_=C3=84?= <ollie@example.com>",
+			"Reply-To": "Support <support@example.com>",
+			"Subject":  "=?utf-8?q?=C3=9Cber_deploy?=",
+		},
+		body:        "a < b\r\nc",
+		contentType: "text/plain; charset=utf-8",
+		attachments: []EmailAttachment{
+			{FileName: "log.txt", ContentType: "text/plain", Content: strings.NewReader("log")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("MailGateway.Forward returned error: %v", err)
+	}
+	if len(msgs) != 1 || *msgs[0].ID != 2 {
+		t.Errorf("MailGateway.Forward returned %+v, want the message of the attachment", msgs)
+	}
+}
+
+func TestMailGateway_Forward_html(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/v1/messages/team_inbox/xxx", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{
+			"source":       "gateway",
+			"from_address": "not an address",
+			"subject":      "hi",
+			"content":      "<p>a</p>",
+		})
+		fmt.Fprint(w, `{}`)
+	})
+
+	g := NewMailGateway(client, "xxx", FlowRef{})
+	g.Source = "gateway"
+	msgs, err := g.Forward(ctx, &testEmail{
+		headers:     map[string]string{"From": "not an address", "Subject": "hi"},
+		body:        "<p>a</p>",
+		contentType: "text/html",
+	})
+	if err != nil || msgs != nil {
+		t.Errorf("MailGateway.Forward returned %+v, %v, want no messages", msgs, err)
+	}
+}
+
+func TestMailGateway_Forward_attachmentFailure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/v1/messages/team_inbox/xxx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		if _, header, _ := r.FormFile("content"); header.Filename == "bad.bin" {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		fmt.Fprint(w, `{"id":3,"event":"file"}`)
+	})
+
+	g := NewMailGateway(client, "xxx", OrgID("org").Flow("flow"))
+	msgs, err := g.Forward(ctx, &testEmail{attachments: []EmailAttachment{
+		{FileName: "bad.bin", Content: strings.NewReader("x")},
+		{FileName: "good.bin", Content: strings.NewReader("y")},
+	}})
+	berr, ok := err.(*BulkError)
+	if !ok || len(berr.Errors) != 1 || berr.Errors[0].ID != "bad.bin" {
+		t.Fatalf("MailGateway.Forward returned error %v, want a BulkError for bad.bin", err)
+	}
+	if len(msgs) != 1 || *msgs[0].ID != 3 {
+		t.Errorf("MailGateway.Forward returned %+v, want the message of good.bin", msgs)
+	}
+}
@@ -0,0 +1,120 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaintenanceBackoff is how long a request or a Stream refused for
+// maintenance waits before trying again, when the API advertises no retry
+// window.
+const DefaultMaintenanceBackoff = time.Minute
+
+// ErrMaintenance matches, with errors.Is, the *Error of a request refused
+// because the API is under maintenance.
+var ErrMaintenance = errors.New("flowdock: API under maintenance")
+
+// Maintenance is the maintenance announced by a 503 Service Unavailable
+// response, with a JSON body such as {"maintenance": true} or a banner
+// mentioning it.
+type Maintenance struct {
+	Message    string        // of the body, if any
+	RetryAfter time.Duration // the advertised retry window, 0 if none
+}
+
+// IsMaintenance reports whether err is, or wraps, an *Error refusing a
+// request because the API is under maintenance.
+func IsMaintenance(err error) bool {
+	return errors.Is(err, ErrMaintenance)
+}
+
+// MaintenanceOf returns the maintenance err reports, if it is, or wraps,
+// an *Error refusing a request for maintenance.
+func MaintenanceOf(err error) (*Maintenance, bool) {
+	var e *Error
+	if errors.As(err, &e) && e.Maintenance != nil {
+		return e.Maintenance, true
+	}
+	return nil, false
+}
+
+// Is reports whether target is ErrMaintenance, for the errors of requests
+// refused for maintenance.
+func (r *Error) Is(target error) bool {
+	return target == ErrMaintenance && r.Maintenance != nil
+}
+
+// maintenanceBody is the JSON body of a maintenance response.
+type maintenanceBody struct {
+	Maintenance bool   `json:"maintenance"`
+	Error       string `json:"error"`
+	Message     string `json:"message"`
+	RetryAfter  int    `json:"retry_after"` // in seconds
+}
+
+// parseMaintenance sets the Maintenance of r if its response announces
+// one.
+func (r *Error) parseMaintenance() {
+	if r.StatusCode() != http.StatusServiceUnavailable {
+		return
+	}
+	var body maintenanceBody
+	if json.Unmarshal(r.Data, &body) == nil {
+		if !body.Maintenance && body.Error != "maintenance" && !mentionsMaintenance(body.Message) {
+			return
+		}
+	} else if !mentionsMaintenance(string(r.Data)) {
+		return
+	}
+
+	m := &Maintenance{Message: body.Message}
+	if body.RetryAfter > 0 {
+		m.RetryAfter = time.Duration(body.RetryAfter) * time.Second
+	} else {
+		m.RetryAfter = retryAfterHeader(r.Response)
+	}
+	r.Maintenance = m
+}
+
+func mentionsMaintenance(s string) bool {
+	return strings.Contains(strings.ToLower(s), "maintenance")
+}
+
+// retryAfterHeader returns the delay of the Retry-After header of resp, in
+// seconds or as an HTTP date, or 0 if there is none.
+func retryAfterHeader(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	at, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+	now, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		now = time.Now()
+	}
+	if d := at.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// maintenanceBackoff returns how long to wait before trying again after
+// err, if it refused a request for maintenance: the advertised window, or
+// def.
+func maintenanceBackoff(err error, def time.Duration) (time.Duration, bool) {
+	m, ok := MaintenanceOf(err)
+	if !ok {
+		return 0, false
+	}
+	if m.RetryAfter > 0 {
+		return m.RetryAfter, true
+	}
+	return def, true
+}
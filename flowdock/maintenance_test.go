@@ -0,0 +1,85 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckResponse_maintenance(t *testing.T) {
+	tests := []struct {
+		code   int
+		header string
+		body   string
+		want   *Maintenance
+	}{
+		{503, "", `{"maintenance":true,"message":"Upgrading","retry_after":600}`, &Maintenance{Message: "Upgrading", RetryAfter: 10 * time.Minute}},
+		{503, "120", `{"error":"maintenance"}`, &Maintenance{RetryAfter: 2 * time.Minute}},
+		{503, "", `<html><h1>Flowdock is down for maintenance</h1></html>`, &Maintenance{}},
+		{503, "", `{"message":"overloaded"}`, nil},
+		{500, "", `{"maintenance":true}`, nil},
+	}
+	for i, tt := range tests {
+		resp := &http.Response{
+			Request:    &http.Request{},
+			StatusCode: tt.code,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		err := CheckResponse(resp)
+		m, ok := MaintenanceOf(err)
+		if ok != (tt.want != nil) || ok && *m != *tt.want {
+			t.Errorf("#%d: MaintenanceOf returned %+v, %v, want %+v", i, m, ok, tt.want)
+		}
+		if IsMaintenance(err) != ok || errors.Is(err, ErrMaintenance) != ok {
+			t.Errorf("#%d: IsMaintenance returned %v", i, IsMaintenance(err))
+		}
+	}
+}
+
+func TestDo_retryMaintenance(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	client.Retry = &RetryPolicy{MaxAttempts: 2}
+
+	attempts := 0
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"maintenance":true,"retry_after":300}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Messages.createInFlow(context.Background(), "o", "f", &MessagesCreateOptions{Event: "message", Content: "hi"})
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(DefaultMaintenanceBackoff)
+	select {
+	case err := <-done:
+		t.Fatalf("request returned %v before the maintenance window", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(5 * time.Minute)
+	if err := <-done; err != nil || attempts != 2 {
+		t.Errorf("request returned %v after %d attempts, want a retried POST", err, attempts)
+	}
+}
@@ -1,13 +1,28 @@
 package flowdock
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/bernerdschaefer/eventsource"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// MaxCodeMessageLength is the longest code snippet, in bytes, that PostCode
+// sends as a chat message. Longer snippets are uploaded as a file.
+const MaxCodeMessageLength = 8192
+
 // MessagesService handles communication with the messages related methods of
 // the Flowdock API.
 //
@@ -19,84 +34,330 @@ type MessagesService struct {
 // MessagesListOptions specifies the optional parameters to the
 // MessageService.List method.
 type MessagesListOptions struct {
-	Event   string   `url:"event,omitempty"`
-	Limit   int      `url:"limit,omitempty"`
-	SinceID int      `url:"since_id,omitempty"`
-	UntilID int      `url:"until_id,omitempty"`
-	Tags    []string `url:"tags,comma,omitempty"`
-	TagMode string   `url:"tag_mode,omitempty"`
-	Search  string   `url:"search,omitempty"`
+	Event   string `url:"event,omitempty"`
+	Limit   int    `url:"limit,omitempty"`
+	SinceID int    `url:"since_id,omitempty"`
+	UntilID int    `url:"until_id,omitempty"`
+	Tags    Tags   `url:"tags,omitempty"`
+	TagMode string `url:"tag_mode,omitempty"`
+	Search  string `url:"search,omitempty"`
+	Sort    string `url:"sort,omitempty"` // "asc" or "desc", the default
 }
 
-// Stream the messages for the given flow.
+// Stream the messages for the given flow. The token is passed as selected by
+// the Client's StreamAuth. The returned Stream reconnects on its own and must
+// be closed once done, or is closed with ctx. A connection refused with a
+// 401 or 403 ends it, delivering a StreamAuthError on its Errors channel,
+// as does a panic of the code of the Client run on each message, such as
+// its Mute, with a *PanicError unless the Client's RestartOnPanic is set.
+//
+// The message channel belongs to the Stream, which closes it once ended:
+// by Close, by ctx, by a refused connection or by a panic. Readers can range over it,
+// and the Stream stops waiting for them once closed, so a reader may stop
+// reading at any time provided it closes the Stream, or cancels ctx. The
+// Stream's Done and Err tell when and why it ended, and its LastEventID
+// where StreamFrom resumes it.
 //
 // Flowdock API docs: https://flowdock.com/api/streaming and
 // https://www.flowdock.com/api/messages
-func (s *MessagesService) Stream(token, org, flow string) (chan Message, *eventsource.EventSource, error) {
-	retryDuration := 3 * time.Second
+func (s *MessagesService) Stream(ctx context.Context, token, org, flow string) (<-chan Message, *Stream, error) {
+	return s.StreamFrom(ctx, token, org, flow, "")
+}
+
+// StreamFrom streams the messages for the given flow as Stream does,
+// resuming after the event with ID lastEventID, as returned by the
+// LastEventID of a Stream which ended, so that no message is lost in
+// between. An empty lastEventID streams from now on.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamFrom(ctx context.Context, token, org, flow, lastEventID string) (<-chan Message, *Stream, error) {
+	return s.stream(ctx, token, fmt.Sprintf("flows/%v/%v", org, flow), &StreamOptions{LastEventID: lastEventID})
+}
+
+// stream streams the messages of the streaming API URL u with opt.
+func (s *MessagesService) stream(ctx context.Context, token, u string, opt *StreamOptions) (<-chan Message, *Stream, error) {
+	return streamEvents(ctx, s, token, u, opt, func(event *event) (Message, bool) {
+		m := s.streamed(event)
+		if m == nil {
+			return Message{}, false
+		}
+		return *m, true
+	})
+}
 
-	u := fmt.Sprintf("flows/%v/%v?access_token=%v", org, flow, token)
+// streamEvents streams the values decoded from the events of the streaming
+// API URL u with opt. Events for which decode returns false are skipped.
+func streamEvents[T any](ctx context.Context, s *MessagesService, token, u string, opt *StreamOptions, decode func(*event) (T, bool)) (<-chan T, *Stream, error) {
+	if ctx == nil {
+		return nil, nil, errNonNilContext
+	}
 
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
 	req, err := s.client.NewStreamRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
+	s.client.AuthorizeStreamRequest(req, token)
 
-	messageCh := make(chan Message)
-	es := eventsource.New(req, retryDuration)
+	ch := make(chan T)
+	stream := newStream(s.client, req)
+	if opt != nil {
+		stream.lastEventID = opt.LastEventID
+		stream.policy = opt.Reconnect
+	}
+	stream.bind(ctx)
 
 	go func() {
-		defer es.Close()
+		defer close(ch)
+		defer stream.Close()
 		for {
-			event, err := es.Read()
-
+			event, err := stream.read()
+			if err == ErrEventTooLarge {
+				s.client.Logf("skipped Stream event: %v", err)
+				continue
+			}
 			if err != nil {
-				s.client.Log.Printf("failed to read Stream eventsource: %v", err)
+				if err != ErrStreamClosed {
+					stream.fail(err)
+				}
 				return
 			}
 
-			m := new(Message)
-			err = json.Unmarshal([]byte(event.Data), m)
-			if err != nil {
-				s.client.Log.Printf("bad JSON data from Stream eventsource: %v", err)
+			var v T
+			var ok bool
+			if err := protect(func() error { v, ok = decode(event); return nil }); err != nil {
+				s.client.Logf("Stream reader panicked: %v", err)
+				if !s.client.RestartOnPanic {
+					stream.fail(err)
+					return
+				}
+				stream.report(err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- v:
+			case <-stream.done:
 				return
 			}
-			messageCh <- *m
 		}
 	}()
 
-	return messageCh, es, err
+	return ch, stream, err
+}
+
+// streamed returns the message of a streamed event, once observed by the
+// client, or nil if it is dropped.
+func (s *MessagesService) streamed(event *event) *Message {
+	m := new(Message)
+	if err := json.Unmarshal(event.Data, m); err != nil {
+		s.client.Logf("skipped bad JSON data from Stream: %v", err)
+		return nil
+	}
+	s.client.checkDeprecated(m)
+	if s.client.FloodDetector != nil {
+		s.client.FloodDetector.Observe(m)
+	}
+	if s.client.Mute != nil && s.client.Mute.Muted(m) {
+		return nil
+	}
+	return m
 }
 
-// List of the messages for the given flow.
+// StreamRaw opens the stream of the given flow without reading it, for
+// Stream.CopyTo. The token is passed as selected by the Client's StreamAuth.
+// The returned Stream must be closed once done, or is closed with ctx.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamRaw(ctx context.Context, token, org, flow string) (*Stream, error) {
+	if ctx == nil {
+		return nil, errNonNilContext
+	}
+
+	u := fmt.Sprintf("flows/%v/%v", org, flow)
+
+	req, err := s.client.NewStreamRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.client.AuthorizeStreamRequest(req, token)
+
+	stream := newStream(s.client, req)
+	stream.bind(ctx)
+	return stream, nil
+}
+
+// List of the messages for the given flow. Messages older than the
+// HistoryFloor of the client are dropped, so that a page can come back
+// short, or empty, before the end of the history: Iterate walks the pages
+// from the floor.
 //
 // Flowdock API docs: https://www.flowdock.com/api/messages
-func (s *MessagesService) List(org, flow string, opt *MessagesListOptions) ([]Message, *http.Response, error) {
-	u := fmt.Sprintf("flows/%v/%v/messages", org, flow)
+func (s *MessagesService) List(ctx context.Context, org, flow string, opt *MessagesListOptions) ([]Message, *http.Response, error) {
+	messages, resp, err := s.list(ctx, org, flow, opt)
+	if err != nil {
+		return nil, resp, err
+	}
+	return s.client.withinFloor(messages), resp, err
+}
 
-	u, err := addOptions(u, opt)
+// list is List without the HistoryFloor, for the walks which page through
+// the history and withhold its messages themselves.
+func (s *MessagesService) list(ctx context.Context, org, flow string, opt *MessagesListOptions) ([]Message, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/messages", org, flow)
+	u, err := s.client.addOptions(u, opt)
 	if err != nil {
 		return nil, nil, err
 	}
+	return s.client.listMessages(ctx, u)
+}
 
-	req, err := s.client.NewRequest("GET", u, nil)
+// listMessages requests the list of messages at u.
+func (c *Client) listMessages(ctx context.Context, u string) ([]Message, *http.Response, error) {
+	req, err := c.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	var messages []Message
-	resp, err := s.client.Do(req, &messages)
+	resp, err := c.Do(ctx, req, &messages)
 	if err != nil {
 		return nil, resp, err
 	}
+	for i := range messages {
+		c.checkDeprecated(&messages[i])
+	}
 
 	return messages, resp, err
 }
 
+// defaultIteratePageSize is the number of messages per page of Iterate,
+// unless set by its options: the most the API returns.
+const defaultIteratePageSize = 100
+
+// Iterate returns an iterator over all the messages of the given flow
+// matching opt, fetching pages of opt.Limit messages at a time with ctx,
+// 100 by default. Pages are requested newest first, with UntilID set to
+// the oldest message of the previous page, or oldest first when opt.Sort
+// is "asc", with SinceID set to the newest one, until the range opt
+// delimits is exhausted. Messages older than the HistoryFloor of the client
+// are skipped: walks newest first stop at the floor, and walks oldest first
+// start there, once found with a walk newest first from the end of the
+// history down to SinceID.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) Iterate(ctx context.Context, org, flow string, opt *MessagesListOptions) *MessageIterator {
+	u := fmt.Sprintf("flows/%v/%v/messages", org, flow)
+
+	var o MessagesListOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.Limit == 0 {
+		o.Limit = defaultIteratePageSize
+	}
+
+	next, err := s.client.addOptions(u, &o)
+	if err != nil {
+		return &MessageIterator{err: err}
+	}
+	it := &iterator{
+		ctx:    ctx,
+		client: s.client,
+		limit:  o.Limit,
+		next:   next,
+		after: func(lastID int) (string, error) {
+			if o.Sort == "asc" {
+				o.SinceID = lastID
+			} else {
+				o.UntilID = lastID
+			}
+			return s.client.addOptions(u, &o)
+		},
+	}
+	i := &MessageIterator{it: it, client: s.client, desc: o.Sort != "asc"}
+	if !i.desc && s.client.HistoryFloor > 0 {
+		i.seek = func() error {
+			id, err := s.floorID(ctx, org, flow, o.SinceID, o.UntilID)
+			if err != nil || id <= o.SinceID {
+				return err
+			}
+			o.SinceID = id
+			it.next, err = s.client.addOptions(u, &o)
+			return err
+		}
+	}
+	return i
+}
+
+// floorID returns the ID of the newest message of the given flow between
+// since and until older than the HistoryFloor of the client, or since if
+// there is none, for walks oldest first to start from the floor without
+// reading the history before it. It walks newest first from until down to
+// the floor, or to since.
+func (s *MessagesService) floorID(ctx context.Context, org, flow string, since, until int) (int, error) {
+	floor := s.client.historyFloor()
+	opt := &MessagesListOptions{SinceID: since, UntilID: until, Limit: defaultIteratePageSize}
+	for {
+		page, _, err := s.list(ctx, org, flow, opt)
+		if err != nil {
+			return 0, err
+		}
+		for i := range page {
+			if beforeFloor(&page[i], floor) && page[i].ID != nil {
+				return *page[i].ID, nil
+			}
+		}
+		if len(page) < opt.Limit || messageID(page[len(page)-1]) == 0 {
+			return since, nil
+		}
+		opt.UntilID = messageID(page[len(page)-1])
+	}
+}
+
+// ListAll lists all the messages of the given flow matching opt, following
+// its pages as Iterate does. maxPages, if positive, bounds the number of
+// pages fetched; the messages of those are returned with ErrMaxPages when
+// the last one didn't end the list.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) ListAll(ctx context.Context, org, flow string, opt *MessagesListOptions, maxPages int) ([]Message, error) {
+	it := s.Iterate(ctx, org, flow, opt)
+	it.MaxPages = maxPages
+
+	var messages []Message
+	for it.Next() {
+		messages = append(messages, it.Message())
+	}
+	return messages, it.Err()
+}
+
+// ListFlows lists the messages of each of flows with opt, concurrently on
+// the client's Workers. The returned lists are in the order of flows, with
+// nil for the flows which failed to be listed. Failures do not stop the
+// listing of the other flows and are reported in a *BulkError, identified
+// by their flow reference.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) ListFlows(ctx context.Context, flows []FlowRef, opt *MessagesListOptions) ([][]Message, error) {
+	lists := make([][]Message, len(flows))
+	errs := s.client.Workers.Run(ctx, len(flows), func(ctx context.Context, i int) error {
+		messages, _, err := s.ListRef(ctx, flows[i], opt)
+		lists[i] = messages
+		return err
+	})
+	return lists, bulkErrors(errs, func(i int) string { return flows[i].String() })
+}
+
 // Get a single message by ID.
 //
 // Flowdock API docs: https://www.flowdock.com/api/messages
-func (s *MessagesService) Get(org, flowName string, id int) (*Message, *http.Response, error) {
+func (s *MessagesService) Get(ctx context.Context, org, flowName string, id int) (*Message, *http.Response, error) {
 	u := fmt.Sprintf("flows/%v/%v/messages/%d", org, flowName, id)
 
 	req, err := s.client.NewRequest("GET", u, nil)
@@ -105,72 +366,82 @@ func (s *MessagesService) Get(org, flowName string, id int) (*Message, *http.Res
 	}
 
 	message := new(Message)
-	resp, err := s.client.Do(req, message)
+	resp, err := s.client.Do(ctx, req, message)
 	if err != nil {
 		return nil, resp, err
 	}
+	s.client.checkDeprecated(message)
 
 	return message, resp, err
 }
 
 type MessagesEditOptions struct {
-	Content string   `url:"content,omitempty"`
-	Tags    []string `url:"tags,comma,omitempty"`
+	Content string `url:"content,omitempty" json:"content,omitempty"`
+	Tags    Tags   `url:"tags,omitempty" json:"tags,omitempty"`
 }
 
-func (s *MessagesService) Edit(org, flowName string, id int, opt *MessagesEditOptions) (*http.Response, error) {
+func (s *MessagesService) Edit(ctx context.Context, org, flowName string, id int, opt *MessagesEditOptions) (*http.Response, error) {
 	u := fmt.Sprintf("/flows/%s/%s/messages/%d", org, flowName, id)
 
-	u, err := addOptions(u, opt)
-	if err != nil {
-		return nil, err
-	}
-	req, err := s.client.NewRequest("PUT", u, nil)
+	req, err := s.client.newMessageRequest("PUT", u, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.client.Do(req, nil)
+	return s.client.Do(ctx, req, nil)
 }
 
-func (s *MessagesService) Delete(org, flowName string, id int) (*http.Response, error) {
+func (s *MessagesService) Delete(ctx context.Context, org, flowName string, id int) (*http.Response, error) {
 	u := fmt.Sprintf("/flows/%s/%s/messages/%d", org, flowName, id)
 	req, err := s.client.NewRequest("DELETE", u, nil)
 	if err != nil {
 		return nil, err
 	}
-	return s.client.Do(req, nil)
+	return s.client.Do(ctx, req, nil)
+}
+
+// DeleteBulk deletes the messages with the given IDs, concurrently on the
+// client's Workers. Failures do not stop the deletion of the other messages
+// and are reported in a *BulkError.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) DeleteBulk(ctx context.Context, org, flowName string, ids []int) error {
+	errs := s.client.Workers.Run(ctx, len(ids), func(ctx context.Context, i int) error {
+		_, err := s.Delete(ctx, org, flowName, ids[i])
+		return err
+	})
+	return bulkErrors(errs, func(i int) string { return strconv.Itoa(ids[i]) })
 }
 
 // MessagesCreateOptions specifies the optional parameters to the
 // MessageService.Create method.
 type MessagesCreateOptions struct {
-	FlowID           string   `url:"flow,omitempty"`
-	MessageID        int      `url:"message,omitempty"`
-	Event            string   `url:"event,omitempty"`
-	Content          string   `url:"content,omitempty"`
-	Tags             []string `url:"tags,comma,omitempty"`
-	UUID             string   `url:"uuid,omitempty"`
-	ExternalUserName string   `url:"external_user_name,omitempty"`
-	Subject          string   `url:"subject,omitempty"`
-	FromAddress      string   `url:"from_address,omitempty"`
-	Source           string   `url:"source,omitempty"`
+	FlowID           string `url:"flow,omitempty" json:"flow,omitempty"`
+	MessageID        int    `url:"message,omitempty" json:"message,omitempty"`
+	ThreadID         string `url:"thread_id,omitempty" json:"thread_id,omitempty"`
+	Event            string `url:"event,omitempty" json:"event,omitempty"`
+	Content          string `url:"content,omitempty" json:"content,omitempty"`
+	Tags             Tags   `url:"tags,omitempty" json:"tags,omitempty"`
+	UUID             string `url:"uuid,omitempty" json:"uuid,omitempty"`
+	ExternalUserName string `url:"external_user_name,omitempty" json:"external_user_name,omitempty"`
+	Subject          string `url:"subject,omitempty" json:"subject,omitempty"`
+	FromAddress      string `url:"from_address,omitempty" json:"from_address,omitempty"`
+	Source           string `url:"source,omitempty" json:"source,omitempty"`
 }
 
 // CreateComment for the specified organization
 //
 // Flowdock API docs: https://www.flowdock.com/api/messages
-func (s *MessagesService) CreateComment(opt *MessagesCreateOptions) (*Message, *http.Response, error) {
+func (s *MessagesService) CreateComment(ctx context.Context, opt *MessagesCreateOptions) (*Message, *http.Response, error) {
 	u := "comments"
 
-	u, err := addOptions(u, opt)
-	req, err := s.client.NewRequest("POST", u, nil)
+	req, err := s.client.newMessageRequest("POST", u, opt)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	message := new(Message)
-	resp, err := s.client.Do(req, message)
+	resp, err := s.client.Do(ctx, req, message)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -181,21 +452,214 @@ func (s *MessagesService) CreateComment(opt *MessagesCreateOptions) (*Message, *
 // Create a message for the specified organization
 //
 // Flowdock API docs: https://www.flowdock.com/api/messages
-func (s *MessagesService) Create(opt *MessagesCreateOptions) (*Message, *http.Response, error) {
+func (s *MessagesService) Create(ctx context.Context, opt *MessagesCreateOptions) (*Message, *http.Response, error) {
 	u := "messages"
 
-	u, err := addOptions(u, opt)
-	req, err := s.client.NewRequest("POST", u, nil)
+	req, err := s.client.newMessageRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message := new(Message)
+	resp, err := s.client.Do(ctx, req, message)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return message, resp, err
+}
+
+// newMessageRequest returns a request creating or editing a message with
+// opt, sent as a JSON body, or as URL query parameters with the Client's
+// LegacyMessageQuery.
+func (c *Client) newMessageRequest(method, u string, opt interface{}) (*http.Request, error) {
+	if c.LegacyMessageQuery {
+		u, err := c.addOptions(u, opt)
+		if err != nil {
+			return nil, err
+		}
+		return c.NewRequest(method, u, nil)
+	}
+	if v := reflect.ValueOf(opt); v.Kind() == reflect.Ptr && v.IsNil() {
+		opt = nil
+	}
+	return c.NewRequest(method, u, opt)
+}
+
+// CreateBatch creates a message for each of opts. The returned messages are
+// in the order of opts, with nil for the messages that failed to be created.
+// Failures do not stop the creation of the other messages and are reported in
+// a *BulkError, identified by their UUID when set.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) CreateBatch(ctx context.Context, opts []*MessagesCreateOptions) ([]*Message, error) {
+	messages := make([]*Message, len(opts))
+	bulkErr := new(BulkError)
+	for i, opt := range opts {
+		m, _, err := s.Create(ctx, opt)
+		if err != nil {
+			bulkErr.add(i, opt.UUID, err)
+			continue
+		}
+		messages[i] = m
+	}
+	return messages, bulkErr.err()
+}
+
+// PostCode posts a code snippet to the given flow. Short snippets are sent as
+// a preformatted chat message, while snippets longer than
+// MaxCodeMessageLength (or that can't be fenced) are uploaded as a file named
+// filename.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) PostCode(ctx context.Context, org, flow, filename, code string) (*Message, *http.Response, error) {
+	if len(code) > MaxCodeMessageLength || strings.Contains(code, "```") {
+		return s.uploadFile(ctx, org, flow, filename, "text/plain; charset=utf-8", strings.NewReader(code))
+	}
+
+	return s.createInFlow(ctx, org, flow, &MessagesCreateOptions{
+		Event:   "message",
+		Content: "```\n" + strings.TrimRight(code, "\n") + "\n```",
+	})
+}
+
+// createInFlow creates a message in the flow named flow of the organization
+// org, which needs no flow ID.
+func (s *MessagesService) createInFlow(ctx context.Context, org, flow string, opt *MessagesCreateOptions) (*Message, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/messages", org, flow)
+
+	req, err := s.client.newMessageRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message := new(Message)
+	resp, err := s.client.Do(ctx, req, message)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return message, resp, err
+}
+
+// ErrNotImage is returned by PostImage for content which is not an image.
+var ErrNotImage = errors.New("flowdock: content is not an image")
+
+// PostImage uploads the image read from img to the given flow, where it is
+// shown as an inline preview. The image type is detected from its content
+// and, for the preview to render, the extension of name is set to match it.
+// Content which is not a GIF, PNG, JPEG, BMP or WebP image is refused with
+// ErrNotImage.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) PostImage(ctx context.Context, org, flow string, img io.Reader, name string) (*Message, *http.Response, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(img, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := imageExts[contentType]
+	if !ok {
+		return nil, nil, ErrNotImage
+	}
+
+	cur := strings.ToLower(path.Ext(name))
+	if cur == ".jpeg" {
+		cur = ".jpg"
+	}
+	if cur != ext {
+		name = strings.TrimSuffix(name, path.Ext(name)) + ext
+	}
+	return s.uploadFile(ctx, org, flow, name, contentType, io.MultiReader(bytes.NewReader(head), img))
+}
+
+// UploadFile uploads the file read from r to the given flow, as a "file"
+// event named filename. Its content type is the one of the extension of
+// filename, or else detected from its content.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) UploadFile(ctx context.Context, org, flow, filename string, r io.Reader) (*Message, *http.Response, error) {
+	contentType := mime.TypeByExtension(path.Ext(filename))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, nil, err
+		}
+		head = head[:n]
+		contentType = http.DetectContentType(head)
+		r = io.MultiReader(bytes.NewReader(head), r)
+	}
+	return s.uploadFile(ctx, org, flow, filename, contentType, r)
+}
+
+// imageExts are the extensions of the image types PostImage accepts.
+var imageExts = map[string]string{
+	"image/gif":  ".gif",
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/bmp":  ".bmp",
+	"image/webp": ".webp",
+}
+
+// uploadFile posts the content of r as a file message named filename, of
+// type contentType. With an UploadDedup set on the Client, a content
+// uploaded recently returns the earlier message and a nil response.
+func (s *MessagesService) uploadFile(ctx context.Context, org, flow, filename, contentType string, r io.Reader) (*Message, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/messages", org, flow)
+
+	dedup, hash := s.client.UploadDedup, ""
+	if dedup != nil {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		hash = contentHash(data)
+		if m, ok := dedup.lookup(s.client.Clock, org, flow, hash); ok {
+			return m, nil, nil
+		}
+		r = bytes.NewReader(data)
+	}
+
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	if err := w.WriteField("event", "file"); err != nil {
+		return nil, nil, err
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data",
+		map[string]string{"name": "content", "filename": filename}))
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.client.NewUploadRequest(u, body, w.FormDataContentType())
 	if err != nil {
 		return nil, nil, err
 	}
 
 	message := new(Message)
-	resp, err := s.client.Do(req, message)
+	resp, err := s.client.Do(ctx, req, message)
 	if err != nil {
 		return nil, resp, err
 	}
 
+	if dedup != nil {
+		if err := dedup.remember(s.client.Clock, org, flow, hash, message); err != nil {
+			s.client.Logf("failed to remember upload: %v", err)
+		}
+	}
 	return message, resp, err
 }
 
@@ -209,30 +673,266 @@ type Message struct {
 	RawContent       *json.RawMessage `json:"content,omitempty"`
 	MessageID        *int             `json:"message,omitempty"`
 	Tags             *[]string        `json:"tags,omitempty"`
+	ThreadID         *string          `json:"thread_id,omitempty"`
 	UUID             *string          `json:"uuid,omitempty"`
 	ExternalUserName *string          `json:"external_user_name,omitempty"`
-	App              *string          `json:"app,omitempty"` // deprecated
+	App              *string          `json:"app,omitempty"` // deprecated, see MigrateApp
+
+	// EmojiReactions holds the IDs of the users who reacted to the
+	// message, by emoji shortcode without colons, such as "+1".
+	EmojiReactions *map[string][]string `json:"emojiReactions,omitempty"`
+
+	// fields of the JSON representation, in order, kept so that a decoded
+	// Message encodes back as it was, with the fields not mapped above
+	decoded []jsonField
+}
+
+// messageFields holds the JSON names of the mapped Message fields, with
+// their position in its encoding.
+var messageFields = jsonFieldNames(reflect.TypeOf(Message{}))
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Fields that are
+// not mapped by Message are retained and encoded back by MarshalJSON.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	if err := json.Unmarshal(data, (*message)(m)); err != nil {
+		return err
+	}
+
+	fields, err := objectFields(data)
+	if err != nil {
+		return err
+	}
+	m.decoded = nil
+	if !encodesAsMessage(fields) {
+		m.decoded = fields
+	}
+	return nil
+}
+
+// encodesAsMessage reports whether the fields of a JSON object are encoded
+// back as they are by the encoding of Message: they are all mapped, not
+// null, and in the order of Message.
+func encodesAsMessage(fields []jsonField) bool {
+	last := -1
+	for _, f := range fields {
+		i, ok := messageFields[f.name]
+		if !ok || i <= last || string(f.value) == "null" {
+			return false
+		}
+		last = i
+	}
+	return true
+}
+
+// MarshalJSON implements the json.Marshaler interface. A decoded Message
+// encodes back with the fields it was decoded from, in their order, so that
+// an unchanged Message encodes as it was received: fields not mapped by
+// Message are copied, and fields which were null stay null while unset.
+// Fields set since are added after them.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	data, err := json.Marshal(message(m))
+	if err != nil || m.decoded == nil {
+		return data, err
+	}
+
+	mapped, err := objectFields(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]json.RawMessage, len(mapped))
+	for _, f := range mapped {
+		values[f.name] = f.value
+	}
+
+	buf := new(bytes.Buffer)
+	write := func(name string, value json.RawMessage) {
+		if buf.Len() > 0 {
+			buf.WriteByte(',')
+		} else {
+			buf.WriteByte('{')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	for _, f := range m.decoded {
+		if _, ok := messageFields[f.name]; !ok {
+			write(f.name, f.value)
+		} else if value, ok := values[f.name]; ok {
+			write(f.name, value)
+			delete(values, f.name)
+		} else if string(f.value) == "null" {
+			write(f.name, f.value)
+		}
+	}
+	for _, f := range mapped {
+		if _, ok := values[f.name]; ok {
+			write(f.name, f.value)
+		}
+	}
+	if buf.Len() == 0 {
+		buf.WriteByte('{')
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonField is a field of a JSON object.
+type jsonField struct {
+	name  string
+	value json.RawMessage
+}
+
+// objectFields returns the fields of the JSON object data in order, or nil
+// if data is null.
+func objectFields(data []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	t, err := dec.Token()
+	if err != nil || t == nil {
+		return nil, err
+	}
+	if t != json.Delim('{') {
+		return nil, fmt.Errorf("flowdock: JSON %v is not an object", t)
+	}
+
+	fields := []jsonField{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{name: t.(string), value: value})
+	}
+	return fields, nil
+}
+
+// SentIn returns when the message was sent, in the time zone loc. It
+// returns the zero time when the message has no sent time.
+func (m *Message) SentIn(loc *time.Location) time.Time {
+	if m.Sent == nil {
+		return time.Time{}
+	}
+	return m.Sent.In(loc)
+}
+
+// Age returns how long before now the message was sent, or 0 when the
+// message has no sent time.
+func (m *Message) Age(now time.Time) time.Duration {
+	if m.Sent == nil {
+		return 0
+	}
+	return now.Sub(m.Sent.Time)
+}
+
+// DayKey returns the day the message was sent on in the time zone loc, as
+// "2006-01-02". Messages sent on the same local day share the same key, which
+// makes it suitable to group messages per day. It returns "" when the message
+// has no sent time.
+func (m *Message) DayKey(loc *time.Location) string {
+	if m.Sent == nil {
+		return ""
+	}
+	return m.Sent.In(loc).Format("2006-01-02")
+}
+
+// ReplyOptions returns the options to reply content to m with Create, in
+// the thread of m, or with CreateComment for messages older than threads,
+// which have no ThreadID. The tags of m are carried over, except the ones
+// set by Flowdock, such as ":user:" mentions, which start with ":".
+func (m *Message) ReplyOptions(content string) *MessagesCreateOptions {
+	opt := &MessagesCreateOptions{Event: "message", Content: content}
+	if m.FlowID != nil {
+		opt.FlowID = *m.FlowID
+	}
+
+	switch {
+	case m.ThreadID != nil:
+		opt.ThreadID = *m.ThreadID
+	case m.MessageID != nil:
+		opt.Event, opt.MessageID = "comment", *m.MessageID
+	case m.ID != nil:
+		opt.Event, opt.MessageID = "comment", *m.ID
+	}
+
+	if m.Tags != nil {
+		for _, tag := range *m.Tags {
+			if !strings.HasPrefix(tag, ":") {
+				opt.Tags = append(opt.Tags, tag)
+			}
+		}
+	}
+	return opt
+}
+
+// contentTypes returns a new Content of the type of the events which have
+// their own, by event. The content of the others is a JsonContent.
+var contentTypes = map[string]func() Content{
+	"message":        func() Content { return new(MessageContent) },
+	"comment":        func() Content { return new(CommentContent) },
+	"vcs":            func() Content { return new(VcsContent) },
+	"tag-change":     func() Content { return new(TagChange) },
+	"file":           func() Content { return new(FileContent) },
+	"mail":           func() Content { return new(MailContent) },
+	"status":         func() Content { return new(StatusContent) },
+	"activity":       func() Content { return new(ActivityContent) },
+	"discussion":     func() Content { return new(ActivityContent) },
+	"user-edit":      func() Content { return new(UserEditContent) },
+	"action":         func() Content { return new(ActionContent) },
+	"message-edit":   func() Content { return new(MessageEditContent) },
+	"emoji-reaction": func() Content { return new(EmojiReactionContent) },
 }
 
 // Content of a Message
 //
-// It can be a MessageContent, CommentContent, etc. Depends on the Event
-func (m *Message) Content() (content Content) {
-
-	switch *m.Event {
-	case "message":
-		content = new(MessageContent)
-	case "comment":
-		content = &CommentContent{}
-	case "vcs":
-		content = &VcsContent{}
-	default:
+// It can be a MessageContent, CommentContent, etc. Depends on the Event.
+// Content panics if the content is malformed; use ContentE for messages
+// from third parties.
+func (m *Message) Content() Content {
+	content, err := m.ContentE()
+	if err != nil {
+		panic(err.Error())
+	}
+	return content
+}
+
+// ContentE is like Content, but returns an error for malformed content
+// rather than panicking.
+func (m *Message) ContentE() (content Content, err error) {
+	if m.RawContent == nil {
+		return new(JsonContent), nil
+	}
+
+	var event string
+	if m.Event != nil {
+		event = *m.Event
+	}
+
+	if newContent, ok := contentTypes[event]; ok {
+		content = newContent()
+	} else {
 		content = new(JsonContent)
 	}
 
 	if err := json.Unmarshal([]byte(*m.RawContent), &content); err != nil {
-		panic(err.Error())
+		return nil, err
 	}
 
-	return content
+	return content, nil
+}
+
+// contentText returns the String of the content of m, or "" if the content
+// is malformed.
+func (m *Message) contentText() string {
+	content, err := m.ContentE()
+	if err != nil {
+		return ""
+	}
+	return content.String()
 }
@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -15,14 +16,12 @@ type Content interface {
 type MessageContent string
 
 // Return the string version of a MessageContent
-//
 func (c *MessageContent) String() string {
 	return string(*c)
 }
 
 // JsonContent is the default type for Message.Content() that does not have its
 // own explicit type.
-//
 type JsonContent string
 
 // Unmarshal the json data into JsonContent (i.e. just a string really)
@@ -34,7 +33,6 @@ func (c *JsonContent) UnmarshalJSON(data []byte) error {
 }
 
 // Return the string version of a JsonContent
-//
 func (c *JsonContent) String() string {
 	return string(*c)
 }
@@ -49,9 +47,34 @@ type CommentContent struct {
 //
 // It returns the *CommentContent.Text
 func (c *CommentContent) String() string {
+	if c.Text == nil {
+		return ""
+	}
 	return *c.Text
 }
 
+// FileContent represents a Message's Content when Message.Event is "file"
+type FileContent struct {
+	Path        *string `json:"path"`
+	FileName    *string `json:"file_name"`
+	ContentType *string `json:"content_type"`
+	FileSize    *int    `json:"file_size"`
+	Image       *struct {
+		Width  *int `json:"width"`
+		Height *int `json:"height"`
+	} `json:"image,omitempty"` // of image files
+}
+
+// Return the string version of a FileContent
+//
+// It returns the *FileContent.Path
+func (c *FileContent) String() string {
+	if c.Path == nil {
+		return ""
+	}
+	return *c.Path
+}
+
 // VCS (i.e. Github)
 type VcsContent struct {
 	Issue struct {
@@ -75,11 +98,19 @@ type VcsContent struct {
 
 // Return the string version of a VcsContent
 //
-// It returns the *CommentContent.Text
+// It returns "repository: event by user url", or "" when the content has
+// neither a repository nor an event.
 func (c *VcsContent) String() string {
-	var user, url string
-	event := *c.Event
-	name := *c.Repository.Name
+	if c.Repository.Name == nil && c.Event == nil {
+		return ""
+	}
+	var name, event, user, url string
+	if c.Repository.Name != nil {
+		name = *c.Repository.Name
+	}
+	if c.Event != nil {
+		event = *c.Event
+	}
 
 	if c.Pusher.Name != nil {
 		user = *c.Pusher.Name
@@ -99,3 +130,115 @@ func (c *VcsContent) String() string {
 
 	return fmt.Sprintf("%s: %s by %s %s", name, event, user, url)
 }
+
+// StatusContent represents a Message's Content when Message.Event is
+// "status": the status the user set.
+type StatusContent string
+
+// Return the string version of a StatusContent
+func (c *StatusContent) String() string {
+	return string(*c)
+}
+
+// ActivityContent represents a Message's Content when Message.Event is
+// "activity" or "discussion": a message an integration posted in a thread,
+// as with IntegrationsService.Create.
+type ActivityContent struct {
+	Title  *string `json:"title"`
+	Body   *string `json:"body,omitempty"` // HTML, of discussions
+	Author *Author `json:"author,omitempty"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Contents which
+// are a plain string, rather than an object, are taken as the Body.
+func (c *ActivityContent) UnmarshalJSON(data []byte) error {
+	var body string
+	if json.Unmarshal(data, &body) == nil {
+		*c = ActivityContent{Body: &body}
+		return nil
+	}
+	type activityContent ActivityContent
+	return json.Unmarshal(data, (*activityContent)(c))
+}
+
+// Return the string version of an ActivityContent
+//
+// It returns the *ActivityContent.Title, or else its Body
+func (c *ActivityContent) String() string {
+	if c.Title != nil {
+		return *c.Title
+	}
+	if c.Body != nil {
+		return *c.Body
+	}
+	return ""
+}
+
+// UserEditContent represents a Message's Content when Message.Event is
+// "user-edit": a user changed their details, such as their nick.
+type UserEditContent struct {
+	User *User `json:"user"`
+}
+
+// Return the string version of a UserEditContent
+//
+// It returns the nick of the *UserEditContent.User
+func (c *UserEditContent) String() string {
+	if c.User == nil || c.User.Nick == nil {
+		return ""
+	}
+	return *c.User.Nick
+}
+
+// ActionContent represents a Message's Content when Message.Event is
+// "action": a change of the members of the flow, such as "join", "leave"
+// or "add_people".
+type ActionContent struct {
+	Type        *string `json:"type"`
+	Description *string `json:"description,omitempty"`
+}
+
+// Return the string version of an ActionContent
+//
+// It returns the *ActionContent.Description, or else its Type
+func (c *ActionContent) String() string {
+	if c.Description != nil {
+		return *c.Description
+	}
+	if c.Type != nil {
+		return *c.Type
+	}
+	return ""
+}
+
+// MessageEditContent represents a Message's Content when Message.Event is
+// "message-edit": the content of a message was edited.
+type MessageEditContent struct {
+	MessageID      int     `json:"message"`
+	UpdatedContent *string `json:"updated_content"`
+}
+
+// Return the string version of a MessageEditContent
+//
+// It returns the *MessageEditContent.UpdatedContent
+func (c *MessageEditContent) String() string {
+	if c.UpdatedContent == nil {
+		return ""
+	}
+	return *c.UpdatedContent
+}
+
+// EmojiReactionContent represents a Message's Content when Message.Event is
+// "emoji-reaction": a user added or removed a reaction to a message.
+type EmojiReactionContent struct {
+	MessageID int    `json:"message"`
+	Emoji     string `json:"emoji"`
+	Type      string `json:"type"` // "add" or "remove"
+}
+
+// Return the string version of an EmojiReactionContent
+//
+// It returns the emoji shortcode between colons.
+func (c *EmojiReactionContent) String() string {
+	return ":" + c.Emoji + ":"
+}
@@ -1,20 +1,30 @@
 package flowdock
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMessagesService_Stream(t *testing.T) {
 	setup()
 	defer teardown()
+
+	ctx := context.Background()
 	more := make(chan bool, 1)
 
 	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
-		testFormValues(t, r, values{"access_token": "token"})
+		testHeader(t, r, "Authorization", "Bearer token")
+		testFormValues(t, r, values{})
 		w.Header().Set("Content-Type", "text/event-stream")
 
 		var id int
@@ -32,7 +42,7 @@ func TestMessagesService_Stream(t *testing.T) {
 	})
 	defer close(more)
 
-	stream, _, err := client.Messages.Stream("token", "org", "flow")
+	stream, _, err := client.Messages.Stream(ctx, "token", "org", "flow")
 	more <- true // tell test server to send a message
 
 	if err != nil {
@@ -49,6 +59,8 @@ func TestMessagesService_Stream(t *testing.T) {
 func TestMessagesService_List(t *testing.T) {
 	setup()
 	defer teardown()
+
+	ctx := context.Background()
 	var idOne = 3816534
 	var eventOne = "message"
 	var content = []string{"Hello NYC", "Hello World"}
@@ -86,7 +98,7 @@ func TestMessagesService_List(t *testing.T) {
 		fmt.Fprint(w, `[{"id":"1"}, {"id":"2"}]`)
 	})
 
-	messages, _, err := client.Messages.List("org", "flow", nil)
+	messages, _, err := client.Messages.List(ctx, "org", "flow", nil)
 	if err != nil {
 		t.Errorf("Messages.List returned error: %v", err)
 	}
@@ -115,13 +127,152 @@ func TestMessagesService_List(t *testing.T) {
 	}
 }
 
+// handleMessageHistory serves the messages 1 to n of flows/org/flow,
+// filtered and sorted as asked. Message i is sent on day i after the Epoch.
+func handleMessageHistory(t *testing.T, n int) *int {
+	requests := new(int)
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		*requests++
+		since, _ := strconv.Atoi(r.FormValue("since_id"))
+		until, _ := strconv.Atoi(r.FormValue("until_id"))
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+		if until == 0 {
+			until = n + 1
+		}
+
+		var ids []string
+		if r.FormValue("sort") == "asc" {
+			for id := since + 1; id < until && len(ids) < limit; id++ {
+				ids = append(ids, fmt.Sprintf(`{"id":%d,"sent":%d}`, id, id*dayMillis))
+			}
+		} else {
+			for id := until - 1; id > since && len(ids) < limit; id-- {
+				ids = append(ids, fmt.Sprintf(`{"id":%d,"sent":%d}`, id, id*dayMillis))
+			}
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(ids, ","))
+	})
+	return requests
+}
+
+// messageIDs returns the IDs of the messages of it.
+func messageIDs(it *MessageIterator) []int {
+	var ids []int
+	for it.Next() {
+		m := it.Message()
+		ids = append(ids, *m.ID)
+	}
+	return ids
+}
+
+func TestMessagesService_Iterate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	requests := handleMessageHistory(t, 7)
+
+	it := client.Messages.Iterate(ctx, "org", "flow", &MessagesListOptions{Limit: 3})
+	if got, want := messageIDs(it), []int{7, 6, 5, 4, 3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MessageIterator returned %v, want %v", got, want)
+	}
+	if it.Err() != nil || *requests != 3 {
+		t.Errorf("MessageIterator returned error %v after %d requests, want none after 3", it.Err(), *requests)
+	}
+
+	it = client.Messages.Iterate(ctx, "org", "flow", &MessagesListOptions{Limit: 2, SinceID: 2, UntilID: 6, Sort: "asc"})
+	if got, want := messageIDs(it), []int{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MessageIterator returned %v, want %v", got, want)
+	}
+}
+
+func TestMessagesService_Iterate_historyFloor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	requests := handleMessageHistory(t, 7)
+	client.Clock = NewFakeClock(time.Unix(0, 0).Add(10 * 24 * time.Hour))
+	client.HistoryFloor = 5*24*time.Hour + 12*time.Hour
+
+	it := client.Messages.Iterate(ctx, "org", "flow", &MessagesListOptions{Limit: 2})
+	if got, want := messageIDs(it), []int{7, 6, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MessageIterator returned %v, want %v", got, want)
+	}
+	if it.Err() != nil || *requests != 2 {
+		t.Errorf("MessageIterator returned error %v after %d requests, want none after 2", it.Err(), *requests)
+	}
+
+	*requests = 0
+	it = client.Messages.Iterate(ctx, "org", "flow", &MessagesListOptions{Limit: 2, Sort: "asc"})
+	if got, want := messageIDs(it), []int{5, 6, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MessageIterator returned %v, want %v", got, want)
+	}
+	if it.Err() != nil || *requests != 3 {
+		t.Errorf("MessageIterator returned error %v after %d requests, want none after 3: one to find the floor, two from there", it.Err(), *requests)
+	}
+
+	messages, _, err := client.Messages.List(ctx, "org", "flow", &MessagesListOptions{Limit: 3, Sort: "asc"})
+	if err != nil || len(messages) != 0 {
+		t.Errorf("Messages.List returned %v and %v, want the messages before the floor dropped", messages, err)
+	}
+}
+
+func TestMessagesService_ListAll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	handleMessageHistory(t, 250)
+
+	messages, err := client.Messages.ListAll(ctx, "org", "flow", nil, 0)
+	if err != nil || len(messages) != 250 || *messages[249].ID != 1 {
+		t.Errorf("Messages.ListAll returned %d messages and %v, want the 250 messages", len(messages), err)
+	}
+
+	messages, err = client.Messages.ListAll(ctx, "org", "flow", nil, 2)
+	if err != ErrMaxPages || len(messages) != 200 {
+		t.Errorf("Messages.ListAll returned %d messages and %v, want 200 and ErrMaxPages", len(messages), err)
+	}
+}
+
+func TestMessagesService_ListFlows(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	for flow, id := range map[string]int{"a": 1, "b": 2} {
+		id := id
+		mux.HandleFunc("/flows/org/"+flow+"/messages", func(w http.ResponseWriter, r *http.Request) {
+			testMethod(t, r, "GET")
+			testFormValues(t, r, values{"limit": "1"})
+			fmt.Fprintf(w, `[{"id":%d}]`, id)
+		})
+	}
+
+	flows := []FlowRef{{Org: "org", Flow: "b"}, {Org: "org", Flow: "missing"}, {Org: "org", Flow: "a"}}
+	lists, err := client.Messages.ListFlows(ctx, flows, &MessagesListOptions{Limit: 1})
+
+	berr, ok := err.(*BulkError)
+	if !ok || len(berr.Errors) != 1 || berr.Errors[0].ID != "org/missing" || berr.Errors[0].Index != 1 {
+		t.Fatalf("Messages.ListFlows returned %v, want a BulkError for org/missing", err)
+	}
+	one, two := 1, 2
+	want := [][]Message{{{ID: &two}}, nil, {{ID: &one}}}
+	if !reflect.DeepEqual(lists, want) {
+		t.Errorf("Messages.ListFlows returned %+v, want %+v", lists, want)
+	}
+}
+
 func TestMessagesService_Create_message(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
-		testFormValues(t, r, values{"event": "message",
+		testJSONValues(t, r, values{"event": "message",
 			"content": "Howdy-Doo @Jackie #awesome",
 		})
 		fmt.Fprint(w, `{
@@ -134,7 +285,7 @@ func TestMessagesService_Create_message(t *testing.T) {
 		Event:   "message",
 		Content: "Howdy-Doo @Jackie #awesome",
 	}
-	message, _, err := client.Messages.Create(&opt)
+	message, _, err := client.Messages.Create(ctx, &opt)
 	if err != nil {
 		t.Errorf("Messages.Create returned error: %v", err)
 	}
@@ -148,13 +299,51 @@ func TestMessagesService_Create_message(t *testing.T) {
 	}
 }
 
+func TestMessagesService_Create_longContent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	content := strings.Repeat("Ünïcode & ?query=string #", 2000)
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			t.Errorf("Request query = %q, want none", r.URL.RawQuery)
+		}
+		testJSONValues(t, r, values{"flow": "flow-id", "event": "message", "content": content})
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	opt := &MessagesCreateOptions{FlowID: "flow-id", Event: "message", Content: content}
+	if _, _, err := client.Messages.Create(ctx, opt); err != nil {
+		t.Errorf("Messages.Create returned error: %v", err)
+	}
+}
+
+func TestMessagesService_Create_legacyQuery(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"flow": "flow-id", "event": "message", "content": "hi", "tags": "a,b"})
+		fmt.Fprint(w, `{"id":1}`)
+	})
+	client.LegacyMessageQuery = true
+
+	opt := &MessagesCreateOptions{FlowID: "flow-id", Event: "message", Content: "hi", Tags: Tags{"a", "b"}}
+	if _, _, err := client.Messages.Create(ctx, opt); err != nil {
+		t.Errorf("Messages.Create returned error: %v", err)
+	}
+}
+
 func TestMessagesService_Create_comment(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "POST")
-		testFormValues(t, r, values{"event": "comment",
+		testJSONValues(t, r, values{"event": "comment",
 			"content": "This is a comment",
 		})
 		fmt.Fprint(w, `{
@@ -167,7 +356,7 @@ func TestMessagesService_Create_comment(t *testing.T) {
 		Event:   "comment",
 		Content: "This is a comment",
 	}
-	message, _, err := client.Messages.CreateComment(&opt)
+	message, _, err := client.Messages.CreateComment(ctx, &opt)
 	if err != nil {
 		t.Errorf("Messages.CreateComment returned error: %v", err)
 	}
@@ -196,10 +385,35 @@ func TestCommentContent_String(t *testing.T) {
 	}
 }
 
+func TestContent_String_missingFields(t *testing.T) {
+	tests := []struct {
+		event, content, want string
+	}{
+		{"comment", `{"title":"Title of parent"}`, ""},
+		{"comment", `{}`, ""},
+		{"vcs", `{}`, ""},
+		{"vcs", `{"event":"push"}`, ": push by Unknown "},
+		{"vcs", `{"repository":{"name":"repo"}}`, "repo:  by Unknown "},
+		{"vcs", `{"event":"push","repository":{"name":"repo"},"pusher":{"name":"bob"},"compare":"u"}`, "repo: push by bob u"},
+	}
+	for _, tt := range tests {
+		raw := json.RawMessage(tt.content)
+		m := &Message{Event: &tt.event, RawContent: &raw}
+		content, err := m.ContentE()
+		if err != nil {
+			t.Fatalf("Message.ContentE(%s) returned error: %v", tt.content, err)
+		}
+		if got := content.String(); got != tt.want {
+			t.Errorf("String of %s content %s = %q, want %q", tt.event, tt.content, got, tt.want)
+		}
+	}
+}
+
 func TestMessageService_Get(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	wantID := 1
 
 	mux.HandleFunc("/flows/orgname/flowname/messages/1", func(w http.ResponseWriter, r *http.Request) {
@@ -207,7 +421,7 @@ func TestMessageService_Get(t *testing.T) {
 		fmt.Fprint(w, `{"id":1}`)
 	})
 
-	m, _, err := client.Messages.Get("orgname", "flowname", wantID)
+	m, _, err := client.Messages.Get(ctx, "orgname", "flowname", wantID)
 	if err != nil {
 		t.Errorf("Messages.Get returned error: %v", err)
 	}
@@ -222,16 +436,20 @@ func TestMessageService_Edit(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/orgname/flowname/messages/1", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "PUT")
+		testHeader(t, r, "Content-Type", "application/json")
+		testJSONValues(t, r, values{"content": "new content", "tags": "a,b"})
 		fmt.Fprint(w, `{}`)
 	})
 
 	opts := &MessagesEditOptions{
 		Content: "new content",
+		Tags:    Tags{"a", " b", ""},
 	}
 
-	_, err := client.Messages.Edit("orgname", "flowname", 1, opts)
+	_, err := client.Messages.Edit(ctx, "orgname", "flowname", 1, opts)
 	if err != nil {
 		t.Errorf("Messages.Edit returned error: %v", err)
 	}
@@ -241,12 +459,390 @@ func TestMessageService_Delete(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/flows/orgname/flowname/messages/1", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "DELETE")
 	})
 
-	_, err := client.Messages.Delete("orgname", "flowname", 1)
+	_, err := client.Messages.Delete(ctx, "orgname", "flowname", 1)
 	if err != nil {
 		t.Errorf("Messages.Delete returned error: %v", err)
 	}
 }
+
+func TestMessagesService_PostCode_message(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{"event": "message",
+			"content": "```\nfmt.Println(42)\n```",
+		})
+		fmt.Fprint(w, `{"id":1,"event":"message"}`)
+	})
+
+	message, _, err := client.Messages.PostCode(ctx, "org", "flow", "main.go", "fmt.Println(42)\n")
+	if err != nil {
+		t.Errorf("Messages.PostCode returned error: %v", err)
+	}
+
+	if *message.ID != 1 {
+		t.Errorf("Messages.PostCode returned %+v, want %+v", *message.ID, 1)
+	}
+}
+
+func TestMessagesService_PostCode_file(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	code := strings.Repeat("x", MaxCodeMessageLength+1)
+
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if event := r.FormValue("event"); event != "file" {
+			t.Errorf("Request event = %v, want %v", event, "file")
+		}
+
+		file, header, err := r.FormFile("content")
+		if err != nil {
+			t.Fatalf("Request has no file content: %v", err)
+		}
+		if header.Filename != "main.go" {
+			t.Errorf("Request filename = %v, want %v", header.Filename, "main.go")
+		}
+		if ct := header.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("Request file Content-Type = %v, want text/plain", ct)
+		}
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != code {
+			t.Errorf("Request file content has %d bytes, want %d", len(data), len(code))
+		}
+		fmt.Fprint(w, `{"id":2,"event":"file"}`)
+	})
+
+	message, _, err := client.Messages.PostCode(ctx, "org", "flow", "main.go", code)
+	if err != nil {
+		t.Errorf("Messages.PostCode returned error: %v", err)
+	}
+
+	if *message.Event != "file" {
+		t.Errorf("Messages.PostCode returned %+v, want %+v", *message.Event, "file")
+	}
+}
+
+func TestMessagesService_PostImage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 600)
+
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		file, header, err := r.FormFile("content")
+		if err != nil {
+			t.Fatalf("Request has no file content: %v", err)
+		}
+		if header.Filename != "chart.png" {
+			t.Errorf("Request filename = %v, want %v", header.Filename, "chart.png")
+		}
+		if ct := header.Header.Get("Content-Type"); ct != "image/png" {
+			t.Errorf("Request file Content-Type = %v, want image/png", ct)
+		}
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != png {
+			t.Errorf("Request file content has %d bytes, want %d", len(data), len(png))
+		}
+		fmt.Fprint(w, `{"id":3,"event":"file"}`)
+	})
+
+	message, _, err := client.Messages.PostImage(ctx, "org", "flow", strings.NewReader(png), "chart")
+	if err != nil {
+		t.Fatalf("Messages.PostImage returned error: %v", err)
+	}
+	if *message.ID != 3 {
+		t.Errorf("Messages.PostImage returned %+v, want %+v", *message.ID, 3)
+	}
+}
+
+func TestMessagesService_UploadFile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var contentTypes []string
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		file, header, err := r.FormFile("content")
+		if err != nil {
+			t.Fatalf("Request has no file content: %v", err)
+		}
+		if event := r.FormValue("event"); event != "file" {
+			t.Errorf("Request event = %q, want file", event)
+		}
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != "%PDF-1.4 report" {
+			t.Errorf("Request file content = %q, want the uploaded content", data)
+		}
+		contentTypes = append(contentTypes, header.Filename+" "+header.Header.Get("Content-Type"))
+		fmt.Fprint(w, `{"id":3,"event":"file"}`)
+	})
+
+	for _, name := range []string{"report.pdf", "report"} {
+		message, _, err := client.Messages.UploadFile(ctx, "org", "flow", name, strings.NewReader("%PDF-1.4 report"))
+		if err != nil || *message.ID != 3 {
+			t.Errorf("Messages.UploadFile returned %+v, %v, want message 3", message, err)
+		}
+	}
+	want := []string{"report.pdf application/pdf", "report application/pdf"}
+	if !reflect.DeepEqual(contentTypes, want) {
+		t.Errorf("Messages.UploadFile uploaded %q, want %q", contentTypes, want)
+	}
+}
+
+func TestMessagesService_PostImage_notImage(t *testing.T) {
+	ctx := context.Background()
+	_, _, err := client.Messages.PostImage(ctx, "org", "flow", strings.NewReader("plain text"), "chart.png")
+	if err != ErrNotImage {
+		t.Errorf("Messages.PostImage returned %v, want ErrNotImage", err)
+	}
+}
+
+func TestMessage_JSON_roundTrip(t *testing.T) {
+	tests := []string{
+		`{"id":1}`,
+		`{
+			"app": "chat",
+			"sent": 1317397485508,
+			"uuid": "odHapx1VWp7WTrdQ",
+			"tags": [],
+			"flow": "deadbeefdeadbeef",
+			"id": 3816534,
+			"event": "message",
+			"content": "Hello NYC",
+			"attachments": [],
+			"thread_id": "4W_LQEybVaX-gJmi",
+			"user": "18"
+		}`,
+		`{
+			"event": "comment",
+			"content": {"title": "Title of parent", "text": "This is a comment"},
+			"sent": 1317715340213,
+			"edited": null,
+			"thread": {"title": "t", "fields": [{"label": "l", "value": "v"}]}
+		}`,
+	}
+
+	for _, input := range tests {
+		m := new(Message)
+		if err := json.Unmarshal([]byte(input), m); err != nil {
+			t.Fatalf("json.Unmarshal(%s) returned error: %v", input, err)
+		}
+
+		output, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("json.Marshal(%+v) returned error: %v", m, err)
+		}
+
+		want := new(bytes.Buffer)
+		json.Compact(want, []byte(input))
+		if string(output) != want.String() {
+			t.Errorf("Message JSON round-trip returned %s, want %s", output, want)
+		}
+	}
+}
+
+func TestMessage_MarshalJSON_changed(t *testing.T) {
+	m := new(Message)
+	json.Unmarshal([]byte(`{"x":1,"thread_id":null,"id":1,"tags":["a"]}`), m)
+	m.ID, m.Tags = nil, nil
+	event := "message"
+	m.Event = &event
+
+	output, err := json.Marshal(m)
+	if want := `{"x":1,"thread_id":null,"event":"message"}`; err != nil || string(output) != want {
+		t.Errorf("json.Marshal returned %s and %v, want %s", output, err, want)
+	}
+}
+
+func TestMessage_Content_empty(t *testing.T) {
+	m := new(Message)
+	if got := m.Content().String(); got != "" {
+		t.Errorf("Message.Content returned %q for an empty message, want \"\"", got)
+	}
+}
+
+func TestMessage_ContentE_malformed(t *testing.T) {
+	event, raw := "comment", json.RawMessage(`"not a comment"`)
+	m := &Message{Event: &event, RawContent: &raw}
+	if content, err := m.ContentE(); err == nil {
+		t.Errorf("Message.ContentE returned %#v for malformed content, want an error", content)
+	}
+	if got := m.contentText(); got != "" {
+		t.Errorf("Message.contentText returned %q for malformed content, want \"\"", got)
+	}
+
+	// Muted and Message don't panic on it
+	mute := NewMute()
+	mute.MuteKeyword("comment")
+	if mute.Muted(m) {
+		t.Errorf("Muted returned true for malformed content")
+	}
+	if got := NewEntityExtractor().Message(m); got != nil {
+		t.Errorf("EntityExtractor.Message returned %v for malformed content", got)
+	}
+}
+
+func TestMessage_Content_types(t *testing.T) {
+	tests := []struct {
+		event, content string
+		want           Content
+		str            string
+	}{
+		{"status", `"In a meeting"`, new(StatusContent), "In a meeting"},
+		{"activity", `{"title":"Build passed","author":{"name":"CI"}}`, new(ActivityContent), "Build passed"},
+		{"discussion", `"<p>LGTM</p>"`, new(ActivityContent), "<p>LGTM</p>"},
+		{"user-edit", `{"user":{"id":1,"nick":"alice"}}`, new(UserEditContent), "alice"},
+		{"action", `{"type":"add_people","description":"added bob"}`, new(ActionContent), "added bob"},
+		{"message-edit", `{"message":3,"updated_content":"fixed"}`, new(MessageEditContent), "fixed"},
+		{"tag-change", `{"message":3,"add":["a"]}`, new(TagChange), "message 3: added [a], removed []"},
+		{"emoji-reaction", `{"message":3,"emoji":"+1","type":"add"}`, new(EmojiReactionContent), ":+1:"},
+		{"unknown", `{"x":1}`, new(JsonContent), `{"x":1}`},
+	}
+	for _, tt := range tests {
+		raw := json.RawMessage(tt.content)
+		m := &Message{Event: &tt.event, RawContent: &raw}
+		c := m.Content()
+		if reflect.TypeOf(c) != reflect.TypeOf(tt.want) || c.String() != tt.str {
+			t.Errorf("Message.Content of a %s event returned %T %q, want %T %q", tt.event, c, c.String(), tt.want, tt.str)
+		}
+	}
+
+	raw := json.RawMessage(`{"message":3,"updated_content":"fixed"}`)
+	event := "message-edit"
+	if c := (&Message{Event: &event, RawContent: &raw}).Content().(*MessageEditContent); c.MessageID != 3 {
+		t.Errorf("Message.Content returned %+v, want the edit of message 3", c)
+	}
+}
+
+func TestMessageService_DeleteBulk(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/orgname/flowname/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+	})
+	mux.HandleFunc("/flows/orgname/flowname/messages/2", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/flows/orgname/flowname/messages/3", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	err := client.Messages.DeleteBulk(ctx, "orgname", "flowname", []int{1, 2, 3})
+	bulkErr, ok := err.(*BulkError)
+	if !ok {
+		t.Fatalf("Messages.DeleteBulk returned %v, want a *BulkError", err)
+	}
+
+	if len(bulkErr.Errors) != 2 || bulkErr.Errors[0].ID != "2" || bulkErr.Errors[1].ID != "3" {
+		t.Errorf("Messages.DeleteBulk returned errors %+v, want errors for 2 and 3", bulkErr.Errors)
+	}
+	if want := []int{1}; !reflect.DeepEqual(bulkErr.RetryableIndexes(), want) {
+		t.Errorf("BulkError.RetryableIndexes() = %v, want %v", bulkErr.RetryableIndexes(), want)
+	}
+}
+
+func TestMessageService_CreateBatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		content := jsonValues(t, r).Get("content")
+		if content == "bad" {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"event":"message","content":%q}`, content)
+	})
+
+	opts := []*MessagesCreateOptions{
+		{Event: "message", Content: "one"},
+		{Event: "message", Content: "bad", UUID: "uuid-2"},
+		{Event: "message", Content: "three"},
+	}
+	messages, err := client.Messages.CreateBatch(ctx, opts)
+
+	bulkErr, ok := err.(*BulkError)
+	if !ok || len(bulkErr.Errors) != 1 || bulkErr.Errors[0].ID != "uuid-2" || bulkErr.Errors[0].Retryable {
+		t.Fatalf("Messages.CreateBatch returned %v, want a non-retryable error for uuid-2", err)
+	}
+	if messages[1] != nil || messages[0].Content().String() != "one" || messages[2].Content().String() != "three" {
+		t.Errorf("Messages.CreateBatch returned %+v", messages)
+	}
+}
+
+func TestMessage_SentHelpers(t *testing.T) {
+	m := new(Message)
+	json.Unmarshal([]byte(`{"sent":1385596800000}`), m) // 2013-11-28 00:00 UTC
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	if got := m.SentIn(ny); got.Hour() != 19 || got.Location() != ny {
+		t.Errorf("Message.SentIn(New York) = %v, want 19:00 in New York", got)
+	}
+	if got := m.DayKey(time.UTC); got != "2013-11-28" {
+		t.Errorf("Message.DayKey(UTC) = %v, want 2013-11-28", got)
+	}
+	if got := m.DayKey(ny); got != "2013-11-27" {
+		t.Errorf("Message.DayKey(New York) = %v, want 2013-11-27", got)
+	}
+
+	now := time.Date(2013, time.November, 28, 1, 30, 0, 0, time.UTC)
+	if got := m.Age(now); got != 90*time.Minute {
+		t.Errorf("Message.Age() = %v, want 1h30m", got)
+	}
+
+	empty := new(Message)
+	if !empty.SentIn(ny).IsZero() || empty.Age(now) != 0 || empty.DayKey(ny) != "" {
+		t.Errorf("Message sent helpers returned non-zero values without a sent time")
+	}
+}
+
+func TestMessage_ReplyOptions(t *testing.T) {
+	tests := []struct {
+		message string
+		want    *MessagesCreateOptions
+	}{
+		{
+			`{"id":3,"flow":"f1","event":"message","thread_id":"t1","tags":["release",":user:9","#ops"]}`,
+			&MessagesCreateOptions{FlowID: "f1", ThreadID: "t1", Event: "message", Content: "ok", Tags: []string{"release", "#ops"}},
+		},
+		{
+			`{"id":3,"flow":"f1","event":"message"}`,
+			&MessagesCreateOptions{FlowID: "f1", MessageID: 3, Event: "comment", Content: "ok"},
+		},
+		{
+			`{"id":4,"flow":"f1","event":"comment","message":3,"tags":[":thread"]}`,
+			&MessagesCreateOptions{FlowID: "f1", MessageID: 3, Event: "comment", Content: "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		m := new(Message)
+		if err := json.Unmarshal([]byte(tt.message), m); err != nil {
+			t.Fatalf("json.Unmarshal(%s) returned error: %v", tt.message, err)
+		}
+		if got := m.ReplyOptions("ok"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Message.ReplyOptions for %s returned %+v, want %+v", tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Mute silences streamed messages sent by some users or whose content
+// matches some keyword patterns. Set it as the Client's Mute to drop matching
+// messages before they are delivered. It is safe for concurrent use, so
+// entries can be added and removed while streams are running.
+//
+// A Mute encodes to and decodes from JSON so it can be persisted.
+type Mute struct {
+	mu       sync.RWMutex
+	users    map[string]bool
+	keywords map[string]*regexp.Regexp
+}
+
+// NewMute returns an empty Mute.
+func NewMute() *Mute {
+	return &Mute{
+		users:    make(map[string]bool),
+		keywords: make(map[string]*regexp.Regexp),
+	}
+}
+
+// MuteUser silences the messages of the user with the given ID.
+func (m *Mute) MuteUser(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[id] = true
+}
+
+// UnmuteUser lifts the silencing of the user with the given ID.
+func (m *Mute) UnmuteUser(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.users, id)
+}
+
+// MuteKeyword silences the messages whose content matches the regular
+// expression pattern.
+func (m *Mute) MuteKeyword(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keywords[pattern] = re
+	return nil
+}
+
+// UnmuteKeyword lifts the silencing of the pattern.
+func (m *Mute) UnmuteKeyword(pattern string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keywords, pattern)
+}
+
+// Muted reports whether msg is silenced.
+func (m *Mute) Muted(msg *Message) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if msg.UserID != nil && m.users[*msg.UserID] {
+		return true
+	}
+	if len(m.keywords) == 0 || msg.RawContent == nil {
+		return false
+	}
+
+	content := msg.contentText()
+	for _, re := range m.keywords {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// muteJSON is the persisted form of a Mute.
+type muteJSON struct {
+	Users    []string `json:"users"`
+	Keywords []string `json:"keywords"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m *Mute) MarshalJSON() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v := muteJSON{Users: []string{}, Keywords: []string{}}
+	for id := range m.users {
+		v.Users = append(v.Users, id)
+	}
+	for pattern := range m.keywords {
+		v.Keywords = append(v.Keywords, pattern)
+	}
+	sort.Strings(v.Users)
+	sort.Strings(v.Keywords)
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// entries of the Mute.
+func (m *Mute) UnmarshalJSON(data []byte) error {
+	var v muteJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	users := make(map[string]bool)
+	for _, id := range v.Users {
+		users[id] = true
+	}
+	keywords := make(map[string]*regexp.Regexp)
+	for _, pattern := range v.Keywords {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		keywords[pattern] = re
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users = users
+	m.keywords = keywords
+	return nil
+}
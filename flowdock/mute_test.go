@@ -0,0 +1,106 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMute_Muted(t *testing.T) {
+	m := NewMute()
+	m.MuteUser("2")
+	if err := m.MuteKeyword(`(?i)build #\d+ passed`); err != nil {
+		t.Fatalf("Mute.MuteKeyword returned error: %v", err)
+	}
+
+	tests := []struct {
+		json string
+		want bool
+	}{
+		{`{"event":"message","user":"1","content":"hello"}`, false},
+		{`{"event":"message","user":"2","content":"hello"}`, true},
+		{`{"event":"message","user":"3","content":"Build #12 passed"}`, true},
+		{`{"event":"message","user":"3","content":"build #12 failed"}`, false},
+		{`{"event":"comment","user":"3","content":{"title":"Build #12 passed"}}`, false},
+		{`{"event":"comment","user":"3","content":{}}`, false},
+		{`{"event":"vcs","user":"3","content":{}}`, false},
+		{`{"event":"vcs","user":"3","content":{"event":"push"}}`, false},
+	}
+
+	for _, tt := range tests {
+		msg := new(Message)
+		json.Unmarshal([]byte(tt.json), msg)
+		if got := m.Muted(msg); got != tt.want {
+			t.Errorf("Mute.Muted(%s) = %v, want %v", tt.json, got, tt.want)
+		}
+	}
+
+	m.UnmuteUser("2")
+	m.UnmuteKeyword(`(?i)build #\d+ passed`)
+	msg := new(Message)
+	json.Unmarshal([]byte(tests[1].json), msg)
+	if m.Muted(msg) {
+		t.Errorf("Mute.Muted(%s) = true after UnmuteUser", tests[1].json)
+	}
+}
+
+func TestMute_MuteKeyword_invalid(t *testing.T) {
+	if err := NewMute().MuteKeyword("("); err == nil {
+		t.Errorf("Mute.MuteKeyword expected an error")
+	}
+}
+
+func TestMute_JSON(t *testing.T) {
+	m := NewMute()
+	m.MuteUser("2")
+	m.MuteUser("1")
+	m.MuteKeyword("deploy")
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	want := `{"users":["1","2"],"keywords":["deploy"]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal returned %s, want %s", data, want)
+	}
+
+	loaded := NewMute()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	msg := &Message{UserID: &idOne}
+	if !loaded.Muted(msg) {
+		t.Errorf("loaded Mute does not silence user 1")
+	}
+}
+
+func TestMessagesService_Stream_mute(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"event\":\"message\",\"user\":\"2\",\"content\":\"noise\"}\n\n")
+		fmt.Fprint(w, "data: {\"event\":\"message\",\"user\":\"1\",\"content\":\"signal\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	client.Mute = NewMute()
+	client.Mute.MuteUser("2")
+
+	stream, es, err := client.Messages.Stream(ctx, "token", "org", "flow")
+	if err != nil {
+		t.Fatalf("Messages.Stream returned error: %v", err)
+	}
+	defer es.Close()
+
+	msg := <-stream
+	if msg.Content().String() != "signal" {
+		t.Errorf("Messages.Stream delivered %v, want the unmuted message", msg.Content())
+	}
+}
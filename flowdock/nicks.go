@@ -0,0 +1,159 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NickHistory records the nicks users went by, as observed on streams and in
+// user listings, in a Store. Transcripts mentioning nicks which were changed
+// since, or taken by another user, are then attributed to the users who held
+// them at the time with ResolveHistorical.
+type NickHistory struct {
+	store Store
+	clock Clock
+	mu    sync.Mutex
+}
+
+// nickHolder is a user holding a nick from a time on, as kept in the Store
+// under the nick.
+type nickHolder struct {
+	User int       `json:"user"`
+	From time.Time `json:"from"`
+}
+
+// NewNickHistory returns a NickHistory keeping nicks in store, or in memory
+// if store is nil. Nicks observed without a time are dated with clock, or
+// the SystemClock if nil.
+func NewNickHistory(store Store, clock Clock) *NickHistory {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &NickHistory{store: store, clock: clock}
+}
+
+// Record notes that the user with the given id went by nick from at on.
+func (h *NickHistory) Record(id int, nick string, at time.Time) error {
+	key := nickKey(nick)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	holders, err := h.holders(key)
+	if err != nil {
+		return err
+	}
+	holders = append(holders, nickHolder{User: id, From: at})
+	sort.SliceStable(holders, func(i, j int) bool { return holders[i].From.Before(holders[j].From) })
+
+	// a user keeps a nick until another takes it: merge their records
+	merged := holders[:0]
+	for _, holder := range holders {
+		if n := len(merged); n > 0 && merged[n-1].User == holder.User {
+			continue
+		}
+		merged = append(merged, holder)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return h.store.Set(key, data)
+}
+
+// RecordUsers records the nicks of users, such as those of a listing
+// fetched with UsersService.List, as held at the time.
+func (h *NickHistory) RecordUsers(users []User) error {
+	now := h.clock.Now()
+	for _, u := range users {
+		if u.ID == nil || u.Nick == nil || *u.Nick == "" {
+			continue
+		}
+		if err := h.Record(*u.ID, *u.Nick, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch records the nicks of the users edited by the "user-edit" events of
+// msgs, such as a flow Stream, as of the time the events were sent. Every
+// message is forwarded to the returned channel, closed when msgs is. Errors
+// of the Store are dropped: a missed nick only makes older transcripts
+// harder to attribute.
+func (h *NickHistory) Watch(msgs <-chan Message) <-chan Message {
+	out := make(chan Message)
+	go func() {
+		defer close(out)
+		for m := range msgs {
+			if u, ok := m.UserEdit(); ok && u.ID != nil && u.Nick != nil && *u.Nick != "" {
+				at := h.clock.Now()
+				if m.Sent != nil {
+					at = m.Sent.Time
+				}
+				h.Record(*u.ID, *u.Nick, at)
+			}
+			out <- m
+		}
+	}()
+	return out
+}
+
+// ResolveHistorical returns the ID of the user who went by nick at the time
+// at, ignoring case and a leading "@", and whether the nick is known. Before
+// the nick was first recorded, its first known holder is assumed.
+func (h *NickHistory) ResolveHistorical(nick string, at time.Time) (int, bool, error) {
+	h.mu.Lock()
+	holders, err := h.holders(nickKey(nick))
+	h.mu.Unlock()
+	if err != nil || len(holders) == 0 {
+		return 0, false, err
+	}
+
+	holder := holders[0]
+	for _, hh := range holders[1:] {
+		if hh.From.After(at) {
+			break
+		}
+		holder = hh
+	}
+	return holder.User, true, nil
+}
+
+// holders returns the holders of the nick stored under key, by time. The
+// caller must hold h.mu.
+func (h *NickHistory) holders(key string) ([]nickHolder, error) {
+	data, ok, err := h.store.Get(key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var holders []nickHolder
+	if err := json.Unmarshal(data, &holders); err != nil {
+		return nil, err
+	}
+	return holders, nil
+}
+
+// nickKey returns the Store key of nick.
+func nickKey(nick string) string {
+	return "nicks/" + strings.ToLower(strings.TrimPrefix(nick, "@"))
+}
+
+// UserEdit returns the user m reports the new details of, if m is a
+// "user-edit" event.
+func (m *Message) UserEdit() (*User, bool) {
+	if m.Event == nil || *m.Event != "user-edit" || m.RawContent == nil {
+		return nil, false
+	}
+	var content UserEditContent
+	if err := json.Unmarshal(*m.RawContent, &content); err != nil || content.User == nil {
+		return nil, false
+	}
+	return content.User, true
+}
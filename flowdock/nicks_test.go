@@ -0,0 +1,67 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNickHistory_ResolveHistorical(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2015, 1, d, 0, 0, 0, 0, time.UTC) }
+	h := NewNickHistory(nil, NewFakeClock(day(20)))
+
+	// alice went by bob, then bob was taken by user 2
+	h.Record(1, "bob", day(1))
+	h.Record(1, "Bob", day(3)) // same holder
+	h.Record(2, "bob", day(10))
+
+	name, sent := "alice", &Time{day(5)}
+	raw := json.RawMessage(`{"user":{"id":1,"nick":"alice"}}`)
+	event := "user-edit"
+	msgs := make(chan Message, 1)
+	msgs <- Message{Event: &event, Sent: sent, RawContent: &raw}
+	close(msgs)
+	for range h.Watch(msgs) {
+	}
+
+	id, nick := 3, "carol"
+	if err := h.RecordUsers([]User{{ID: &id, Nick: &nick}, {Name: &name}}); err != nil {
+		t.Fatalf("NickHistory.RecordUsers returned error: %v", err)
+	}
+
+	tests := []struct {
+		nick string
+		at   time.Time
+		want int
+		ok   bool
+	}{
+		{"bob", day(2), 1, true},
+		{"@BOB", day(9), 1, true},
+		{"bob", day(10), 2, true},
+		{"bob", day(30), 2, true},
+		{"alice", day(6), 1, true},
+		{"alice", day(1), 1, true}, // before its first record
+		{"carol", day(20), 3, true},
+		{"dave", day(1), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok, err := h.ResolveHistorical(tt.nick, tt.at)
+		if err != nil {
+			t.Fatalf("NickHistory.ResolveHistorical returned error: %v", err)
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NickHistory.ResolveHistorical(%q, %v) = %v, %v, want %v, %v", tt.nick, tt.at, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMessage_UserEdit(t *testing.T) {
+	event, other := "user-edit", "message"
+	raw := json.RawMessage(`{"user":{"id":1,"nick":"alice"}}`)
+	if u, ok := (&Message{Event: &event, RawContent: &raw}).UserEdit(); !ok || *u.ID != 1 || *u.Nick != "alice" {
+		t.Errorf("Message.UserEdit returned %+v, %v", u, ok)
+	}
+	if _, ok := (&Message{Event: &other, RawContent: &raw}).UserEdit(); ok {
+		t.Errorf("Message.UserEdit reported a message as a user edit")
+	}
+}
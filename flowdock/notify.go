@@ -0,0 +1,113 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultNotifyIdle is how long after their last activity in a flow Notify
+// still considers a user to follow it.
+const DefaultNotifyIdle = 15 * time.Minute
+
+// Reach is how a message posted for a user is expected to reach them.
+//
+// The API doesn't expose the notification preferences of other users, so
+// a user's reach is inferred from what the user lists of a flow expose:
+// whether the user has the flow open, and when they were last active in it.
+// Mentions in a flow they follow are seen in any case, while a private
+// message notifies them whatever their flow settings.
+type Reach int
+
+const (
+	// ReachFlow means that the user follows the flow, and that mentioning
+	// them in it reaches them.
+	ReachFlow Reach = iota
+
+	// ReachPrivate means that the user may not see a mention in the flow,
+	// and is better sent a private message.
+	ReachPrivate
+)
+
+func (r Reach) String() string {
+	switch r {
+	case ReachFlow:
+		return "flow"
+	case ReachPrivate:
+		return "private"
+	}
+	return fmt.Sprintf("Reach(%d)", int(r))
+}
+
+// ReachOf returns the reach of u, as listed in the users of a flow, at now:
+// ReachFlow if u has the flow open and was active in it within idle, and
+// ReachPrivate otherwise.
+func ReachOf(u *User, now time.Time, idle time.Duration) Reach {
+	if u.InFlow == nil || !*u.InFlow || u.Disabled != nil && *u.Disabled {
+		return ReachPrivate
+	}
+	last := u.LastActivity
+	if u.LastPing != nil && (last == nil || u.LastPing.After(last.Time)) {
+		last = u.LastPing
+	}
+	if last == nil || now.Sub(last.Time) > idle {
+		return ReachPrivate
+	}
+	return ReachFlow
+}
+
+// CreatePrivate sends a private message to the user with ID userID.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) CreatePrivate(ctx context.Context, userID int, opt *MessagesCreateOptions) (*Message, *http.Response, error) {
+	u := fmt.Sprintf("private/%d/messages", userID)
+
+	req, err := s.client.newMessageRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message := new(Message)
+	resp, err := s.client.Do(ctx, req, message)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return message, resp, err
+}
+
+// Notify posts content for the user with ID userID where it reaches them,
+// as decided by ReachOf with DefaultNotifyIdle: in the flow, mentioning
+// them, when they follow it, and in a private message otherwise. Users who
+// are not in the flow are sent a private message too. It returns the posted
+// message and where it was posted.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) Notify(ctx context.Context, flow FlowRef, userID int, content string) (*Message, Reach, error) {
+	if err := flow.validate(); err != nil {
+		return nil, ReachPrivate, err
+	}
+
+	it := s.client.Users.Iterate(ctx, string(flow.Org), flow.Flow, nil)
+	for it.Next() {
+		u := it.User()
+		if u.ID == nil || *u.ID != userID {
+			continue
+		}
+		if u.Nick == nil || ReachOf(&u, s.client.Clock.Now(), DefaultNotifyIdle) != ReachFlow {
+			break
+		}
+		m, _, err := s.createInFlow(ctx, string(flow.Org), flow.Flow, &MessagesCreateOptions{
+			Event:   "message",
+			Content: "@" + *u.Nick + " " + content,
+		})
+		return m, ReachFlow, err
+	}
+	if err := it.Err(); err != nil {
+		return nil, ReachPrivate, err
+	}
+
+	m, _, err := s.CreatePrivate(ctx, userID, &MessagesCreateOptions{Event: "message", Content: content})
+	return m, ReachPrivate, err
+}
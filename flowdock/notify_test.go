@@ -0,0 +1,84 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReachOf(t *testing.T) {
+	now := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+	recent := &Time{now.Add(-time.Minute)}
+	old := &Time{now.Add(-time.Hour)}
+	in, out := true, false
+
+	tests := []struct {
+		user User
+		want Reach
+	}{
+		{User{InFlow: &in, LastActivity: recent}, ReachFlow},
+		{User{InFlow: &in, LastActivity: old, LastPing: recent}, ReachFlow},
+		{User{InFlow: &in, LastActivity: old}, ReachPrivate},
+		{User{InFlow: &in}, ReachPrivate},
+		{User{InFlow: &out, LastActivity: recent}, ReachPrivate},
+		{User{LastActivity: recent}, ReachPrivate},
+		{User{InFlow: &in, LastActivity: recent, Disabled: &in}, ReachPrivate},
+	}
+	for i, tt := range tests {
+		if got := ReachOf(&tt.user, now, 15*time.Minute); got != tt.want {
+			t.Errorf("ReachOf of user %d = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMessagesService_Notify(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)
+	client.Clock = NewFakeClock(now)
+	mux.HandleFunc("/users/org/flow/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		recent := now.Add(-time.Minute).UnixNano() / int64(time.Millisecond)
+		fmt.Fprintf(w, `[{"id":1,"nick":"here","in_flow":true,"last_activity":%d},
+			{"id":2,"nick":"away","in_flow":false,"last_activity":%d}]`, recent, recent)
+	})
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{"event": "message", "content": "@here deploy done"})
+		fmt.Fprint(w, `{"id":10}`)
+	})
+	var private []string
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{"event": "message", "content": "deploy done"})
+		private = append(private, r.URL.Path)
+		fmt.Fprint(w, `{"id":11}`)
+	})
+
+	flow := OrgID("org").Flow("flow")
+	tests := []struct {
+		user  int
+		reach Reach
+		id    int
+	}{
+		{1, ReachFlow, 10},
+		{2, ReachPrivate, 11},
+		{3, ReachPrivate, 11},
+	}
+	for _, tt := range tests {
+		m, reach, err := client.Messages.Notify(ctx, flow, tt.user, "deploy done")
+		if err != nil {
+			t.Fatalf("Messages.Notify returned error: %v", err)
+		}
+		if reach != tt.reach || *m.ID != tt.id {
+			t.Errorf("Messages.Notify of user %d returned %v, %v, want %v, %v", tt.user, *m.ID, reach, tt.id, tt.reach)
+		}
+	}
+	if want := "/private/3/messages"; len(private) != 2 || private[1] != want {
+		t.Errorf("private messages sent to %v, want the last one to %v", private, want)
+	}
+}
@@ -1,9 +1,12 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type OrganizationUpdateOptions struct {
@@ -12,12 +15,21 @@ type OrganizationUpdateOptions struct {
 
 type OrganizationsService struct {
 	client *Client
+
+	mu       sync.Mutex
+	resolved map[string]string // name given to Resolve => parameterized name
+}
+
+// OrganizationsGetOptions specifies the optional parameters to the
+// OrganizationsService.GetByID method.
+type OrganizationsGetOptions struct {
+	ID int `url:"id,omitempty"`
 }
 
 // All organizations authenticated user belongs to.
 //
 // Flowdock API docs: https://www.flowdock.com/api/organizations
-func (s *OrganizationsService) All() ([]Organization, *http.Response, error) {
+func (s *OrganizationsService) All(ctx context.Context) ([]Organization, *http.Response, error) {
 	u := "organizations"
 
 	req, err := s.client.NewRequest("GET", u, nil)
@@ -26,7 +38,7 @@ func (s *OrganizationsService) All() ([]Organization, *http.Response, error) {
 	}
 
 	organizations := new([]Organization)
-	resp, err := s.client.Do(req, organizations)
+	resp, err := s.client.Do(ctx, req, organizations)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -37,7 +49,7 @@ func (s *OrganizationsService) All() ([]Organization, *http.Response, error) {
 // GetByParameterizedName fetches an organization by it's parameterized_name.
 //
 // Flowdock API docs: https://www.flowdock.com/api/organizations
-func (s *OrganizationsService) GetByParameterizedName(name string) (*Organization, *http.Response, error) {
+func (s *OrganizationsService) GetByParameterizedName(ctx context.Context, name string) (*Organization, *http.Response, error) {
 	u := fmt.Sprintf("organizations/%v", name)
 
 	req, err := s.client.NewRequest("GET", u, nil)
@@ -46,7 +58,7 @@ func (s *OrganizationsService) GetByParameterizedName(name string) (*Organizatio
 	}
 
 	organization := new(Organization)
-	resp, err := s.client.Do(req, organization)
+	resp, err := s.client.Do(ctx, req, organization)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -57,16 +69,19 @@ func (s *OrganizationsService) GetByParameterizedName(name string) (*Organizatio
 // GetByID fetches an organization by it's id.
 //
 // Flowdock API docs: https://www.flowdock.com/api/organizations
-func (s *OrganizationsService) GetByID(id int) (*Organization, *http.Response, error) {
-	u := fmt.Sprintf("organizations/find?id=%v", id)
+func (s *OrganizationsService) GetByID(ctx context.Context, id int) (*Organization, *http.Response, error) {
+	u, err := s.client.addOptions("organizations/find", OrganizationsGetOptions{ID: id})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	req, err := s.client.NewRequest("GET", url.QueryEscape(u), nil)
+	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	organization := new(Organization)
-	resp, err := s.client.Do(req, organization)
+	resp, err := s.client.Do(ctx, req, organization)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -74,20 +89,76 @@ func (s *OrganizationsService) GetByID(id int) (*Organization, *http.Response, e
 	return organization, resp, err
 }
 
+// Resolve returns the parameterized name of an organization, which is how
+// organizations are referred to by the other API calls, given either its
+// numeric ID, its parameterized name or its display name. Display names are
+// matched regardless of case. Results are cached for the life of the Client.
+//
+// Flowdock API docs: https://www.flowdock.com/api/organizations
+func (s *OrganizationsService) Resolve(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	resolved, ok := s.resolved[name]
+	s.mu.Unlock()
+	if ok {
+		return resolved, nil
+	}
+
+	if id, err := strconv.Atoi(name); err == nil {
+		org, _, err := s.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if org.ParameterizedName == nil {
+			return "", fmt.Errorf("flowdock: organization %d has no parameterized name", id)
+		}
+		return s.remember(name, *org.ParameterizedName), nil
+	}
+
+	orgs, _, err := s.All(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// parameterized names are unique, so they take precedence over display
+	// names
+	for _, org := range orgs {
+		if org.ParameterizedName != nil && *org.ParameterizedName == name {
+			return s.remember(name, *org.ParameterizedName), nil
+		}
+	}
+	for _, org := range orgs {
+		if org.Name != nil && org.ParameterizedName != nil && strings.EqualFold(*org.Name, name) {
+			return s.remember(name, *org.ParameterizedName), nil
+		}
+	}
+	return "", fmt.Errorf("flowdock: no organization named %q", name)
+}
+
+func (s *OrganizationsService) remember(name, parameterizedName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resolved == nil {
+		s.resolved = make(map[string]string)
+	}
+	s.resolved[name] = parameterizedName
+	return parameterizedName
+}
+
 // Update an organization by id.
 //
 // Flowdock API docs: https://www.flowdock.com/api/organizations
-func (s *OrganizationsService) Update(id int, opt *OrganizationUpdateOptions) (*Organization, *http.Response, error) {
+func (s *OrganizationsService) Update(ctx context.Context, id int, opt *OrganizationUpdateOptions) (*Organization, *http.Response, error) {
 	u := fmt.Sprintf("organizations/%v", id)
 
-	u, err := addOptions(u, opt)
+	u, err := s.client.addOptions(u, opt)
 	req, err := s.client.NewRequest("PUT", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	organization := new(Organization)
-	resp, err := s.client.Do(req, organization)
+	resp, err := s.client.Do(ctx, req, organization)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -1,6 +1,7 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -16,12 +17,13 @@ func TestOrganizationsService_All(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/organizations", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `[{"id":1}, {"id":2}]`)
 	})
 
-	organizations, _, err := client.Organizations.All()
+	organizations, _, err := client.Organizations.All(ctx)
 	if err != nil {
 		t.Errorf("Organizations.All returned error: %v", err)
 	}
@@ -36,6 +38,7 @@ func TestOrganizationsService_GetByParameterizedName(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	name := "parameterizedorgname"
 
 	mux.HandleFunc("/organizations/parameterizedorgname", func(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +46,7 @@ func TestOrganizationsService_GetByParameterizedName(t *testing.T) {
 		fmt.Fprint(w, `{"parameterized_name":"parameterizedorgname"}`)
 	})
 
-	organization, _, err := client.Organizations.GetByParameterizedName(name)
+	organization, _, err := client.Organizations.GetByParameterizedName(ctx, name)
 	if err != nil {
 		t.Errorf("Organizations.GetByParameterizedName returned error: %v", err)
 	}
@@ -58,12 +61,14 @@ func TestOrganizationsService_GetByID(t *testing.T) {
 	setup()
 	defer teardown()
 
-	mux.HandleFunc("/organizations/find?id=1", func(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	mux.HandleFunc("/organizations/find", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"id": "1"})
 		fmt.Fprint(w, `{"id":1}`)
 	})
 
-	organization, _, err := client.Organizations.GetByID(organizationID1)
+	organization, _, err := client.Organizations.GetByID(ctx, organizationID1)
 	if err != nil {
 		t.Errorf("Organizations.GetByID returned error: %v", err)
 	}
@@ -78,6 +83,7 @@ func TestOrganizationsService_Update(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	name := "new-name"
 
 	mux.HandleFunc("/organizations/1", func(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +94,7 @@ func TestOrganizationsService_Update(t *testing.T) {
 	opts := &OrganizationUpdateOptions{
 		Name: name,
 	}
-	organization, _, err := client.Organizations.Update(organizationID1, opts)
+	organization, _, err := client.Organizations.Update(ctx, organizationID1, opts)
 	if err != nil {
 		t.Errorf("Organizations.Update returned error: %v", err)
 	}
@@ -98,3 +104,51 @@ func TestOrganizationsService_Update(t *testing.T) {
 		t.Errorf("Organizations.Update returned %+v, want %+v", organization.Name, want.Name)
 	}
 }
+
+func TestOrganizationsService_Resolve(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	calls := 0
+	mux.HandleFunc("/organizations", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `[
+			{"id":1, "name":"Acme Corp", "parameterized_name":"acme"},
+			{"id":2, "name":"acme", "parameterized_name":"acme-2"}
+		]`)
+	})
+	mux.HandleFunc("/organizations/find", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"id": "2"})
+		fmt.Fprint(w, `{"id":2, "name":"acme", "parameterized_name":"acme-2"}`)
+	})
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"acme", "acme"},
+		{"acme corp", "acme"},
+		{"acme-2", "acme-2"},
+		{"2", "acme-2"},
+		{"Acme Corp", "acme"},
+	}
+	for _, tt := range tests {
+		got, err := client.Organizations.Resolve(ctx, tt.name)
+		if err != nil {
+			t.Errorf("Organizations.Resolve(%q) returned error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("Organizations.Resolve(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	client.Organizations.Resolve(ctx, "acme")
+	if calls != 4 {
+		t.Errorf("Organizations.Resolve listed organizations %d times, want 4", calls)
+	}
+
+	if _, err := client.Organizations.Resolve(ctx, "nope"); err == nil {
+		t.Errorf("Organizations.Resolve(nope) expected an error")
+	}
+}
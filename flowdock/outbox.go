@@ -0,0 +1,277 @@
+package flowdock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultOutboxBackoff is the delay before an Outbox retries a send
+	// which failed temporarily. It doubles on each failure, up to
+	// DefaultOutboxMaxBackoff.
+	DefaultOutboxBackoff    = time.Second
+	DefaultOutboxMaxBackoff = time.Minute
+)
+
+// Outbox sends messages reliably. Messages are first written to a Store,
+// then sent in order by a worker which retries the temporary failures, so
+// that messages enqueued before a crash are sent on restart when the Store is
+// durable, such as a FileStore.
+//
+// Each message gets a UUID when enqueued. The Outbox records the messages it
+// sent and never sends them twice; a crash while a message is in flight sends
+// it again with the same UUID, which lets readers discard the duplicate.
+type Outbox struct {
+	client *Client
+	store  Store
+
+	// Backoff and MaxBackoff bound the delay before retrying temporary
+	// failures. They default to DefaultOutboxBackoff and
+	// DefaultOutboxMaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// OnFailure, if set, is called for the messages rejected by the API,
+	// with a 4xx status other than 429. They are kept aside and listed by
+	// Failed. Messages failing otherwise, such as on a cancelled context,
+	// stay pending.
+	OnFailure func(opt *MessagesCreateOptions, err error)
+
+	mu     sync.Mutex
+	seq    int
+	wake   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// NewOutbox returns an Outbox sending through client and keeping messages in
+// store. A nil store keeps them in memory, which doesn't survive crashes.
+func NewOutbox(client *Client, store Store) *Outbox {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Outbox{
+		client:     client,
+		store:      store,
+		Backoff:    DefaultOutboxBackoff,
+		MaxBackoff: DefaultOutboxMaxBackoff,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+}
+
+// Enqueue stores opt to be sent, giving it a UUID if it has none, and
+// returns the UUID. Comments, whose MessageID is set, are sent with
+// CreateComment.
+func (o *Outbox) Enqueue(opt *MessagesCreateOptions) (string, error) {
+	if opt.UUID == "" {
+		uuid, err := newUUID()
+		if err != nil {
+			return "", err
+		}
+		opt.UUID = uuid
+	}
+
+	data, err := json.Marshal(opt)
+	if err != nil {
+		return "", err
+	}
+
+	o.mu.Lock()
+	o.seq++
+	key := fmt.Sprintf("outbox/pending/%020d-%010d", o.client.Clock.Now().UnixNano(), o.seq)
+	o.mu.Unlock()
+	if err := o.store.Set(key, data); err != nil {
+		return "", err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return opt.UUID, nil
+}
+
+// Pending returns the number of messages waiting to be sent.
+func (o *Outbox) Pending() (int, error) {
+	keys, err := o.store.Keys("outbox/pending/")
+	return len(keys), err
+}
+
+// Failed returns the messages rejected by the API. Messages stored in a
+// form which couldn't be decoded are set aside too, but not returned.
+func (o *Outbox) Failed() ([]*MessagesCreateOptions, error) {
+	keys, err := o.store.Keys("outbox/failed/")
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []*MessagesCreateOptions
+	for _, key := range keys {
+		data, ok, err := o.store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		opt := new(MessagesCreateOptions)
+		if ok && json.Unmarshal(data, opt) == nil {
+			opts = append(opts, opt)
+		}
+	}
+	return opts, nil
+}
+
+// Flush sends the pending messages in order. It stops at the first
+// temporary failure, which is returned, leaving that message and the
+// following ones pending. Messages which can't be read from the Store are
+// skipped, and the first such error is returned once the others are sent;
+// those which can't be decoded are set aside with the failed ones.
+func (o *Outbox) Flush(ctx context.Context) error {
+	keys, err := o.store.Keys("outbox/pending/")
+	if err != nil {
+		return err
+	}
+
+	var skipped error
+	for _, key := range keys {
+		data, ok, err := o.store.Get(key)
+		if err != nil {
+			o.client.Logf("outbox skipped %s: %v", key, err)
+			if skipped == nil {
+				skipped = err
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		opt := new(MessagesCreateOptions)
+		if err := json.Unmarshal(data, opt); err != nil {
+			o.client.Logf("outbox set %s aside: %v", key, err)
+			if err := o.setAside(key, strings.TrimPrefix(key, "outbox/pending/"), data); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := o.send(ctx, key, data, opt); err != nil {
+			return err
+		}
+	}
+	return skipped
+}
+
+// send sends the pending message opt stored as data under key, unless it
+// was sent already.
+func (o *Outbox) send(ctx context.Context, key string, data []byte, opt *MessagesCreateOptions) error {
+	sent := "outbox/sent/" + opt.UUID
+	_, done, err := o.store.Get(sent)
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		if opt.MessageID != 0 {
+			_, _, err = o.client.Messages.CreateComment(ctx, opt)
+		} else {
+			_, _, err = o.client.Messages.Create(ctx, opt)
+		}
+		switch {
+		case err != nil && (ctx.Err() != nil || !rejected(err)):
+			return err
+		case err != nil:
+			if err := o.setAside(key, opt.UUID, data); err != nil {
+				return err
+			}
+			if o.OnFailure != nil {
+				o.OnFailure(opt, err)
+			}
+			return nil
+		}
+		if err := o.store.Set(sent, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := o.store.Delete(key); err != nil {
+		return err
+	}
+	return o.store.Delete(sent)
+}
+
+// rejected reports whether the API refused a message for good: with a 4xx
+// status other than 429. Other failures, such as a cancelled ctx, keep the
+// message pending.
+func rejected(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	c := e.StatusCode()
+	return c >= 400 && c < 500 && c != http.StatusTooManyRequests
+}
+
+// setAside moves the message stored as data under key to the failed ones,
+// under name.
+func (o *Outbox) setAside(key, name string, data []byte) error {
+	if err := o.store.Set("outbox/failed/"+name, data); err != nil {
+		return err
+	}
+	return o.store.Delete(key)
+}
+
+// Run sends the enqueued messages until Close is called or ctx is done,
+// retrying temporary failures with an exponential backoff. Messages enqueued
+// through another Outbox sharing the Store are sent when Run starts and
+// after each Enqueue.
+func (o *Outbox) Run(ctx context.Context) error {
+	backoff := o.Backoff
+	for {
+		var wait <-chan time.Time
+		wake := o.wake
+		if err := o.Flush(ctx); err != nil {
+			o.client.Logf("outbox send failed, retrying in %v: %v", backoff, err)
+			wait, wake = o.client.Clock.After(backoff), nil
+			if backoff *= 2; backoff > o.MaxBackoff {
+				backoff = o.MaxBackoff
+			}
+		} else {
+			backoff = o.Backoff
+		}
+
+		select {
+		case <-wait:
+		case <-wake:
+		case <-o.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close makes Run return. Pending messages stay in the Store.
+func (o *Outbox) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.closed {
+		o.closed = true
+		close(o.done)
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
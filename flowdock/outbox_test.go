@@ -0,0 +1,274 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutbox_Flush(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var (
+		mu    sync.Mutex
+		fail  = 1
+		posts []string
+	)
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		testMethod(t, r, "POST")
+		form := jsonValues(t, r)
+		if form.Get("content") == "rejected" {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		if fail > 0 {
+			fail--
+			http.Error(w, "Service Unavailable", 503)
+			return
+		}
+		posts = append(posts, form.Get("content"))
+		fmt.Fprintf(w, `{"uuid":%q}`, form.Get("uuid"))
+	})
+
+	var failures []string
+	o := NewOutbox(client, nil)
+	o.OnFailure = func(opt *MessagesCreateOptions, err error) {
+		failures = append(failures, opt.Content)
+	}
+	for _, content := range []string{"one", "rejected", "two"} {
+		uuid, err := o.Enqueue(&MessagesCreateOptions{FlowID: "f", Event: "message", Content: content})
+		if err != nil || len(uuid) != 36 {
+			t.Fatalf("Outbox.Enqueue returned %q, %v", uuid, err)
+		}
+	}
+
+	if err := o.Flush(ctx); !isRetryable(err) {
+		t.Errorf("Outbox.Flush returned %v, want a temporary error", err)
+	}
+	if n, _ := o.Pending(); n != 3 {
+		t.Errorf("Outbox.Pending returned %d after a temporary failure, want 3", n)
+	}
+
+	if err := o.Flush(ctx); err != nil {
+		t.Fatalf("Outbox.Flush returned error: %v", err)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(posts, want) {
+		t.Errorf("Outbox.Flush posted %v, want %v", posts, want)
+	}
+	if n, _ := o.Pending(); n != 0 {
+		t.Errorf("Outbox.Pending returned %d, want 0", n)
+	}
+
+	failed, err := o.Failed()
+	if err != nil || len(failed) != 1 || failed[0].Content != "rejected" {
+		t.Errorf("Outbox.Failed returned %+v, %v, want the rejected message", failed, err)
+	}
+	if want := []string{"rejected"}; !reflect.DeepEqual(failures, want) {
+		t.Errorf("Outbox.OnFailure was called for %v, want %v", failures, want)
+	}
+}
+
+func TestOutbox_Flush_alreadySent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("message sent twice")
+	})
+
+	// a crash after the message was sent, before it was removed
+	store := NewMemoryStore()
+	o := NewOutbox(client, store)
+	uuid, _ := o.Enqueue(&MessagesCreateOptions{FlowID: "f", Content: "sent"})
+	store.Set("outbox/sent/"+uuid, nil)
+
+	if err := NewOutbox(client, store).Flush(context.Background()); err != nil {
+		t.Fatalf("Outbox.Flush returned error: %v", err)
+	}
+	if keys, _ := store.Keys("outbox/"); len(keys) != 0 {
+		t.Errorf("Outbox left %v in the Store", keys)
+	}
+}
+
+func TestOutbox_Flush_rateLimited(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := handleRate(Rate{Limit: 100, Remaining: 0, Reset: now.Add(time.Minute)})
+	client.Clock = NewFakeClock(now)
+	client.RateLimitPolicy = RateLimitFail
+
+	o := NewOutbox(client, nil)
+	for _, content := range []string{"one", "two"} {
+		o.Enqueue(&MessagesCreateOptions{FlowID: "f", Event: "message", Content: content})
+	}
+
+	// the first message exhausts the rate limit, the second is kept
+	if err := o.Flush(ctx); !IsRateLimited(err) || !isRetryable(err) {
+		t.Errorf("Outbox.Flush returned %v, want a temporary rate limit error", err)
+	}
+	if n, _ := o.Pending(); n != 1 || *requests != 1 {
+		t.Errorf("Outbox.Flush left %d messages pending after %d requests, want 1 after 1", n, *requests)
+	}
+	if failed, _ := o.Failed(); len(failed) != 0 {
+		t.Errorf("Outbox.Failed returned %+v for a rate limited message", failed)
+	}
+}
+
+// blockingLimiter holds the requests until their ctx is done.
+type blockingLimiter struct{}
+
+func (blockingLimiter) Wait(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestOutbox_Flush_cancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("message sent past the Limiter")
+	})
+	client.Limiter = blockingLimiter{}
+
+	o := NewOutbox(client, nil)
+	o.OnFailure = func(opt *MessagesCreateOptions, err error) {
+		t.Errorf("Outbox.OnFailure called for %q: %v", opt.Content, err)
+	}
+	o.Enqueue(&MessagesCreateOptions{FlowID: "f", Event: "message", Content: "one"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := o.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Outbox.Flush returned %v, want context.Canceled", err)
+	}
+	if n, _ := o.Pending(); n != 1 {
+		t.Errorf("Outbox.Pending returned %d after a cancelled send, want 1", n)
+	}
+	if failed, _ := o.Failed(); len(failed) != 0 {
+		t.Errorf("Outbox.Failed returned %+v for a cancelled send", failed)
+	}
+}
+
+func TestOutbox_Flush_undecodable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var posts []string
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		posts = append(posts, jsonValues(t, r).Get("content"))
+		fmt.Fprint(w, `{}`)
+	})
+
+	store := NewMemoryStore()
+	store.Set("outbox/pending/0", []byte(`{"content":`))
+	o := NewOutbox(client, store)
+	o.Enqueue(&MessagesCreateOptions{FlowID: "f", Event: "message", Content: "next"})
+
+	for i := 0; i < 2; i++ {
+		if err := o.Flush(context.Background()); err != nil {
+			t.Fatalf("Outbox.Flush returned error: %v", err)
+		}
+	}
+	if want := []string{"next"}; !reflect.DeepEqual(posts, want) {
+		t.Errorf("Outbox.Flush posted %v, want %v", posts, want)
+	}
+	if n, _ := o.Pending(); n != 0 {
+		t.Errorf("Outbox.Pending returned %d, want 0", n)
+	}
+	if data, ok, _ := store.Get("outbox/failed/0"); !ok || string(data) != `{"content":` {
+		t.Errorf("Outbox kept %q aside, want the undecodable message", data)
+	}
+	if failed, err := o.Failed(); err != nil || len(failed) != 0 {
+		t.Errorf("Outbox.Failed returned %+v, %v, want none", failed, err)
+	}
+}
+
+func TestOutbox_Flush_unreadable(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var posts []string
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		posts = append(posts, jsonValues(t, r).Get("content"))
+		fmt.Fprint(w, `{}`)
+	})
+
+	store := &unreadableStore{Store: NewMemoryStore(), key: "outbox/pending/0"}
+	store.Set("outbox/pending/0", []byte(`{"content":"unreadable"}`))
+	o := NewOutbox(client, store)
+	o.Enqueue(&MessagesCreateOptions{FlowID: "f", Event: "message", Content: "next"})
+
+	if err := o.Flush(context.Background()); err != errUnreadable {
+		t.Errorf("Outbox.Flush returned %v, want the read error", err)
+	}
+	if want := []string{"next"}; !reflect.DeepEqual(posts, want) {
+		t.Errorf("Outbox.Flush posted %v, want %v", posts, want)
+	}
+	if n, _ := o.Pending(); n != 1 {
+		t.Errorf("Outbox.Pending returned %d, want the unreadable message", n)
+	}
+}
+
+var errUnreadable = errors.New("unreadable")
+
+// unreadableStore fails to read key.
+type unreadableStore struct {
+	Store
+	key string
+}
+
+func (s *unreadableStore) Get(key string) ([]byte, bool, error) {
+	if key == s.key {
+		return nil, false, errUnreadable
+	}
+	return s.Store.Get(key)
+}
+
+func TestOutbox_Run(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	sent := make(chan string, 1)
+	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
+		form := jsonValues(t, r)
+		if want := (url.Values{"message": {"1"}, "content": {"hi"}, "uuid": {"u-1"}}); !reflect.DeepEqual(form, want) {
+			t.Errorf("Request body = %v, want %v", form, want)
+		}
+		sent <- form.Get("uuid")
+		fmt.Fprint(w, `{}`)
+	})
+
+	o := NewOutbox(client, nil)
+	done := make(chan error)
+	go func() { done <- o.Run(ctx) }()
+
+	o.Enqueue(&MessagesCreateOptions{MessageID: 1, Content: "hi", UUID: "u-1"})
+	select {
+	case uuid := <-sent:
+		if uuid != "u-1" {
+			t.Errorf("Outbox.Run sent %v, want u-1", uuid)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Outbox.Run did not send the enqueued comment")
+	}
+
+	o.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Outbox.Run returned error: %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ListOptions specifies the optional paging parameters of list methods.
+type ListOptions struct {
+	// Limit is the number of items per page.
+	Limit int `url:"limit,omitempty"`
+
+	// SinceID only lists items whose ID is greater than SinceID.
+	SinceID int `url:"since_id,omitempty"`
+}
+
+// nextPageURL returns the URL of the page following resp as advertised by
+// the "next" relation of its Link header, or "" when there is none.
+func nextPageURL(resp *http.Response) string {
+	for _, header := range resp.Header["Link"] {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			if len(parts) < 2 {
+				continue
+			}
+
+			u := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(u, "<") || !strings.HasSuffix(u, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				param = strings.Replace(strings.TrimSpace(param), " ", "", -1)
+				if param == `rel="next"` || param == "rel=next" {
+					return u[1 : len(u)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ErrMaxPages is returned by the Err method of an iterator which stopped
+// after fetching its MaxPages pages, before the end of the list.
+var ErrMaxPages = errors.New("flowdock: iteration stopped after MaxPages pages")
+
+// iterator is the paging engine shared by the typed iterators. Pages are
+// followed through the Link header when the API provides one, and otherwise
+// by asking for the items after the last one received, until a page comes
+// back short.
+type iterator struct {
+	ctx      context.Context
+	client   *Client
+	limit    int                              // page size, 0 when left to the API
+	after    func(lastID int) (string, error) // URL of the page after lastID
+	next     string                           // URL of the next page, "" once exhausted
+	pages    int
+	maxPages int
+	err      error
+}
+
+func newIterator(ctx context.Context, client *Client, u string, opt *ListOptions) (*iterator, error) {
+	var o ListOptions
+	if opt != nil {
+		o = *opt
+	}
+
+	next, err := client.addOptions(u, &o)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{
+		ctx:    ctx,
+		client: client,
+		limit:  o.Limit,
+		next:   next,
+		after: func(lastID int) (string, error) {
+			o.SinceID = lastID
+			return client.addOptions(u, &o)
+		},
+	}, nil
+}
+
+// fetch requests the next page and decodes it into page, a pointer to a
+// slice. lastID returns the ID of the last item of a non-empty page. It
+// returns false once the list is exhausted or an error occurred.
+func (it *iterator) fetch(page interface{}, lastID func() int) bool {
+	if it.next == "" || it.err != nil {
+		return false
+	}
+	if it.maxPages > 0 && it.pages >= it.maxPages {
+		it.err = ErrMaxPages
+		return false
+	}
+
+	req, err := it.client.NewRequest("GET", it.next, nil)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	v := reflect.ValueOf(page).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	resp, err := it.client.Do(it.ctx, req, page)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.pages++
+
+	n := v.Len()
+	switch {
+	case nextPageURL(resp) != "":
+		it.next = nextPageURL(resp)
+	case n == 0 || it.limit == 0 || n < it.limit:
+		it.next = ""
+	default:
+		if it.next, err = it.after(lastID()); err != nil {
+			it.err = err
+			return false
+		}
+	}
+	return n > 0
+}
+
+// A UserIterator walks a paged list of users.
+//
+//	it := client.Users.IterateAll(ctx, &flowdock.ListOptions{Limit: 100})
+//	for it.Next() {
+//		user := it.User()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type UserIterator struct {
+	it    *iterator
+	page  []User
+	index int
+	err   error
+}
+
+// Next advances to the next user, fetching pages as needed. It returns false
+// when there are no more users or an error occurred.
+func (i *UserIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+
+	i.index++
+	for i.index >= len(i.page) {
+		if !i.it.fetch(&i.page, func() int { return userID(i.page[len(i.page)-1]) }) {
+			i.err = i.it.err
+			i.page = nil
+			return false
+		}
+		i.index = 0
+	}
+	return true
+}
+
+// User returns the current user.
+func (i *UserIterator) User() User {
+	return i.page[i.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (i *UserIterator) Err() error {
+	return i.err
+}
+
+func userID(u User) int {
+	if u.ID == nil {
+		return 0
+	}
+	return *u.ID
+}
+
+// A MessageIterator walks the messages of a flow, page by page. See
+// MessagesService.Iterate.
+type MessageIterator struct {
+	// MaxPages, if set, is the maximum number of pages fetched, as a
+	// safeguard against walking a huge history by mistake. The iteration
+	// then stops with ErrMaxPages. It must be set before the first call to
+	// Next.
+	MaxPages int
+
+	it     *iterator
+	page   []Message
+	index  int
+	err    error
+	client *Client
+	desc   bool         // whether the messages are walked newest first
+	seek   func() error // moves the start of the walk to the HistoryFloor
+}
+
+// Next advances to the next message, fetching pages as needed. It returns
+// false when there are no more messages or an error occurred.
+func (i *MessageIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+	if i.seek != nil {
+		err := i.seek()
+		if i.seek = nil; err != nil {
+			i.err = err
+			return false
+		}
+	}
+
+	i.index++
+	for i.index >= len(i.page) {
+		i.it.maxPages = i.MaxPages
+		if !i.it.fetch(&i.page, func() int { return messageID(i.page[len(i.page)-1]) }) {
+			i.err = i.it.err
+			i.page = nil
+			return false
+		}
+		for j := range i.page {
+			i.client.checkDeprecated(&i.page[j])
+		}
+		i.page = i.dropBeforeFloor(i.page)
+		i.index = 0
+	}
+	return true
+}
+
+// Message returns the current message.
+func (i *MessageIterator) Message() Message {
+	return i.page[i.index]
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (i *MessageIterator) Err() error {
+	return i.err
+}
+
+// dropBeforeFloor drops the messages of page older than the HistoryFloor of
+// the client. Walking newest first, the first of them ends the walk, since
+// the next ones are older still.
+func (i *MessageIterator) dropBeforeFloor(page []Message) []Message {
+	floor := i.client.historyFloor()
+	kept := page[:0]
+	for j := range page {
+		if beforeFloor(&page[j], floor) {
+			if i.desc {
+				i.it.next = ""
+			}
+			continue
+		}
+		kept = append(kept, page[j])
+	}
+	return kept
+}
+
+// historyFloor returns the time before which messages are withheld by
+// HistoryFloor, or the zero Time if none are.
+func (c *Client) historyFloor() time.Time {
+	if c.HistoryFloor <= 0 {
+		return time.Time{}
+	}
+	return c.Clock.Now().Add(-c.HistoryFloor)
+}
+
+// withinFloor drops the messages older than the HistoryFloor of c from
+// messages.
+func (c *Client) withinFloor(messages []Message) []Message {
+	floor := c.historyFloor()
+	if floor.IsZero() {
+		return messages
+	}
+	kept := messages[:0]
+	for i := range messages {
+		if !beforeFloor(&messages[i], floor) {
+			kept = append(kept, messages[i])
+		}
+	}
+	return kept
+}
+
+// beforeFloor reports whether m was sent before floor, as returned by
+// historyFloor.
+func beforeFloor(m *Message, floor time.Time) bool {
+	return !floor.IsZero() && m.Sent != nil && m.Sent.Before(floor)
+}
+
+func messageID(m Message) int {
+	if m.ID == nil {
+		return 0
+	}
+	return *m.ID
+}
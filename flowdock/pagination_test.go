@@ -0,0 +1,29 @@
+package flowdock
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{``, ""},
+		{`<https://api.flowdock.com/users?page=2>; rel="next"`, "https://api.flowdock.com/users?page=2"},
+		{`<https://api.flowdock.com/users?page=1>; rel="prev", <https://api.flowdock.com/users?page=3>; rel="next"`, "https://api.flowdock.com/users?page=3"},
+		{`<https://api.flowdock.com/users?page=9>; rel="last"`, ""},
+		{`https://api.flowdock.com/users?page=2; rel="next"`, ""},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.link != "" {
+			resp.Header.Set("Link", tt.link)
+		}
+		if got := nextPageURL(resp); got != tt.want {
+			t.Errorf("nextPageURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// pollPageSize is the number of messages Poll asks for per request.
+const pollPageSize = 100
+
+// Poll lists the messages of the given flow sent after the message sinceID
+// every interval, and sends them in order on the returned channel, for
+// environments where the persistent connection of Stream is prohibited. A
+// sinceID of 0 only sends the messages sent from now on.
+//
+// Requests are conditional: the ETag and Last-Modified headers of the
+// previous response, when the API sends them, let it answer 304 Not
+// Modified without listing anything when the flow is quiet. Temporary
+// failures are logged and retried at the next interval; other failures are
+// logged and close the channel, as does the end of ctx.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) Poll(ctx context.Context, org, flow string, sinceID int, interval time.Duration) <-chan Message {
+	p := &poller{s: s, ctx: ctx, org: org, flow: flow, since: sinceID}
+	msgs := make(chan Message)
+	go func() {
+		defer close(msgs)
+		started := sinceID != 0
+		for {
+			ok := true
+			if started {
+				ok = p.poll(msgs)
+			} else {
+				started, ok = p.latest()
+			}
+			if !ok {
+				return
+			}
+			select {
+			case <-s.client.Clock.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return msgs
+}
+
+// poller holds the state of a Poll between requests.
+type poller struct {
+	s            *MessagesService
+	ctx          context.Context
+	org, flow    string
+	since        int
+	etag         string // of the messages after validSince
+	lastModified string // of the messages after validSince
+	validSince   int
+}
+
+// latest sets the starting point of a Poll without a sinceID to the latest
+// message of the flow. It reports whether it did, and whether the Poll goes
+// on.
+func (p *poller) latest() (bool, bool) {
+	page, err := p.list(&MessagesListOptions{Limit: 1}, false)
+	if err != nil {
+		return false, p.failed(err)
+	}
+	if len(page) > 0 && page[0].ID != nil {
+		p.since = *page[0].ID
+	}
+	return true, true
+}
+
+// poll sends the messages sent since the last one, a page at a time. It
+// reports whether the Poll goes on.
+func (p *poller) poll(msgs chan<- Message) bool {
+	for {
+		opt := &MessagesListOptions{SinceID: p.since, Limit: pollPageSize, Sort: "asc"}
+		page, err := p.list(opt, true)
+		if err != nil {
+			return p.failed(err)
+		}
+
+		for _, m := range page {
+			select {
+			case msgs <- m:
+			case <-p.ctx.Done():
+				return false
+			}
+			if m.ID != nil && *m.ID > p.since {
+				p.since = *m.ID
+			}
+		}
+		if len(page) < pollPageSize {
+			return true
+		}
+	}
+}
+
+// list lists messages, conditionally if conditional is true. A 304 Not
+// Modified response lists none.
+func (p *poller) list(opt *MessagesListOptions, conditional bool) ([]Message, error) {
+	u, err := p.s.client.addOptions(fmt.Sprintf("flows/%v/%v/messages", p.org, p.flow), opt)
+	if err != nil {
+		return nil, err
+	}
+	req, err := p.s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if conditional && opt.SinceID == p.validSince {
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		if p.lastModified != "" {
+			req.Header.Set("If-Modified-Since", p.lastModified)
+		}
+	}
+
+	var page []Message
+	resp, err := p.s.client.Do(p.ctx, req, &page)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conditional {
+		p.etag = resp.Header.Get("ETag")
+		p.lastModified = resp.Header.Get("Last-Modified")
+		p.validSince = opt.SinceID
+	}
+	return page, nil
+}
+
+// failed logs err, and reports whether the Poll goes on after it.
+func (p *poller) failed(err error) bool {
+	if p.ctx.Err() != nil {
+		return false
+	}
+	p.s.client.Logf("poll of %s/%s failed: %v", p.org, p.flow, err)
+	return isRetryable(err)
+}
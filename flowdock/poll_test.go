@@ -0,0 +1,98 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMessagesService_Poll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := make(chan string, 10)
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		since := r.FormValue("since_id")
+		select {
+		case requests <- since + " " + r.Header.Get("If-None-Match"):
+		default:
+		}
+		switch {
+		case since == "1":
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `[{"id":2},{"id":3}]`)
+		case since == "3" && r.Header.Get("If-None-Match") == "":
+			w.Header().Set("ETag", `"v2"`)
+			fmt.Fprint(w, `[]`)
+		case since == "3" && len(requests) < 5:
+			w.WriteHeader(http.StatusNotModified)
+		case since == "3":
+			w.Header().Set("ETag", `"v3"`)
+			fmt.Fprint(w, `[{"id":4}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := client.Messages.Poll(ctx, "o", "f", 1, time.Millisecond)
+	for _, want := range []int{2, 3, 4} {
+		m := <-msgs
+		if m.ID == nil || *m.ID != want {
+			t.Fatalf("Messages.Poll sent %+v, want message %d", m, want)
+		}
+	}
+	cancel()
+	for range msgs {
+	}
+
+	want := []string{`1 `, `3 `, `3 "v2"`}
+	for i, w := range want {
+		if got := <-requests; got != w {
+			t.Errorf("request %d was %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestMessagesService_Poll_fromLatest(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("since_id") {
+		case "":
+			testFormValues(t, r, values{"limit": "1"})
+			fmt.Fprint(w, `[{"id":7}]`)
+		case "7":
+			fmt.Fprint(w, `[{"id":8}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := client.Messages.Poll(ctx, "o", "f", 0, time.Millisecond)
+	if m := <-msgs; m.ID == nil || *m.ID != 8 {
+		t.Errorf("Messages.Poll sent %+v, want message 8", m)
+	}
+	cancel()
+	for range msgs {
+	}
+}
+
+func TestMessagesService_Poll_notFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such flow", http.StatusNotFound)
+	})
+
+	msgs := client.Messages.Poll(context.Background(), "o", "f", 1, time.Millisecond)
+	if _, ok := <-msgs; ok {
+		t.Errorf("Messages.Poll sent a message for a missing flow")
+	}
+}
@@ -0,0 +1,180 @@
+package flowdock
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is the longest message content, in characters, shown by
+// a Preview. Longer content is truncated.
+const MaxMessageLength = 8096
+
+// Emoji maps the emoji shortcodes rendered by a Preview, without their
+// colons, to their characters. Add your own to render them too.
+var Emoji = map[string]string{
+	"+1":               "\U0001F44D",
+	"-1":               "\U0001F44E",
+	"eyes":             "\U0001F440",
+	"fire":             "\U0001F525",
+	"heart":            "❤️",
+	"joy":              "\U0001F602",
+	"rocket":           "\U0001F680",
+	"smile":            "\U0001F604",
+	"tada":             "\U0001F389",
+	"thumbsdown":       "\U0001F44E",
+	"thumbsup":         "\U0001F44D",
+	"warning":          "⚠️",
+	"white_check_mark": "✅",
+	"wink":             "\U0001F609",
+	"x":                "❌",
+}
+
+var (
+	emojiRegexp   = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+	mentionRegexp = regexp.MustCompile(`(?:^|\s)@([\w.-]*\w)`)
+	hashtagRegexp = regexp.MustCompile(`(?:^|\s)#([\w-]+)`)
+)
+
+// everyoneMentions are the mentions notifying every user of the flow.
+var everyoneMentions = map[string]bool{"all": true, "everyone": true, "team": true}
+
+// Preview is how a message will appear once posted, for interactive tools
+// to confirm it before posting.
+type Preview struct {
+	Subject string // of inbox messages
+	Text    string // the content, emoji rendered
+
+	// Mentions are the users the message mentions with "@nick". Nicks
+	// matching no user are listed in UnknownMentions. Everyone is true
+	// when the message mentions @everyone, @all or @team.
+	Mentions        []User
+	UnknownMentions []string
+	Everyone        bool
+
+	// Tags are the tags of the message, including its hashtags, without
+	// "#".
+	Tags []string
+
+	// Truncated is true when the content is longer than MaxMessageLength
+	// and was cut.
+	Truncated bool
+}
+
+// PreviewMessage renders the chat message opt as it will appear in a flow
+// whose users are users.
+func PreviewMessage(opt *MessagesCreateOptions, users []User) *Preview {
+	return newPreview(opt.Subject, opt.Content, opt.Tags, users)
+}
+
+// PreviewInbox renders the inbox message opt as it will appear in a flow
+// whose users are users.
+func PreviewInbox(opt *InboxCreateOptions, users []User) *Preview {
+	return newPreview(opt.Subject, opt.Content, opt.Tags, users)
+}
+
+func newPreview(subject, content string, tags []string, users []User) *Preview {
+	p := &Preview{Subject: subject}
+
+	if utf8.RuneCountInString(content) > MaxMessageLength {
+		content = string([]rune(content)[:MaxMessageLength])
+		p.Truncated = true
+	}
+	p.Text = emojiRegexp.ReplaceAllStringFunc(content, func(code string) string {
+		if e, ok := Emoji[code[1:len(code)-1]]; ok {
+			return e
+		}
+		return code
+	})
+
+	seen := make(map[string]bool)
+	for _, m := range mentionRegexp.FindAllStringSubmatch(content, -1) {
+		nick := strings.ToLower(m[1])
+		if seen[nick] {
+			continue
+		}
+		seen[nick] = true
+
+		if everyoneMentions[nick] {
+			p.Everyone = true
+		} else if u, ok := userByNick(users, nick); ok {
+			p.Mentions = append(p.Mentions, u)
+		} else {
+			p.UnknownMentions = append(p.UnknownMentions, m[1])
+		}
+	}
+
+	for _, tag := range tags {
+		p.addTag(tag)
+	}
+	for _, m := range hashtagRegexp.FindAllStringSubmatch(content, -1) {
+		p.addTag(m[1])
+	}
+	return p
+}
+
+// addTag adds tag to the tags of the preview, unless it has it already.
+func (p *Preview) addTag(tag string) {
+	tag = strings.TrimPrefix(tag, "#")
+	if tag != "" && !containsTag(p.Tags, tag) {
+		p.Tags = append(p.Tags, tag)
+	}
+}
+
+// userByNick returns the user of users whose nick is nick, ignoring case.
+func userByNick(users []User, nick string) (User, bool) {
+	for _, u := range users {
+		if u.Nick != nil && strings.EqualFold(*u.Nick, nick) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// String renders the preview as plain text.
+func (p *Preview) String() string {
+	var buf bytes.Buffer
+	if p.Subject != "" {
+		fmt.Fprintf(&buf, "Subject: %s\n\n", p.Subject)
+	}
+	buf.WriteString(p.Text)
+	if p.Truncated {
+		buf.WriteString(" [truncated]")
+	}
+	buf.WriteString("\n")
+
+	var mentions []string
+	if p.Everyone {
+		mentions = append(mentions, "everyone")
+	}
+	for _, u := range p.Mentions {
+		mentions = append(mentions, userLabel(u))
+	}
+	for _, nick := range p.UnknownMentions {
+		mentions = append(mentions, "@"+nick+" (unknown)")
+	}
+	if len(mentions) > 0 {
+		fmt.Fprintf(&buf, "Notifies: %s\n", strings.Join(mentions, ", "))
+	}
+	if len(p.Tags) > 0 {
+		fmt.Fprintf(&buf, "Tags: #%s\n", strings.Join(p.Tags, " #"))
+	}
+	return buf.String()
+}
+
+// userLabel returns "Name (@nick)", or what u has of it.
+func userLabel(u User) string {
+	var nick string
+	if u.Nick != nil {
+		nick = "@" + *u.Nick
+	}
+	if u.Name == nil || *u.Name == "" {
+		return nick
+	}
+	if nick == "" {
+		return *u.Name
+	}
+	return *u.Name + " (" + nick + ")"
+}
@@ -0,0 +1,51 @@
+package flowdock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreviewMessage(t *testing.T) {
+	nick, name := "alice", "Alice A."
+	users := []User{{Nick: &nick, Name: &name}}
+
+	p := PreviewMessage(&MessagesCreateOptions{
+		Content: "@Alice @bob @team ship it :rocket: :nope: #release #Deploy",
+		Tags:    []string{"#deploy", "ops"},
+	}, users)
+
+	if want := "@Alice @bob @team ship it \U0001F680 :nope: #release #Deploy"; p.Text != want {
+		t.Errorf("Preview.Text = %q, want %q", p.Text, want)
+	}
+	if !reflect.DeepEqual(p.Mentions, users) {
+		t.Errorf("Preview.Mentions = %+v, want %+v", p.Mentions, users)
+	}
+	if want := []string{"bob"}; !reflect.DeepEqual(p.UnknownMentions, want) || !p.Everyone {
+		t.Errorf("Preview.UnknownMentions = %v, Everyone = %v, want %v, true", p.UnknownMentions, p.Everyone, want)
+	}
+	if want := []string{"deploy", "ops", "release"}; !reflect.DeepEqual(p.Tags, want) {
+		t.Errorf("Preview.Tags = %v, want %v", p.Tags, want)
+	}
+
+	want := "@Alice @bob @team ship it \U0001F680 :nope: #release #Deploy\n" +
+		"Notifies: everyone, Alice A. (@alice), @bob (unknown)\n" +
+		"Tags: #deploy #ops #release\n"
+	if got := p.String(); got != want {
+		t.Errorf("Preview.String returned %q, want %q", got, want)
+	}
+}
+
+func TestPreviewInbox_truncated(t *testing.T) {
+	p := PreviewInbox(&InboxCreateOptions{
+		Subject: "Build failed",
+		Content: strings.Repeat("é", MaxMessageLength+1),
+	}, nil)
+
+	if !p.Truncated || len([]rune(p.Text)) != MaxMessageLength {
+		t.Errorf("Preview has %d characters, Truncated = %v, want %d, true", len([]rune(p.Text)), p.Truncated, MaxMessageLength)
+	}
+	if s := p.String(); !strings.HasPrefix(s, "Subject: Build failed\n\n") || !strings.Contains(s, " [truncated]\n") {
+		t.Errorf("Preview.String returned %q", s)
+	}
+}
@@ -0,0 +1,118 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/go-querystring/query"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidTag is returned when encoding a tag which can't be sent in the
+// comma-separated tags parameter.
+var ErrInvalidTag = errors.New("flowdock: invalid tag")
+
+// QueryEncoder encodes the options of requests, structs whose fields carry
+// "url" tags such as MessagesListOptions, as URL query parameters. Set it as
+// the Client's QueryEncoder to replace DefaultQueryEncoder.
+type QueryEncoder interface {
+	Encode(opt interface{}) (url.Values, error)
+}
+
+// QueryEncoderFunc is a QueryEncoder calling a function.
+type QueryEncoderFunc func(opt interface{}) (url.Values, error)
+
+// Encode implements the QueryEncoder interface.
+func (f QueryEncoderFunc) Encode(opt interface{}) (url.Values, error) {
+	return f(opt)
+}
+
+// DefaultQueryEncoder encodes options with go-querystring.
+var DefaultQueryEncoder QueryEncoder = QueryEncoderFunc(query.Values)
+
+// Tags are the tags of a message, sent as a comma-separated list. Tags are
+// trimmed of surrounding white space, and empty ones are dropped. Tags
+// holding a comma or a control character, which would be split or break the
+// list, fail to encode with ErrInvalidTag. Other characters, such as "#",
+// "&" or non-ASCII letters, are escaped as needed.
+type Tags []string
+
+// String returns the comma-separated list of the tags.
+func (t Tags) String() string {
+	s, _ := t.encode()
+	return s
+}
+
+// EncodeValues implements the query.Encoder interface of go-querystring.
+func (t Tags) EncodeValues(key string, v *url.Values) error {
+	s, err := t.encode()
+	if err != nil {
+		return err
+	}
+	if s != "" {
+		v.Set(key, s)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the tags as a JSON array, for the requests sending
+// them in a JSON body. Tags are trimmed and checked as in query parameters.
+func (t Tags) MarshalJSON() ([]byte, error) {
+	s, err := t.encode()
+	if err != nil {
+		return nil, err
+	}
+	tags := []string{}
+	if s != "" {
+		tags = strings.Split(s, ",")
+	}
+	return json.Marshal(tags)
+}
+
+func (t Tags) encode() (string, error) {
+	tags := make([]string, 0, len(t))
+	for _, tag := range t {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if strings.IndexFunc(tag, invalidTagRune) >= 0 {
+			return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		tags = append(tags, tag)
+	}
+	return strings.Join(tags, ","), nil
+}
+
+func invalidTagRune(r rune) bool {
+	return r == ',' || r < ' ' || r == 0x7f
+}
+
+// addOptions adds the parameters in opt as URL query parameters to s, as
+// encoded by the Client's QueryEncoder. opt must be a struct whose fields
+// may contain "url" tags.
+func (c *Client) addOptions(s string, opt interface{}) (string, error) {
+	v := reflect.ValueOf(opt)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return s, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return s, err
+	}
+
+	enc := c.QueryEncoder
+	if enc == nil {
+		enc = DefaultQueryEncoder
+	}
+	qs, err := enc.Encode(opt)
+	if err != nil {
+		return s, err
+	}
+
+	u.RawQuery = qs.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,123 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestTags_encoding(t *testing.T) {
+	tests := []struct {
+		tags  Tags
+		query string // of MessagesListOptions{Tags: tags}
+		err   bool
+	}{
+		{nil, "", false},
+		{Tags{}, "", false},
+		{Tags{"a"}, "tags=a", false},
+		{Tags{"a", "b"}, "tags=a%2Cb", false},
+		{Tags{" a ", "", "  ", "b"}, "tags=a%2Cb", false},
+		{Tags{"", " "}, "", false},
+		{Tags{"#release"}, "tags=%23release", false},
+		{Tags{"a&b=c"}, "tags=a%26b%3Dc", false},
+		{Tags{"100%"}, "tags=100%25", false},
+		{Tags{"a+b"}, "tags=a%2Bb", false},
+		{Tags{"two words"}, "tags=two+words", false},
+		{Tags{"?x/y"}, "tags=%3Fx%2Fy", false},
+		{Tags{":user:1", "@wm"}, "tags=%3Auser%3A1%2C%40wm", false},
+		{Tags{"café", "日本"}, "tags=caf%C3%A9%2C%E6%97%A5%E6%9C%AC", false},
+		{Tags{"a,b"}, "", true},
+		{Tags{"a\nb"}, "", true},
+		{Tags{"a\tb"}, "", true},
+		{Tags{"a\x00b"}, "", true},
+		{Tags{"a\x7fb"}, "", true},
+	}
+
+	for _, tt := range tests {
+		u, err := client.addOptions("flows/o/f/messages", &MessagesListOptions{Tags: tt.tags})
+		if tt.err {
+			if !errors.Is(err, ErrInvalidTag) {
+				t.Errorf("addOptions(%q) returned error %v, want ErrInvalidTag", []string(tt.tags), err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("addOptions(%q) returned error %v", []string(tt.tags), err)
+			continue
+		}
+		parsed, _ := url.Parse(u)
+		if parsed.RawQuery != tt.query {
+			t.Errorf("addOptions(%q) returned query %q, want %q", []string(tt.tags), parsed.RawQuery, tt.query)
+		}
+	}
+}
+
+func TestTags_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		tags Tags
+		json string
+	}{
+		{Tags{}, `[]`},
+		{Tags{" a ", "", "#b"}, `["a","#b"]`},
+		{Tags{"café"}, `["café"]`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.tags)
+		if err != nil {
+			t.Errorf("json.Marshal(%q) returned error: %v", []string(tt.tags), err)
+			continue
+		}
+		if string(data) != tt.json {
+			t.Errorf("json.Marshal(%q) = %s, want %s", []string(tt.tags), data, tt.json)
+		}
+	}
+
+	if _, err := json.Marshal(Tags{"a,b"}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("json.Marshal returned error %v, want ErrInvalidTag", err)
+	}
+}
+
+func TestTags_String(t *testing.T) {
+	if got, want := (Tags{" a", "b ", ""}).String(), "a,b"; got != want {
+		t.Errorf("Tags.String returned %q, want %q", got, want)
+	}
+}
+
+func TestClient_QueryEncoder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/o/f/messages", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"custom": "1"})
+		fmt.Fprint(w, `[]`)
+	})
+
+	var encoded interface{}
+	client.QueryEncoder = QueryEncoderFunc(func(opt interface{}) (url.Values, error) {
+		encoded = opt
+		return url.Values{"custom": {"1"}}, nil
+	})
+	opt := &MessagesListOptions{Limit: 5}
+	if _, _, err := client.Messages.List(ctx, "o", "f", opt); err != nil {
+		t.Fatalf("Messages.List returned error: %v", err)
+	}
+	if encoded != opt {
+		t.Errorf("QueryEncoder encoded %v, want %v", encoded, opt)
+	}
+}
+
+func TestMessagesService_Create_invalidTag(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	_, _, err := client.Messages.Create(ctx, &MessagesCreateOptions{Tags: []string{"a,b"}})
+	if !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("Messages.Create returned %v, want ErrInvalidTag", err)
+	}
+}
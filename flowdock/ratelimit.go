@@ -0,0 +1,312 @@
+package flowdock
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter paces the API requests of a Client. A Limiter shared by several
+// Clients paces them together.
+type Limiter interface {
+	// Wait blocks until a request may be sent, or ctx is done. Limiters
+	// may serve waiting requests in the order of their PriorityOf(ctx).
+	Wait(ctx context.Context) error
+}
+
+// Priority is the priority of a request waiting for a Limiter.
+type Priority int
+
+const (
+	// PriorityLow is for background traffic, such as exports, which may
+	// wait for the requests of higher priority.
+	PriorityLow Priority = -1
+
+	// PriorityNormal is the priority of requests by default.
+	PriorityNormal Priority = 0
+
+	// PriorityHigh is for interactive traffic, such as bot replies, sent
+	// before the waiting requests of lower priority.
+	PriorityHigh Priority = 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx tagging the requests made with it with
+// priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityOf returns the priority of the requests made with ctx, as set by
+// WithPriority, or PriorityNormal.
+func PriorityOf(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// withDefaultPriority tags ctx with priority p, unless it has one already.
+func withDefaultPriority(ctx context.Context, p Priority) context.Context {
+	if _, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return ctx
+	}
+	return WithPriority(ctx, p)
+}
+
+// RateLimiter is a Limiter letting n requests through per period, in
+// bursts of up to n requests. Once it is saturated, the waiting requests
+// are let through by priority, and in the order they came in within a
+// priority.
+type RateLimiter struct {
+	// Clock defaults to SystemClock.
+	Clock Clock
+
+	mu     sync.Mutex
+	n      int
+	period time.Duration
+	tokens float64
+	last   time.Time
+	queue  waitQueue
+	seq    uint64
+}
+
+// NewRateLimiter returns a RateLimiter letting n requests through per
+// period.
+func NewRateLimiter(n int, period time.Duration) *RateLimiter {
+	return &RateLimiter{Clock: SystemClock, n: n, period: period, tokens: float64(n)}
+}
+
+// Wait implements the Limiter interface.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	w := l.enqueue(PriorityOf(ctx))
+	for {
+		wait, ok := l.take(w)
+		if ok {
+			return nil
+		}
+		select {
+		case <-l.Clock.After(wait):
+		case <-ctx.Done():
+			l.dequeue(w)
+			return ctx.Err()
+		}
+	}
+}
+
+// enqueue queues a request of priority p.
+func (l *RateLimiter) enqueue(p Priority) *waiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	w := &waiter{priority: p, seq: l.seq}
+	heap.Push(&l.queue, w)
+	return w
+}
+
+// dequeue removes w from the queue, once its context is done.
+func (l *RateLimiter) dequeue(w *waiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	heap.Remove(&l.queue, w.index)
+}
+
+// take takes a token for w if one is available and w is next in the queue,
+// and otherwise returns how long until w may try again.
+func (l *RateLimiter) take(w *waiter) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Clock.Now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.period) * float64(l.n)
+		if l.tokens > float64(l.n) {
+			l.tokens = float64(l.n)
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		if l.queue[0] == w {
+			l.tokens--
+			heap.Pop(&l.queue)
+			return 0, true
+		}
+		// The token is for the request ahead of w, which takes it as soon
+		// as it wakes up; w waits for the next one.
+		return time.Duration(float64(l.period)/float64(l.n)) + 1, false
+	}
+	return time.Duration((1-l.tokens)/float64(l.n)*float64(l.period)) + 1, false
+}
+
+// waiter is a request waiting for a RateLimiter.
+type waiter struct {
+	priority Priority
+	seq      uint64 // order of arrival
+	index    int    // in the waitQueue
+}
+
+// waitQueue is a heap of waiters, the highest priority first, and the
+// first arrived first within a priority.
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x interface{}) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return w
+}
+
+// Headers of the API responses reporting the rate limit of the client.
+const (
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+)
+
+// Rate is the rate limit of the client, as reported by the API.
+type Rate struct {
+	// Limit is the number of requests allowed per rate limit window.
+	Limit int
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is when the current window ends.
+	Reset time.Time
+}
+
+// exhausted reports whether no request is left in the window of r at now.
+func (r Rate) exhausted(now time.Time) bool {
+	return r.Limit > 0 && r.Remaining <= 0 && now.Before(r.Reset)
+}
+
+// ParseRate parses the rate limit headers of resp. It reports false if
+// resp has none.
+func ParseRate(resp *http.Response) (Rate, bool) {
+	if resp == nil {
+		return Rate{}, false
+	}
+	limit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
+	if err != nil {
+		return Rate{}, false
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateRemaining))
+	if err != nil {
+		return Rate{}, false
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateReset), 10, 64)
+	if err != nil {
+		return Rate{}, false
+	}
+	return Rate{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// RateLimitPolicy selects what a Client does with a request while the rate
+// limit reported by the API is exhausted.
+type RateLimitPolicy int
+
+const (
+	// RateLimitIgnore sends the request anyway.
+	RateLimitIgnore RateLimitPolicy = iota
+
+	// RateLimitWait waits for the rate limit window to reset before
+	// sending the request.
+	RateLimitWait
+
+	// RateLimitFail fails the request with a *RateLimitError without
+	// sending it.
+	RateLimitFail
+)
+
+// RateLimitError is returned, with the RateLimitFail policy, for requests
+// which were not sent because the rate limit was exhausted. IsRateLimited
+// reports true for it.
+type RateLimitError struct {
+	Rate    Rate
+	Request *http.Request
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("flowdock: %v %v not sent: rate limit of %d requests exhausted until %v",
+		e.Request.Method, Redact(e.Request.URL.String()), e.Rate.Limit, e.Rate.Reset)
+}
+
+// Rate returns the rate limit reported by the last API response which had
+// one, or the zero Rate if none had.
+func (c *Client) Rate() Rate {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rate
+}
+
+// updateRate records the rate limit reported by resp, if any.
+func (c *Client) updateRate(resp *http.Response) {
+	if rate, ok := ParseRate(resp); ok {
+		c.rateMu.Lock()
+		c.rate = rate
+		c.rateMu.Unlock()
+	}
+}
+
+// checkRate applies the Client's RateLimitPolicy to req, once the rate
+// limit is exhausted.
+func (c *Client) checkRate(req *http.Request) error {
+	if c.RateLimitPolicy == RateLimitIgnore {
+		return nil
+	}
+	rate := c.Rate()
+	if !rate.exhausted(c.Clock.Now()) {
+		return nil
+	}
+
+	if c.RateLimitPolicy == RateLimitFail {
+		return &RateLimitError{Rate: rate, Request: req}
+	}
+	c.Logf("rate limit exhausted, waiting until %v", rate.Reset)
+	select {
+	case <-c.Clock.After(rate.Reset.Sub(c.Clock.Now())):
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
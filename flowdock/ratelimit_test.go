@@ -0,0 +1,230 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := NewFakeClock(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewRateLimiter(2, time.Minute)
+	l.Clock = clock
+
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("RateLimiter.Wait returned error: %v", err)
+		}
+	}
+
+	done := make(chan error)
+	go func() { done <- l.Wait(context.Background()) }()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("RateLimiter.Wait returned before a token was available")
+	default:
+	}
+	clock.Advance(31 * time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("RateLimiter.Wait returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("RateLimiter.Wait returned %v, want context.Canceled", err)
+	}
+}
+
+func TestRateLimiter_priority(t *testing.T) {
+	clock := NewFakeClock(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewRateLimiter(1, time.Minute)
+	l.Clock = clock
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("RateLimiter.Wait returned error: %v", err)
+	}
+
+	order := make(chan Priority, 3)
+	wait := func(p Priority) {
+		if err := l.Wait(WithPriority(context.Background(), p)); err != nil {
+			t.Errorf("RateLimiter.Wait returned error: %v", err)
+		}
+		order <- p
+	}
+	for i, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		go wait(p)
+		for clock.Waiters() < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for i, want := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		for clock.Waiters() < 3-i {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(time.Minute + time.Second)
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("RateLimiter let %v through, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("RateLimiter did not let %v through", want)
+		}
+	}
+}
+
+func TestRateLimiter_cancelQueued(t *testing.T) {
+	clock := NewFakeClock(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewRateLimiter(1, time.Minute)
+	l.Clock = clock
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("RateLimiter.Wait returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(WithPriority(context.Background(), PriorityHigh))
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("RateLimiter.Wait returned %v, want context.Canceled", err)
+	}
+
+	// The canceled request no longer holds the queue.
+	clock.Advance(time.Minute)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("RateLimiter.Wait returned error: %v", err)
+	}
+}
+
+func TestPriorityOf(t *testing.T) {
+	ctx := context.Background()
+	if got := PriorityOf(ctx); got != PriorityNormal {
+		t.Errorf("PriorityOf returned %v, want %v", got, PriorityNormal)
+	}
+	if got := PriorityOf(WithPriority(ctx, PriorityLow)); got != PriorityLow {
+		t.Errorf("PriorityOf returned %v, want %v", got, PriorityLow)
+	}
+	if got := PriorityOf(withDefaultPriority(WithPriority(ctx, PriorityHigh), PriorityLow)); got != PriorityHigh {
+		t.Errorf("PriorityOf returned %v, want %v", got, PriorityHigh)
+	}
+}
+
+// denyLimiter is a Limiter refusing every request.
+type denyLimiter struct{}
+
+func (denyLimiter) Wait(ctx context.Context) error { return context.DeadlineExceeded }
+
+func TestDo_limiter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request sent despite the Limiter")
+	})
+
+	client.Limiter = denyLimiter{}
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); err != context.DeadlineExceeded {
+		t.Errorf("Do returned %v, want the error of the Limiter", err)
+	}
+}
+
+// handleRate serves / reporting rate with its headers.
+func handleRate(rate Rate) *int {
+	requests := new(int)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rate.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rate.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(rate.Reset.Unix(), 10))
+		fmt.Fprint(w, `{}`)
+	})
+	return requests
+}
+
+func TestClient_Rate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	want := Rate{Limit: 100, Remaining: 42, Reset: time.Unix(1420070400, 0)}
+	handleRate(want)
+
+	if got := client.Rate(); got != (Rate{}) {
+		t.Errorf("Client.Rate returned %+v before any request, want the zero Rate", got)
+	}
+	req, _ := client.NewRequest("GET", "/", nil)
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	if got := client.Rate(); got != want {
+		t.Errorf("Client.Rate returned %+v, want %+v", got, want)
+	}
+}
+
+func TestDo_rateLimitFail(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := handleRate(Rate{Limit: 100, Remaining: 0, Reset: now.Add(time.Minute)})
+	client.Clock = NewFakeClock(now)
+	client.RateLimitPolicy = RateLimitFail
+
+	for i := 0; i < 2; i++ {
+		req, _ := client.NewRequest("GET", "/", nil)
+		client.Do(ctx, req, nil)
+	}
+	req, _ := client.NewRequest("GET", "/", nil)
+	_, err := client.Do(ctx, req, nil)
+	if e, ok := err.(*RateLimitError); !ok || e.Rate.Remaining != 0 || !IsRateLimited(err) {
+		t.Errorf("Do returned %v, want a *RateLimitError", err)
+	}
+	if *requests != 1 {
+		t.Errorf("Do sent %d requests, want 1 before the rate limit was known exhausted", *requests)
+	}
+}
+
+func TestDo_rateLimitWait(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	requests := handleRate(Rate{Limit: 100, Remaining: 0, Reset: now.Add(time.Minute)})
+	clock := NewFakeClock(now)
+	client.Clock = clock
+	client.RateLimitPolicy = RateLimitWait
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	client.Do(ctx, req, nil)
+
+	done := make(chan error)
+	go func() {
+		req, _ := client.NewRequest("GET", "/", nil)
+		_, err := client.Do(ctx, req, nil)
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if *requests != 1 {
+		t.Errorf("Do sent a request while the rate limit was exhausted")
+	}
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil || *requests != 2 {
+		t.Errorf("Do returned %v after %d requests, want a request once the window reset", err, *requests)
+	}
+}
@@ -0,0 +1,73 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AddReaction reacts to the message with the given ID with emoji, a
+// shortcode with or without colons such as ":+1:" or "+1", as the
+// authenticated user. Reacting again with the same emoji does nothing.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) AddReaction(ctx context.Context, org, flow string, id int, emoji string) (*http.Response, error) {
+	return s.reaction(ctx, "PUT", org, flow, id, emoji)
+}
+
+// RemoveReaction removes the reaction with emoji of the authenticated user
+// to the message with the given ID.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) RemoveReaction(ctx context.Context, org, flow string, id int, emoji string) (*http.Response, error) {
+	return s.reaction(ctx, "DELETE", org, flow, id, emoji)
+}
+
+func (s *MessagesService) reaction(ctx context.Context, method, org, flow string, id int, emoji string) (*http.Response, error) {
+	name := reactionName(emoji)
+	if name == "" {
+		return nil, fmt.Errorf("flowdock: empty reaction emoji")
+	}
+
+	u := fmt.Sprintf("flows/%s/%s/messages/%d/emoji_reactions/%s", org, flow, id, url.PathEscape(name))
+	req, err := s.client.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(ctx, req, nil)
+}
+
+// Reactions returns the number of users who reacted to m with each emoji,
+// by shortcode without colons, as set in its EmojiReactions.
+func (m *Message) Reactions() map[string]int {
+	if m.EmojiReactions == nil {
+		return nil
+	}
+	counts := make(map[string]int, len(*m.EmojiReactions))
+	for emoji, users := range *m.EmojiReactions {
+		counts[emoji] = len(users)
+	}
+	return counts
+}
+
+// reactionName returns the shortcode of emoji without colons, as the API
+// keys reactions.
+func reactionName(emoji string) string {
+	return strings.Trim(strings.TrimSpace(emoji), ":")
+}
+
+// EmojiReaction returns the reaction m reports, if m is an "emoji-reaction"
+// event.
+func (m *Message) EmojiReaction() (*EmojiReactionContent, bool) {
+	if m.Event == nil || *m.Event != "emoji-reaction" || m.RawContent == nil {
+		return nil, false
+	}
+	c := new(EmojiReactionContent)
+	if err := json.Unmarshal(*m.RawContent, c); err != nil {
+		return nil, false
+	}
+	return c, true
+}
@@ -0,0 +1,64 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMessagesService_AddReaction(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var served []string
+	mux.HandleFunc("/flows/org/flow/messages/1/emoji_reactions/+1", func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.Method)
+	})
+
+	if _, err := client.Messages.AddReaction(ctx, "org", "flow", 1, ":+1:"); err != nil {
+		t.Errorf("Messages.AddReaction returned error: %v", err)
+	}
+	if _, err := client.Messages.RemoveReaction(ctx, "org", "flow", 1, "+1"); err != nil {
+		t.Errorf("Messages.RemoveReaction returned error: %v", err)
+	}
+	if want := []string{"PUT", "DELETE"}; !reflect.DeepEqual(served, want) {
+		t.Errorf("requests served as %v, want %v", served, want)
+	}
+
+	if _, err := client.Messages.AddReaction(ctx, "org", "flow", 1, "::"); err == nil {
+		t.Errorf("Messages.AddReaction returned no error for an empty emoji")
+	}
+}
+
+func TestMessage_Reactions(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(`{"id":1,"emojiReactions":{"+1":["1","2"],"tada":["3"]}}`), &m); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if got, want := m.Reactions(), map[string]int{"+1": 2, "tada": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Reactions returned %v, want %v", got, want)
+	}
+	if got := new(Message).Reactions(); got != nil {
+		t.Errorf("Reactions returned %v for a message without reactions", got)
+	}
+}
+
+func TestMessage_EmojiReaction(t *testing.T) {
+	raw := json.RawMessage(`{"message":42,"emoji":"+1","type":"add"}`)
+	event := "emoji-reaction"
+	m := Message{Event: &event, RawContent: &raw}
+
+	c, ok := m.EmojiReaction()
+	want := &EmojiReactionContent{MessageID: 42, Emoji: "+1", Type: "add"}
+	if !ok || !reflect.DeepEqual(c, want) {
+		t.Errorf("Message.EmojiReaction returned %+v, %v, want %+v", c, ok, want)
+	}
+
+	event = "comment"
+	if _, ok := m.EmojiReaction(); ok {
+		t.Errorf("Message.EmojiReaction returned true for a comment")
+	}
+}
@@ -0,0 +1,74 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// reconnectBuffer is how many Reconnect notifications a Stream holds for
+// its reader. Those arriving when it is full are dropped.
+const reconnectBuffer = 16
+
+// ErrReconnectsExhausted ends a Stream whose ReconnectPolicy's MaxRetries
+// attempts to reconnect failed in a row.
+var ErrReconnectsExhausted = errors.New("flowdock: stream reconnections exhausted")
+
+// ReconnectPolicy sets how a Stream reconnects once its connection is lost,
+// or a connection attempt failed. Connections resume after the last
+// received event, with its Last-Event-ID, so that no message is lost.
+type ReconnectPolicy struct {
+	// Backoff returns the delay before the nth attempt in a row to
+	// reconnect, from 1. Defaults to the retry delay of the server, 3s
+	// unless it sends another.
+	Backoff func(n int) time.Duration
+
+	// MaxRetries is the number of attempts in a row to reconnect after
+	// which the Stream ends with ErrReconnectsExhausted. Attempts refused
+	// for maintenance don't count, and wait for the maintenance window.
+	// Zero retries forever.
+	MaxRetries int
+}
+
+// Reconnect notifies that a Stream is about to reconnect.
+type Reconnect struct {
+	Attempt     int           // attempts in a row, from 1
+	Err         error         // the loss of the connection, or the failure of the last attempt
+	LastEventID string        // from which the connection resumes
+	Delay       time.Duration // waited before the attempt
+}
+
+// StreamWithReconnect streams the messages for the given flow as Stream
+// does, reconnecting as set by policy: with its backoff between attempts,
+// and giving up after its MaxRetries attempts in a row, sending an error
+// wrapping ErrReconnectsExhausted on the Stream's Errors channel. A nil
+// policy reconnects forever, as Stream. The Stream's Reconnects channel
+// notifies each attempt.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamWithReconnect(ctx context.Context, token, org, flow string, policy *ReconnectPolicy) (<-chan Message, *Stream, error) {
+	return s.stream(ctx, token, fmt.Sprintf("flows/%v/%v", org, flow), &StreamOptions{Reconnect: policy})
+}
+
+// Reconnects returns the channel notifying each attempt of the Stream to
+// reconnect, before it waits for the attempt. Notifications are dropped
+// while the channel is full, so that a Stream never waits for its reader.
+// The channel is closed with the Stream.
+func (s *Stream) Reconnects() <-chan Reconnect {
+	return s.reconnects
+}
+
+// notify sends r on the Reconnects channel, unless the stream was closed or
+// the channel is full.
+func (s *Stream) notify(r Reconnect) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		select {
+		case s.reconnects <- r:
+		default:
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMessagesService_StreamWithReconnect(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	connections := 0
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		n := connections
+		mu.Unlock()
+		if n == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id: 5\ndata: {\"id\":5,\"event\":\"message\"}\n\n")
+			return // connection lost
+		}
+		testHeader(t, r, "Last-Event-ID", "5")
+		http.Error(w, `{"message":"unavailable"}`, http.StatusServiceUnavailable)
+	})
+
+	policy := &ReconnectPolicy{
+		Backoff:    func(n int) time.Duration { return time.Duration(n) * time.Millisecond },
+		MaxRetries: 2,
+	}
+	msgs, stream, err := client.Messages.StreamWithReconnect(context.Background(), "token", "org", "flow", policy)
+	if err != nil {
+		t.Fatalf("Messages.StreamWithReconnect returned error: %v", err)
+	}
+	defer stream.Close()
+
+	var ids []int
+	for m := range msgs {
+		ids = append(ids, *m.ID)
+	}
+	if len(ids) != 1 || ids[0] != 5 {
+		t.Errorf("Messages.StreamWithReconnect sent messages %v, want [5]", ids)
+	}
+	if err := stream.Err(); !errors.Is(err, ErrReconnectsExhausted) {
+		t.Errorf("Stream.Err returned %v, want ErrReconnectsExhausted", err)
+	}
+	if err := <-stream.Errors(); !errors.Is(err, ErrReconnectsExhausted) {
+		t.Errorf("Stream.Errors sent %v, want ErrReconnectsExhausted", err)
+	}
+	if connections != 3 {
+		t.Errorf("Stream connected %d times, want 3", connections)
+	}
+
+	var got []Reconnect
+	for r := range stream.Reconnects() {
+		got = append(got, r)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Stream.Reconnects sent %+v, want 2 notifications", got)
+	}
+	for i, r := range got {
+		if r.Attempt != i+1 || r.Delay != time.Duration(i+1)*time.Millisecond || r.LastEventID != "5" || r.Err == nil {
+			t.Errorf("Stream.Reconnects sent %+v as notification %d", r, i+1)
+		}
+	}
+}
+
+func TestStream_reconnectMaintenance(t *testing.T) {
+	setup()
+	defer teardown()
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	var mu sync.Mutex
+	connections := 0
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connections++
+		n := connections
+		mu.Unlock()
+		if n == 1 {
+			http.Error(w, `{"maintenance":true,"retry_after":60}`, http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, `{"message":"boom"}`, http.StatusInternalServerError)
+	})
+
+	policy := &ReconnectPolicy{Backoff: func(int) time.Duration { return time.Second }, MaxRetries: 1}
+	_, stream, err := client.Messages.StreamWithReconnect(context.Background(), "token", "org", "flow", policy)
+	if err != nil {
+		t.Fatalf("Messages.StreamWithReconnect returned error: %v", err)
+	}
+	defer stream.Close()
+
+	for {
+		select {
+		case <-stream.Done():
+		case <-time.After(time.Millisecond):
+			clock.Advance(time.Second)
+			continue
+		}
+		break
+	}
+	if !errors.Is(stream.Err(), ErrReconnectsExhausted) {
+		t.Errorf("Stream.Err returned %v, want ErrReconnectsExhausted", stream.Err())
+	}
+	var delays []time.Duration
+	for r := range stream.Reconnects() {
+		delays = append(delays, r.Delay)
+	}
+	if want := []time.Duration{time.Minute, time.Second}; fmt.Sprint(delays) != fmt.Sprint(want) {
+		t.Errorf("Stream waited %v before reconnecting, want %v", delays, want)
+	}
+	if connections != 3 {
+		t.Errorf("Stream connected %d times, want 3", connections)
+	}
+}
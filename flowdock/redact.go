@@ -0,0 +1,64 @@
+package flowdock
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// redacted replaces the secrets removed by Redact.
+const redacted = "REDACTED"
+
+// A Redactor removes secrets from text about to be logged or embedded in an
+// error, such as URLs and requests.
+type Redactor func(s string) string
+
+var (
+	// user info of URLs, where NewClientWithToken puts the token
+	urlUserInfo = regexp.MustCompile(`(://)[^/@\s]+@`)
+
+	// token query parameters, such as the one of StreamAuthQuery
+	tokenParam = regexp.MustCompile(`(?i)((?:access_token|flow_token|flow_api_token|token)=)[^&\s"]+`)
+
+	// Authorization header values
+	authScheme = regexp.MustCompile(`(?i)((?:Bearer|Basic) )[^\s"]+`)
+)
+
+// Redact removes the secrets the library knows about from s: the credentials
+// in URLs, the token query parameters and the Authorization header values.
+func Redact(s string) string {
+	s = urlUserInfo.ReplaceAllString(s, "${1}"+redacted+"@")
+	s = tokenParam.ReplaceAllString(s, "${1}"+redacted)
+	return authScheme.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redact removes secrets from s with Redact, then with the Client's
+// Redactor if it has one.
+func (c *Client) redact(s string) string {
+	s = Redact(s)
+	if c.Redactor != nil {
+		s = c.Redactor(s)
+	}
+	return s
+}
+
+// Logf logs through the Client's logger with secrets redacted, as the
+// Client and the packages built on it do.
+func (c *Client) Logf(format string, v ...interface{}) {
+	if c.Log == nil {
+		return
+	}
+	c.Log.Output(2, c.redact(fmt.Sprintf(format, v...)))
+}
+
+// redactError removes secrets from the URL embedded in err, which is kept
+// as the error returned by the http.Client and CheckResponse.
+func (c *Client) redactError(err error) error {
+	switch e := err.(type) {
+	case *url.Error:
+		e.URL = c.redact(e.URL)
+	case *ErrorResponse:
+		e.redact = c.redact
+	}
+	return err
+}
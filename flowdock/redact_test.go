@@ -0,0 +1,76 @@
+package flowdock
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"GET https://tok3n@api.flowdock.com/flows", "GET https://REDACTED@api.flowdock.com/flows"},
+		{"GET https://stream.flowdock.com/flows/o/f?access_token=s3cret&active=true", "GET https://stream.flowdock.com/flows/o/f?access_token=REDACTED&active=true"},
+		{`{"flow_token":"x"} flow_token=abc`, `{"flow_token":"x"} flow_token=REDACTED`},
+		{"Authorization: Bearer abc.def", "Authorization: Bearer REDACTED"},
+		{"Authorization: Basic dG9rZW46", "Authorization: Basic REDACTED"},
+		{"nothing to hide", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDo_redactsErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Request", 400)
+	})
+	client.Redactor = func(s string) string {
+		return strings.Replace(s, "hunter2", "*******", -1)
+	}
+
+	req, _ := client.NewRequest("GET", "?access_token=s3cret&password=hunter2", nil)
+	_, err := client.Do(ctx, req, nil)
+	if err == nil {
+		t.Fatal("Expected HTTP 400 error.")
+	}
+	if msg := err.Error(); strings.Contains(msg, "s3cret") || strings.Contains(msg, "hunter2") {
+		t.Errorf("Do returned error %q, want secrets redacted", msg)
+	}
+}
+
+func TestDo_redactsTransportErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	req, _ := client.NewRequest("GET", "http://tok3n@127.0.0.1:0/flows", nil)
+	_, err := client.Do(ctx, req, nil)
+	if err == nil {
+		t.Fatal("Expected a connection error.")
+	}
+	if msg := err.Error(); strings.Contains(msg, "tok3n") {
+		t.Errorf("Do returned error %q, want the token redacted", msg)
+	}
+}
+
+func TestLogf_redacts(t *testing.T) {
+	c := NewClient(nil)
+	buf := new(bytes.Buffer)
+	c.Log = log.New(buf, "", 0)
+
+	c.Logf("failed to connect: %v", "GET https://x/flows?access_token=s3cret")
+	if got, want := buf.String(), "failed to connect: GET https://x/flows?access_token=REDACTED\n"; got != want {
+		t.Errorf("Logf logged %q, want %q", got, want)
+	}
+}
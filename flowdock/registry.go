@@ -0,0 +1,164 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrUnknownAccount is returned for accounts missing from a Registry.
+var ErrUnknownAccount = errors.New("flowdock: unknown account")
+
+// Registry holds the Clients of several named accounts, such as the
+// organizations served by a hosted bot. The registered Clients share the
+// Limiter of the Registry, their requests are counted per account, and the
+// flows they stream are delivered on a single channel.
+type Registry struct {
+	limiter Limiter
+	streams *StreamManager
+
+	mu       sync.Mutex
+	accounts map[string]*account
+}
+
+// account is a registered Client.
+type account struct {
+	client *Client
+	token  string
+	stats  AccountStats
+}
+
+// AccountStats counts the API requests of an account.
+type AccountStats struct {
+	Requests int64 // API requests, stream connections included
+	Errors   int64 // requests which failed or got an error status
+}
+
+// NewRegistry returns an empty Registry whose Clients share limiter. A nil
+// limiter leaves their requests unpaced.
+func NewRegistry(limiter Limiter) *Registry {
+	return &Registry{
+		limiter:  limiter,
+		streams:  NewStreamManager(nil, ""),
+		accounts: make(map[string]*account),
+	}
+}
+
+// Register adds client under name, streaming with token, passed as
+// selected by the Client's StreamAuth. It sets the Limiter of client and
+// wraps its Tracer to count its requests. Registering a name again replaces
+// its Client for the flows streamed afterwards.
+func (r *Registry) Register(name string, client *Client, token string) {
+	a := &account{client: client, token: token}
+	if r.limiter != nil {
+		client.Limiter = r.limiter
+	}
+	client.Tracer = &countingTracer{next: client.Tracer, stats: &a.stats}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accounts[name] = a
+}
+
+// Client returns the Client registered under name.
+func (r *Registry) Client(name string) (*Client, bool) {
+	a, ok := r.account(name)
+	if !ok {
+		return nil, false
+	}
+	return a.client, true
+}
+
+// Names returns the sorted names of the registered accounts.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.accounts))
+	for name := range r.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the request counts of the account registered under name.
+func (r *Registry) Stats(name string) (AccountStats, bool) {
+	a, ok := r.account(name)
+	if !ok {
+		return AccountStats{}, false
+	}
+	return AccountStats{
+		Requests: atomic.LoadInt64(&a.stats.Requests),
+		Errors:   atomic.LoadInt64(&a.stats.Errors),
+	}, true
+}
+
+// Stream starts streaming the flow named flow in the organization org with
+// the account registered under name. Its messages are delivered by Events,
+// with Envelope.Account set to name.
+func (r *Registry) Stream(name, org, flow string) error {
+	a, ok := r.account(name)
+	if !ok {
+		return ErrUnknownAccount
+	}
+	return r.streams.add(name, a.client, a.token, org, flow)
+}
+
+// StreamOrg streams every open flow the account registered under name
+// joined in the organization org, listed with ctx.
+func (r *Registry) StreamOrg(ctx context.Context, name, org string) error {
+	a, ok := r.account(name)
+	if !ok {
+		return ErrUnknownAccount
+	}
+	return r.streams.addOrg(ctx, name, a.client, a.token, org)
+}
+
+// StopStream stops streaming the flow named flow in the organization org
+// with the account registered under name.
+func (r *Registry) StopStream(name, org, flow string) {
+	r.streams.remove(name, org, flow)
+}
+
+// Events returns the channel of the messages streamed by every account. It
+// is closed by Close.
+func (r *Registry) Events() <-chan Envelope {
+	return r.streams.Events()
+}
+
+// Close stops every stream and closes the Events channel.
+func (r *Registry) Close() {
+	r.streams.Close()
+}
+
+func (r *Registry) account(name string) (*account, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.accounts[name]
+	return a, ok
+}
+
+// countingTracer is the Tracer counting the requests of an account, before
+// passing them to the Tracer of its Client.
+type countingTracer struct {
+	next  Tracer
+	stats *AccountStats
+}
+
+func (t *countingTracer) Start(req *http.Request, op Operation) (*http.Request, func(*http.Response, error)) {
+	atomic.AddInt64(&t.stats.Requests, 1)
+	end := func(*http.Response, error) {}
+	if t.next != nil {
+		req, end = t.next.Start(req, op)
+	}
+	return req, func(resp *http.Response, err error) {
+		if err != nil || resp != nil && resp.StatusCode >= 400 {
+			atomic.AddInt64(&t.stats.Errors, 1)
+		}
+		end(resp, err)
+	}
+}
@@ -0,0 +1,66 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	handleFlowStream("o1", "a", "t1")
+	mux.HandleFunc("/flows/o1/a/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	mux.HandleFunc("/flows/o1/a/messages/2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":2}`)
+	})
+
+	limiter := NewRateLimiter(100, time.Second)
+	r := NewRegistry(limiter)
+	for _, name := range []string{"beta", "alpha"} {
+		c := NewClient(nil)
+		c.RestURL, _ = url.Parse(server.URL)
+		c.StreamURL, _ = url.Parse(streamServer.URL)
+		r.Register(name, c, "token-"+name)
+	}
+
+	if got, want := r.Names(), []string{"alpha", "beta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.Names returned %v, want %v", got, want)
+	}
+	alpha, ok := r.Client("alpha")
+	if !ok || alpha.Limiter != limiter {
+		t.Fatalf("Registry.Client returned %v, %v, want a Client sharing the Limiter", alpha, ok)
+	}
+	if _, ok := r.Client("gamma"); ok {
+		t.Errorf("Registry.Client returned true for an unknown account")
+	}
+
+	alpha.Messages.Get(ctx, "o1", "a", 1)
+	alpha.Messages.Get(ctx, "o1", "a", 2)
+	if got, _ := r.Stats("alpha"); got != (AccountStats{Requests: 2, Errors: 1}) {
+		t.Errorf("Registry.Stats returned %+v, want 2 requests and 1 error", got)
+	}
+	if got, _ := r.Stats("beta"); got != (AccountStats{}) {
+		t.Errorf("Registry.Stats returned %+v for an idle account", got)
+	}
+
+	if err := r.Stream("beta", "o1", "a"); err != nil {
+		t.Fatalf("Registry.Stream returned error: %v", err)
+	}
+	if err := r.Stream("gamma", "o1", "a"); err != ErrUnknownAccount {
+		t.Errorf("Registry.Stream returned %v, want ErrUnknownAccount", err)
+	}
+	env := <-r.Events()
+	if env.Account != "beta" || env.Org != "o1" || env.Flow != "a" || env.ThreadID != "t1" {
+		t.Errorf("Registry delivered %+v, want a message of beta in o1/a", env)
+	}
+	r.Close()
+}
@@ -0,0 +1,137 @@
+package flowdock
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// FlowResolver keeps long-lived references to flows working across renames
+// of the flow or its organization. It remembers the ID of every flow it
+// resolves, and when a request for a flow fails with a 404 it looks the ID up
+// in the flow listing to find the new names and retries. Redirects sent by
+// the API for renamed resources are followed by the http.Client already.
+type FlowResolver struct {
+	client *Client
+
+	// OnRename, if set, is called when a rename is detected.
+	OnRename func(oldOrg, oldFlow, newOrg, newFlow string)
+
+	mu    sync.Mutex
+	ids   map[string]string    // "org/flow" => flow ID
+	names map[string][2]string // old "org/flow" => current org and flow
+}
+
+// NewFlowResolver returns a FlowResolver using client.
+func NewFlowResolver(client *Client) *FlowResolver {
+	return &FlowResolver{
+		client: client,
+		ids:    make(map[string]string),
+		names:  make(map[string][2]string),
+	}
+}
+
+// Names returns the current organization and flow names of a flow known as
+// org/flow.
+func (r *FlowResolver) Names(org, flow string) (string, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if names, ok := r.names[org+"/"+flow]; ok {
+		return names[0], names[1]
+	}
+	return org, flow
+}
+
+// Do calls fn with the current names of the flow known as org/flow. If fn
+// fails with a 404 because the flow was renamed, fn is called again with the
+// new names.
+//
+// The first call for a flow fetches it to learn its ID, with ctx as are the
+// lookups of renamed flows.
+func (r *FlowResolver) Do(ctx context.Context, org, flow string, fn func(org, flow string) (*http.Response, error)) (*http.Response, error) {
+	key := org + "/" + flow
+
+	r.mu.Lock()
+	_, known := r.ids[key]
+	r.mu.Unlock()
+	if !known {
+		if err := r.learn(ctx, org, flow); err != nil && !IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	curOrg, curFlow := r.Names(org, flow)
+	resp, err := fn(curOrg, curFlow)
+	if !IsNotFound(err) {
+		return resp, err
+	}
+
+	newOrg, newFlow, ok, lookupErr := r.lookup(ctx, key)
+	if lookupErr != nil || !ok || (newOrg == curOrg && newFlow == curFlow) {
+		return resp, err
+	}
+
+	r.mu.Lock()
+	r.names[key] = [2]string{newOrg, newFlow}
+	r.mu.Unlock()
+	if r.OnRename != nil {
+		r.OnRename(curOrg, curFlow, newOrg, newFlow)
+	}
+
+	return fn(newOrg, newFlow)
+}
+
+// Get fetches the flow known as org/flow, following renames.
+func (r *FlowResolver) Get(ctx context.Context, org, flow string) (*Flow, *http.Response, error) {
+	var f *Flow
+	resp, err := r.Do(ctx, org, flow, func(org, flow string) (*http.Response, error) {
+		var (
+			resp *http.Response
+			err  error
+		)
+		f, resp, err = r.client.Flows.Get(ctx, org, flow)
+		return resp, err
+	})
+	return f, resp, err
+}
+
+// learn fetches the flow known as org/flow to remember its ID.
+func (r *FlowResolver) learn(ctx context.Context, org, flow string) error {
+	f, _, err := r.client.Flows.Get(ctx, org, flow)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f.ID != nil {
+		r.ids[org+"/"+flow] = *f.ID
+	}
+	return nil
+}
+
+// lookup finds the current names of the flow remembered under key in the
+// listing of all flows.
+func (r *FlowResolver) lookup(ctx context.Context, key string) (org, flow string, ok bool, err error) {
+	r.mu.Lock()
+	id, known := r.ids[key]
+	r.mu.Unlock()
+	if !known {
+		return "", "", false, nil
+	}
+
+	flows, _, err := r.client.Flows.List(ctx, true, nil)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	for _, f := range flows {
+		if f.ID == nil || *f.ID != id || f.ParameterizedName == nil ||
+			f.Organization == nil || f.Organization.ParameterizedName == nil {
+			continue
+		}
+		return *f.Organization.ParameterizedName, *f.ParameterizedName, true, nil
+	}
+	return "", "", false, nil
+}
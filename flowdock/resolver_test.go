@@ -0,0 +1,77 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFlowResolver_Get_renamed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	renamed := false
+	mux.HandleFunc("/flows/org/old", func(w http.ResponseWriter, r *http.Request) {
+		if renamed {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"id":"flow-id","parameterized_name":"old"}`)
+	})
+	mux.HandleFunc("/flows/org/new", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"flow-id","parameterized_name":"new"}`)
+	})
+	mux.HandleFunc("/flows/all", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"id":"other-id","parameterized_name":"other","organization":{"parameterized_name":"org"}},
+			{"id":"flow-id","parameterized_name":"new","organization":{"parameterized_name":"org"}}
+		]`)
+	})
+
+	var notices []string
+	r := NewFlowResolver(client)
+	r.OnRename = func(oldOrg, oldFlow, newOrg, newFlow string) {
+		notices = append(notices, fmt.Sprintf("%s/%s => %s/%s", oldOrg, oldFlow, newOrg, newFlow))
+	}
+
+	flow, _, err := r.Get(ctx, "org", "old")
+	if err != nil {
+		t.Fatalf("FlowResolver.Get returned error: %v", err)
+	}
+	if *flow.ParameterizedName != "old" {
+		t.Errorf("FlowResolver.Get returned %v, want old", *flow.ParameterizedName)
+	}
+
+	renamed = true
+	flow, _, err = r.Get(ctx, "org", "old")
+	if err != nil {
+		t.Fatalf("FlowResolver.Get returned error after rename: %v", err)
+	}
+	if *flow.ParameterizedName != "new" {
+		t.Errorf("FlowResolver.Get returned %v after rename, want new", *flow.ParameterizedName)
+	}
+
+	if org, name := r.Names("org", "old"); org != "org" || name != "new" {
+		t.Errorf("FlowResolver.Names returned %v/%v, want org/new", org, name)
+	}
+	if len(notices) != 1 || notices[0] != "org/old => org/new" {
+		t.Errorf("FlowResolver.OnRename was called with %v", notices)
+	}
+}
+
+func TestFlowResolver_Get_unknown(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, _, err := NewFlowResolver(client).Get(ctx, "org", "missing")
+	if !IsNotFound(err) {
+		t.Errorf("FlowResolver.Get returned %v, want a 404 error", err)
+	}
+}
@@ -0,0 +1,179 @@
+package flowdock
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultRetryAttempts is the number of attempts of a request, the
+	// first included, a RetryPolicy makes by default.
+	DefaultRetryAttempts = 3
+
+	// DefaultRetryBackoff is the delay before the first retry of a
+	// RetryPolicy by default. It doubles on each retry, up to
+	// DefaultRetryMaxBackoff.
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 30 * time.Second
+)
+
+// DefaultRetryStatusCodes are the response statuses a RetryPolicy retries
+// by default: rate limiting and temporary server failures.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy selects the requests Client.Do sends again after a transient
+// failure, and how long it waits before doing so. The delay the API asks
+// for with a Retry-After header is waited instead of the backoff. Requests
+// refused for maintenance, see ErrMaintenance, wait for the advertised
+// maintenance window, or MaintenanceBackoff, instead.
+//
+// Only idempotent requests are retried after network failures and server
+// errors, unless RetryNonIdempotent is set: a POST which failed that way
+// may have been processed. Rate limited requests, and those refused for
+// maintenance, were not processed and are always retried. Requests whose
+// body can't be read again, such as file uploads, are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request, the
+	// first included. Defaults to DefaultRetryAttempts.
+	MaxAttempts int
+
+	// Backoff returns the delay before the nth retry, from 1. Defaults to
+	// ExponentialBackoff(DefaultRetryBackoff, DefaultRetryMaxBackoff).
+	Backoff func(n int) time.Duration
+
+	// StatusCodes are the response statuses which are retried. Defaults
+	// to DefaultRetryStatusCodes.
+	StatusCodes []int
+
+	// RetryNonIdempotent, if set, retries POST and PATCH requests after
+	// network failures and server errors too, at the risk of sending a
+	// message twice.
+	RetryNonIdempotent bool
+
+	// MaintenanceBackoff is the delay before retrying a request refused
+	// for maintenance, when the API advertises no retry window. Defaults
+	// to DefaultMaintenanceBackoff.
+	MaintenanceBackoff time.Duration
+}
+
+// ExponentialBackoff returns a RetryPolicy.Backoff waiting base before the
+// first retry, and twice as long before each of the next ones, up to max.
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		d := base
+		for i := 1; i < n && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// attempts returns the maximum number of attempts of a request.
+func (p *RetryPolicy) attempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return DefaultRetryAttempts
+}
+
+// backoff returns the delay before the nth retry, after err.
+func (p *RetryPolicy) backoff(n int, err error) time.Duration {
+	def := p.MaintenanceBackoff
+	if def <= 0 {
+		def = DefaultMaintenanceBackoff
+	}
+	if d, ok := maintenanceBackoff(err, def); ok {
+		return d
+	}
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(DefaultRetryBackoff, DefaultRetryMaxBackoff)
+	}
+	return retryAfter(err, backoff(n))
+}
+
+// retries reports whether req, which failed with err, is to be sent again.
+func (p *RetryPolicy) retries(req *http.Request, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		code := e.StatusCode()
+		if !p.retriesStatus(code) {
+			return false
+		}
+		if code == http.StatusTooManyRequests || e.Maintenance != nil {
+			return true // not processed
+		}
+	} else if !isRetryable(err) {
+		return false
+	}
+	return p.RetryNonIdempotent || isIdempotent(req.Method)
+}
+
+func (p *RetryPolicy) retriesStatus(code int) bool {
+	codes := p.StatusCodes
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// doRetry sends req with do, sending it again as selected by the Client's
+// Retry.
+func (c *Client) doRetry(req *http.Request, v interface{}) (*http.Response, error) {
+	p := c.Retry
+	if p == nil {
+		return c.do(req, v)
+	}
+
+	ctx := req.Context()
+	for n := 1; ; n++ {
+		resp, err := c.do(req, v)
+		if err == nil || n >= p.attempts() || ctx.Err() != nil || !p.retries(req, err) {
+			return resp, err
+		}
+
+		wait := p.backoff(n, err)
+		c.Logf("%s %s failed, retrying in %v: %v", req.Method, req.URL, wait, err)
+		select {
+		case <-c.Clock.After(wait):
+		case <-ctx.Done():
+			return resp, err
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
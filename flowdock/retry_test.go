@@ -0,0 +1,169 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noBackoff retries immediately.
+func noBackoff(int) time.Duration { return 0 }
+
+func TestDo_retry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var requests int
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		if requests++; requests < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"id":"org:flow"}`)
+	})
+
+	client.Retry = &RetryPolicy{Backoff: noBackoff}
+	flow, _, err := client.Flows.Get(ctx, "org", "flow")
+	if err != nil {
+		t.Fatalf("Flows.Get returned error: %v", err)
+	}
+	if want := (&Flow{ID: &idOrgFlow}); !reflect.DeepEqual(flow, want) || requests != 3 {
+		t.Errorf("Flows.Get returned %+v after %d requests, want %+v after 3", flow, requests, want)
+	}
+}
+
+func TestDo_retry_maxAttempts(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var requests int
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	client.Retry = &RetryPolicy{MaxAttempts: 2, Backoff: noBackoff}
+	if _, _, err := client.Flows.Get(ctx, "org", "flow"); err == nil || requests != 2 {
+		t.Errorf("Flows.Get returned %v after %d requests, want an error after 2", err, requests)
+	}
+}
+
+func TestDo_retry_nonIdempotent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var bodies []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if strings.HasSuffix(r.URL.Path, "/limited") && len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	client.Retry = &RetryPolicy{Backoff: noBackoff}
+	req, _ := client.NewRequest("POST", "unavailable", map[string]string{"a": "b"})
+	if _, err := client.Do(ctx, req, nil); err == nil || len(bodies) != 1 {
+		t.Errorf("Do returned %v after %d requests, want no retry of a POST", err, len(bodies))
+	}
+
+	bodies = nil
+	req, _ = client.NewRequest("POST", "limited", map[string]string{"a": "b"})
+	client.Do(ctx, req, nil)
+	if want := []string{"{\"a\":\"b\"}\n", "{\"a\":\"b\"}\n"}; len(bodies) < 2 || !reflect.DeepEqual(bodies[:2], want) {
+		t.Errorf("Do sent bodies %q, want the rate limited POST sent again with %q", bodies, want)
+	}
+
+	bodies = nil
+	client.Retry.RetryNonIdempotent = true
+	req, _ = client.NewRequest("POST", "unavailable", nil)
+	client.Do(ctx, req, nil)
+	if len(bodies) != DefaultRetryAttempts {
+		t.Errorf("Do sent %d requests, want %d with RetryNonIdempotent", len(bodies), DefaultRetryAttempts)
+	}
+}
+
+func TestDo_retry_retryAfter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var requests int
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		if requests++; requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"id":"org:flow"}`)
+	})
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	client.Retry = new(RetryPolicy)
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Flows.Get(ctx, "org", "flow")
+		done <- err
+	}()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(6 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("Flows.Get returned %v before the Retry-After delay", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Flows.Get returned error: %v", err)
+	}
+}
+
+func TestRetryPolicy_retries(t *testing.T) {
+	get, _ := http.NewRequest("GET", "/", nil)
+	upload, _ := http.NewRequest("PUT", "/", ioutil.NopCloser(strings.NewReader("x")))
+	p := &RetryPolicy{StatusCodes: []int{http.StatusBadGateway}}
+
+	tests := []struct {
+		req  *http.Request
+		code int
+		want bool
+	}{
+		{get, http.StatusBadGateway, true},
+		{get, http.StatusServiceUnavailable, false},
+		{get, http.StatusNotFound, false},
+		{upload, http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		err := &Error{Response: &http.Response{StatusCode: tt.code}}
+		if got := p.retries(tt.req, err); got != tt.want {
+			t.Errorf("retries(%s, %d) = %v, want %v", tt.req.Method, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	var got []time.Duration
+	for n := 1; n <= 4; n++ {
+		got = append(got, backoff(n))
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExponentialBackoff returned %v, want %v", got, want)
+	}
+}
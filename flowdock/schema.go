@@ -0,0 +1,207 @@
+package flowdock
+
+//go:generate go run ./internal/schemagen schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect of the generated schemas.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaModels are the types whose schemas Schemas returns, by file name.
+var schemaModels = map[string]interface{}{
+	"message.json":                 Message{},
+	"comment_content.json":         CommentContent{},
+	"file_content.json":            FileContent{},
+	"mail_content.json":            MailContent{},
+	"tag_change.json":              TagChange{},
+	"vcs_content.json":             VcsContent{},
+	"messages_create_options.json": MessagesCreateOptions{},
+	"messages_edit_options.json":   MessagesEditOptions{},
+	"messages_list_options.json":   MessagesListOptions{},
+	"inbox_create_options.json":    InboxCreateOptions{},
+}
+
+// Schemas returns the JSON Schemas of the messages, of their contents and
+// of the options of the requests posting and listing them, by file name.
+// They are generated from the Go types, and are the ones of the schema
+// directory of this package, for programs in other languages reading the
+// messages archived by Go ones to validate them or generate bindings.
+func Schemas() (map[string][]byte, error) {
+	schemas := make(map[string][]byte, len(schemaModels))
+	for name, v := range schemaModels {
+		data, err := JSONSchema(v)
+		if err != nil {
+			return nil, err
+		}
+		schemas[name] = data
+	}
+	return schemas, nil
+}
+
+// JSONSchema returns the JSON Schema of the JSON encoding of the type of v,
+// which is described by its json struct tags, or by its url ones for the
+// options of requests. Named struct types other than the one of v are
+// described in its $defs.
+func JSONSchema(v interface{}) ([]byte, error) {
+	g := &schemaGen{defs: make(map[string]interface{})}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := g.typeSchema(t)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = t.Name()
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// jsonSchemaer is implemented by the types whose JSON encoding isn't
+// described by their Go type.
+type jsonSchemaer interface {
+	jsonSchema(g *schemaGen) map[string]interface{}
+}
+
+var (
+	jsonSchemaerType = reflect.TypeOf((*jsonSchemaer)(nil)).Elem()
+	rawMessageType   = reflect.TypeOf(json.RawMessage(nil))
+	timeType         = reflect.TypeOf(time.Time{})
+	flowdockTimeType = reflect.TypeOf(Time{})
+)
+
+// schemaGen generates the schema of a type and of the named struct types
+// it refers to, which are kept in defs.
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+// schema returns the schema of t, referring to the one in defs when t is a
+// named struct type.
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" || t == timeType || t == flowdockTimeType {
+		return g.typeSchema(t)
+	}
+
+	if _, ok := g.defs[t.Name()]; !ok {
+		g.defs[t.Name()] = nil // breaks cycles
+		g.defs[t.Name()] = g.typeSchema(t)
+	}
+	return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+}
+
+// typeSchema returns the schema of t itself.
+func (g *schemaGen) typeSchema(t reflect.Type) map[string]interface{} {
+	if reflect.PtrTo(t).Implements(jsonSchemaerType) {
+		return reflect.New(t).Interface().(jsonSchemaer).jsonSchema(g)
+	}
+
+	switch {
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of the object encoding the struct type t.
+func (g *schemaGen) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addFields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// addFields adds the schemas of the encoded fields of t to properties,
+// including those of its embedded structs.
+func (g *schemaGen) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = strings.Split(f.Tag.Get("url"), ",")[0]
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.addFields(f.Type, properties)
+			continue
+		}
+		if f.PkgPath != "" || name == "-" {
+			continue // unexported or not encoded
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = g.schema(f.Type)
+	}
+}
+
+func (*Time) jsonSchema(*schemaGen) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": "milliseconds since Epoch"}
+}
+
+func (*JsonContent) jsonSchema(*schemaGen) map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// jsonSchema describes the content of the events which have their own
+// Content type, as selected by Message.Content.
+func (*Message) jsonSchema(g *schemaGen) map[string]interface{} {
+	schema := g.structSchema(reflect.TypeOf(Message{}))
+
+	events := make([]string, 0, len(contentTypes))
+	for event := range contentTypes {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	var conditions []interface{}
+	for _, event := range events {
+		content := reflect.TypeOf(contentTypes[event]())
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{"event": map[string]interface{}{"const": event}},
+				"required":   []string{"event"},
+			},
+			"then": map[string]interface{}{
+				"properties": map[string]interface{}{"content": g.schema(content)},
+			},
+		})
+	}
+	schema["allOf"] = conditions
+	return schema
+}
@@ -0,0 +1,106 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSchemas_upToDate(t *testing.T) {
+	schemas, err := Schemas()
+	if err != nil {
+		t.Fatalf("Schemas returned error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join("schema", "*.json"))
+	if len(files) != len(schemas) {
+		t.Errorf("schema holds %d files, want %d; run go generate", len(files), len(schemas))
+	}
+	for name, want := range schemas {
+		got, err := ioutil.ReadFile(filepath.Join("schema", name))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("schema/%s is out of date; run go generate", name)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	type Named struct {
+		Count int `json:"count"`
+	}
+	v := struct {
+		ID       *int                  `json:"id,omitempty"`
+		Sent     *Time                 `json:"sent,omitempty"`
+		Raw      json.RawMessage       `json:"raw"`
+		Inner    struct{ Name string } `json:"inner"`
+		Nested   []*Named              `json:"nested"`
+		Labels   map[string]string     `json:"labels"`
+		Option   string                `url:"option,omitempty"`
+		Skipped  string                `json:"-"`
+		internal string
+	}{}
+
+	data, err := JSONSchema(&v)
+	if err != nil {
+		t.Fatalf("JSONSchema returned error: %v", err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+
+	var want map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "",
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"sent": {"type": "integer", "description": "milliseconds since Epoch"},
+			"raw": {},
+			"inner": {"type": "object", "properties": {"Name": {"type": "string"}}},
+			"nested": {"type": "array", "items": {"$ref": "#/$defs/Named"}},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"option": {"type": "string"}
+		},
+		"$defs": {
+			"Named": {"type": "object", "properties": {"count": {"type": "integer"}}}
+		}
+	}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONSchema returned %s", data)
+	}
+}
+
+func TestJSONSchema_message(t *testing.T) {
+	data, err := JSONSchema(Message{})
+	if err != nil {
+		t.Fatalf("JSONSchema returned error: %v", err)
+	}
+	var schema struct {
+		Properties map[string]interface{}
+		AllOf      []struct {
+			If struct {
+				Properties struct {
+					Event struct{ Const string }
+				}
+			}
+		}
+	}
+	json.Unmarshal(data, &schema)
+
+	for name := range messageFields {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("JSONSchema of Message lacks the field %s", name)
+		}
+	}
+	events := make(map[string]bool)
+	for _, cond := range schema.AllOf {
+		events[cond.If.Properties.Event.Const] = true
+	}
+	for event := range contentTypes {
+		if !events[event] {
+			t.Errorf("JSONSchema of Message lacks the content of %s events", event)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SourcesService handles communication with the source related methods of
+// the Flowdock API. A source is an integration of a flow, whose flow_token
+// authenticates the messages posted through the IntegrationsService.
+//
+// Flowdock API docs: https://www.flowdock.com/api/sources
+type SourcesService struct {
+	client *Client
+}
+
+// Source represents an integration source of a flow.
+type Source struct {
+	ID          *int         `json:"id,omitempty"`
+	Name        *string      `json:"name,omitempty"`
+	FlowToken   *string      `json:"flow_token,omitempty"`
+	ExternalURL *string      `json:"external_url,omitempty"`
+	Application *Application `json:"application,omitempty"`
+	CreatedAt   *time.Time   `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time   `json:"updated_at,omitempty"`
+}
+
+// Application is the application a Source was created by.
+type Application struct {
+	ID      *int    `json:"id,omitempty"`
+	Name    *string `json:"name,omitempty"`
+	IconURL *string `json:"icon_url,omitempty"`
+	URL     *string `json:"url,omitempty"`
+}
+
+// SourceCreateOptions specifies the parameters to the SourcesService.Create
+// method.
+type SourceCreateOptions struct {
+	Name        string `json:"name"`
+	ExternalURL string `json:"external_url,omitempty"`
+}
+
+// List the sources of a flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/sources
+func (s *SourcesService) List(ctx context.Context, org, flow string) ([]Source, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/sources", org, flow)
+
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := new([]Source)
+	resp, err := s.client.Do(ctx, req, sources)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return *sources, resp, err
+}
+
+// Get a source of a flow by its id.
+//
+// Flowdock API docs: https://www.flowdock.com/api/sources
+func (s *SourcesService) Get(ctx context.Context, org, flow string, id int) (*Source, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/sources/%v", org, flow, id)
+
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := new(Source)
+	resp, err := s.client.Do(ctx, req, source)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return source, resp, err
+}
+
+// Create a source in a flow. The returned source holds the FlowToken to
+// post integration messages with.
+//
+// Flowdock API docs: https://www.flowdock.com/api/sources
+func (s *SourcesService) Create(ctx context.Context, org, flow string, opt *SourceCreateOptions) (*Source, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/sources", org, flow)
+
+	req, err := s.client.NewRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := new(Source)
+	resp, err := s.client.Do(ctx, req, source)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return source, resp, err
+}
+
+// Delete a source of a flow, revoking its flow token.
+//
+// Flowdock API docs: https://www.flowdock.com/api/sources
+func (s *SourcesService) Delete(ctx context.Context, org, flow string, id int) (*http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/sources/%v", org, flow, id)
+
+	req, err := s.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(ctx, req, nil)
+}
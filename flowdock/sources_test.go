@@ -0,0 +1,90 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSourcesService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/sources", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[{"id":1,"name":"CI"},{"id":2,"name":"Tracker"}]`)
+	})
+
+	sources, _, err := client.Sources.List(ctx, "org", "flow")
+	if err != nil {
+		t.Errorf("Sources.List returned error: %v", err)
+	}
+	one, two, ci, tracker := 1, 2, "CI", "Tracker"
+	want := []Source{{ID: &one, Name: &ci}, {ID: &two, Name: &tracker}}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("Sources.List returned %+v, want %+v", sources, want)
+	}
+}
+
+func TestSourcesService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/sources/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":1,"flow_token":"token","application":{"name":"CI"}}`)
+	})
+
+	source, _, err := client.Sources.Get(ctx, "org", "flow", 1)
+	if err != nil {
+		t.Fatalf("Sources.Get returned error: %v", err)
+	}
+	if *source.FlowToken != "token" || *source.Application.Name != "CI" {
+		t.Errorf("Sources.Get returned %+v", source)
+	}
+}
+
+func TestSourcesService_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	input := &SourceCreateOptions{Name: "CI", ExternalURL: "https://ci.example.com"}
+	mux.HandleFunc("/flows/org/flow/sources", func(w http.ResponseWriter, r *http.Request) {
+		v := new(SourceCreateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+
+		testMethod(t, r, "POST")
+		if !reflect.DeepEqual(v, input) {
+			t.Errorf("Request body = %+v, want %+v", v, input)
+		}
+		fmt.Fprint(w, `{"id":1,"name":"CI","flow_token":"token"}`)
+	})
+
+	source, _, err := client.Sources.Create(ctx, "org", "flow", input)
+	if err != nil {
+		t.Fatalf("Sources.Create returned error: %v", err)
+	}
+	if *source.ID != 1 || *source.FlowToken != "token" {
+		t.Errorf("Sources.Create returned %+v", source)
+	}
+}
+
+func TestSourcesService_Delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/sources/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+	})
+
+	if _, err := client.Sources.Delete(ctx, "org", "flow", 1); err != nil {
+		t.Errorf("Sources.Delete returned error: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package flowdock
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxEventSize is the default maximum size, in bytes, of a single
+// event read from the streaming API.
+const DefaultMaxEventSize = 1 << 20
+
+// ErrEventTooLarge is returned when a stream event exceeds the maximum event
+// size. The oversized event is discarded and the stream stays usable.
+var ErrEventTooLarge = errors.New("flowdock: stream event too large")
+
+// utf8BOM may prefix the very first line of an event stream.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// event is a single server-sent event.
+type event struct {
+	ID    string
+	Type  string
+	Data  []byte
+	Retry time.Duration
+
+	// Raw is the frame of the event as received, in frame mode only.
+	Raw []byte
+}
+
+// eventDecoder reads server-sent events as described by
+// http://www.w3.org/TR/eventsource/. It accepts CRLF, LF and CR line endings
+// and a leading byte order mark, and bounds the memory used by any one event.
+type eventDecoder struct {
+	r       *bufio.Reader
+	maxSize int
+	started bool
+	skipLF  bool
+	lastID  string
+	retry   time.Duration
+
+	// in frame mode, every frame is dispatched, including the ones
+	// without data, and carries its raw bytes
+	frames bool
+	raw    bytes.Buffer
+}
+
+func newEventDecoder(r io.Reader, maxSize int) *eventDecoder {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxEventSize
+	}
+	return &eventDecoder{r: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// Decode returns the next event of the stream. Events whose frame is cut
+// short by the end of the stream are dropped and io.EOF is returned. An
+// event larger than the maximum size is skipped and reported with
+// ErrEventTooLarge, after which decoding can continue.
+func (d *eventDecoder) Decode() (*event, error) {
+	var (
+		ev       = new(event)
+		data     bytes.Buffer
+		hasData  bool
+		size     int
+		tooLarge bool
+	)
+
+	d.raw.Reset()
+	for {
+		line, truncated, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 && !truncated {
+			// a blank line dispatches the event
+			if tooLarge {
+				d.raw.Reset()
+				return nil, ErrEventTooLarge
+			}
+			if !hasData && !d.frames {
+				ev = new(event)
+				size = 0
+				continue
+			}
+			ev.ID = d.lastID
+			ev.Retry = d.retry
+			if hasData {
+				ev.Data = bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+			}
+			if d.frames {
+				d.completeCRLF()
+				ev.Raw = append([]byte(nil), d.raw.Bytes()...)
+			}
+			return ev, nil
+		}
+
+		size += len(line)
+		if tooLarge || truncated || size > d.maxSize {
+			tooLarge = true
+			data.Reset()
+			continue
+		}
+
+		if line[0] == ':' {
+			continue // comment
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			value = bytes.TrimPrefix(value, []byte(" "))
+		}
+
+		switch string(field) {
+		case "event":
+			ev.Type = string(value)
+		case "data":
+			hasData = true
+			data.Write(value)
+			data.WriteByte('\n')
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				d.lastID = string(value)
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 31); err == nil {
+				d.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// readLine returns the next line without its terminator. Lines longer than
+// the maximum event size are truncated and reported as such; the remainder
+// of the line is consumed and discarded.
+func (d *eventDecoder) readLine() (line []byte, truncated bool, err error) {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			// a line without terminator at the end of the stream is
+			// an incomplete frame
+			return nil, false, err
+		}
+
+		// a LF right after a CR belongs to the same CRLF line ending
+		skipLF := d.skipLF
+		d.skipLF = false
+
+		// raw frames are bounded as well: the line terminators of an
+		// event under the maximum size at most triple it
+		if d.frames && d.raw.Len() <= 3*d.maxSize && !(skipLF && b == '\n' && d.raw.Len() == 0) {
+			d.raw.WriteByte(b)
+		}
+
+		switch b {
+		case '\n':
+			if skipLF {
+				continue
+			}
+			return d.trimBOM(line), truncated, nil
+		case '\r':
+			d.skipLF = true
+			return d.trimBOM(line), truncated, nil
+		}
+
+		if len(line) >= d.maxSize {
+			truncated = true
+			continue
+		}
+		line = append(line, b)
+	}
+}
+
+// completeCRLF adds the LF of a CRLF line ending ending a frame to the raw
+// frame, if it arrived already.
+func (d *eventDecoder) completeCRLF() {
+	if !d.skipLF || d.r.Buffered() == 0 {
+		return
+	}
+	if b, err := d.r.Peek(1); err == nil && b[0] == '\n' {
+		d.r.ReadByte()
+		d.raw.WriteByte('\n')
+		d.skipLF = false
+	}
+}
+
+// trimBOM strips the byte order mark from the first line of the stream.
+func (d *eventDecoder) trimBOM(line []byte) []byte {
+	if !d.started {
+		d.started = true
+		line = bytes.TrimPrefix(line, utf8BOM)
+		if d.frames && bytes.HasPrefix(d.raw.Bytes(), utf8BOM) {
+			d.raw.Next(len(utf8BOM))
+		}
+	}
+	return line
+}
@@ -0,0 +1,41 @@
+//go:build go1.18
+// +build go1.18
+
+package flowdock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func FuzzEventDecoder(f *testing.F) {
+	f.Add([]byte("id: 1\ndata: {\"event\":\"message\",\"content\":\"hi\"}\n\n"))
+	f.Add([]byte("\xEF\xBB\xBFdata: a\r\n\r\ndata: b\r\r"))
+	f.Add([]byte(": keepalive\nretry: 10\nevent: x\ndata\n\n"))
+	f.Add([]byte("data: " + string(bytes.Repeat([]byte{0xff}, 300)) + "\n\n"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		const maxSize = 256
+		dec := newEventDecoder(bytes.NewReader(input), maxSize)
+
+		// every call consumes input, so the decoder must terminate well
+		// before running out of calls
+		for i := 0; i <= len(input)+1; i++ {
+			ev, err := dec.Decode()
+			if err == io.EOF {
+				return
+			}
+			if err == ErrEventTooLarge {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Decode returned unexpected error: %v", err)
+			}
+			if len(ev.Data) > maxSize {
+				t.Fatalf("Decode returned %d bytes of data, more than %d", len(ev.Data), maxSize)
+			}
+		}
+		t.Fatalf("Decode did not reach the end of %d bytes of input", len(input))
+	})
+}
@@ -0,0 +1,135 @@
+package flowdock
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeAll(t *testing.T, input string, maxSize int) ([]event, []error) {
+	dec := newEventDecoder(strings.NewReader(input), maxSize)
+
+	var events []event
+	var errs []error
+	for {
+		ev, err := dec.Decode()
+		if err == io.EOF {
+			return events, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		events = append(events, *ev)
+	}
+}
+
+func TestEventDecoder_Decode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []event
+	}{
+		{
+			name:  "LF",
+			input: "id: 1\ndata: {\"a\":1}\n\n",
+			want:  []event{{ID: "1", Data: []byte(`{"a":1}`)}},
+		},
+		{
+			name:  "CRLF",
+			input: "id: 1\r\ndata: a\r\n\r\ndata: b\r\n\r\n",
+			want:  []event{{ID: "1", Data: []byte("a")}, {ID: "1", Data: []byte("b")}},
+		},
+		{
+			name:  "CR",
+			input: "data: a\r\rdata: b\r\r",
+			want:  []event{{Data: []byte("a")}, {Data: []byte("b")}},
+		},
+		{
+			name:  "BOM",
+			input: "\xEF\xBB\xBFdata: a\n\n",
+			want:  []event{{Data: []byte("a")}},
+		},
+		{
+			name:  "multi-line data",
+			input: "data: a\ndata\ndata:b\n\n",
+			want:  []event{{Data: []byte("a\n\nb")}},
+		},
+		{
+			name:  "comments and unknown fields",
+			input: ": keepalive\nfoo: bar\nevent: message\ndata: a\n\n",
+			want:  []event{{Type: "message", Data: []byte("a")}},
+		},
+		{
+			name:  "events without data are not dispatched",
+			input: "event: ping\n\nid: 7\n\ndata: a\n\n",
+			want:  []event{{ID: "7", Data: []byte("a")}},
+		},
+		{
+			name:  "retry",
+			input: "retry: 1500\ndata: a\n\nretry: nope\ndata: b\n\n",
+			want: []event{
+				{Data: []byte("a"), Retry: 1500 * time.Millisecond},
+				{Data: []byte("b"), Retry: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "partial frame",
+			input: "data: a\n\ndata: b\n",
+			want:  []event{{Data: []byte("a")}},
+		},
+	}
+
+	for _, tt := range tests {
+		events, errs := decodeAll(t, tt.input, 0)
+		if len(errs) != 0 {
+			t.Errorf("%s: Decode returned errors %v", tt.name, errs)
+		}
+		if !reflect.DeepEqual(events, tt.want) {
+			t.Errorf("%s: Decode returned %+v, want %+v", tt.name, events, tt.want)
+		}
+	}
+}
+
+func TestEventDecoder_Decode_tooLarge(t *testing.T) {
+	input := "data: " + strings.Repeat("x", 64) + "\n\n" +
+		"data: 0123456789\ndata: 0123456789\ndata: 0123456789\n\n" +
+		"data: ok\n\n"
+
+	events, errs := decodeAll(t, input, 32)
+
+	want := []event{{Data: []byte("ok")}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Decode returned %+v, want %+v", events, want)
+	}
+	if len(errs) != 2 || errs[0] != ErrEventTooLarge || errs[1] != ErrEventTooLarge {
+		t.Errorf("Decode returned errors %v, want two ErrEventTooLarge", errs)
+	}
+}
+
+func TestEventDecoder_Decode_frames(t *testing.T) {
+	frames := []string{
+		"\xEF\xBB\xBF: hello\r\n\r\n",
+		"id: 1\r\ndata: a\r\ndata: b\r\n\r\n",
+		"retry: 10\n\n",
+		"event: x\rdata: c\r\r",
+	}
+	dec := newEventDecoder(strings.NewReader(strings.Join(frames, "")+"data: cut"), 0)
+	dec.frames = true
+
+	frames[0] = strings.TrimPrefix(frames[0], "\xEF\xBB\xBF")
+	for _, want := range frames {
+		ev, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		if string(ev.Raw) != want {
+			t.Errorf("Decode returned frame %q, want %q", ev.Raw, want)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode returned %v for a partial frame, want io.EOF", err)
+	}
+}
@@ -0,0 +1,353 @@
+package flowdock
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store is a key/value store used by the library to persist state, such as
+// cached lookups, across calls and, for durable implementations, across
+// process runs. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key, and whether there was one.
+	Get(key string) ([]byte, bool, error)
+
+	// Set stores value under key.
+	Set(key string, value []byte) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(key string) error
+
+	// Keys returns the sorted keys starting with prefix.
+	Keys(prefix string) ([]string, error)
+}
+
+// CompareAndSwapper is implemented by the Stores able to update a key
+// atomically, as needed to coordinate several processes sharing a Store.
+type CompareAndSwapper interface {
+	// CompareAndSwap stores value under key if the current value is old,
+	// a nil old standing for a missing key, and reports whether it did.
+	CompareAndSwap(key string, old, value []byte) (bool, error)
+}
+
+// MemoryStore is a Store keeping its values in memory.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Get implements the Store interface.
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+// Set implements the Store interface.
+func (s *MemoryStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete implements the Store interface.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// CompareAndSwap implements the CompareAndSwapper interface.
+func (s *MemoryStore) CompareAndSwap(key string, old, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.values[key]
+	if ok != (old != nil) || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+	s.values[key] = append([]byte(nil), value...)
+	return true, nil
+}
+
+// Keys implements the Store interface.
+func (s *MemoryStore) Keys(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []string
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// FileStore is a Store keeping each value in its own file of a directory, so
+// that state survives across runs of short-lived programs.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a FileStore using dir, which is created if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// fileStoreTemp, fileStoreLock and fileStoreHash prefix temporary, lock and
+// hashed files. Escaped keys never start with them, since an escaped "%" is
+// always followed by two hexadecimal digits.
+const (
+	fileStoreTemp = "%tmp"
+	fileStoreLock = "%lock"
+	fileStoreHash = "%hash"
+)
+
+// fileStoreMaxName is the length of the longest escaped key used as a file
+// name, leaving room for the prefixes of lock files within the 255 bytes
+// most file systems allow. The files of longer keys are named after their
+// hash, and start with the escaped key on a line of its own.
+const fileStoreMaxName = 200
+
+// fileStoreStaleLock is the age after which the lock file of a key is
+// considered left over by a crashed process, and removed.
+const fileStoreStaleLock = 10 * time.Second
+
+// path returns the file of key.
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, s.name(key))
+}
+
+// name returns the name of the file of key: the escaped key, or its hash
+// when it is too long. Dots are escaped too so that no key maps to "." or
+// "..".
+func (s *FileStore) name(key string) string {
+	name := escapeKey(key)
+	if len(name) > fileStoreMaxName {
+		sum := sha256.Sum256([]byte(key))
+		return fileStoreHash + hex.EncodeToString(sum[:])
+	}
+	return name
+}
+
+func escapeKey(key string) string {
+	return strings.Replace(url.QueryEscape(key), ".", "%2E", -1)
+}
+
+// Get implements the Store interface.
+func (s *FileStore) Get(key string) ([]byte, bool, error) {
+	name := s.name(key)
+	stored, value, err := s.read(name)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if stored != key {
+		return nil, false, fmt.Errorf("flowdock: file %s of FileStore holds the key %q, not %q", name, stored, key)
+	}
+	return value, true, nil
+}
+
+// read returns the key and the value of the file name.
+func (s *FileStore) read(name string) (string, []byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(name, fileStoreHash) {
+		key, err := url.QueryUnescape(name)
+		return key, data, err
+	}
+
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return "", nil, fmt.Errorf("flowdock: file %s of FileStore has no key", name)
+	}
+	key, err := url.QueryUnescape(string(data[:i]))
+	return key, data[i+1:], err
+}
+
+// Set implements the Store interface. Values are replaced atomically.
+func (s *FileStore) Set(key string, value []byte) error {
+	name := s.name(key)
+	if strings.HasPrefix(name, fileStoreHash) {
+		value = append([]byte(escapeKey(key)+"\n"), value...)
+	}
+
+	tmp, err := ioutil.TempFile(s.dir, fileStoreTemp)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+// CompareAndSwap implements the CompareAndSwapper interface. Processes
+// sharing the directory of the FileStore exclude each other with a lock
+// file, waited for as long as it takes: see CompareAndSwapContext.
+func (s *FileStore) CompareAndSwap(key string, old, value []byte) (bool, error) {
+	return s.CompareAndSwapContext(context.Background(), key, old, value)
+}
+
+// CompareAndSwapContext is CompareAndSwap waiting for the lock file of key
+// until ctx is done, and then returning its error.
+func (s *FileStore) CompareAndSwapContext(ctx context.Context, key string, old, value []byte) (bool, error) {
+	unlock, err := s.lock(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	cur, ok, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if ok != (old != nil) || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+	return true, s.Set(key, value)
+}
+
+// lock waits for the lock file of key until ctx is done, and returns the
+// function releasing it.
+func (s *FileStore) lock(ctx context.Context, key string) (func(), error) {
+	path := filepath.Join(s.dir, fileStoreLock+s.name(key))
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		broken, err := breakStaleLock(path)
+		if err != nil {
+			return nil, err
+		}
+		if broken {
+			continue
+		}
+
+		t := time.NewTimer(10 * time.Millisecond)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// breakStaleLock removes the lock file at path if it is stale, and reports
+// whether the lock may be free. Waiters finding it stale together would
+// otherwise remove the lock taken by the first of them in between: the
+// lock file is moved aside first, and only removed if it is the stale one.
+// Otherwise, it is the lock of another waiter, which is put back.
+func breakStaleLock(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil || time.Since(info.ModTime()) <= fileStoreStaleLock {
+		return false, err
+	}
+
+	aside := fmt.Sprintf("%s%%stale%d-%d", path, os.Getpid(), atomic.AddUint64(&staleLocks, 1))
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return true, nil // broken by another waiter
+		}
+		return false, err
+	}
+	defer os.Remove(aside)
+
+	moved, err := os.Stat(aside)
+	if err != nil {
+		return false, err
+	}
+	if os.SameFile(info, moved) {
+		return true, nil
+	}
+	if err := os.Link(aside, path); err != nil && !os.IsExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// staleLocks numbers the stale lock files moved aside by breakStaleLock.
+var staleLocks uint64
+
+// Delete implements the Store interface.
+func (s *FileStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Keys implements the Store interface.
+func (s *FileStore) Keys(prefix string) ([]string, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || strings.HasPrefix(name, fileStoreTemp) || strings.HasPrefix(name, fileStoreLock) {
+			continue
+		}
+		var key string
+		if strings.HasPrefix(name, fileStoreHash) {
+			key, _, err = s.read(name)
+		} else {
+			key, err = url.QueryUnescape(name)
+		}
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,147 @@
+package flowdock
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, s Store) {
+	if _, ok, err := s.Get("missing"); ok || err != nil {
+		t.Errorf("Store.Get(missing) = %v, %v, want false, nil", ok, err)
+	}
+
+	for _, key := range []string{"users/1", "users/2", "flows/org/flow", ".."} {
+		if err := s.Set(key, []byte(key)); err != nil {
+			t.Fatalf("Store.Set(%q) returned error: %v", key, err)
+		}
+	}
+	s.Set("users/1", []byte("updated"))
+
+	value, ok, err := s.Get("users/1")
+	if !ok || err != nil || string(value) != "updated" {
+		t.Errorf("Store.Get(users/1) = %q, %v, %v, want updated", value, ok, err)
+	}
+	if value, _, _ := s.Get(".."); string(value) != ".." {
+		t.Errorf("Store.Get(..) = %q, want ..", value)
+	}
+
+	keys, err := s.Keys("users/")
+	if err != nil {
+		t.Errorf("Store.Keys returned error: %v", err)
+	}
+	if want := []string{"users/1", "users/2"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Store.Keys(users/) = %v, want %v", keys, want)
+	}
+
+	if err := s.Delete("users/1"); err != nil {
+		t.Errorf("Store.Delete returned error: %v", err)
+	}
+	if err := s.Delete("users/1"); err != nil {
+		t.Errorf("Store.Delete of a missing key returned error: %v", err)
+	}
+	if _, ok, _ := s.Get("users/1"); ok {
+		t.Errorf("Store.Get returned a deleted key")
+	}
+}
+
+func testCompareAndSwap(t *testing.T, s CompareAndSwapper) {
+	tests := []struct {
+		old, value string
+		absent     bool
+		want       bool
+	}{
+		{"", "a", true, true},  // create a missing key
+		{"", "b", true, false}, // but not twice
+		{"b", "c", false, false},
+		{"a", "c", false, true},
+	}
+	for _, tt := range tests {
+		var old []byte
+		if !tt.absent {
+			old = []byte(tt.old)
+		}
+		swapped, err := s.CompareAndSwap("leader", old, []byte(tt.value))
+		if err != nil {
+			t.Fatalf("CompareAndSwap returned error: %v", err)
+		}
+		if swapped != tt.want {
+			t.Errorf("CompareAndSwap(%q, %q) = %v, want %v", tt.old, tt.value, swapped, tt.want)
+		}
+	}
+
+	if value, _, _ := s.(Store).Get("leader"); string(value) != "c" {
+		t.Errorf("Store.Get after CompareAndSwap = %q, want c", value)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+	testCompareAndSwap(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowdock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore returned error: %v", err)
+	}
+	testStore(t, s)
+	testCompareAndSwap(t, s)
+
+	if keys, _ := s.Keys(""); len(keys) != 4 {
+		t.Errorf("FileStore.Keys = %v, want no lock files", keys)
+	}
+
+	long := "flows/" + strings.Repeat("é", 200)
+	if err := s.Set(long, []byte("v")); err != nil {
+		t.Fatalf("FileStore.Set of a long key returned error: %v", err)
+	}
+	if value, ok, err := s.Get(long); !ok || err != nil || string(value) != "v" {
+		t.Errorf("FileStore.Get of a long key = %q, %v, %v, want v", value, ok, err)
+	}
+	if keys, _ := s.Keys("flows/" + strings.Repeat("é", 100)); !reflect.DeepEqual(keys, []string{long}) {
+		t.Errorf("FileStore.Keys = %q, want the long key", keys)
+	}
+	if _, err := s.CompareAndSwap(long, []byte("v"), []byte("w")); err != nil {
+		t.Errorf("FileStore.CompareAndSwap of a long key returned error: %v", err)
+	}
+}
+
+func TestFileStore_lock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowdock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _ := NewFileStore(dir)
+
+	lock := filepath.Join(dir, fileStoreLock+s.name("leader"))
+	if err := ioutil.WriteFile(lock, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.CompareAndSwapContext(ctx, "leader", nil, []byte("a")); err != context.DeadlineExceeded {
+		t.Errorf("FileStore.CompareAndSwapContext returned %v with the key locked, want context.DeadlineExceeded", err)
+	}
+
+	stale := time.Now().Add(-2 * fileStoreStaleLock)
+	os.Chtimes(lock, stale, stale)
+	if swapped, err := s.CompareAndSwap("leader", nil, []byte("a")); !swapped || err != nil {
+		t.Errorf("FileStore.CompareAndSwap returned %v, %v with a stale lock, want true", swapped, err)
+	}
+	if infos, _ := ioutil.ReadDir(dir); len(infos) != 1 {
+		t.Errorf("FileStore left %d files, want the one of the key", len(infos))
+	}
+}
@@ -0,0 +1,442 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultRetryDelay is how long a Stream waits before reconnecting, unless
+// the server advertises another delay.
+const defaultRetryDelay = 3 * time.Second
+
+// ErrStreamClosed is returned when reading from a Stream that was closed.
+var ErrStreamClosed = errors.New("flowdock: stream closed")
+
+// ErrStreamUnauthorized matches, with errors.Is, the StreamAuthError ending
+// a Stream.
+var ErrStreamUnauthorized = errors.New("flowdock: stream unauthorized")
+
+// StreamAuthError ends a Stream whose connection was refused with a 401
+// Unauthorized or 403 Forbidden response. Unlike other failures, it is not
+// retried: the token is invalid, or lacks access to the flow.
+type StreamAuthError struct {
+	Response *ErrorResponse
+}
+
+func (e *StreamAuthError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrStreamUnauthorized, e.Response)
+}
+
+// Is reports whether target is ErrStreamUnauthorized.
+func (e *StreamAuthError) Is(target error) bool {
+	return target == ErrStreamUnauthorized
+}
+
+// Unwrap returns the refusing response.
+func (e *StreamAuthError) Unwrap() error {
+	return e.Response
+}
+
+// A Stream is a connection to the Flowdock streaming API. Dropped connections
+// are transparently reopened, resuming after the last received event, as
+// set by the ReconnectPolicy of MessagesService.StreamWithReconnect.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+type Stream struct {
+	client *Client
+	req    *http.Request
+	retry  time.Duration
+	policy *ReconnectPolicy // nil reconnects forever after the retry delay
+
+	mu          sync.Mutex
+	resp        *http.Response
+	end         func(*http.Response, error) // of the connection's trace
+	dec         *eventDecoder
+	lastEventID string
+	failures    int  // reconnection attempts since the last connection
+	paused      int  // of failures, those refused for maintenance
+	frames      bool // whether connections decode raw frames, for CopyTo
+	active      *bool
+	reconnect   bool // whether the connection was dropped to be reopened at once
+	closed      bool
+	err         error // why the stream ended, for Err
+	done        chan struct{}
+	errs        chan error
+	reconnects  chan Reconnect
+}
+
+func newStream(client *Client, req *http.Request) *Stream {
+	return &Stream{
+		client: client,
+		req:    req,
+		retry:  defaultRetryDelay,
+		done:   make(chan struct{}),
+		errs:   make(chan error, 1),
+
+		reconnects: make(chan Reconnect, reconnectBuffer),
+	}
+}
+
+// bind closes the stream once ctx is done, aborting its connection
+// attempts.
+func (s *Stream) bind(ctx context.Context) {
+	s.req = s.req.WithContext(ctx)
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.closeWith(ctx.Err())
+		case <-s.done:
+		}
+	}()
+}
+
+// LastEventID returns the ID of the last event read from the Stream, from
+// which MessagesService.StreamFrom resumes once the Stream ended.
+func (s *Stream) LastEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventID
+}
+
+// Done returns a channel closed once the Stream ended: closed, canceled by
+// its context, or failed. Err then tells why.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns nil until Done is closed. Then, it returns why the Stream
+// ended: nil if closed with Close, the error of its context if canceled
+// by it, and the error which ended it otherwise, as sent on Errors.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Errors returns the channel receiving the error ending the Stream, such as
+// a StreamAuthError or a *PanicError, before the Stream closes. Lost
+// connections and other temporary failures are retried instead, until a
+// ReconnectPolicy gives up with ErrReconnectsExhausted. With the
+// RestartOnPanic of the Client, it receives the panics the Stream recovered
+// from too. The channel is closed with the Stream, so that ranging over it
+// ends with the Stream.
+func (s *Stream) Errors() <-chan error {
+	return s.errs
+}
+
+// fail ends the stream because of err, unless it was closed already.
+func (s *Stream) fail(err error) {
+	s.report(err)
+	s.closeWith(err)
+}
+
+// report sends err on the Errors channel, unless the stream was closed, or
+// an error is already waiting to be received.
+func (s *Stream) report(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		select {
+		case s.errs <- err:
+		default:
+		}
+	}
+}
+
+// SetActive sets the presence of the user in the flows of the stream, as
+// shown to the other users: active, or idle. The connection is reopened
+// with the new presence at once, resuming after the last received event,
+// so that readers of the stream carry on as if nothing happened. By
+// default, the presence is left to the server.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *Stream) SetActive(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active != nil && *s.active == active {
+		return
+	}
+	s.active = &active
+	if s.resp != nil && !s.closed {
+		s.reconnect = true
+		s.resp.Body.Close()
+	}
+}
+
+// Close the stream and its underlying connection. A read blocked on the
+// connection returns ErrStreamClosed. The Errors channel is closed at once,
+// and the message channel of MessagesService.Stream once its reader
+// returns. Close may be called any number of times, from any goroutine.
+func (s *Stream) Close() {
+	s.closeWith(nil)
+}
+
+// closeWith closes the stream because of err, unless it was closed already.
+func (s *Stream) closeWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	close(s.done)
+	close(s.errs)
+	close(s.reconnects)
+	if s.resp != nil {
+		s.resp.Body.Close()
+	}
+	if s.end != nil {
+		s.end(s.resp, nil)
+		s.end = nil
+	}
+}
+
+// read returns the next event of the stream, reconnecting as needed. It only
+// fails once the stream is closed or its connection refused for good, or for
+// events the decoder rejects.
+func (s *Stream) read() (*event, error) {
+	for {
+		dec, err := s.connect()
+		if err != nil {
+			return nil, err
+		}
+
+		ev, err := dec.Decode()
+		switch {
+		case err == nil:
+			s.mu.Lock()
+			s.lastEventID = ev.ID
+			if ev.Retry > 0 {
+				s.retry = ev.Retry
+			}
+			s.mu.Unlock()
+			return ev, nil
+		case err == ErrEventTooLarge:
+			return nil, err
+		}
+
+		closed, reconnect := s.disconnect(err)
+		if closed {
+			return nil, ErrStreamClosed
+		}
+		if reconnect {
+			continue
+		}
+		s.client.Logf("stream connection lost: %v", err)
+		if err := s.wait(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// CopyTo writes the frames of the stream to w as received from the server,
+// for instance to proxy or archive the stream, until the stream is closed or
+// w fails. Only whole frames are written: a frame cut by a lost connection is
+// dropped, and resumed by the next connection. Frames larger than the
+// maximum event size are dropped too.
+//
+// CopyTo must be the only reader of the Stream, such as one returned by
+// MessagesService.StreamRaw. It returns nil once the Stream is closed.
+func (s *Stream) CopyTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	s.frames = true
+	if s.dec != nil {
+		s.dec.frames = true
+	}
+	s.mu.Unlock()
+
+	var written int64
+	for {
+		ev, err := s.read()
+		switch {
+		case err == ErrEventTooLarge:
+			s.client.Logf("skipped Stream frame: %v", err)
+			continue
+		case err == ErrStreamClosed:
+			return written, nil
+		case err != nil:
+			return written, err
+		}
+
+		n, err := w.Write(ev.Raw)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// connect returns the decoder of the current connection, opening a new one
+// if needed. Failed attempts are retried as wait allows, except
+// those refused with a 401 or 403 which end the stream with a
+// StreamAuthError. A 401 invalidating cached Credentials is retried at once,
+// with a new token.
+func (s *Stream) connect() (*eventDecoder, error) {
+	reauthorized := false
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, ErrStreamClosed
+		}
+		if s.dec != nil {
+			dec := s.dec
+			s.mu.Unlock()
+			return dec, nil
+		}
+		req := s.request()
+		s.mu.Unlock()
+
+		end := func(*http.Response, error) {}
+		if s.client.Tracer != nil {
+			req, end = s.client.Tracer.Start(req, s.client.operation(req, true))
+		}
+
+		err := s.client.authorizeFromCredentials(req)
+		var resp *http.Response
+		if err == nil {
+			resp, err = s.client.client.Do(req)
+		}
+		if err == nil {
+			if err = CheckResponse(resp); err != nil {
+				resp.Body.Close()
+				if c := resp.StatusCode; c == http.StatusUnauthorized || c == http.StatusForbidden {
+					if s.client.rejectedCredentials(resp) && !reauthorized {
+						reauthorized = true
+						end(resp, s.client.redactError(err))
+						continue
+					}
+					err = &StreamAuthError{Response: s.client.redactError(err).(*ErrorResponse)}
+					end(resp, err)
+					s.client.Logf("stream connection refused: %v", err)
+					s.fail(err)
+					return nil, err
+				}
+			}
+		}
+		if err == nil {
+			s.mu.Lock()
+			if s.closed {
+				s.mu.Unlock()
+				resp.Body.Close()
+				end(resp, nil)
+				return nil, ErrStreamClosed
+			}
+			s.resp = resp
+			s.end = end
+			s.dec = newEventDecoder(resp.Body, s.client.maxEventSize())
+			s.dec.frames = s.frames
+			s.failures, s.paused = 0, 0
+			s.mu.Unlock()
+			continue
+		}
+
+		err = s.client.redactError(err)
+		end(resp, err)
+		s.client.Logf("failed to connect stream: %v", err)
+		if err := s.wait(err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// request returns a copy of the stream request carrying the Last-Event-ID
+// of the last received event, and the presence set by SetActive. The caller
+// must hold s.mu.
+func (s *Stream) request() *http.Request {
+	req := new(http.Request)
+	*req = *s.req
+	u := *s.req.URL
+	if s.active != nil {
+		q := u.Query()
+		if *s.active {
+			q.Set("active", "true")
+		} else {
+			q.Set("active", "idle")
+		}
+		u.RawQuery = q.Encode()
+	}
+	req.URL = &u
+	req.Header = make(http.Header)
+	for k, v := range s.req.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+	return req
+}
+
+// disconnect drops the current connection, lost because of err, and reports
+// whether the stream was closed, and whether the connection was dropped by
+// SetActive to be reopened at once.
+func (s *Stream) disconnect(err error) (closed, reconnect bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resp != nil {
+		s.resp.Body.Close()
+	}
+	if s.end != nil {
+		s.end(s.resp, err)
+		s.end = nil
+	}
+	s.resp = nil
+	s.dec = nil
+	reconnect, s.reconnect = s.reconnect, false
+	return s.closed, reconnect
+}
+
+// wait sleeps before reconnecting after err, for the backoff of the
+// stream's policy or else the retry delay, or until the stream is closed.
+// Connections refused for maintenance wait for the maintenance window at
+// least. It ends the stream with ErrReconnectsExhausted instead once the
+// policy's MaxRetries attempts failed in a row, not counting those refused
+// for maintenance.
+func (s *Stream) wait(err error) error {
+	window, maintenance := maintenanceBackoff(err, DefaultMaintenanceBackoff)
+	s.mu.Lock()
+	s.failures++
+	if maintenance {
+		s.paused++
+	}
+	r := Reconnect{Attempt: s.failures, Err: err, LastEventID: s.lastEventID, Delay: s.retry}
+	failed := s.failures - s.paused
+	p := s.policy
+	s.mu.Unlock()
+
+	if p != nil && p.MaxRetries > 0 && failed > p.MaxRetries {
+		err = fmt.Errorf("%w after %d attempts: %v", ErrReconnectsExhausted, p.MaxRetries, err)
+		s.client.Logf("stream given up: %v", err)
+		s.fail(err)
+		return err
+	}
+	if p != nil && p.Backoff != nil {
+		r.Delay = p.Backoff(r.Attempt)
+	}
+	if maintenance && r.Delay < window {
+		r.Delay = window
+	}
+	s.notify(r)
+
+	select {
+	case <-s.client.Clock.After(r.Delay):
+		return nil
+	case <-s.done:
+		return ErrStreamClosed
+	}
+}
@@ -0,0 +1,448 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStream_reconnect(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var lastEventIDs []string
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		// every connection sends a single event, then drops
+		id := len(lastEventIDs)
+		fmt.Fprintf(w, "retry: 1\nid: %d\ndata: message %d\n\n", id, id)
+	})
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+	defer stream.Close()
+
+	for i := 1; i <= 2; i++ {
+		ev, err := stream.read()
+		if err != nil {
+			t.Fatalf("Stream.read returned error: %v", err)
+		}
+		if want := fmt.Sprintf("message %d", i); string(ev.Data) != want {
+			t.Errorf("Stream.read returned %q, want %q", ev.Data, want)
+		}
+	}
+
+	if len(lastEventIDs) != 2 || lastEventIDs[0] != "" || lastEventIDs[1] != "1" {
+		t.Errorf("Stream sent Last-Event-ID headers %q, want [\"\" \"1\"]", lastEventIDs)
+	}
+}
+
+func TestStream_SetActive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	connections := make(chan *http.Request, 3)
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		connections <- r
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: %d\ndata: message\n\n", len(connections))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+	defer stream.Close()
+
+	if _, err := stream.read(); err != nil {
+		t.Fatalf("Stream.read returned error: %v", err)
+	}
+	stream.SetActive(false)
+	stream.SetActive(false)
+	if _, err := stream.read(); err != nil {
+		t.Fatalf("Stream.read returned error: %v", err)
+	}
+
+	for i, want := range []string{"", "idle"} {
+		r := <-connections
+		if got := r.URL.Query().Get("active"); got != want {
+			t.Errorf("connection %d had active = %q, want %q", i, got, want)
+		}
+		if i == 1 && r.Header.Get("Last-Event-ID") != "1" {
+			t.Errorf("connection %d had Last-Event-ID %q, want the last event", i, r.Header.Get("Last-Event-ID"))
+		}
+	}
+	select {
+	case <-connections:
+		t.Errorf("Stream reconnected for a presence it already had")
+	default:
+	}
+}
+
+func TestStream_Close(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := stream.read()
+		errCh <- err
+	}()
+
+	stream.Close()
+	if err := <-errCh; err != ErrStreamClosed {
+		t.Errorf("Stream.read returned %v after Close, want %v", err, ErrStreamClosed)
+	}
+}
+
+func TestStream_unauthorized(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		setup()
+		attempts := 0
+		mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			http.Error(w, "denied", status)
+		})
+
+		req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+		stream := newStream(client, req)
+
+		_, err := stream.read()
+		if _, ok := err.(*StreamAuthError); !ok || !errors.Is(err, ErrStreamUnauthorized) {
+			t.Errorf("%d: Stream.read returned %v, want a StreamAuthError", status, err)
+		}
+		if got := <-stream.Errors(); got != err {
+			t.Errorf("%d: Stream.Errors delivered %v, want %v", status, got, err)
+		}
+		if _, err := stream.read(); err != ErrStreamClosed {
+			t.Errorf("%d: Stream.read returned %v after a StreamAuthError, want %v", status, err, ErrStreamClosed)
+		}
+		if attempts != 1 {
+			t.Errorf("%d: Stream connected %d times, want 1", status, attempts)
+		}
+		teardown()
+	}
+}
+
+func TestStream_unauthorized_rotatedCredentials(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: hi\n\n")
+	})
+
+	token := "old"
+	client.Credentials = NewCachedCredentials(CredentialsFunc(func(context.Context) (string, error) {
+		t := token
+		token = "new"
+		return t, nil
+	}), time.Hour)
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+	defer stream.Close()
+
+	ev, err := stream.read()
+	if err != nil {
+		t.Fatalf("Stream.read returned error: %v", err)
+	}
+	if string(ev.Data) != "hi" {
+		t.Errorf("Stream.read returned %q, want %q", ev.Data, "hi")
+	}
+}
+
+func TestMessagesService_Stream_context(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, stream, err := client.Messages.Stream(ctx, "token", "org", "flow")
+	if err != nil {
+		t.Fatalf("Messages.Stream returned error: %v", err)
+	}
+
+	cancel()
+	select {
+	case <-stream.done:
+	case <-time.After(time.Second):
+		t.Errorf("Stream is open after its context was canceled")
+	}
+}
+
+// drain reads msgs and errs until both are closed, failing t if they are
+// still open after a second.
+func drain(t *testing.T, msgs <-chan Message, errs <-chan error) (n int, err error) {
+	timeout := time.After(time.Second)
+	for msgs != nil || errs != nil {
+		select {
+		case _, ok := <-msgs:
+			if !ok {
+				msgs = nil
+				continue
+			}
+			n++
+		case e, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			err = e
+		case <-timeout:
+			t.Fatalf("Stream channels are open after the Stream ended")
+		}
+	}
+	return n, err
+}
+
+func TestMessagesService_Stream_closeChannels(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for id := 0; r.Context().Err() == nil; id++ {
+			fmt.Fprintf(w, "id: %d\ndata: {\"event\":\"message\"}\n\n", id)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ends := map[string]func(*Stream){
+		"Close":   (*Stream).Close,
+		"context": func(*Stream) { cancel() },
+	}
+	for name, end := range ends {
+		msgs, stream, err := client.Messages.Stream(ctx, "token", "org", "flow")
+		if err != nil {
+			t.Fatalf("Messages.Stream returned error: %v", err)
+		}
+		<-msgs // the producer is blocked on the next message, unread
+		end(stream)
+		if _, err := drain(t, msgs, stream.Errors()); err != nil {
+			t.Errorf("%s: Stream.Errors delivered %v, want none", name, err)
+		}
+		stream.Close()
+	}
+}
+
+func TestStream_Done(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/open", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/flows/org/denied", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"denied"}`, http.StatusUnauthorized)
+	})
+
+	tests := []struct {
+		flow string
+		end  func(*Stream, context.CancelFunc)
+		want func(error) bool
+	}{
+		{"open", func(s *Stream, _ context.CancelFunc) { s.Close() }, func(err error) bool { return err == nil }},
+		{"open", func(_ *Stream, cancel context.CancelFunc) { cancel() }, func(err error) bool { return err == context.Canceled }},
+		{"denied", func(*Stream, context.CancelFunc) {}, func(err error) bool { return errors.Is(err, ErrStreamUnauthorized) }},
+	}
+	for i, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, stream, err := client.Messages.Stream(ctx, "token", "org", tt.flow)
+		if err != nil {
+			t.Fatalf("Messages.Stream returned error: %v", err)
+		}
+		if tt.flow == "open" && stream.Err() != nil {
+			t.Errorf("#%d: Stream.Err returned %v before the Stream ended", i, stream.Err())
+		}
+		tt.end(stream, cancel)
+		select {
+		case <-stream.Done():
+		case <-time.After(time.Second):
+			t.Fatalf("#%d: Stream.Done is open after the Stream ended", i)
+		}
+		if err := stream.Err(); !tt.want(err) {
+			t.Errorf("#%d: Stream.Err returned %v", i, err)
+		}
+		cancel()
+	}
+}
+
+func TestMessagesService_StreamFrom(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Last-Event-ID", "7")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 8\ndata: {\"id\":8,\"event\":\"message\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	msgs, stream, err := client.Messages.StreamFrom(context.Background(), "token", "org", "flow", "7")
+	if err != nil {
+		t.Fatalf("Messages.StreamFrom returned error: %v", err)
+	}
+	defer stream.Close()
+	if m := <-msgs; m.ID == nil || *m.ID != 8 {
+		t.Errorf("Messages.StreamFrom delivered %+v, want message 8", m)
+	}
+	if id := stream.LastEventID(); id != "8" {
+		t.Errorf("Stream.LastEventID returned %q, want 8", id)
+	}
+}
+
+func TestMessagesService_Stream_unauthorized(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"denied"}`, http.StatusUnauthorized)
+	})
+
+	msgs, stream, err := client.Messages.Stream(context.Background(), "token", "org", "flow")
+	if err != nil {
+		t.Fatalf("Messages.Stream returned error: %v", err)
+	}
+	n, err := drain(t, msgs, stream.Errors())
+	if n != 0 || !errors.Is(err, ErrStreamUnauthorized) {
+		t.Errorf("Messages.Stream delivered %d messages and %v, want a StreamAuthError", n, err)
+	}
+}
+
+func TestMessagesService_Stream_panic(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":1,\"event\":\"message\",\"app\":\"chat\"}\n\n")
+		fmt.Fprint(w, "data: {\"id\":2,\"event\":\"message\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	client.OnDeprecated = func(Deprecation) { panic("boom") }
+
+	for _, restart := range []bool{false, true} {
+		client.RestartOnPanic = restart
+		msgs, stream, err := client.Messages.Stream(context.Background(), "token", "org", "flow")
+		if err != nil {
+			t.Fatalf("Messages.Stream returned error: %v", err)
+		}
+		if restart {
+			if m := <-msgs; m.ID == nil || *m.ID != 2 {
+				t.Errorf("Messages.Stream delivered %+v after a panic, want message 2", m)
+			}
+			stream.Close()
+		}
+		_, err = drain(t, msgs, stream.Errors())
+		if perr, ok := err.(*PanicError); !ok || perr.Value != "boom" {
+			t.Errorf("restart %v: Stream.Errors delivered %v, want a *PanicError", restart, err)
+		}
+	}
+}
+
+func TestStream_Close_concurrent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func(i int) {
+			if i%2 == 0 {
+				stream.Close()
+			} else {
+				stream.fail(ErrStreamClosed)
+			}
+			done <- struct{}{}
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	for range stream.Errors() {
+	}
+}
+
+// chanWriter sends what is written to it on a channel.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestStream_CopyTo(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var lastEventIDs []string
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(lastEventIDs) == 1 {
+			fmt.Fprint(w, "retry: 1\nid: 1\ndata: {}\n\ndata: cut")
+			return
+		}
+		fmt.Fprint(w, ": keepalive\n\nid: 2\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	stream, err := client.Messages.StreamRaw(ctx, "token", "org", "flow")
+	if err != nil {
+		t.Fatalf("Messages.StreamRaw returned error: %v", err)
+	}
+
+	frames := make(chanWriter)
+	done := make(chan error)
+	go func() {
+		_, err := stream.CopyTo(frames)
+		done <- err
+	}()
+
+	for _, want := range []string{"retry: 1\nid: 1\ndata: {}\n\n", ": keepalive\n\n", "id: 2\ndata: {}\n\n"} {
+		if got := <-frames; got != want {
+			t.Errorf("Stream.CopyTo wrote %q, want %q", got, want)
+		}
+	}
+	stream.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Stream.CopyTo returned error: %v", err)
+	}
+	if len(lastEventIDs) != 2 || lastEventIDs[1] != "1" {
+		t.Errorf("Stream sent Last-Event-ID headers %q, want [\"\" \"1\"]", lastEventIDs)
+	}
+}
@@ -0,0 +1,170 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FlowFilter selects a flow streamed by MessagesService.StreamFlows.
+type FlowFilter = FlowRef
+
+// FlowFilters are the flows a stream of several flows is restricted to,
+// sent as the comma-separated "org/flow" list of the filter parameter.
+type FlowFilters []FlowFilter
+
+// EncodeValues implements the query.Encoder interface of go-querystring.
+// Invalid flow references fail with ErrInvalidFlowRef.
+func (f FlowFilters) EncodeValues(key string, v *url.Values) error {
+	names := make([]string, len(f))
+	for i, ref := range f {
+		if err := ref.validate(); err != nil {
+			return fmt.Errorf("%w: %q", err, ref.String())
+		}
+		names[i] = ref.String()
+	}
+	if len(names) > 0 {
+		v.Set(key, strings.Join(names, ","))
+	}
+	return nil
+}
+
+// StreamOptions specifies the optional parameters to the methods of
+// MessagesService streaming messages, such as StreamWithOptions and
+// StreamFlows.
+type StreamOptions struct {
+	// Filter restricts the streams of several flows, such as StreamUser,
+	// to these flows, so that the server only sends their events.
+	// StreamFlows sets it to its filters, and it is ignored by the
+	// streams of a single flow.
+	Filter FlowFilters `url:"filter,omitempty"`
+
+	// Active sets the presence of the user in the flows: "true" for
+	// active, "idle", or empty to leave it to the server. See
+	// Stream.SetActive to change it later on.
+	Active string `url:"active,omitempty"`
+
+	// User, if set, streams the private messages of the user too.
+	User bool `url:"user,int,omitempty"`
+
+	// LastEventID resumes the stream after this event, as StreamFrom.
+	LastEventID string `url:"-"`
+
+	// Reconnect sets how the stream reconnects, as for
+	// StreamWithReconnect.
+	Reconnect *ReconnectPolicy `url:"-"`
+}
+
+// StreamWithOptions streams the messages for the given flow as Stream
+// does, with the presence, resumption and reconnection set by opt.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamWithOptions(ctx context.Context, token, org, flow string, opt *StreamOptions) (<-chan Message, *Stream, error) {
+	var o StreamOptions
+	if opt != nil {
+		o = *opt
+	}
+	o.Filter = nil // the flow is in the URL
+	return s.stream(ctx, token, fmt.Sprintf("flows/%v/%v", org, flow), &o)
+}
+
+// FlowMessage is a message streamed by StreamFlows or StreamUser, with the
+// flow it was posted in. Flow is zero for private messages.
+type FlowMessage struct {
+	Message
+	Flow FlowRef
+}
+
+// StreamFlows streams the messages of several flows on a single connection,
+// as Stream does for one. Each message is sent with the flow of filters it
+// was posted in, so the flows are looked up first, failing for unknown ones.
+// The flows must be of organizations streamed from the same endpoint, see
+// Client.OrgEndpoints, or StreamFlows fails with ErrMixedEndpoints.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamFlows(ctx context.Context, token string, filters []FlowFilter, opt *StreamOptions) (<-chan FlowMessage, *Stream, error) {
+	if ctx == nil {
+		return nil, nil, errNonNilContext
+	}
+	if len(filters) == 0 {
+		return nil, nil, ErrInvalidFlowRef
+	}
+
+	refs := make(map[string]FlowRef, len(filters))
+	for _, f := range filters {
+		flow, _, err := s.client.Flows.GetRef(ctx, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if flow.ID != nil {
+			refs[*flow.ID] = f
+		}
+	}
+
+	var o StreamOptions
+	if opt != nil {
+		o = *opt
+	}
+	o.Filter = filters
+	return s.streamFlows(ctx, token, &o, refs)
+}
+
+// StreamUser streams the messages of every flow the user of token joined,
+// and their private messages, on a single connection, as Stream does for a
+// flow. Each message is sent with its flow, looked up first in the flows
+// the user of the client joined; messages of other flows, such as those
+// joined after the lookup, and private messages have a zero Flow. The User
+// of opt is always set; its Filter restricts the flows streamed.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func (s *MessagesService) StreamUser(ctx context.Context, token string, opt *StreamOptions) (<-chan FlowMessage, *Stream, error) {
+	if ctx == nil {
+		return nil, nil, errNonNilContext
+	}
+
+	flows, _, err := s.client.Flows.List(ctx, false, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	refs := make(map[string]FlowRef, len(flows))
+	for _, f := range flows {
+		if f.ID != nil && f.ParameterizedName != nil && f.Organization != nil && f.Organization.ParameterizedName != nil {
+			refs[*f.ID] = FlowRef{Org: OrgID(*f.Organization.ParameterizedName), Flow: *f.ParameterizedName}
+		}
+	}
+
+	user := StreamOptions{User: true}
+	if opt != nil {
+		user = *opt
+		user.User = true
+	}
+	return s.streamFlows(ctx, token, &user, refs)
+}
+
+// streamFlows streams the messages of the flows of the Filter of opt, all
+// those of the user if none, annotated with their flow as found in refs by
+// ID.
+func (s *MessagesService) streamFlows(ctx context.Context, token string, opt *StreamOptions, refs map[string]FlowRef) (<-chan FlowMessage, *Stream, error) {
+	msgs, stream, err := s.stream(ctx, token, "flows", opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flowCh := make(chan FlowMessage)
+	go func() {
+		defer close(flowCh)
+		for m := range msgs {
+			fm := FlowMessage{Message: m}
+			if m.FlowID != nil {
+				fm.Flow = refs[*m.FlowID]
+			}
+			select {
+			case flowCh <- fm:
+			case <-stream.done:
+				return
+			}
+		}
+	}()
+	return flowCh, stream, nil
+}
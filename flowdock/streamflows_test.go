@@ -0,0 +1,132 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMessagesService_StreamFlows(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/one", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"id-1"}`)
+	})
+	mux.HandleFunc("/flows/org/two", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"id-2"}`)
+	})
+	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"filter": "org/one,org/two", "active": "idle", "user": "1"})
+		testHeader(t, r, "Last-Event-ID", "3")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 4\ndata: {\"id\":4,\"event\":\"message\",\"flow\":\"id-2\"}\n\n")
+		fmt.Fprint(w, "id: 5\ndata: {\"id\":5,\"event\":\"message\",\"flow\":\"id-1\"}\n\n")
+		fmt.Fprint(w, "id: 6\ndata: {\"id\":6,\"event\":\"message\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	filters := []FlowFilter{{Org: "org", Flow: "one"}, {Org: "org", Flow: "two"}}
+	opt := &StreamOptions{Active: "idle", User: true, LastEventID: "3"}
+	msgs, stream, err := client.Messages.StreamFlows(context.Background(), "token", filters, opt)
+	if err != nil {
+		t.Fatalf("Messages.StreamFlows returned error: %v", err)
+	}
+	defer stream.Close()
+
+	want := []struct {
+		id   int
+		flow FlowRef
+	}{{4, filters[1]}, {5, filters[0]}, {6, FlowRef{}}}
+	for _, w := range want {
+		m := <-msgs
+		if *m.ID != w.id || m.Flow != w.flow {
+			t.Errorf("Messages.StreamFlows sent message %d of %v, want %d of %v", *m.ID, m.Flow, w.id, w.flow)
+		}
+	}
+}
+
+func TestMessagesService_StreamFlows_unknownFlow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	filters := []FlowFilter{{Org: "org", Flow: "missing"}}
+	if _, _, err := client.Messages.StreamFlows(context.Background(), "token", filters, nil); !IsNotFound(err) {
+		t.Errorf("Messages.StreamFlows returned %v, want a 404", err)
+	}
+	if _, _, err := client.Messages.StreamFlows(context.Background(), "token", nil, nil); err != ErrInvalidFlowRef {
+		t.Errorf("Messages.StreamFlows returned %v, want ErrInvalidFlowRef", err)
+	}
+}
+
+func TestMessagesService_StreamUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			fmt.Fprint(w, `[{"id":"id-1","parameterized_name":"one","organization":{"parameterized_name":"org"}}]`)
+			return
+		}
+		testFormValues(t, r, values{"user": "1", "filter": "org/one"})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 1\ndata: {\"id\":1,\"event\":\"message\",\"flow\":\"id-1\"}\n\n")
+		fmt.Fprint(w, "id: 2\ndata: {\"id\":2,\"event\":\"message\",\"to\":\"3\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	msgs, stream, err := client.Messages.StreamUser(context.Background(), "token", &StreamOptions{Filter: FlowFilters{{Org: "org", Flow: "one"}}})
+	if err != nil {
+		t.Fatalf("Messages.StreamUser returned error: %v", err)
+	}
+	defer stream.Close()
+
+	if m := <-msgs; *m.ID != 1 || m.Flow != (FlowRef{Org: "org", Flow: "one"}) {
+		t.Errorf("Messages.StreamUser sent message %d of %v, want 1 of org/one", *m.ID, m.Flow)
+	}
+	if m := <-msgs; *m.ID != 2 || m.Flow != (FlowRef{}) {
+		t.Errorf("Messages.StreamUser sent message %d of %v, want the private message 2", *m.ID, m.Flow)
+	}
+}
+
+func TestMessagesService_StreamWithOptions(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"active": "idle"})
+		testHeader(t, r, "Last-Event-ID", "7")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 8\ndata: {\"id\":8,\"event\":\"message\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	opt := &StreamOptions{Active: "idle", LastEventID: "7", Filter: FlowFilters{{Org: "org", Flow: "other"}}}
+	msgs, stream, err := client.Messages.StreamWithOptions(context.Background(), "token", "org", "flow", opt)
+	if err != nil {
+		t.Fatalf("Messages.StreamWithOptions returned error: %v", err)
+	}
+	defer stream.Close()
+	if m := <-msgs; *m.ID != 8 {
+		t.Errorf("Messages.StreamWithOptions sent message %d, want 8", *m.ID)
+	}
+}
+
+func TestFlowFilters_EncodeValues(t *testing.T) {
+	v := make(url.Values)
+	if err := (FlowFilters{{Org: "o", Flow: "a"}, {Org: "o", Flow: "b"}}).EncodeValues("filter", &v); err != nil {
+		t.Fatalf("FlowFilters.EncodeValues returned error: %v", err)
+	}
+	if got := v.Get("filter"); got != "o/a,o/b" {
+		t.Errorf("FlowFilters.EncodeValues set %q, want o/a,o/b", got)
+	}
+	if err := (FlowFilters{{Org: "o"}}).EncodeValues("filter", &v); !errors.Is(err, ErrInvalidFlowRef) {
+		t.Errorf("FlowFilters.EncodeValues returned %v, want ErrInvalidFlowRef", err)
+	}
+}
@@ -0,0 +1,303 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ErrStreamManagerClosed is returned when adding flows to a closed
+// StreamManager.
+var ErrStreamManagerClosed = errors.New("flowdock: stream manager closed")
+
+// Envelope is a message delivered by a StreamManager, labeled with where it
+// comes from.
+type Envelope struct {
+	Account  string // name of the account in a Registry, "" otherwise
+	Org      string // parameterized name of the organization
+	Flow     string // parameterized name of the flow
+	ThreadID string // "" for messages outside threads
+	Message  Message
+}
+
+// StreamManager streams the messages of several flows, possibly of
+// several organizations, into a single channel of Envelopes.
+type StreamManager struct {
+	client *Client
+	token  string
+
+	// Cursors, if set, is where Shutdown persists the ID of the last
+	// message handled by Serve in each flow, for Cursor to find it after
+	// a restart.
+	Cursors Store
+
+	mu      sync.Mutex
+	streams map[string]*Stream // by "account/org/flow"
+	events  chan Envelope
+	wg      sync.WaitGroup // forward calls
+	serving sync.WaitGroup // Serve calls
+	drained chan struct{}  // closed once no more events are delivered
+	handled map[string]int // last message handled by Serve, by cursor key
+	closed  bool
+}
+
+// NewStreamManager returns a StreamManager opening streams through client
+// with token, passed as selected by the Client's StreamAuth.
+func NewStreamManager(client *Client, token string) *StreamManager {
+	return &StreamManager{
+		client:  client,
+		token:   token,
+		streams: make(map[string]*Stream),
+		events:  make(chan Envelope),
+		drained: make(chan struct{}),
+		handled: make(map[string]int),
+	}
+}
+
+// Events returns the channel of the messages of the streamed flows. It is
+// closed by Close and Shutdown.
+func (m *StreamManager) Events() <-chan Envelope {
+	return m.events
+}
+
+// Add starts streaming the flow named flow in the organization org. Adding
+// a flow already streamed does nothing.
+func (m *StreamManager) Add(org, flow string) error {
+	return m.add("", m.client, m.token, org, flow)
+}
+
+// add streams a flow through client for account.
+func (m *StreamManager) add(account string, client *Client, token, org, flow string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStreamManagerClosed
+	}
+	key := account + "/" + org + "/" + flow
+	if m.streams[key] != nil {
+		return nil
+	}
+
+	msgs, stream, err := client.Messages.Stream(context.Background(), token, org, flow)
+	if err != nil {
+		return err
+	}
+	m.streams[key] = stream
+	m.wg.Add(1)
+	go m.forward(Envelope{Account: account, Org: org, Flow: flow}, msgs, stream)
+	return nil
+}
+
+// AddOrg streams every open flow the user joined in the organization org,
+// listed with ctx.
+func (m *StreamManager) AddOrg(ctx context.Context, org string) error {
+	return m.addOrg(ctx, "", m.client, m.token, org)
+}
+
+// addOrg streams the flows of org through client for account.
+func (m *StreamManager) addOrg(ctx context.Context, account string, client *Client, token, org string) error {
+	flows, _, err := client.Flows.List(ctx, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range flows {
+		if f.Organization == nil || f.Organization.ParameterizedName == nil ||
+			*f.Organization.ParameterizedName != org || f.ParameterizedName == nil {
+			continue
+		}
+		if f.Open != nil && !*f.Open {
+			continue
+		}
+		if err := m.add(account, client, token, org, *f.ParameterizedName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove stops streaming the flow named flow in the organization org.
+func (m *StreamManager) Remove(org, flow string) {
+	m.remove("", org, flow)
+}
+
+// remove stops streaming a flow of account.
+func (m *StreamManager) remove(account, org, flow string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := account + "/" + org + "/" + flow
+	if stream := m.streams[key]; stream != nil {
+		stream.Close()
+		delete(m.streams, key)
+	}
+}
+
+// Serve calls handle for each message of the streamed flows until Close or
+// Shutdown is called. Serve may be called from several goroutines to handle
+// messages concurrently.
+//
+// A panic of handle is recovered, and returned by Serve as a *PanicError,
+// without the message counting as handled in the cursor of its flow. With
+// the RestartOnPanic of the client, it is logged instead, and Serve goes on
+// with the next message.
+func (m *StreamManager) Serve(handle func(Envelope)) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.serving.Add(1)
+	m.mu.Unlock()
+	defer m.serving.Done()
+
+	for {
+		select {
+		case env, ok := <-m.events:
+			if !ok {
+				return nil
+			}
+			if err := protect(func() error { handle(env); return nil }); err != nil {
+				if !m.client.RestartOnPanic {
+					return err
+				}
+				m.client.Logf("StreamManager handler panicked on %s/%s: %v", env.Org, env.Flow, err)
+			}
+			if env.Message.ID != nil {
+				m.mu.Lock()
+				m.handled[cursorKey(env.Account, env.Org, env.Flow)] = *env.Message.ID
+				m.mu.Unlock()
+			}
+		case <-m.drained:
+			return nil
+		}
+	}
+}
+
+// Close stops streaming every flow and closes the Events channel at once,
+// dropping the messages being delivered. See Shutdown.
+func (m *StreamManager) Close() {
+	if m.stop() {
+		close(m.events)
+	}
+}
+
+// Shutdown stops streaming every flow, waits for the running handlers of
+// Serve to return, persists the cursors of the flows in Cursors and closes
+// the Events channel. Handlers still running when ctx is done are not
+// waited for, and Shutdown returns the error of ctx.
+func (m *StreamManager) Shutdown(ctx context.Context) error {
+	if !m.stop() {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.serving.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if persistErr := m.persist(); err == nil {
+		err = persistErr
+	}
+	close(m.events)
+	return err
+}
+
+// Cursor returns the ID of the last message of the flow named flow in the
+// organization org handled by Serve, or persisted in Cursors by a previous
+// Shutdown, or 0 if there is none.
+func (m *StreamManager) Cursor(org, flow string) (int, error) {
+	key := cursorKey("", org, flow)
+	m.mu.Lock()
+	id, ok := m.handled[key]
+	m.mu.Unlock()
+	if ok || m.Cursors == nil {
+		return id, nil
+	}
+
+	data, ok, err := m.Cursors.Get(key)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return strconv.Atoi(string(data))
+}
+
+// stop closes the streams and waits for the messages being delivered to be
+// handed over or dropped. It reports whether the StreamManager was open.
+func (m *StreamManager) stop() bool {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return false
+	}
+	m.closed = true
+	for key, stream := range m.streams {
+		stream.Close()
+		delete(m.streams, key)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	close(m.drained)
+	return true
+}
+
+// persist stores the cursors of the handled messages in Cursors.
+func (m *StreamManager) persist() error {
+	if m.Cursors == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, id := range m.handled {
+		if err := m.Cursors.Set(key, []byte(strconv.Itoa(id))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cursorKey returns the Cursors key of a flow of account.
+func cursorKey(account, org, flow string) string {
+	if account == "" {
+		return fmt.Sprintf("streams/%s/%s", org, flow)
+	}
+	return fmt.Sprintf("streams/%s/%s/%s", account, org, flow)
+}
+
+// forward delivers the messages of the stream of a flow, labeled like
+// label, until the stream is closed.
+func (m *StreamManager) forward(label Envelope, msgs <-chan Message, stream *Stream) {
+	defer m.wg.Done()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			env := label
+			env.Message = msg
+			if msg.ThreadID != nil {
+				env.ThreadID = *msg.ThreadID
+			}
+			select {
+			case m.events <- env:
+			case <-stream.done:
+				return
+			}
+		case <-stream.done:
+			return
+		}
+	}
+}
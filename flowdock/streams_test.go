@@ -0,0 +1,211 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// handleFlowStream streams a message of the given thread on flows/org/flow.
+func handleFlowStream(org, flow, thread string) {
+	mux.HandleFunc("/flows/"+org+"/"+flow, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: 1\ndata: {\"event\":\"message\",\"content\":\"in %s\",\"thread_id\":\"%s\"}\n\n", flow, thread)
+		w.(responseWriter).Flush()
+		<-r.Context().Done()
+	})
+}
+
+func TestStreamManager(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	handleFlowStream("o1", "a", "t1")
+	handleFlowStream("o2", "b", "t2")
+	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `[
+			{"parameterized_name": "b", "open": true, "organization": {"parameterized_name": "o2"}},
+			{"parameterized_name": "closed", "open": false, "organization": {"parameterized_name": "o2"}},
+			{"parameterized_name": "a", "open": true, "organization": {"parameterized_name": "o1"}}
+		]`)
+	})
+
+	m := NewStreamManager(client, "token")
+	if err := m.Add("o1", "a"); err != nil {
+		t.Fatalf("StreamManager.Add returned error: %v", err)
+	}
+	if err := m.AddOrg(ctx, "o2"); err != nil {
+		t.Fatalf("StreamManager.AddOrg returned error: %v", err)
+	}
+
+	got := make(map[string]Envelope)
+	for i := 0; i < 2; i++ {
+		env := <-m.Events()
+		got[env.Flow] = env
+	}
+	m.Close()
+
+	for flow, want := range map[string]Envelope{
+		"a": {Org: "o1", Flow: "a", ThreadID: "t1"},
+		"b": {Org: "o2", Flow: "b", ThreadID: "t2"},
+	} {
+		env := got[flow]
+		if env.Message.Content().String() != "in "+flow {
+			t.Errorf("StreamManager delivered %v for flow %s", env.Message.Content(), flow)
+		}
+		env.Message = Message{}
+		if !reflect.DeepEqual(env, want) {
+			t.Errorf("StreamManager delivered %+v, want %+v", env, want)
+		}
+	}
+
+	if _, ok := <-m.Events(); ok {
+		t.Errorf("StreamManager.Events is open after Close")
+	}
+	if err := m.Add("o1", "a"); err != ErrStreamManagerClosed {
+		t.Errorf("StreamManager.Add returned %v after Close, want ErrStreamManagerClosed", err)
+	}
+}
+
+// handleFlowMessages streams the messages with the given ids on
+// flows/org/flow.
+func handleFlowMessages(org, flow string, ids ...int) {
+	mux.HandleFunc("/flows/"+org+"/"+flow, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, id := range ids {
+			fmt.Fprintf(w, "id: %d\ndata: {\"id\":%d,\"event\":\"message\",\"content\":\"%d\"}\n\n", id, id, id)
+		}
+		w.(responseWriter).Flush()
+		<-r.Context().Done()
+	})
+}
+
+func TestStreamManager_Shutdown(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleFlowMessages("o", "f", 1, 2)
+
+	m := NewStreamManager(client, "token")
+	m.Cursors = NewMemoryStore()
+	if err := m.Add("o", "f"); err != nil {
+		t.Fatalf("StreamManager.Add returned error: %v", err)
+	}
+
+	started, release := make(chan int), make(chan bool)
+	served := make(chan bool)
+	var handled []int
+	go func() {
+		m.Serve(func(env Envelope) {
+			started <- *env.Message.ID
+			<-release
+			handled = append(handled, *env.Message.ID)
+		})
+		close(served)
+	}()
+
+	<-started
+	release <- true
+	<-started // the handler of message 2 is running
+
+	shutdown := make(chan error)
+	go func() { shutdown <- m.Shutdown(context.Background()) }()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("StreamManager.Shutdown returned %v before the handler", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	release <- true
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("StreamManager.Shutdown returned error: %v", err)
+	}
+	<-served
+	if want := []int{1, 2}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("StreamManager handled %v, want %v", handled, want)
+	}
+	if _, ok := <-m.Events(); ok {
+		t.Errorf("StreamManager.Events is open after Shutdown")
+	}
+
+	if data, _, _ := m.Cursors.Get("streams/o/f"); string(data) != "2" {
+		t.Errorf("StreamManager persisted cursor %q, want 2", data)
+	}
+	restarted := NewStreamManager(client, "token")
+	restarted.Cursors = m.Cursors
+	if id, err := restarted.Cursor("o", "f"); err != nil || id != 2 {
+		t.Errorf("StreamManager.Cursor returned %d, %v, want 2", id, err)
+	}
+}
+
+func TestStreamManager_Shutdown_deadline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleFlowMessages("o", "f", 1)
+
+	m := NewStreamManager(client, "token")
+	if err := m.Add("o", "f"); err != nil {
+		t.Fatalf("StreamManager.Add returned error: %v", err)
+	}
+
+	started, release := make(chan bool), make(chan bool)
+	go m.Serve(func(Envelope) {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("StreamManager.Shutdown returned %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestStreamManager_Serve_panic(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handleFlowMessages("o", "f", 1, 2)
+
+	for _, restart := range []bool{false, true} {
+		client.RestartOnPanic = restart
+		m := NewStreamManager(client, "token")
+		if err := m.Add("o", "f"); err != nil {
+			t.Fatalf("StreamManager.Add returned error: %v", err)
+		}
+
+		var handled []int
+		err := m.Serve(func(env Envelope) {
+			if *env.Message.ID == 1 {
+				panic("boom")
+			}
+			handled = append(handled, *env.Message.ID)
+			m.Close()
+		})
+		m.Close()
+
+		if restart {
+			if err != nil || !reflect.DeepEqual(handled, []int{2}) {
+				t.Errorf("StreamManager.Serve returned %v after handling %v, want message 2 handled", err, handled)
+			}
+			if id, _ := m.Cursor("o", "f"); id != 2 {
+				t.Errorf("StreamManager.Cursor returned %d, want 2", id)
+			}
+			continue
+		}
+		if perr, ok := err.(*PanicError); !ok || perr.Value != "boom" || handled != nil {
+			t.Errorf("StreamManager.Serve returned %v after handling %v, want a *PanicError", err, handled)
+		}
+		if id, _ := m.Cursor("o", "f"); id != 0 {
+			t.Errorf("StreamManager.Cursor returned %d after a panic, want 0", id)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// TypedMessage is a streamed message whose content is decoded directly as
+// the Content type T, such as *VcsContent, by StreamTyped.
+type TypedMessage[T Content] struct {
+	ID       *int      `json:"id,omitempty"`
+	FlowID   *string   `json:"flow,omitempty"`
+	Sent     *Time     `json:"sent,omitempty"`
+	UserID   *string   `json:"user,omitempty"`
+	Event    *string   `json:"event,omitempty"`
+	Content  T         `json:"content"`
+	Tags     *[]string `json:"tags,omitempty"`
+	ThreadID *string   `json:"thread_id,omitempty"`
+}
+
+// StreamTyped streams the messages for the given flow as StreamWithOptions
+// does, keeping only the events whose content is of type T, such as the
+// "vcs" events for *VcsContent, and decoding them directly as a
+// TypedMessage. It spares high-volume consumers of a single event type
+// the decoding of the other events and of a Message. The Client's Mute,
+// FloodDetector and OnDeprecated, if set, still observe each event as a
+// Message.
+//
+// Flowdock API docs: https://flowdock.com/api/streaming
+func StreamTyped[T Content](ctx context.Context, client *Client, token, org, flow string, opt *StreamOptions) (<-chan TypedMessage[T], *Stream, error) {
+	events := contentEvents(reflect.TypeOf((*T)(nil)).Elem())
+	if len(events) == 0 {
+		var zero T
+		return nil, nil, fmt.Errorf("flowdock: no event has content of type %T", zero)
+	}
+
+	var o StreamOptions
+	if opt != nil {
+		o = *opt
+	}
+	o.Filter = nil // the flow is in the URL
+
+	s := client.Messages
+	observed := client.Mute != nil || client.FloodDetector != nil || client.OnDeprecated != nil
+	return streamEvents(ctx, s, token, fmt.Sprintf("flows/%v/%v", org, flow), &o, func(event *event) (TypedMessage[T], bool) {
+		var m TypedMessage[T]
+		var head struct {
+			Event string `json:"event"`
+		}
+		if err := json.Unmarshal(event.Data, &head); err != nil {
+			s.client.Logf("skipped bad JSON data from Stream: %v", err)
+			return m, false
+		}
+		if !events[head.Event] {
+			return m, false
+		}
+		if observed && s.streamed(event) == nil {
+			return m, false
+		}
+		if err := json.Unmarshal(event.Data, &m); err != nil {
+			s.client.Logf("skipped bad JSON data from Stream: %v", err)
+			return m, false
+		}
+		return m, true
+	})
+}
+
+// contentEvents returns the events whose content is of type t.
+func contentEvents(t reflect.Type) map[string]bool {
+	events := make(map[string]bool)
+	for event, newContent := range contentTypes {
+		if reflect.TypeOf(newContent()) == t {
+			events[event] = true
+		}
+	}
+	return events
+}
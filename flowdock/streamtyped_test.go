@@ -0,0 +1,44 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStreamTyped(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"active": "idle"})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"event\":\"message\",\"user\":\"1\",\"content\":\"not vcs\"}\n\n")
+		fmt.Fprint(w, "data: {\"event\":\"vcs\",\"id\":1,\"user\":\"2\",\"content\":{\"event\":\"push\",\"compare\":\"https://example.com/muted\"}}\n\n")
+		fmt.Fprint(w, "data: {\"event\":\"vcs\",\"id\":2,\"user\":\"1\",\"content\":{\"event\":\"push\",\"compare\":\"https://example.com/master\"}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	client.Mute = NewMute()
+	client.Mute.MuteUser("2")
+
+	msgs, stream, err := StreamTyped[*VcsContent](ctx, client, "token", "org", "flow", &StreamOptions{Active: "idle"})
+	if err != nil {
+		t.Fatalf("StreamTyped returned error: %v", err)
+	}
+	defer stream.Close()
+
+	m := <-msgs
+	if *m.ID != 2 || m.Content.CompareURL == nil || *m.Content.CompareURL != "https://example.com/master" {
+		t.Errorf("StreamTyped delivered %+v, want the unmuted vcs message", m)
+	}
+}
+
+func TestStreamTyped_unknownContent(t *testing.T) {
+	if _, _, err := StreamTyped[*JsonContent](context.Background(), NewClient(nil), "token", "org", "flow", nil); err == nil {
+		t.Errorf("StreamTyped returned no error for a content type of no event")
+	}
+}
@@ -0,0 +1,133 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultSummaryLength is the number of characters of the first message
+	// of a conversation a HeuristicSummarizer keeps by default.
+	DefaultSummaryLength = 140
+
+	// DefaultSummaryReactions is the number of reactions a
+	// HeuristicSummarizer lists by default.
+	DefaultSummaryReactions = 3
+)
+
+// Summarizer summarizes conversations for Digest. Implement it to plug in
+// another summarizer, such as a language model or an extractive one,
+// without this package depending on it.
+type Summarizer interface {
+	Summarize(ctx context.Context, c *Conversation) (string, error)
+}
+
+// SummarizerFunc is a function implementing Summarizer.
+type SummarizerFunc func(ctx context.Context, c *Conversation) (string, error)
+
+// Summarize implements the Summarizer interface.
+func (f SummarizerFunc) Summarize(ctx context.Context, c *Conversation) (string, error) {
+	return f(ctx, c)
+}
+
+// HeuristicSummarizer is the Summarizer of Digest by default. It summarizes
+// a conversation with its first message, followed by its top reactions: the
+// emoji of the replies made of emoji only, such as ":+1:", the most used
+// first.
+type HeuristicSummarizer struct {
+	// MaxLength is the number of characters of the first message kept,
+	// followed by "…" when it is cut. Defaults to DefaultSummaryLength.
+	MaxLength int
+
+	// MaxReactions is the number of reactions listed. Defaults to
+	// DefaultSummaryReactions.
+	MaxReactions int
+}
+
+// Summarize implements the Summarizer interface.
+func (s *HeuristicSummarizer) Summarize(ctx context.Context, c *Conversation) (string, error) {
+	max := s.MaxLength
+	if max <= 0 {
+		max = DefaultSummaryLength
+	}
+	text := []rune(strings.Join(strings.Fields(c.Starter.contentText()), " "))
+	if len(text) > max {
+		text = append(text[:max], '…')
+	}
+	summary := string(text)
+
+	if reactions := s.reactions(c); len(reactions) > 0 {
+		summary += " (" + strings.Join(reactions, ", ") + ")"
+	}
+	return summary, nil
+}
+
+// reactions returns the top reactions of c with their count, such as
+// ":+1: 3".
+func (s *HeuristicSummarizer) reactions(c *Conversation) []string {
+	counts := make(map[string]int)
+	for _, m := range c.Replies {
+		content := strings.TrimSpace(m.contentText())
+		if content == "" || strings.TrimSpace(emojiRegexp.ReplaceAllString(content, "")) != "" {
+			continue
+		}
+		for _, emoji := range emojiRegexp.FindAllString(content, -1) {
+			counts[emoji]++
+		}
+	}
+
+	emoji := make([]string, 0, len(counts))
+	for e := range counts {
+		emoji = append(emoji, e)
+	}
+	sort.Slice(emoji, func(i, j int) bool {
+		if counts[emoji[i]] != counts[emoji[j]] {
+			return counts[emoji[i]] > counts[emoji[j]]
+		}
+		return emoji[i] < emoji[j]
+	})
+
+	max := s.MaxReactions
+	if max <= 0 {
+		max = DefaultSummaryReactions
+	}
+	if len(emoji) > max {
+		emoji = emoji[:max]
+	}
+	for i, e := range emoji {
+		emoji[i] = fmt.Sprintf("%s %d", e, counts[e])
+	}
+	return emoji
+}
+
+// ConversationSummary is the summary of a conversation in a Digest.
+type ConversationSummary struct {
+	Conversation *Conversation
+	Summary      string
+}
+
+// Digest summarizes each of convs, as returned by Conversations, with s, or
+// with a HeuristicSummarizer if s is nil. The failures of some
+// conversations don't stop the summary of the others, and are reported in a
+// *BulkError identified by the conversation ID; their summaries are left
+// empty.
+func Digest(ctx context.Context, convs []*Conversation, s Summarizer) ([]ConversationSummary, error) {
+	if s == nil {
+		s = new(HeuristicSummarizer)
+	}
+
+	summaries := make([]ConversationSummary, len(convs))
+	berr := new(BulkError)
+	for i, c := range convs {
+		summaries[i].Conversation = c
+		summary, err := s.Summarize(ctx, c)
+		if err != nil {
+			berr.add(i, c.ID, err)
+			continue
+		}
+		summaries[i].Summary = summary
+	}
+	return summaries, berr.err()
+}
@@ -0,0 +1,58 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	var msgs []Message
+	json.Unmarshal([]byte(`[
+		{"id": 1, "event": "message", "thread_id": "a", "content": "Deploy   is\nblocked on the migration"},
+		{"id": 2, "event": "message", "thread_id": "a", "content": ":+1:"},
+		{"id": 3, "event": "message", "thread_id": "a", "content": ":eyes: :+1:"},
+		{"id": 4, "event": "message", "thread_id": "a", "content": "I'm on it :tada:"},
+		{"id": 5, "event": "message", "thread_id": "b", "content": "Lunch?"}
+	]`), &msgs)
+	convs := Conversations(msgs, 0)
+
+	summaries, err := Digest(context.Background(), convs, &HeuristicSummarizer{MaxLength: 16})
+	if err != nil {
+		t.Fatalf("Digest returned error: %v", err)
+	}
+	var got []string
+	for _, s := range summaries {
+		got = append(got, s.Summary)
+	}
+	want := []string{"Deploy is blocke… (:+1: 2, :eyes: 1)", "Lunch?"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Digest returned %q, want %q", got, want)
+	}
+}
+
+func TestDigest_summarizerFailure(t *testing.T) {
+	var msgs []Message
+	json.Unmarshal([]byte(`[
+		{"id": 1, "event": "message", "thread_id": "a", "content": "one"},
+		{"id": 2, "event": "message", "thread_id": "b", "content": "two"}
+	]`), &msgs)
+
+	s := SummarizerFunc(func(ctx context.Context, c *Conversation) (string, error) {
+		if c.ID == "a" {
+			return "", errors.New("unavailable")
+		}
+		return "summary of " + c.ID, nil
+	})
+	summaries, err := Digest(context.Background(), Conversations(msgs, 0), s)
+
+	berr, ok := err.(*BulkError)
+	if !ok || len(berr.Errors) != 1 || berr.Errors[0].ID != "a" {
+		t.Fatalf("Digest returned %v, want a BulkError for a", err)
+	}
+	if summaries[0].Summary != "" || summaries[1].Summary != "summary of b" {
+		t.Errorf("Digest returned %+v, want b summarized only", summaries)
+	}
+}
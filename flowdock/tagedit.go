@@ -0,0 +1,92 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AddTags adds tags to the message with the given ID, keeping its other
+// tags, unlike Edit which replaces them. Tags the message has already,
+// ignoring case and leading "#", are not added again, and the message is
+// not edited when it has them all. The edit is not atomic, see EditTags.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) AddTags(ctx context.Context, org, flow string, id int, tags ...string) (*http.Response, error) {
+	return s.EditTags(ctx, org, flow, id, func(current Tags) Tags {
+		for _, tag := range tags {
+			if !containsTag(current, tag) {
+				current = append(current, tag)
+			}
+		}
+		return current
+	})
+}
+
+// RemoveTags removes tags from the message with the given ID, ignoring case
+// and leading "#", keeping its other tags. The message is not edited when
+// it has none of them. The edit is not atomic, see EditTags.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) RemoveTags(ctx context.Context, org, flow string, id int, tags ...string) (*http.Response, error) {
+	return s.EditTags(ctx, org, flow, id, func(current Tags) Tags {
+		var kept Tags
+		for _, tag := range current {
+			if !containsTag(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// EditTags sets the tags of the message with the given ID to the result of
+// edit, called with its current tags. Unchanged tags are not written back,
+// and the returned response is then the one of the read.
+//
+// The edit is not atomic: the API takes no precondition on the edits of
+// messages, so that tags set by another edit between the read and the
+// write of EditTags are lost.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) EditTags(ctx context.Context, org, flow string, id int, edit func(Tags) Tags) (*http.Response, error) {
+	m, resp, err := s.Get(ctx, org, flow, id)
+	if err != nil {
+		return resp, err
+	}
+
+	var current Tags
+	if m.Tags != nil {
+		current = append(current, *m.Tags...)
+	}
+	tags := edit(append(Tags(nil), current...))
+	if equalTags(tags, current) {
+		return resp, nil
+	}
+
+	u := fmt.Sprintf("/flows/%s/%s/messages/%d", org, flow, id)
+	req, err := s.client.newMessageRequest("PUT", u, &tagsEdit{Tags: tags})
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(ctx, req, nil)
+}
+
+// tagsEdit edits the tags of a message only. Unlike MessagesEditOptions,
+// it sends empty tags, to remove the last one.
+type tagsEdit struct {
+	Tags Tags `url:"tags" json:"tags"`
+}
+
+// equalTags reports whether a and b hold the same tags in the same order.
+func equalTags(a, b Tags) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
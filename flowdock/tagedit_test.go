@@ -0,0 +1,84 @@
+package flowdock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMessagesService_AddTags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	tags := []string{"deploy"}
+	puts := 0
+	mux.HandleFunc("/flows/org/flow/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			data, _ := json.Marshal(tags)
+			fmt.Fprintf(w, `{"id":1,"tags":%s}`, data)
+		case "PUT":
+			puts++
+			var body struct{ Tags []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			tags = body.Tags
+		}
+	})
+
+	if _, err := client.Messages.AddTags(ctx, "org", "flow", 1, "#Deploy", "prod", "prod"); err != nil {
+		t.Fatalf("Messages.AddTags returned error: %v", err)
+	}
+	if want := []string{"deploy", "prod"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Messages.AddTags set the tags %q, want %q", tags, want)
+	}
+	if _, err := client.Messages.AddTags(ctx, "org", "flow", 1, "prod"); err != nil || puts != 1 {
+		t.Errorf("Messages.AddTags of a tag already there returned %v after %d edits, want no edit", err, puts)
+	}
+
+	if _, err := client.Messages.RemoveTags(ctx, "org", "flow", 1, "DEPLOY", "prod"); err != nil {
+		t.Fatalf("Messages.RemoveTags returned error: %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("Messages.RemoveTags left the tags %q, want none", tags)
+	}
+}
+
+func TestMessagesService_EditTags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	tags := []string{"a", "b"}
+	puts := 0
+	mux.HandleFunc("/flows/org/flow/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			data, _ := json.Marshal(tags)
+			fmt.Fprintf(w, `{"id":1,"tags":%s}`, data)
+		case "PUT":
+			puts++
+			var body struct{ Tags []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			tags = body.Tags
+		}
+	})
+
+	var got Tags
+	_, err := client.Messages.EditTags(ctx, "org", "flow", 1, func(current Tags) Tags {
+		got = append(Tags(nil), current...)
+		return append(current[1:], "c")
+	})
+	if err != nil {
+		t.Fatalf("Messages.EditTags returned error: %v", err)
+	}
+	if want := (Tags{"a", "b"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages.EditTags edited the tags %q, want the current %q", got, want)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(tags, want) || puts != 1 {
+		t.Errorf("Messages.EditTags set the tags %q in %d edits, want %q in 1", tags, puts, want)
+	}
+}
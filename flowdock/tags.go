@@ -0,0 +1,89 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TagChange represents a Message's Content when Message.Event is
+// "tag-change": tags were added to or removed from a message.
+type TagChange struct {
+	MessageID int      `json:"message"`
+	Added     []string `json:"add"`
+	Removed   []string `json:"remove"`
+}
+
+// Return the string version of a TagChange
+func (c *TagChange) String() string {
+	return fmt.Sprintf("message %d: added %v, removed %v", c.MessageID, c.Added, c.Removed)
+}
+
+// TagChange returns the tag change m reports, if m is a "tag-change" event.
+func (m *Message) TagChange() (*TagChange, bool) {
+	if m.Event == nil || *m.Event != "tag-change" || m.RawContent == nil {
+		return nil, false
+	}
+	c := new(TagChange)
+	if err := json.Unmarshal(*m.RawContent, c); err != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// HasAdded reports whether tag was added, ignoring case and leading "#".
+func (c *TagChange) HasAdded(tag string) bool {
+	return containsTag(c.Added, tag)
+}
+
+// HasRemoved reports whether tag was removed, ignoring case and leading "#".
+func (c *TagChange) HasRemoved(tag string) bool {
+	return containsTag(c.Removed, tag)
+}
+
+// SubscribeTags reads msgs, such as a flow Stream, and sends the tag changes
+// adding or removing any of tags on the returned channel, or every tag
+// change if no tags are given. Other messages are discarded. The channel is
+// closed when msgs is.
+func SubscribeTags(msgs <-chan Message, tags ...string) <-chan TagChange {
+	changes := make(chan TagChange)
+	go func() {
+		defer close(changes)
+		for m := range msgs {
+			c, ok := m.TagChange()
+			if !ok || !c.matches(tags) {
+				continue
+			}
+			changes <- *c
+		}
+	}()
+	return changes
+}
+
+// matches reports whether c adds or removes any of tags, or whether tags is
+// empty.
+func (c *TagChange) matches(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if c.HasAdded(tag) || c.HasRemoved(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTag(tags []string, tag string) bool {
+	tag = normalizeTag(tag)
+	for _, t := range tags {
+		if normalizeTag(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
@@ -0,0 +1,55 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func tagChangeMessage(content string) Message {
+	raw := json.RawMessage(content)
+	event := "tag-change"
+	return Message{Event: &event, RawContent: &raw}
+}
+
+func TestMessage_TagChange(t *testing.T) {
+	m := tagChangeMessage(`{"message":42,"add":["#todo"],"remove":["wip"]}`)
+
+	c, ok := m.TagChange()
+	if !ok {
+		t.Fatal("Message.TagChange returned false for a tag-change event")
+	}
+	want := &TagChange{MessageID: 42, Added: []string{"#todo"}, Removed: []string{"wip"}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Message.TagChange returned %+v, want %+v", c, want)
+	}
+	if !reflect.DeepEqual(m.Content(), want) {
+		t.Errorf("Message.Content returned %+v, want %+v", m.Content(), want)
+	}
+	if !c.HasAdded("TODO") || !c.HasRemoved("#wip") || c.HasAdded("wip") {
+		t.Errorf("TagChange.HasAdded/HasRemoved mismatch for %+v", c)
+	}
+
+	event := "message"
+	if _, ok := (&Message{Event: &event}).TagChange(); ok {
+		t.Errorf("Message.TagChange returned true for a chat message")
+	}
+}
+
+func TestSubscribeTags(t *testing.T) {
+	msgs := make(chan Message, 4)
+	event := "message"
+	msgs <- Message{Event: &event}
+	msgs <- tagChangeMessage(`{"message":1,"add":["other"]}`)
+	msgs <- tagChangeMessage(`{"message":2,"add":["#approved"]}`)
+	msgs <- tagChangeMessage(`{"message":3,"remove":["todo"]}`)
+	close(msgs)
+
+	var ids []int
+	for c := range SubscribeTags(msgs, "approved", "#todo") {
+		ids = append(ids, c.MessageID)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("SubscribeTags sent changes of %v, want %v", ids, want)
+	}
+}
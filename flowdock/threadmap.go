@@ -0,0 +1,127 @@
+package flowdock
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ThreadMap maps the external thread IDs of integration sources to the IDs
+// of the Flowdock threads their messages landed in, and back. Kept in a
+// durable Store and maintained by a ThreadUpdater, it lets an integration
+// find, after a restart, the thread of an alert it posted before: to reply
+// to it with ThreadsService.CreateMessage, or to recognize the alert of
+// the thread of a streamed message. Sources are told apart by the hash of
+// their flow token, which is not stored. A ThreadMap is safe for
+// concurrent use.
+type ThreadMap struct {
+	store Store
+	mu    sync.Mutex // serializes the updates of both ways
+}
+
+// ExternalThread is the external thread of a source that a Flowdock thread
+// was created for.
+type ExternalThread struct {
+	Source string `json:"source"` // hash of the flow token of the source
+	ID     string `json:"id"`     // external thread ID
+}
+
+// NewThreadMap returns a ThreadMap kept in store. A nil store keeps it in
+// memory, for the life of the process only.
+func NewThreadMap(store Store) *ThreadMap {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &ThreadMap{store: store}
+}
+
+// ThreadSource returns the Source of the ExternalThreads of the source
+// with the given flow token.
+func ThreadSource(flowToken string) string {
+	return contentHash([]byte(flowToken))
+}
+
+// Set records that the external thread externalID of the source with the
+// given flow token is the Flowdock thread threadID.
+func (m *ThreadMap) Set(flowToken, externalID, threadID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ext := ExternalThread{Source: ThreadSource(flowToken), ID: externalID}
+	prev, ok, err := m.get(externalThreadKey(ext))
+	if err != nil {
+		return err
+	}
+	if ok && prev == threadID {
+		return nil
+	}
+
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Set(flowdockThreadKey(threadID), data); err != nil {
+		return err
+	}
+	if err := m.store.Set(externalThreadKey(ext), []byte(threadID)); err != nil {
+		return err
+	}
+	if ok {
+		return m.store.Delete(flowdockThreadKey(prev))
+	}
+	return nil
+}
+
+// ThreadID returns the ID of the Flowdock thread of the external thread
+// externalID of the source with the given flow token, and whether there is
+// one.
+func (m *ThreadMap) ThreadID(flowToken, externalID string) (string, bool, error) {
+	return m.get(externalThreadKey(ExternalThread{Source: ThreadSource(flowToken), ID: externalID}))
+}
+
+// External returns the external thread the Flowdock thread threadID was
+// created for, and whether there is one.
+func (m *ThreadMap) External(threadID string) (*ExternalThread, bool, error) {
+	data, ok, err := m.store.Get(flowdockThreadKey(threadID))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	ext := new(ExternalThread)
+	if err := json.Unmarshal(data, ext); err != nil {
+		return nil, false, fmt.Errorf("flowdock: thread %s: %v", threadID, err)
+	}
+	return ext, true, nil
+}
+
+// Delete forgets the external thread externalID of the source with the
+// given flow token, both ways.
+func (m *ThreadMap) Delete(flowToken, externalID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := externalThreadKey(ExternalThread{Source: ThreadSource(flowToken), ID: externalID})
+	threadID, ok, err := m.get(key)
+	if err != nil || !ok {
+		return err
+	}
+	if err := m.store.Delete(flowdockThreadKey(threadID)); err != nil {
+		return err
+	}
+	return m.store.Delete(key)
+}
+
+func (m *ThreadMap) get(key string) (string, bool, error) {
+	data, ok, err := m.store.Get(key)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
+func externalThreadKey(ext ExternalThread) string {
+	return fmt.Sprintf("threads/external/%s/%s", ext.Source, ext.ID)
+}
+
+func flowdockThreadKey(threadID string) string {
+	return "threads/flowdock/" + threadID
+}
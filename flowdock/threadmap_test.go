@@ -0,0 +1,57 @@
+package flowdock
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestThreadMap(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewThreadMap(store)
+
+	if err := m.Set("token", "alert-1", "a"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if err := m.Set("other", "alert-1", "b"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if id, ok, err := m.ThreadID("token", "alert-1"); err != nil || !ok || id != "a" {
+		t.Errorf("ThreadID returned %q, %v, %v, want a", id, ok, err)
+	}
+	if id, ok, err := m.ThreadID("other", "alert-1"); err != nil || !ok || id != "b" {
+		t.Errorf("ThreadID of another source returned %q, %v, %v, want b", id, ok, err)
+	}
+	ext, ok, err := m.External("a")
+	if want := (&ExternalThread{Source: ThreadSource("token"), ID: "alert-1"}); err != nil || !ok || !reflect.DeepEqual(ext, want) {
+		t.Errorf("External returned %+v, %v, %v, want %+v", ext, ok, err, want)
+	}
+
+	// the external thread moved to another thread
+	if err := m.Set("token", "alert-1", "c"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if _, ok, _ := m.External("a"); ok {
+		t.Errorf("External returned the external thread of a replaced thread")
+	}
+	if ext, ok, _ := m.External("c"); !ok || ext.ID != "alert-1" {
+		t.Errorf("External returned %+v, %v, want alert-1", ext, ok)
+	}
+
+	if err := m.Delete("token", "alert-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := m.ThreadID("token", "alert-1"); ok {
+		t.Errorf("ThreadID returned a deleted thread")
+	}
+	if _, ok, _ := m.External("c"); ok {
+		t.Errorf("External returned a deleted thread")
+	}
+
+	keys, _ := store.Keys("")
+	for _, key := range keys {
+		if strings.Contains(key, "token") {
+			t.Errorf("the flow token is stored in key %q", key)
+		}
+	}
+}
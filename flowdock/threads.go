@@ -0,0 +1,125 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ThreadsService handles communication with the thread related methods of
+// the Flowdock API: the threads of a flow, their messages, and replies to
+// them.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+type ThreadsService struct {
+	client *Client
+}
+
+// ThreadsListOptions specifies the optional parameters to the
+// ThreadsService.List method.
+type ThreadsListOptions struct {
+	Application int `url:"application,omitempty"` // only the threads of this application
+	Limit       int `url:"limit,omitempty"`
+}
+
+// List the threads of a flow, the most recently updated first.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+func (s *ThreadsService) List(ctx context.Context, org, flow string, opt *ThreadsListOptions) ([]Thread, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/threads", org, flow)
+
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	threads := new([]Thread)
+	resp, err := s.client.Do(ctx, req, threads)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return *threads, resp, err
+}
+
+// Get a thread of a flow by its id.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+func (s *ThreadsService) Get(ctx context.Context, org, flow, id string) (*Thread, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/threads/%v", org, flow, id)
+	return s.get(ctx, u)
+}
+
+// GetByID gets a thread by its id alone, whatever its flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+func (s *ThreadsService) GetByID(ctx context.Context, id string) (*Thread, *http.Response, error) {
+	u := fmt.Sprintf("threads/%v", id)
+	return s.get(ctx, u)
+}
+
+func (s *ThreadsService) get(ctx context.Context, u string) (*Thread, *http.Response, error) {
+	req, err := s.client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thread := new(Thread)
+	resp, err := s.client.Do(ctx, req, thread)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return thread, resp, err
+}
+
+// ListMessages lists the messages of a thread of a flow. Messages older
+// than the HistoryFloor of the client are dropped, as by
+// MessagesService.List.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+func (s *ThreadsService) ListMessages(ctx context.Context, org, flow, id string, opt *MessagesListOptions) ([]Message, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/threads/%v/messages", org, flow, id)
+
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages, resp, err := s.client.listMessages(ctx, u)
+	if err != nil {
+		return nil, resp, err
+	}
+	return s.client.withinFloor(messages), resp, err
+}
+
+// CreateMessage posts a message into a thread of a flow. The flow and
+// thread of opt are ignored.
+//
+// Flowdock API docs: https://www.flowdock.com/api/threads
+func (s *ThreadsService) CreateMessage(ctx context.Context, org, flow, id string, opt *MessagesCreateOptions) (*Message, *http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/threads/%v/messages", org, flow, id)
+
+	if opt != nil {
+		o := *opt
+		o.FlowID, o.ThreadID = "", ""
+		opt = &o
+	}
+	req, err := s.client.newMessageRequest("POST", u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message := new(Message)
+	resp, err := s.client.Do(ctx, req, message)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return message, resp, err
+}
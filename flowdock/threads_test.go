@@ -0,0 +1,147 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestThreadsService_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/threads", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"limit": "2"})
+		fmt.Fprint(w, `[{"id":"a","title":"Build failed"},{"id":"b","title":"Deploy"}]`)
+	})
+
+	threads, _, err := client.Threads.List(ctx, "org", "flow", &ThreadsListOptions{Limit: 2})
+	if err != nil {
+		t.Errorf("Threads.List returned error: %v", err)
+	}
+	a, b, failed, deploy := "a", "b", "Build failed", "Deploy"
+	want := []Thread{{ID: &a, Title: &failed}, {ID: &b, Title: &deploy}}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("Threads.List returned %+v, want %+v", threads, want)
+	}
+}
+
+func TestThreadsService_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/threads/a", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{
+			"id": "a",
+			"title": "Build failed",
+			"body": "<p>3 tests failed</p>",
+			"external_url": "https://ci.example.com/builds/1",
+			"status": {"color": "red", "value": "failed"},
+			"fields": [{"label": "Branch", "value": "master"}],
+			"actions": [{
+				"@type": "UpdateAction",
+				"name": "Retry",
+				"target": {"@type": "EntryPoint", "urlTemplate": "https://ci.example.com/builds/1/retry", "httpMethod": "POST"}
+			}],
+			"created_at": "2015-01-01T00:00:00Z"
+		}`)
+	})
+
+	thread, _, err := client.Threads.Get(ctx, "org", "flow", "a")
+	if err != nil {
+		t.Fatalf("Threads.Get returned error: %v", err)
+	}
+	id, title, body, url := "a", "Build failed", "<p>3 tests failed</p>", "https://ci.example.com/builds/1"
+	created := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := &Thread{
+		ID:          &id,
+		Title:       &title,
+		Body:        &body,
+		ExternalURL: &url,
+		Status:      &ThreadStatus{Color: "red", Value: "failed"},
+		Fields:      &[]ThreadField{{Label: "Branch", Value: "master"}},
+		Actions: &[]ThreadAction{{
+			Type: "UpdateAction",
+			Name: "Retry",
+			Target: &ThreadActionTarget{
+				Type:        "EntryPoint",
+				URLTemplate: "https://ci.example.com/builds/1/retry",
+				HTTPMethod:  "POST",
+			},
+		}},
+		CreatedAt: &created,
+	}
+	if !reflect.DeepEqual(thread, want) {
+		t.Errorf("Threads.Get returned %+v, want %+v", thread, want)
+	}
+}
+
+func TestThreadsService_GetByID(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/threads/a", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id":"a"}`)
+	})
+
+	thread, _, err := client.Threads.GetByID(ctx, "a")
+	if err != nil {
+		t.Fatalf("Threads.GetByID returned error: %v", err)
+	}
+	if *thread.ID != "a" {
+		t.Errorf("Threads.GetByID returned %+v", thread)
+	}
+}
+
+func TestThreadsService_ListMessages(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/threads/a/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"limit": "10"})
+		fmt.Fprint(w, `[{"id":1,"thread_id":"a"},{"id":2,"thread_id":"a"}]`)
+	})
+
+	messages, _, err := client.Threads.ListMessages(ctx, "org", "flow", "a", &MessagesListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Threads.ListMessages returned error: %v", err)
+	}
+	if len(messages) != 2 || *messages[0].ID != 1 || *messages[1].ThreadID != "a" {
+		t.Errorf("Threads.ListMessages returned %+v", messages)
+	}
+}
+
+func TestThreadsService_CreateMessage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/threads/a/messages", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testJSONValues(t, r, values{"event": "message", "content": "on it"})
+		fmt.Fprint(w, `{"id":3,"thread_id":"a"}`)
+	})
+
+	opt := &MessagesCreateOptions{Event: "message", Content: "on it", ThreadID: "other"}
+	message, _, err := client.Threads.CreateMessage(ctx, "org", "flow", "a", opt)
+	if err != nil {
+		t.Fatalf("Threads.CreateMessage returned error: %v", err)
+	}
+	if *message.ID != 3 {
+		t.Errorf("Threads.CreateMessage returned %+v", message)
+	}
+	if opt.ThreadID != "other" {
+		t.Errorf("Threads.CreateMessage modified its options: %+v", opt)
+	}
+}
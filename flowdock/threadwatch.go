@@ -0,0 +1,145 @@
+package flowdock
+
+import (
+	"context"
+	"sync"
+)
+
+// threadWatchBuffer is how many messages the channel of a thread watched
+// by a ThreadWatcher holds for its reader.
+const threadWatchBuffer = 16
+
+// ThreadWatcher delivers the messages of a stream, such as a flow Stream,
+// to the watchers of their threads, so that a bot can follow a
+// conversation, such as the thread of a deployment, without filtering
+// every message of the flow itself. Messages of threads nobody watches,
+// and messages without a thread, are dropped.
+//
+// Delivery is in the order of the stream, and waits for the readers of the
+// thread: a watcher must keep receiving until its context is done, or it
+// holds up the other threads.
+type ThreadWatcher struct {
+	cancel chan *threadWatch
+	done   chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	watches map[string][]*threadWatch // by thread ID
+}
+
+// threadWatch is a watcher of a thread.
+type threadWatch struct {
+	thread string
+	ctx    context.Context
+	ch     chan Message
+}
+
+// NewThreadWatcher returns a ThreadWatcher of the messages of msgs. It
+// ends when msgs is closed.
+func NewThreadWatcher(msgs <-chan Message) *ThreadWatcher {
+	w := &ThreadWatcher{
+		cancel:  make(chan *threadWatch),
+		done:    make(chan struct{}),
+		watches: make(map[string][]*threadWatch),
+	}
+	go w.run(msgs)
+	return w
+}
+
+// Watch returns the channel of the messages of the thread with the given
+// ID, from now on. It is closed once ctx is done, or the ThreadWatcher
+// ended. A thread can have several watchers, which all get its messages.
+func (w *ThreadWatcher) Watch(ctx context.Context, threadID string) <-chan Message {
+	tw := &threadWatch{thread: threadID, ctx: ctx, ch: make(chan Message, threadWatchBuffer)}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		close(tw.ch)
+		return tw.ch
+	}
+	w.watches[threadID] = append(w.watches[threadID], tw)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case w.cancel <- tw:
+			case <-w.done:
+			}
+		case <-w.done:
+		}
+	}()
+	return tw.ch
+}
+
+// Done returns a channel closed once the ThreadWatcher ended, and closed
+// the channels of its watchers.
+func (w *ThreadWatcher) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *ThreadWatcher) run(msgs <-chan Message) {
+	defer close(w.done)
+	for {
+		select {
+		case m, ok := <-msgs:
+			if !ok {
+				w.closeAll()
+				return
+			}
+			w.deliver(m)
+		case tw := <-w.cancel:
+			w.remove(tw)
+		}
+	}
+}
+
+// deliver sends m to the watchers of its thread.
+func (w *ThreadWatcher) deliver(m Message) {
+	if m.ThreadID == nil {
+		return
+	}
+	w.mu.Lock()
+	watches := append([]*threadWatch(nil), w.watches[*m.ThreadID]...)
+	w.mu.Unlock()
+
+	for _, tw := range watches {
+		select {
+		case tw.ch <- m:
+		case <-tw.ctx.Done():
+			w.remove(tw)
+		}
+	}
+}
+
+// remove closes the channel of tw, unless it was already.
+func (w *ThreadWatcher) remove(tw *threadWatch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches := w.watches[tw.thread]
+	for i, other := range watches {
+		if other == tw {
+			close(tw.ch)
+			watches = append(watches[:i:i], watches[i+1:]...)
+			break
+		}
+	}
+	if len(watches) == 0 {
+		delete(w.watches, tw.thread)
+	} else {
+		w.watches[tw.thread] = watches
+	}
+}
+
+func (w *ThreadWatcher) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	for _, watches := range w.watches {
+		for _, tw := range watches {
+			close(tw.ch)
+		}
+	}
+	w.watches = nil
+}
@@ -0,0 +1,64 @@
+package flowdock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThreadWatcher(t *testing.T) {
+	msgs := make(chan Message)
+	w := NewThreadWatcher(msgs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deploy := w.Watch(ctx, "deploy")
+	other := w.Watch(context.Background(), "deploy")
+	alerts := w.Watch(context.Background(), "alerts")
+
+	thread := func(id int, thread string) Message {
+		m := Message{ID: &id}
+		if thread != "" {
+			m.ThreadID = &thread
+		}
+		return m
+	}
+	msgs <- thread(1, "deploy")
+	msgs <- thread(2, "")
+	msgs <- thread(3, "alerts")
+	msgs <- thread(4, "deploy")
+
+	for _, ch := range []<-chan Message{deploy, other} {
+		for _, want := range []int{1, 4} {
+			if m := <-ch; *m.ID != want {
+				t.Errorf("thread deploy got message %d, want %d", *m.ID, want)
+			}
+		}
+	}
+	if m := <-alerts; *m.ID != 3 {
+		t.Errorf("thread alerts got message %d, want 3", *m.ID)
+	}
+
+	// a watcher done doesn't hold up the others, even with its channel full
+	cancel()
+	for i := 0; i < threadWatchBuffer+1; i++ {
+		msgs <- thread(5+i, "deploy")
+		<-other
+	}
+	timeout := time.After(time.Second)
+	for range deploy {
+		select {
+		case <-timeout:
+			t.Fatalf("channel of a canceled watcher not closed")
+		default:
+		}
+	}
+
+	close(msgs)
+	<-w.Done()
+	if _, ok := <-alerts; ok {
+		t.Errorf("channel of a watcher still open after the stream ended")
+	}
+	if _, ok := <-w.Watch(context.Background(), "late"); ok {
+		t.Errorf("Watch after the stream ended returned an open channel")
+	}
+}
@@ -6,7 +6,8 @@ import (
 )
 
 // Time represents a Flowdock time stamp which is milliseconds since Epoch
-// based
+// based. Decoded times are in UTC; use In to display them in another time
+// zone.
 type Time struct {
 	time.Time
 }
@@ -14,6 +15,10 @@ type Time struct {
 // UnmarshalJSON implements the json.Unmarshaler interface. The time is
 // expected to be an intger representing milliseconds since Epoch.
 func (t *Time) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
 	result, err := strconv.ParseInt(string(b), 0, 64)
 
 	if err != nil {
@@ -21,7 +26,18 @@ func (t *Time) UnmarshalJSON(b []byte) error {
 	}
 
 	// convert the unix epoch to a Time object
-	t.Time = time.Time(time.Unix(result/1000, 0))
+	t.Time = time.Unix(result/1000, (result%1000)*int64(time.Millisecond)).UTC()
 
 	return nil
 }
+
+// MarshalJSON implements the json.Marshaler interface. The time is encoded
+// as an integer representing milliseconds since Epoch, and the zero time,
+// which UnmarshalJSON decodes null to, as null.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return []byte(strconv.FormatInt(ms, 10)), nil
+}
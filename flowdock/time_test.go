@@ -13,8 +13,42 @@ func TestTime_UnmarshalJSON(t *testing.T) {
 		t.Errorf("Time.UnmarshalJSON returned error: %v", err)
 	}
 
-	want := time.Date(2013, time.November, 27, 9, 57, 31, 0, time.UTC)
+	want := time.Date(2013, time.November, 27, 9, 57, 31, 160*int(time.Millisecond), time.UTC)
+	if flowdockTime.Location() != time.UTC {
+		t.Errorf("Time.UnmarshalJSON set location to %v, wanted UTC", flowdockTime.Location())
+	}
 	if flowdockTime.Local() != want.Local() {
 		t.Errorf("Time.UnmarshalJSON set time to %v, wanted %v", flowdockTime.Local(), want.Local())
 	}
 }
+
+func TestTime_UnmarshalJSON_null(t *testing.T) {
+	flowdockTime := Time{}
+	err := flowdockTime.UnmarshalJSON([]byte("null"))
+	if err != nil {
+		t.Errorf("Time.UnmarshalJSON returned error: %v", err)
+	}
+	if !flowdockTime.IsZero() {
+		t.Errorf("Time.UnmarshalJSON set time to %v, wanted zero time", flowdockTime.Time)
+	}
+}
+
+func TestTime_MarshalJSON(t *testing.T) {
+	flowdockTime := Time{time.Date(2013, time.November, 27, 9, 57, 31, 160*int(time.Millisecond), time.UTC)}
+	json, err := flowdockTime.MarshalJSON()
+	if err != nil {
+		t.Errorf("Time.MarshalJSON returned error: %v", err)
+	}
+
+	want := "1385546251160"
+	if string(json) != want {
+		t.Errorf("Time.MarshalJSON returned %s, wanted %s", json, want)
+	}
+}
+
+func TestTime_MarshalJSON_zero(t *testing.T) {
+	json, err := Time{}.MarshalJSON()
+	if err != nil || string(json) != "null" {
+		t.Errorf("Time.MarshalJSON returned %s and %v, wanted null", json, err)
+	}
+}
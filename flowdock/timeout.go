@@ -0,0 +1,216 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EndpointClass groups the API requests sharing a timeout.
+type EndpointClass string
+
+const (
+	// ClassRead is the class of GET and HEAD requests, such as user and
+	// flow lookups.
+	ClassRead EndpointClass = "read"
+
+	// ClassWrite is the class of the requests changing data, such as
+	// message creation.
+	ClassWrite EndpointClass = "write"
+
+	// ClassUpload is the class of file uploads.
+	ClassUpload EndpointClass = "upload"
+)
+
+// requestClass returns the EndpointClass of req.
+func requestClass(req *http.Request) EndpointClass {
+	switch {
+	case strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/"):
+		return ClassUpload
+	case req.Method == "GET" || req.Method == "HEAD":
+		return ClassRead
+	}
+	return ClassWrite
+}
+
+// TimeoutError is returned for the requests which timed out, either on
+// their default timeout, or on the deadline of the context they were made
+// with. CallerDeadline tells the two apart: whether the API was too slow,
+// or the caller's deadline too short for the request. It wraps the error of
+// the request, which is or wraps context.DeadlineExceeded, or a net.Error
+// timing out.
+type TimeoutError struct {
+	Method string
+	URL    string // redacted
+	Class  EndpointClass
+
+	// DefaultTimeout is the timeout of the request: the Timeouts of its
+	// class, or else the Timeout of the http.Client, or 0 without one.
+	DefaultTimeout time.Duration
+
+	// Remaining is how long was left before the deadline of the caller's
+	// context when the request was made, or 0 without a deadline.
+	Remaining time.Duration
+
+	// CallerDeadline reports that the deadline of the caller's context
+	// was shorter than DefaultTimeout, and bounded the request instead.
+	CallerDeadline bool
+
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	timeout := "no " + string(e.Class) + " timeout"
+	if e.DefaultTimeout > 0 {
+		timeout = fmt.Sprintf("the %s timeout of %v", e.Class, e.DefaultTimeout)
+	}
+	switch {
+	case e.CallerDeadline:
+		return fmt.Sprintf("flowdock: %v %v timed out on the context deadline, %v away when sent, with %s: %v",
+			e.Method, e.URL, e.Remaining, timeout, e.Err)
+	case e.DefaultTimeout > 0:
+		return fmt.Sprintf("flowdock: %v %v timed out after %s: %v", e.Method, e.URL, timeout, e.Err)
+	}
+	return fmt.Sprintf("flowdock: %v %v timed out: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Timeout implements the net.Error interface.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// Temporary implements the net.Error interface. A request timing out on
+// the caller's deadline would time out again.
+func (e *TimeoutError) Temporary() bool { return !e.CallerDeadline }
+
+// timeoutBudget is the time a request is given: its default timeout, or
+// the deadline of its context when shorter.
+type timeoutBudget struct {
+	class     EndpointClass
+	timeout   time.Duration
+	remaining time.Duration
+	deadline  bool // the context deadline is shorter than the timeout
+}
+
+// budget returns the time req is given, at the time it is made.
+func (c *Client) budget(req *http.Request) timeoutBudget {
+	b := timeoutBudget{class: requestClass(req), timeout: c.Timeouts[requestClass(req)]}
+	if b.timeout <= 0 && c.client != nil {
+		b.timeout = c.client.Timeout
+	}
+	if deadline, ok := req.Context().Deadline(); ok {
+		if b.remaining = time.Until(deadline); b.remaining < 0 {
+			b.remaining = 0
+		}
+		b.deadline = b.timeout <= 0 || b.remaining < b.timeout
+	}
+	return b
+}
+
+// timeoutError returns err as a *TimeoutError when req timed out, with
+// the budget b it was given.
+func (c *Client) timeoutError(req *http.Request, b timeoutBudget, err error) error {
+	var ne net.Error
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) && !(errors.As(err, &ne) && ne.Timeout()) {
+		return err
+	}
+	if _, ok := err.(*TimeoutError); ok {
+		return err
+	}
+	return &TimeoutError{
+		Method:         req.Method,
+		URL:            c.redact(req.URL.String()),
+		Class:          b.class,
+		DefaultTimeout: b.timeout,
+		Remaining:      b.remaining,
+		CallerDeadline: b.deadline,
+		Err:            err,
+	}
+}
+
+// send sends req through the http.Client, hedging read requests when the
+// Client's HedgeAfter is set.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.HedgeAfter <= 0 || requestClass(req) != ClassRead {
+		return c.client.Do(req)
+	}
+	return c.hedge(req)
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+// hedge sends req, and sends it a second time if no response came after
+// HedgeAfter. The first response is returned and the other request is
+// canceled.
+func (c *Client) hedge(req *http.Request) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	attempt := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		n := len(cancels) - 1
+		go func() {
+			resp, err := c.client.Do(req.WithContext(ctx))
+			results <- hedgeResult{n, resp, err}
+		}()
+	}
+
+	attempt()
+	timer := c.Clock.After(c.HedgeAfter)
+	for pending := 1; ; {
+		select {
+		case <-timer:
+			timer = nil
+			attempt()
+			pending++
+		case r := <-results:
+			pending--
+			if r.err != nil && pending > 0 {
+				continue // the other request may still succeed
+			}
+			for i, cancel := range cancels {
+				if i != r.attempt {
+					cancel()
+				}
+			}
+			go discard(results, pending)
+			if r.err != nil {
+				cancels[r.attempt]()
+				return nil, r.err
+			}
+			// the request must stay alive until its body is read
+			r.resp.Body = cancelBody{r.resp.Body, cancels[r.attempt]}
+			return r.resp, nil
+		}
+	}
+}
+
+// discard closes the responses of the n hedged requests that lost.
+func discard(results chan hedgeResult, n int) {
+	for ; n > 0; n-- {
+		if r := <-results; r.resp != nil {
+			r.resp.Body.Close()
+		}
+	}
+}
+
+// cancelBody cancels the context of its request once closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,157 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestClass(t *testing.T) {
+	upload, _ := client.NewUploadRequest("flows/o/f/messages", strings.NewReader(""), "multipart/form-data; boundary=x")
+	tests := []struct {
+		method string
+		req    *http.Request
+		want   EndpointClass
+	}{
+		{method: "GET", want: ClassRead},
+		{method: "HEAD", want: ClassRead},
+		{method: "POST", want: ClassWrite},
+		{method: "DELETE", want: ClassWrite},
+		{req: upload, want: ClassUpload},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		if req == nil {
+			req, _ = http.NewRequest(tt.method, "/", nil)
+		}
+		if got := requestClass(req); got != tt.want {
+			t.Errorf("requestClass(%v) = %v, want %v", req.Method, got, tt.want)
+		}
+	}
+}
+
+func TestDo_timeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {})
+	client.Timeouts = map[EndpointClass]time.Duration{ClassRead: 10 * time.Millisecond}
+
+	req, _ := client.NewRequest("GET", "slow", nil)
+	if _, err := client.Do(ctx, req, nil); err == nil {
+		t.Errorf("Do returned no error for a read exceeding its timeout")
+	}
+
+	req, _ = client.NewRequest("POST", "fast", nil)
+	if _, err := client.Do(ctx, req, nil); err != nil {
+		t.Errorf("Do returned error for a write without timeout: %v", err)
+	}
+}
+
+func TestDo_timeoutError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	client.Timeouts = map[EndpointClass]time.Duration{ClassRead: 10 * time.Millisecond}
+
+	// the API is slow
+	req, _ := client.NewRequest("GET", "slow", nil)
+	_, err := client.Do(context.Background(), req, nil)
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Do returned %v, want a *TimeoutError", err)
+	}
+	if terr.CallerDeadline || terr.DefaultTimeout != 10*time.Millisecond || terr.Class != ClassRead || terr.Remaining != 0 {
+		t.Errorf("Do returned %+v, want the read timeout", terr)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !terr.Temporary() {
+		t.Errorf("Do returned %v, want a temporary context.DeadlineExceeded", err)
+	}
+
+	// the caller's deadline is shorter than the timeout
+	client.Timeouts[ClassRead] = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ = client.NewRequest("GET", "slow", nil)
+	_, err = client.Do(ctx, req, nil)
+	if !errors.As(err, &terr) {
+		t.Fatalf("Do returned %v, want a *TimeoutError", err)
+	}
+	if !terr.CallerDeadline || terr.DefaultTimeout != time.Minute || terr.Remaining <= 0 || terr.Remaining > 10*time.Millisecond {
+		t.Errorf("Do returned %+v, want the caller's deadline", terr)
+	}
+	if terr.Temporary() {
+		t.Errorf("TimeoutError on the caller's deadline is temporary")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "context deadline") || !strings.Contains(msg, "read timeout of 1m0s") {
+		t.Errorf("TimeoutError = %q, want the deadline and the timeout", msg)
+	}
+}
+
+func TestDo_hedge(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	release := make(chan struct{})
+	mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		n := attempts
+		mu.Unlock()
+		if n == 1 {
+			select { // stuck until canceled
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"nick":"jackie"}`)
+	})
+	defer close(release)
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	client.HedgeAfter = 50 * time.Millisecond
+
+	done := make(chan error)
+	var user *User
+	go func() {
+		var err error
+		user, _, err = client.Users.Get(ctx, 1)
+		done <- err
+	}()
+
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(client.HedgeAfter)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Users.Get returned error: %v", err)
+		}
+		if *user.Nick != "jackie" {
+			t.Errorf("Users.Get returned %+v, want jackie", user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hedged request was not sent")
+	}
+}
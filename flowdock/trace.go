@@ -0,0 +1,81 @@
+package flowdock
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Operation describes an API request, or a stream connection, to a Tracer.
+type Operation struct {
+	Method string
+
+	// Endpoint is the path of the request relative to the API root, with
+	// organization and flow names and numeric IDs replaced by
+	// placeholders: "flows/{org}/{flow}/messages/{id}".
+	Endpoint string
+
+	// Org and Flow are the names of the flow the request is about, if any.
+	Org, Flow string
+
+	// Stream is true for the connections of a Stream, which last until
+	// they are lost or the Stream is closed.
+	Stream bool
+}
+
+// Tracer observes the API requests and the stream connections of a Client.
+// The otelflowdock package provides one recording OpenTelemetry spans.
+type Tracer interface {
+	// Start is called before req is sent. It returns the request to send
+	// instead, which may carry a new context, and the function to call
+	// with the response or the error. For stream connections, the
+	// function is called when the connection ends, with the error which
+	// ended it, or nil when the Stream is closed.
+	Start(req *http.Request, op Operation) (*http.Request, func(*http.Response, error))
+}
+
+// operation describes req, sent to the streaming API if stream is true.
+func (c *Client) operation(req *http.Request, stream bool) Operation {
+	base := c.RestURL
+	if stream {
+		base = c.StreamURL
+	}
+	for _, e := range c.OrgEndpoints {
+		b := e.RestURL
+		if stream {
+			b = e.StreamURL
+		}
+		if b != nil && b.Host == req.URL.Host && strings.HasPrefix(req.URL.Path, b.Path) {
+			base = b
+			break
+		}
+	}
+
+	op := Operation{Method: req.Method, Stream: stream}
+	path := req.URL.Path
+	if base != nil {
+		path = strings.TrimPrefix(path, base.Path)
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	// flows/{org}/{flow}/..., but not flows/all or flows/find
+	if len(parts) >= 3 && parts[0] == "flows" {
+		op.Org, op.Flow = unescape(parts[1]), unescape(parts[2])
+		parts[1], parts[2] = "{org}", "{flow}"
+	}
+	for i, part := range parts {
+		if _, err := strconv.Atoi(part); err == nil {
+			parts[i] = "{id}"
+		}
+	}
+	op.Endpoint = strings.Join(parts, "/")
+	return op
+}
+
+func unescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
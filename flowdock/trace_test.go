@@ -0,0 +1,121 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTracer records the operations it observes.
+type testTracer struct {
+	mu   sync.Mutex
+	ops  []Operation
+	ends []string
+}
+
+func (t *testTracer) Start(req *http.Request, op Operation) (*http.Request, func(*http.Response, error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ops = append(t.ops, op)
+	return req, func(resp *http.Response, err error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.ends = append(t.ends, fmt.Sprintf("%s %d %v", op.Endpoint, status, err))
+	}
+}
+
+func TestClient_operation(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Operation
+	}{
+		{"flows/all", Operation{Method: "GET", Endpoint: "flows/all"}},
+		{"users/12", Operation{Method: "GET", Endpoint: "users/{id}"}},
+		{"flows/my%20org/main/messages/3", Operation{Method: "GET", Endpoint: "flows/{org}/{flow}/messages/{id}", Org: "my org", Flow: "main"}},
+	}
+	for _, tt := range tests {
+		req, _ := client.NewRequest("GET", tt.url, nil)
+		if got := client.operation(req, false); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("operation(%v) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDo_tracer(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	tracer := new(testTracer)
+	client.Tracer = tracer
+
+	client.Messages.List(ctx, "org", "flow", nil)
+	client.Users.Get(ctx, 1)
+
+	wantOps := []Operation{
+		{Method: "GET", Endpoint: "flows/{org}/{flow}/messages", Org: "org", Flow: "flow"},
+		{Method: "GET", Endpoint: "users/{id}"},
+	}
+	if !reflect.DeepEqual(tracer.ops, wantOps) {
+		t.Errorf("Tracer.Start was called with %+v, want %+v", tracer.ops, wantOps)
+	}
+	if len(tracer.ends) != 2 || tracer.ends[0] != "flows/{org}/{flow}/messages 200 <nil>" {
+		t.Errorf("Tracer ends were %q", tracer.ends)
+	}
+}
+
+func TestStream_tracer(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/flows/org/flow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 1\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	tracer := new(testTracer)
+	client.Tracer = tracer
+
+	req, _ := client.NewStreamRequest("GET", "flows/org/flow", nil)
+	stream := newStream(client, req)
+	if _, err := stream.read(); err != nil {
+		t.Fatalf("Stream.read returned error: %v", err)
+	}
+
+	tracer.mu.Lock()
+	ops, ends := len(tracer.ops), len(tracer.ends)
+	tracer.mu.Unlock()
+	if ops != 1 || !tracer.ops[0].Stream || ends != 0 {
+		t.Errorf("Tracer saw %+v and ends %q while connected", tracer.ops, tracer.ends)
+	}
+
+	stream.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		tracer.mu.Lock()
+		ends := tracer.ends
+		tracer.mu.Unlock()
+		if len(ends) == 1 {
+			if ends[0] != "flows/{org}/{flow} 200 <nil>" {
+				t.Errorf("Tracer end was %q, want a clean close", ends[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream connection trace was not ended by Close")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
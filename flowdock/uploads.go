@@ -0,0 +1,78 @@
+package flowdock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultUploadDedupTTL is how long an UploadDedup remembers uploads by
+// default.
+const DefaultUploadDedupTTL = 24 * time.Hour
+
+// UploadDedup keeps file uploads from being repeated: uploading a content
+// already uploaded to the same flow within TTL returns the message of the
+// earlier upload, whose FileContent holds the path of the file, instead of
+// uploading it again. Contents are compared by SHA-256 hash, regardless of
+// file names. Set it as the Client's UploadDedup.
+type UploadDedup struct {
+	store Store
+
+	// TTL is how long uploads are remembered.
+	TTL time.Duration
+}
+
+// NewUploadDedup returns an UploadDedup remembering uploads in store. A nil
+// store keeps them in memory; share a durable Store, such as a FileStore,
+// between runs of CI jobs.
+func NewUploadDedup(store Store) *UploadDedup {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &UploadDedup{store: store, TTL: DefaultUploadDedupTTL}
+}
+
+// contentHash returns the hash identifying an uploaded content.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func uploadKey(org, flow, hash string) string {
+	return fmt.Sprintf("uploads/%s/%s/%s", org, flow, hash)
+}
+
+// lookup returns the message of the upload of the content with the given
+// hash to org/flow, if it happened within TTL.
+func (d *UploadDedup) lookup(clock Clock, org, flow, hash string) (*Message, bool) {
+	data, ok, err := d.store.Get(uploadKey(org, flow, hash))
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || clock.Now().Sub(entry.Fetched.Time) >= d.TTL {
+		return nil, false
+	}
+	m := new(Message)
+	if err := json.Unmarshal(entry.Value, m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// remember records m as the upload of the content with the given hash to
+// org/flow.
+func (d *UploadDedup) remember(clock Clock, org, flow, hash string, m *Message) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cacheEntry{Fetched: Time{clock.Now()}, Value: value})
+	if err != nil {
+		return err
+	}
+	return d.store.Set(uploadKey(org, flow, hash), data)
+}
@@ -0,0 +1,56 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadDedup(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	uploads := 0
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		fmt.Fprintf(w, `{"id":%d,"event":"file","content":{"path":"/files/%d/report.txt"}}`, uploads, uploads)
+	})
+
+	clock := NewFakeClock(time.Now())
+	client.Clock = clock
+	client.UploadDedup = NewUploadDedup(nil)
+
+	code := strings.Repeat("x", MaxCodeMessageLength+1)
+	upload := func() string {
+		m, _, err := client.Messages.PostCode(ctx, "org", "flow", "report.txt", code)
+		if err != nil {
+			t.Fatalf("Messages.PostCode returned error: %v", err)
+		}
+		return m.Content().String()
+	}
+
+	first := upload()
+	if again := upload(); again != first || uploads != 1 {
+		t.Errorf("second upload returned %v after %d uploads, want %v after 1", again, uploads, first)
+	}
+
+	clock.Advance(DefaultUploadDedupTTL)
+	if upload(); uploads != 2 {
+		t.Errorf("upload after TTL made %d uploads, want 2", uploads)
+	}
+}
+
+func TestFileContent(t *testing.T) {
+	m := &Message{}
+	if err := m.UnmarshalJSON([]byte(`{"event":"file","content":{"path":"/p","file_name":"a.png","file_size":3}}`)); err != nil {
+		t.Fatal(err)
+	}
+	c, ok := m.Content().(*FileContent)
+	if !ok || *c.Path != "/p" || *c.FileName != "a.png" || *c.FileSize != 3 {
+		t.Errorf("Message.Content returned %+v, want a FileContent", m.Content())
+	}
+}
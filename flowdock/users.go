@@ -1,10 +1,13 @@
 package flowdock
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
 
+// UserUpdateOptions are the fields of a user changed by
+// UsersService.Update. Empty fields are left unchanged.
 type UserUpdateOptions struct {
 	Nick  string `json:"nick,omitempty"`
 	Email string `json:"email,omitempty"`
@@ -14,11 +17,16 @@ type UsersService struct {
 	client *Client
 }
 
-// All users visible to the authenticated user.
+// List all the users visible to the authenticated user: the members of
+// its organizations. Large lists are paged according to opt, see IterateAll
+// to walk all pages.
 //
 // Flowdock API docs: https://www.flowdock.com/api/users
-func (s *UsersService) All() ([]User, *http.Response, error) {
-	u := "users"
+func (s *UsersService) List(ctx context.Context, opt *ListOptions) ([]User, *http.Response, error) {
+	u, err := s.client.addOptions("users", opt)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -26,7 +34,7 @@ func (s *UsersService) All() ([]User, *http.Response, error) {
 	}
 
 	users := new([]User)
-	resp, err := s.client.Do(req, users)
+	resp, err := s.client.Do(ctx, req, users)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -34,19 +42,32 @@ func (s *UsersService) All() ([]User, *http.Response, error) {
 	return *users, resp, err
 }
 
-// List the users inside a flow.
+// All is List.
+//
+// Deprecated: use List, or ListByFlow for the users inside a flow.
+func (s *UsersService) All(ctx context.Context) ([]User, *http.Response, error) {
+	return s.List(ctx, nil)
+}
+
+// ListByFlow lists the users inside a flow. Large lists are paged according
+// to opt, see Iterate to walk all pages.
 //
 // Flowdock API docs: https://www.flowdock.com/api/users
-func (s *UsersService) List(org, flow string) ([]User, *http.Response, error) {
+func (s *UsersService) ListByFlow(ctx context.Context, org, flow string, opt *ListOptions) ([]User, *http.Response, error) {
 	u := fmt.Sprintf("users/%v/%v/users", org, flow)
 
+	u, err := s.client.addOptions(u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	users := new([]User)
-	resp, err := s.client.Do(req, users)
+	resp, err := s.client.Do(ctx, req, users)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -54,10 +75,37 @@ func (s *UsersService) List(org, flow string) ([]User, *http.Response, error) {
 	return *users, resp, err
 }
 
+// Iterate returns an iterator over all the users inside a flow, fetching
+// pages of opt.Limit users at a time with ctx.
+//
+// Flowdock API docs: https://www.flowdock.com/api/users
+func (s *UsersService) Iterate(ctx context.Context, org, flow string, opt *ListOptions) *UserIterator {
+	u := fmt.Sprintf("users/%v/%v/users", org, flow)
+
+	it, err := newIterator(ctx, s.client, u, opt)
+	if err != nil {
+		return &UserIterator{err: err}
+	}
+	return &UserIterator{it: it}
+}
+
+// IterateAll returns an iterator over all the users visible to the
+// authenticated user, as List, fetching pages of opt.Limit users at a time
+// with ctx, for organizations too large to list at once.
+//
+// Flowdock API docs: https://www.flowdock.com/api/users
+func (s *UsersService) IterateAll(ctx context.Context, opt *ListOptions) *UserIterator {
+	it, err := newIterator(ctx, s.client, "users", opt)
+	if err != nil {
+		return &UserIterator{err: err}
+	}
+	return &UserIterator{it: it}
+}
+
 // Get a user by their id.
 //
 // Flowdock API docs: https://www.flowdock.com/api/users
-func (s *UsersService) Get(id int) (*User, *http.Response, error) {
+func (s *UsersService) Get(ctx context.Context, id int) (*User, *http.Response, error) {
 	u := fmt.Sprintf("users/%v", id)
 
 	req, err := s.client.NewRequest("GET", u, nil)
@@ -66,7 +114,7 @@ func (s *UsersService) Get(id int) (*User, *http.Response, error) {
 	}
 
 	user := new(User)
-	resp, err := s.client.Do(req, user)
+	resp, err := s.client.Do(ctx, req, user)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -74,20 +122,19 @@ func (s *UsersService) Get(id int) (*User, *http.Response, error) {
 	return user, resp, err
 }
 
-// Update a user by their id.
+// Update a user by their id, changing the nick or email set in opt.
 //
 // Flowdock API docs: https://www.flowdock.com/api/users
-func (s *UsersService) Update(id int, opt *UserUpdateOptions) (*User, *http.Response, error) {
+func (s *UsersService) Update(ctx context.Context, id int, opt *UserUpdateOptions) (*User, *http.Response, error) {
 	u := fmt.Sprintf("users/%v", id)
 
-	u, err := addOptions(u, opt)
-	req, err := s.client.NewRequest("PUT", u, nil)
+	req, err := s.client.NewRequest("PUT", u, opt)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	user := new(User)
-	resp, err := s.client.Do(req, user)
+	resp, err := s.client.Do(ctx, req, user)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -95,13 +142,31 @@ func (s *UsersService) Update(id int, opt *UserUpdateOptions) (*User, *http.Resp
 	return user, resp, err
 }
 
+// AddToFlow adds the user with ID id, a member of the organization org, to
+// the flow named flow.
+//
+// Flowdock API docs: https://www.flowdock.com/api/users
+func (s *UsersService) AddToFlow(ctx context.Context, org, flow string, id int) (*http.Response, error) {
+	u := fmt.Sprintf("flows/%v/%v/users", org, flow)
+
+	req, err := s.client.NewRequest("POST", u, map[string]int{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(ctx, req, nil)
+}
+
 type User struct {
 	ID           *int    `json:"id,omitempty"`
 	Nick         *string `json:"nick,omitempty"`
 	Name         *string `json:"name,omitempty"`
 	Email        *string `json:"email,omitempty"`
 	Avatar       *string `json:"avatar,omitempty"`
+	Website      *string `json:"website,omitempty"`
 	Status       *string `json:"status,omitempty"`
+	InFlow       *bool   `json:"in_flow,omitempty"` // in flow user lists
+	Admin        *bool   `json:"admin,omitempty"`   // in organization user lists
 	Disabled     *bool   `json:"disabled,omitempty"`
 	LastActivity *Time   `json:"last_activity,omitempty"`
 	LastPing     *Time   `json:"last_ping,omitempty"`
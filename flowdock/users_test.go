@@ -1,6 +1,8 @@
 package flowdock
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -12,43 +14,192 @@ var (
 	userID2 int = 2
 )
 
-func TestUsersService_All(t *testing.T) {
+func TestUsersService_List(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `[{"id":1}, {"id":2}]`)
 	})
 
-	users, _, err := client.Users.All()
+	users, _, err := client.Users.List(ctx, nil)
 	if err != nil {
-		t.Errorf("Users.All returned error: %v", err)
+		t.Errorf("Users.List returned error: %v", err)
 	}
 
 	want := []User{{ID: &userID1}, {ID: &userID2}}
 	if !reflect.DeepEqual(users, want) {
-		t.Errorf("Users.All returned %+v, want %+v", users, want)
+		t.Errorf("Users.List returned %+v, want %+v", users, want)
 	}
 }
 
-func TestUsersService_List(t *testing.T) {
+func TestUsersService_List_paged(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"limit": "2", "since_id": "5"})
+		fmt.Fprint(w, `[{"id":6}, {"id":7}]`)
+	})
+
+	users, _, err := client.Users.List(ctx, &ListOptions{Limit: 2, SinceID: 5})
+	if err != nil {
+		t.Errorf("Users.List returned error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Users.List returned %+v, want 2 users", users)
+	}
+}
+
+func TestUsersService_ListByFlow(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/users/orgname/flowname/users", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `[{"id":1}, {"id":2}]`)
 	})
 
-	users, _, err := client.Users.List("orgname", "flowname")
+	users, _, err := client.Users.ListByFlow(ctx, "orgname", "flowname", nil)
 	if err != nil {
-		t.Errorf("Users.List returned error: %v", err)
+		t.Errorf("Users.ListByFlow returned error: %v", err)
 	}
 
 	want := []User{{ID: &userID1}, {ID: &userID2}}
 	if !reflect.DeepEqual(users, want) {
-		t.Errorf("Users.List returned %+v, want %+v", users, want)
+		t.Errorf("Users.ListByFlow returned %+v, want %+v", users, want)
+	}
+}
+
+func TestUsersService_ListByFlow_paged(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/users/orgname/flowname/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		testFormValues(t, r, values{"limit": "2", "since_id": "5"})
+		fmt.Fprint(w, `[{"id":6}, {"id":7}]`)
+	})
+
+	users, _, err := client.Users.ListByFlow(ctx, "orgname", "flowname", &ListOptions{Limit: 2, SinceID: 5})
+	if err != nil {
+		t.Errorf("Users.ListByFlow returned error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Users.ListByFlow returned %+v, want 2 users", users)
+	}
+}
+
+func TestUsersService_Iterate_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/users/orgname/flowname/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.FormValue("page") == "2" {
+			fmt.Fprint(w, `[{"id":3}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/users/orgname/flowname/users?page=2>; rel="next"`, server.URL))
+		fmt.Fprint(w, `[{"id":1}, {"id":2}]`)
+	})
+
+	testUserIterator(t, client.Users.Iterate(ctx, "orgname", "flowname", nil), []int{1, 2, 3})
+}
+
+func TestUsersService_Iterate_since(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	pages := map[string]string{
+		"":  `[{"id":1}, {"id":2}]`,
+		"2": `[{"id":3}, {"id":4}]`,
+		"4": `[]`,
+	}
+	mux.HandleFunc("/users/orgname/flowname/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.FormValue("limit") != "2" {
+			t.Errorf("Request limit = %v, want 2", r.FormValue("limit"))
+		}
+		fmt.Fprint(w, pages[r.FormValue("since_id")])
+	})
+
+	testUserIterator(t, client.Users.Iterate(ctx, "orgname", "flowname", &ListOptions{Limit: 2}), []int{1, 2, 3, 4})
+}
+
+func TestUsersService_Iterate_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/users/orgname/flowname/users", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Request", 400)
+	})
+
+	it := client.Users.Iterate(ctx, "orgname", "flowname", nil)
+	if it.Next() {
+		t.Errorf("UserIterator.Next returned true on error")
+	}
+	if it.Err() == nil {
+		t.Errorf("UserIterator.Err returned nil, want an error")
+	}
+}
+
+func TestUsersService_IterateAll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	pages := map[string]string{
+		"":  `[{"id":1}, {"id":2}]`,
+		"2": `[{"id":3}]`,
+	}
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if r.FormValue("limit") != "2" {
+			t.Errorf("Request limit = %v, want 2", r.FormValue("limit"))
+		}
+		fmt.Fprint(w, pages[r.FormValue("since_id")])
+	})
+
+	testUserIterator(t, client.Users.IterateAll(ctx, &ListOptions{Limit: 2}), []int{1, 2, 3})
+}
+
+func TestUsersService_IterateAll_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("page") == "2" {
+			fmt.Fprint(w, `[{"id":3}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/users?page=2>; rel="next"`, server.URL))
+		fmt.Fprint(w, `[{"id":1}, {"id":2}]`)
+	})
+
+	testUserIterator(t, client.Users.IterateAll(ctx, nil), []int{1, 2, 3})
+}
+
+func testUserIterator(t *testing.T, it *UserIterator, want []int) {
+	var ids []int
+	for it.Next() {
+		ids = append(ids, *it.User().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("UserIterator.Err returned %v", err)
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("UserIterator returned users %v, want %v", ids, want)
 	}
 }
 
@@ -56,12 +207,13 @@ func TestUsersService_Get(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
 		testMethod(t, r, "GET")
 		fmt.Fprint(w, `{"id":1}`)
 	})
 
-	user, _, err := client.Users.Get(userID1)
+	user, _, err := client.Users.Get(ctx, userID1)
 	if err != nil {
 		t.Errorf("Users.Get returned error: %v", err)
 	}
@@ -76,17 +228,24 @@ func TestUsersService_Update(t *testing.T) {
 	setup()
 	defer teardown()
 
+	ctx := context.Background()
 	nick := "new-nick"
 
 	mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+		v := new(UserUpdateOptions)
+		json.NewDecoder(r.Body).Decode(v)
+
 		testMethod(t, r, "PUT")
+		if want := (&UserUpdateOptions{Nick: "new-nick"}); !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
 		fmt.Fprint(w, `{"id":1, "nick":"new-nick"}`)
 	})
 
 	opts := &UserUpdateOptions{
 		Nick: "new-nick",
 	}
-	user, _, err := client.Users.Update(userID1, opts)
+	user, _, err := client.Users.Update(ctx, userID1, opts)
 	if err != nil {
 		t.Errorf("Users.Update returned error: %v", err)
 	}
@@ -96,3 +255,24 @@ func TestUsersService_Update(t *testing.T) {
 		t.Errorf("Users.Update returned %+v, want %+v", user.Nick, want.Nick)
 	}
 }
+
+func TestUsersService_AddToFlow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	mux.HandleFunc("/flows/org/flow/users", func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]int
+		json.NewDecoder(r.Body).Decode(&v)
+
+		testMethod(t, r, "POST")
+		if want := map[string]int{"id": 1}; !reflect.DeepEqual(v, want) {
+			t.Errorf("Request body = %+v, want %+v", v, want)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if _, err := client.Users.AddToFlow(ctx, "org", "flow", userID1); err != nil {
+		t.Errorf("Users.AddToFlow returned error: %v", err)
+	}
+}
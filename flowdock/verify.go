@@ -0,0 +1,111 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultVerifyAttempts is how many times VerifyDelivery looks for a
+	// message by default.
+	DefaultVerifyAttempts = 5
+
+	// DefaultVerifyBackoff is the delay before VerifyDelivery looks for a
+	// message again by default. It doubles after each attempt.
+	DefaultVerifyBackoff = 200 * time.Millisecond
+)
+
+// ErrNotDelivered is returned by VerifyDelivery for messages which were
+// not visible after its last attempt.
+var ErrNotDelivered = errors.New("flowdock: message not delivered")
+
+// VerifyDeliveryOptions specifies the optional parameters to the
+// MessagesService.VerifyDelivery method.
+type VerifyDeliveryOptions struct {
+	// Attempts is how many times the message is looked for. Defaults to
+	// DefaultVerifyAttempts.
+	Attempts int
+
+	// Backoff is the delay before the second attempt, doubled before
+	// each of the next ones. Defaults to DefaultVerifyBackoff.
+	Backoff time.Duration
+}
+
+// VerifyDelivery confirms that m, as returned by Create or another method
+// posting a message in flow, was persisted: it looks the message up until
+// the API returns it, and returns its representation on the server. The
+// message is got by its ID, or, without one, found among the latest
+// messages of the flow by its UUID. Lookups which don't find it, or fail
+// temporarily, are tried again as set by opt, with the Client's Clock; the
+// last failure is then returned, wrapping ErrNotDelivered when the message
+// was not found. It is meant for tests and critical notifications, which
+// must not carry on before their message is visible.
+//
+// Flowdock API docs: https://www.flowdock.com/api/messages
+func (s *MessagesService) VerifyDelivery(ctx context.Context, flow FlowRef, m *Message, opt *VerifyDeliveryOptions) (*Message, error) {
+	if err := flow.validate(); err != nil {
+		return nil, err
+	}
+	if m.ID == nil && (m.UUID == nil || *m.UUID == "") {
+		return nil, fmt.Errorf("flowdock: message to verify has neither an ID nor a UUID")
+	}
+
+	attempts, backoff := DefaultVerifyAttempts, DefaultVerifyBackoff
+	if opt != nil && opt.Attempts > 0 {
+		attempts = opt.Attempts
+	}
+	if opt != nil && opt.Backoff > 0 {
+		backoff = opt.Backoff
+	}
+
+	for n := 1; ; n++ {
+		found, err := s.lookup(ctx, flow, m)
+		if err == nil && found != nil {
+			return found, nil
+		}
+		if err == nil || IsNotFound(err) {
+			err = fmt.Errorf("%w: %s", ErrNotDelivered, deliveryKey(m))
+		} else if !isRetryable(err) {
+			return nil, err
+		}
+		if n >= attempts {
+			return nil, err
+		}
+
+		select {
+		case <-s.client.Clock.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// lookup returns m as found in flow, or nil if it is not.
+func (s *MessagesService) lookup(ctx context.Context, flow FlowRef, m *Message) (*Message, error) {
+	if m.ID != nil {
+		found, _, err := s.Get(ctx, string(flow.Org), flow.Flow, *m.ID)
+		return found, err
+	}
+
+	latest, _, err := s.List(ctx, string(flow.Org), flow.Flow, &MessagesListOptions{Limit: defaultIteratePageSize})
+	if err != nil {
+		return nil, err
+	}
+	for i := range latest {
+		if latest[i].UUID != nil && *latest[i].UUID == *m.UUID {
+			return &latest[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// deliveryKey identifies m in the errors of VerifyDelivery.
+func deliveryKey(m *Message) string {
+	if m.ID != nil {
+		return fmt.Sprintf("message %d", *m.ID)
+	}
+	return "message " + *m.UUID
+}
@@ -0,0 +1,94 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMessagesService_VerifyDelivery(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	gets := 0
+	mux.HandleFunc("/flows/org/flow/messages/1", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		if gets++; gets < 3 {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id":1,"event":"message","content":"hi","sent":1000}`)
+	})
+
+	id := 1
+	opt := &VerifyDeliveryOptions{Backoff: time.Millisecond}
+	m, err := client.Messages.VerifyDelivery(ctx, FlowRef{"org", "flow"}, &Message{ID: &id}, opt)
+	if err != nil {
+		t.Fatalf("Messages.VerifyDelivery returned error: %v", err)
+	}
+	if gets != 3 || m.Sent == nil || m.Content().String() != "hi" {
+		t.Errorf("Messages.VerifyDelivery returned %+v after %d requests, want the message after 3", m, gets)
+	}
+}
+
+func TestMessagesService_VerifyDelivery_uuid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	lists := 0
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		testFormValues(t, r, values{"limit": "100"})
+		if lists++; lists < 2 {
+			fmt.Fprint(w, `[{"id":1,"uuid":"other"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id":1,"uuid":"other"},{"id":2,"uuid":"u-1"}]`)
+	})
+
+	uuid := "u-1"
+	opt := &VerifyDeliveryOptions{Backoff: time.Millisecond}
+	m, err := client.Messages.VerifyDelivery(ctx, FlowRef{"org", "flow"}, &Message{UUID: &uuid}, opt)
+	if err != nil {
+		t.Fatalf("Messages.VerifyDelivery returned error: %v", err)
+	}
+	if *m.ID != 2 {
+		t.Errorf("Messages.VerifyDelivery returned %+v, want message 2", m)
+	}
+}
+
+func TestMessagesService_VerifyDelivery_failures(t *testing.T) {
+	setup()
+	defer teardown()
+
+	ctx := context.Background()
+	requests := map[int]int{}
+	mux.HandleFunc("/flows/org/flow/messages/", func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		fmt.Sscanf(r.URL.Path, "/flows/org/flow/messages/%d", &id)
+		requests[id]++
+		if id == 1 {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"message":"forbidden"}`, http.StatusForbidden)
+	})
+
+	opt := &VerifyDeliveryOptions{Attempts: 3, Backoff: time.Millisecond}
+	missing, forbidden := 1, 2
+	_, err := client.Messages.VerifyDelivery(ctx, FlowRef{"org", "flow"}, &Message{ID: &missing}, opt)
+	if !errors.Is(err, ErrNotDelivered) || requests[1] != 3 {
+		t.Errorf("Messages.VerifyDelivery returned %v after %d requests, want ErrNotDelivered after 3", err, requests[1])
+	}
+	_, err = client.Messages.VerifyDelivery(ctx, FlowRef{"org", "flow"}, &Message{ID: &forbidden}, opt)
+	if !IsForbidden(err) || requests[2] != 1 {
+		t.Errorf("Messages.VerifyDelivery returned %v after %d requests, want a 403 at once", err, requests[2])
+	}
+	if _, err := client.Messages.VerifyDelivery(ctx, FlowRef{"org", "flow"}, &Message{}, opt); err == nil {
+		t.Errorf("Messages.VerifyDelivery returned no error for a message without ID or UUID")
+	}
+}
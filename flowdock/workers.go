@@ -0,0 +1,121 @@
+package flowdock
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// DefaultWorkers is the number of tasks a WorkerPool runs at once by
+// default.
+const DefaultWorkers = 4
+
+// WorkerPool runs the tasks of the concurrent helpers of the client, such as
+// MessagesService.DeleteBulk, MessagesService.ListFlows and
+// Exporter.ExportFlows, and those of its users. A nil *WorkerPool runs
+// DefaultWorkers tasks at once and doesn't fail fast.
+type WorkerPool struct {
+	// Size is the maximum number of tasks run at once. Defaults to
+	// DefaultWorkers.
+	Size int
+
+	// FailFast, if set, cancels the context of the running tasks once one
+	// of them failed, and fails the tasks not started yet with the error
+	// of that context.
+	FailFast bool
+}
+
+// PanicError is the error of a task which panicked, or of user code called
+// by the goroutines of the library, such as the handlers of
+// StreamManager.Serve. See Client.RestartOnPanic.
+type PanicError struct {
+	Value interface{} // value passed to panic
+	Stack []byte      // stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("flowdock: task panicked: %v", e.Value)
+}
+
+// Run calls fn with the indexes 0 to n-1, on up to Size goroutines, and
+// returns once all the calls returned. It returns the errors of the calls
+// in the order of their indexes, nil for those which succeeded, so that fn
+// can store its results by index too for them to stay in input order. A
+// panic of fn is returned as a *PanicError. The tasks not started once ctx
+// is done fail with its error.
+func (p *WorkerPool) Run(ctx context.Context, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	size := DefaultWorkers
+	if p != nil && p.Size > 0 {
+		size = p.Size
+	}
+	if size > n {
+		size = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < size; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = runTask(ctx, i, fn)
+				if errs[i] != nil && p != nil && p.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
+
+// runTask calls fn with i, returning its panic as a *PanicError.
+func runTask(ctx context.Context, i int, fn func(ctx context.Context, i int) error) error {
+	return protect(func() error { return fn(ctx, i) })
+}
+
+// protect calls fn, returning its panic as a *PanicError.
+func protect(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// bulkErrors returns the errors of a bulk operation, as returned by
+// WorkerPool.Run, as a *BulkError, identifying the items with id. It
+// returns nil if none failed.
+func bulkErrors(errs []error, id func(i int) string) error {
+	berr := new(BulkError)
+	for i, err := range errs {
+		if err != nil {
+			berr.add(i, id(i), err)
+		}
+	}
+	return berr.err()
+}
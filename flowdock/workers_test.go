@@ -0,0 +1,104 @@
+package flowdock
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPool_Run(t *testing.T) {
+	ctx := context.Background()
+	errOdd := errors.New("odd")
+
+	var running, max int32
+	results := make([]int, 10)
+	p := &WorkerPool{Size: 3}
+	errs := p.Run(ctx, len(results), func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		results[i] = i * i
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+
+	if want := []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81}; !reflect.DeepEqual(results, want) {
+		t.Errorf("WorkerPool.Run stored %v, want %v", results, want)
+	}
+	for i, err := range errs {
+		if want := (i%2 == 1); (err == errOdd) != want {
+			t.Errorf("WorkerPool.Run returned error %v for %d", err, i)
+		}
+	}
+	if max > 3 {
+		t.Errorf("WorkerPool.Run ran %d tasks at once, want at most 3", max)
+	}
+}
+
+func TestWorkerPool_Run_panic(t *testing.T) {
+	errs := (*WorkerPool)(nil).Run(context.Background(), 2, func(ctx context.Context, i int) error {
+		if i == 1 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	if errs[0] != nil {
+		t.Errorf("WorkerPool.Run returned error %v for 0, want nil", errs[0])
+	}
+	perr, ok := errs[1].(*PanicError)
+	if !ok || perr.Value != "boom" || len(perr.Stack) == 0 {
+		t.Fatalf("WorkerPool.Run returned error %v for 1, want a *PanicError", errs[1])
+	}
+	if want := "flowdock: task panicked: boom"; perr.Error() != want {
+		t.Errorf("PanicError.Error() = %q, want %q", perr.Error(), want)
+	}
+}
+
+func TestWorkerPool_Run_failFast(t *testing.T) {
+	errFirst := errors.New("first")
+
+	var mu sync.Mutex
+	var started []int
+	p := &WorkerPool{Size: 1, FailFast: true}
+	errs := p.Run(context.Background(), 3, func(ctx context.Context, i int) error {
+		mu.Lock()
+		started = append(started, i)
+		mu.Unlock()
+		return errFirst
+	})
+
+	if want := []int{0}; !reflect.DeepEqual(started, want) {
+		t.Errorf("WorkerPool.Run started %v, want %v", started, want)
+	}
+	if want := []error{errFirst, context.Canceled, context.Canceled}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("WorkerPool.Run returned %v, want %v", errs, want)
+	}
+}
+
+func TestWorkerPool_Run_canceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	errs := new(WorkerPool).Run(ctx, 2, func(ctx context.Context, i int) error {
+		called = true
+		return nil
+	})
+	if called {
+		t.Errorf("WorkerPool.Run called the task with a canceled context")
+	}
+	if want := []error{context.Canceled, context.Canceled}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("WorkerPool.Run returned %v, want %v", errs, want)
+	}
+}
@@ -0,0 +1,198 @@
+// Package oauth gets Flowdock access tokens through the OAuth 2
+// authorization code flow, with golang.org/x/oauth2, and builds the
+// flowdock.Client of the user who authorized the application.
+//
+//	conf := oauth.Config(clientID, clientSecret, "https://example.com/callback",
+//		oauth.ScopeFlow, oauth.ScopeOfflineAccess)
+//	http.Redirect(w, r, oauth.AuthorizeURL(conf, state), http.StatusFound)
+//
+//	// in the handler of the callback, once the state checked
+//	token, err := oauth.Exchange(ctx, conf, r.FormValue("code"))
+//	client := oauth.NewClient(oauth.NewTokenSource(ctx, conf, token))
+//
+// Tokens of a TokenSource are refreshed once expired, or once the API
+// rejected them as expired, in which case the client sends the request
+// again with the new token. Refreshing needs ScopeOfflineAccess.
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"golang.org/x/oauth2"
+	"sync"
+)
+
+// Endpoint is the OAuth 2 endpoint of Flowdock.
+var Endpoint = oauth2.Endpoint{
+	AuthURL:   "https://api.flowdock.com/oauth/authorize",
+	TokenURL:  "https://api.flowdock.com/oauth/token",
+	AuthStyle: oauth2.AuthStyleInParams,
+}
+
+// Scopes an application can ask for.
+//
+// Flowdock API docs: https://www.flowdock.com/api/authentication
+const (
+	ScopeFlow          = "flow"           // read and post in the flows of the user
+	ScopePrivate       = "private"        // read and post in private conversations
+	ScopeManage        = "manage"         // manage organizations, flows and users
+	ScopeProfile       = "profile"        // read and edit the profile of the user
+	ScopeOfflineAccess = "offline_access" // get a refresh token
+)
+
+// ErrNoRefreshToken is returned by Refresh for tokens without a refresh
+// token, granted without ScopeOfflineAccess.
+var ErrNoRefreshToken = errors.New("flowdock: oauth: no refresh token")
+
+// Config returns the configuration of the application with the given
+// credentials, as registered in Flowdock, asking for scopes.
+func Config(clientID, clientSecret, redirectURL string, scopes ...string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     Endpoint,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	}
+}
+
+// AuthorizeURL returns the URL to send the user to, to authorize the
+// application of conf. Flowdock redirects the user to the RedirectURL of
+// conf with state, which must be checked, and the code to Exchange.
+//
+// Flowdock API docs: https://www.flowdock.com/api/authentication
+func AuthorizeURL(conf *oauth2.Config, state string, opts ...oauth2.AuthCodeOption) string {
+	return conf.AuthCodeURL(state, opts...)
+}
+
+// Exchange exchanges the code of an authorization for a token.
+//
+// Flowdock API docs: https://www.flowdock.com/api/authentication
+func Exchange(ctx context.Context, conf *oauth2.Config, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	token, err := conf.Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("flowdock: oauth: exchange: %w", err)
+	}
+	return token, nil
+}
+
+// Refresh returns a new token for token, with its refresh token, whether
+// it expired or not, as when the API rejected it.
+//
+// Flowdock API docs: https://www.flowdock.com/api/authentication
+func Refresh(ctx context.Context, conf *oauth2.Config, token *oauth2.Token) (*oauth2.Token, error) {
+	if token.RefreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+	refreshed, err := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("flowdock: oauth: refresh: %w", err)
+	}
+	return refreshed, nil
+}
+
+// TokenSource is an oauth2.TokenSource of the tokens of an application,
+// refreshed once expired or invalidated. Unlike the TokenSource of an
+// oauth2.Config, it refreshes a token the API rejected before its expiry,
+// when the client invalidates it. TokenSource is safe for concurrent use.
+type TokenSource struct {
+	ctx  context.Context
+	conf *oauth2.Config
+
+	// OnRefresh, if not nil, is called with each new token, such as to
+	// save it for the next run: the refresh token may change too.
+	OnRefresh func(*oauth2.Token)
+
+	mu      sync.Mutex
+	token   *oauth2.Token
+	invalid bool
+}
+
+// NewTokenSource returns the TokenSource of conf starting with token.
+// Tokens are refreshed with ctx.
+func NewTokenSource(ctx context.Context, conf *oauth2.Config, token *oauth2.Token) *TokenSource {
+	return &TokenSource{ctx: ctx, conf: conf, token: token}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *TokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() && !s.invalid {
+		return s.token, nil
+	}
+	token, err := Refresh(s.ctx, s.conf, s.token)
+	if err != nil {
+		return nil, err
+	}
+	s.token, s.invalid = token, false
+	if s.OnRefresh != nil {
+		s.OnRefresh(token)
+	}
+	return token, nil
+}
+
+// Invalidate has the current token refreshed by the next call to Token, if
+// its access token is accessToken, the one rejected. Requests rejected
+// together thus refresh the token once.
+func (s *TokenSource) Invalidate(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.AccessToken == accessToken {
+		s.invalid = true
+	}
+}
+
+// Credentials returns the flowdock.CredentialsProvider supplying the
+// access tokens of ts, to API requests and stream connections alike. When
+// ts has an Invalidate(accessToken string) method, as TokenSource does, the
+// Client invalidates the tokens it rejects, and retries the requests
+// rejected with an expired token once.
+func Credentials(ts oauth2.TokenSource) flowdock.CredentialsProvider {
+	c := credentials{ts}
+	if i, ok := ts.(invalidator); ok {
+		return invalidatingCredentials{c, i}
+	}
+	return c
+}
+
+// credentials supplies the access tokens of a TokenSource.
+type credentials struct {
+	ts oauth2.TokenSource
+}
+
+func (c credentials) Token(context.Context) (string, error) {
+	token, err := c.ts.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// invalidator is a TokenSource the Client can invalidate the tokens of, as
+// TokenSource.
+type invalidator interface {
+	Invalidate(accessToken string)
+}
+
+// invalidatingCredentials are credentials whose TokenSource is invalidated
+// by the Client.
+type invalidatingCredentials struct {
+	credentials
+	i invalidator
+}
+
+func (c invalidatingCredentials) Invalidate(accessToken string) {
+	c.i.Invalidate(accessToken)
+}
+
+// NewClient returns a flowdock.Client authorized with the tokens of ts.
+// Use a TokenSource to have the tokens rejected as expired refreshed.
+func NewClient(ts oauth2.TokenSource) *flowdock.Client {
+	client := flowdock.NewClient(nil)
+	client.Credentials = Credentials(ts)
+	return client
+}
@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// tokenServer serves the token endpoint, granting the access token
+// "access-<n>" at the nth grant.
+func tokenServer(t *testing.T) *httptest.Server {
+	grants := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "id" || r.Form.Get("client_secret") != "secret" {
+			t.Errorf("token request has credentials %q", r.Form)
+		}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "code" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh" {
+				t.Errorf("refresh request has refresh token %q", r.Form.Get("refresh_token"))
+			}
+		}
+		grants++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh","token_type":"bearer","expires_in":3600}`, grants)
+	})
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer access-1" {
+			t.Errorf("Authorization = %q, want the access token", got)
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/flows", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"token_expired"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestConfig(t *testing.T) {
+	conf := Config("id", "secret", "https://example.com/callback", ScopeFlow, ScopeOfflineAccess)
+	u, err := url.Parse(AuthorizeURL(conf, "state"))
+	if err != nil {
+		t.Fatalf("AuthorizeURL returned an invalid URL: %v", err)
+	}
+	if got, want := u.Scheme+"://"+u.Host+u.Path, Endpoint.AuthURL; got != want {
+		t.Errorf("AuthorizeURL is at %v, want %v", got, want)
+	}
+	want := url.Values{
+		"client_id":     {"id"},
+		"redirect_uri":  {"https://example.com/callback"},
+		"response_type": {"code"},
+		"scope":         {"flow offline_access"},
+		"state":         {"state"},
+	}
+	if got := u.Query(); got.Encode() != want.Encode() {
+		t.Errorf("AuthorizeURL query = %v, want %v", got, want)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	conf := Config("id", "secret", "")
+	conf.Endpoint.TokenURL = server.URL + "/oauth/token"
+
+	if _, err := Exchange(ctx, conf, "wrong"); err == nil {
+		t.Errorf("Exchange returned no error for an invalid code")
+	}
+	token, err := Exchange(ctx, conf, "code")
+	if err != nil {
+		t.Fatalf("Exchange returned error: %v", err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh" {
+		t.Errorf("Exchange returned %+v", token)
+	}
+
+	client := NewClient(conf.TokenSource(ctx, token))
+	client.RestURL, _ = url.Parse(server.URL + "/")
+	if _, _, err := client.Users.List(ctx, nil); err != nil {
+		t.Errorf("Users.List returned error: %v", err)
+	}
+
+	refreshed, err := Refresh(ctx, conf, token)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if refreshed.AccessToken != "access-2" {
+		t.Errorf("Refresh returned %+v, want a new access token", refreshed)
+	}
+
+	token.RefreshToken = ""
+	if _, err := Refresh(ctx, conf, token); err != ErrNoRefreshToken {
+		t.Errorf("Refresh returned %v, want ErrNoRefreshToken", err)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	conf := Config("id", "secret", "")
+	conf.Endpoint.TokenURL = server.URL + "/oauth/token"
+
+	// the token expires before its time, as far as the API is concerned
+	token := &oauth2.Token{AccessToken: "expired", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+	ts := NewTokenSource(ctx, conf, token)
+	var refreshed []string
+	ts.OnRefresh = func(token *oauth2.Token) {
+		refreshed = append(refreshed, token.AccessToken)
+	}
+
+	client := NewClient(ts)
+	client.RestURL, _ = url.Parse(server.URL + "/")
+	if _, _, err := client.Flows.List(ctx, false, nil); err != nil {
+		t.Errorf("Flows.List returned error: %v", err)
+	}
+	if len(refreshed) != 1 || refreshed[0] != "access-1" {
+		t.Errorf("TokenSource refreshed %v, want [access-1]", refreshed)
+	}
+	if token, _ := ts.Token(); token.AccessToken != "access-1" {
+		t.Errorf("Token returned %+v, want the refreshed token", token)
+	}
+
+	// a request rejected with the expired token comes back late
+	ts.Invalidate("expired")
+	if token, _ := ts.Token(); token.AccessToken != "access-1" || len(refreshed) != 1 {
+		t.Errorf("Token returned %+v after %d refreshes, want access-1 after 1", token, len(refreshed))
+	}
+
+	// the TokenSource of a Config is not invalidated, so not retried
+	client = NewClient(conf.TokenSource(ctx, token))
+	client.RestURL, _ = url.Parse(server.URL + "/")
+	if _, _, err := client.Flows.List(ctx, false, nil); err == nil {
+		t.Errorf("Flows.List returned no error for an expired token")
+	}
+}
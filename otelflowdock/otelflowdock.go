@@ -0,0 +1,86 @@
+// Package otelflowdock records the API requests and stream connections of a
+// flowdock.Client as OpenTelemetry spans.
+//
+//	client := flowdock.NewClient(httpClient)
+//	otelflowdock.Instrument(client, tracerProvider)
+//
+// Request spans last until the response is decoded. Stream connection spans
+// last until the connection is lost or the Stream is closed.
+package otelflowdock
+
+import (
+	"github.com/wm/go-flowdock/flowdock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// instrumentationName identifies the spans of this package.
+const instrumentationName = "github.com/wm/go-flowdock/otelflowdock"
+
+// Attribute keys of the spans, besides the HTTP semantic conventions.
+const (
+	EndpointKey = attribute.Key("flowdock.endpoint")
+	OrgKey      = attribute.Key("flowdock.org")
+	FlowKey     = attribute.Key("flowdock.flow")
+	StreamKey   = attribute.Key("flowdock.stream")
+)
+
+// Tracer is a flowdock.Tracer recording OpenTelemetry spans.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer creating spans with tp, or with the global
+// TracerProvider if tp is nil.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// Instrument makes client record its requests and stream connections as
+// spans of tp, or of the global TracerProvider if tp is nil.
+func Instrument(client *flowdock.Client, tp trace.TracerProvider) {
+	client.Tracer = NewTracer(tp)
+}
+
+// Start implements the flowdock.Tracer interface.
+func (t *Tracer) Start(req *http.Request, op flowdock.Operation) (*http.Request, func(*http.Response, error)) {
+	name := "flowdock " + op.Method + " " + op.Endpoint
+	if op.Stream {
+		name = "flowdock stream " + op.Endpoint
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", op.Method),
+		attribute.String("server.address", req.URL.Hostname()),
+		attribute.String("url.full", flowdock.Redact(req.URL.String())),
+		EndpointKey.String(op.Endpoint),
+		StreamKey.Bool(op.Stream),
+	}
+	if op.Org != "" {
+		attrs = append(attrs, OrgKey.String(op.Org), FlowKey.String(op.Flow))
+	}
+
+	ctx, span := t.tracer.Start(req.Context(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	return req.WithContext(ctx), func(resp *http.Response, err error) {
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+			if resp.StatusCode >= 400 {
+				span.SetStatus(codes.Error, resp.Status)
+			}
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,68 @@
+package otelflowdock
+
+import (
+	"context"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/flows/org/flow/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL + "/")
+	Instrument(client, tp)
+
+	client.Messages.List(ctx, "org", "flow", &flowdock.MessagesListOptions{Limit: 1})
+	client.Users.Get(ctx, 7)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+
+	list := spans[0]
+	if want := "flowdock GET flows/{org}/{flow}/messages"; list.Name() != want {
+		t.Errorf("span name = %q, want %q", list.Name(), want)
+	}
+	if list.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", list.SpanKind())
+	}
+	attrs := attribute.NewSet(list.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		OrgKey:                      attribute.StringValue("org"),
+		FlowKey:                     attribute.StringValue("flow"),
+		StreamKey:                   attribute.BoolValue(false),
+		"http.response.status_code": attribute.IntValue(200),
+	} {
+		if got, ok := attrs.Value(key); !ok || got != want {
+			t.Errorf("span attribute %v = %v, want %v", key, got.Emit(), want.Emit())
+		}
+	}
+
+	get := spans[1]
+	if want := "flowdock GET users/{id}"; get.Name() != want {
+		t.Errorf("span name = %q, want %q", get.Name(), want)
+	}
+	if get.Status().Code != codes.Error {
+		t.Errorf("span status = %v for a 404, want an error", get.Status())
+	}
+}
@@ -0,0 +1,52 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+)
+
+// The emoji the rules with Ack set react with to their events, by default:
+// while the actions run, and once they all succeeded or some failed.
+const (
+	DefaultAckWorking = "hourglass"
+	DefaultAckDone    = "white_check_mark"
+	DefaultAckFailed  = "x"
+)
+
+// ack is the acknowledgment of an event, a reaction to its message added
+// before running the actions of a rule.
+type ack struct {
+	org, flow string // parameterized names of the flow
+	id        int
+}
+
+// acknowledge reacts to the message of ev with AckWorking while its actions
+// run.
+func (e *Engine) acknowledge(ctx context.Context, ev *Event) (*ack, error) {
+	if ev.ID == 0 {
+		return nil, fmt.Errorf("event has no message to react to")
+	}
+	org, flow, err := e.flowNames(ctx, ev.Flow)
+	if err != nil {
+		return nil, err
+	}
+	a := &ack{org: org, flow: flow, id: ev.ID}
+	if _, err := e.client.Messages.AddReaction(ctx, a.org, a.flow, a.id, e.AckWorking); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// done replaces the reaction of the acknowledgment a with AckDone, or
+// AckFailed, once the actions are done or failed.
+func (e *Engine) done(ctx context.Context, a *ack, failed bool) error {
+	emoji := e.AckDone
+	if failed {
+		emoji = e.AckFailed
+	}
+	if _, err := e.client.Messages.AddReaction(ctx, a.org, a.flow, a.id, emoji); err != nil {
+		return err
+	}
+	_, err := e.client.Messages.RemoveReaction(ctx, a.org, a.flow, a.id, e.AckWorking)
+	return err
+}
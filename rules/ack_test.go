@@ -0,0 +1,67 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestEngine_Handle_ack(t *testing.T) {
+	ctx := context.Background()
+	var (
+		reactions []string
+		lookups   int
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/flows/find", func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		fmt.Fprint(w, `{"id":"flow-id","parameterized_name":"flow","organization":{"parameterized_name":"org"}}`)
+	})
+	mux.HandleFunc("/flows/org/flow/messages/", func(w http.ResponseWriter, r *http.Request) {
+		reactions = append(reactions, r.Method+" "+r.URL.Path)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL + "/")
+
+	e, err := New(client, []Rule{
+		{When: Trigger{Tags: []string{"deploy"}}, Then: []Action{{Post: "deploying"}}, Ack: true},
+		{When: Trigger{Tags: []string{"fail"}}, Then: []Action{{Webhook: server.URL + "/missing"}}, Ack: true},
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	if err := e.Handle(ctx, message("message", `"deploy please"`, "deploy")); err != nil {
+		t.Errorf("Engine.Handle returned error: %v", err)
+	}
+	if err := e.Handle(ctx, message("message", `"fail please"`, "fail")); err == nil {
+		t.Errorf("Engine.Handle returned no error, want the webhook failure")
+	}
+
+	const reacted = "/flows/org/flow/messages/7/emoji_reactions/"
+	want := []string{
+		"PUT " + reacted + DefaultAckWorking,
+		"PUT " + reacted + DefaultAckDone,
+		"DELETE " + reacted + DefaultAckWorking,
+		"PUT " + reacted + DefaultAckWorking,
+		"PUT " + reacted + DefaultAckFailed,
+		"DELETE " + reacted + DefaultAckWorking,
+	}
+	if !reflect.DeepEqual(reactions, want) {
+		t.Errorf("Engine.Handle reacted with %q, want %q", reactions, want)
+	}
+	if lookups != 1 {
+		t.Errorf("Engine.Handle looked the flow up %d times, want once", lookups)
+	}
+}
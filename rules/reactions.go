@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"context"
+	"github.com/wm/go-flowdock/flowdock"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9_+-]+$`)
+
+// variationSelector follows some emoji characters, such as "❤️", and is
+// ignored when comparing them.
+const variationSelector = "\ufe0f"
+
+// reacted is the message an "emoji-reaction" event reacts to, fetched once
+// per event for all the rules waiting for reactions.
+type reacted struct {
+	message *flowdock.Message
+	err     error
+}
+
+// reached reports whether ev, a reaction, is the one bringing the reactions
+// of its message to the count t waits for. Reactions are counted from the
+// message as fetched with the reactions API, in the order users reacted, so
+// that the rule runs once per message however close the reactions are, and
+// the Engine keeps no state about them. It sets the Reaction, Reactions,
+// Message and Tags of ev for the rule. Triggers without Reaction are reached
+// by any event.
+func (e *Engine) reached(ctx context.Context, t *Trigger, ev *Event, target *reacted) (bool, error) {
+	if t.Reaction == "" {
+		return true, nil
+	}
+	c, ok := ev.Message.EmojiReaction()
+	emoji := canonicalEmoji(t.Reaction)
+	if !ok || c.Type != "add" || canonicalEmoji(c.Emoji) != emoji || !countsReaction(t, ev.User) {
+		return false, nil
+	}
+
+	if target.message == nil && target.err == nil {
+		target.message, target.err = e.reactedMessage(ctx, ev)
+	}
+	if target.err != nil {
+		return false, target.err
+	}
+
+	var tags []string
+	if target.message.Tags != nil {
+		tags = *target.message.Tags
+	}
+	for _, tag := range t.Tags {
+		if !hasTag(tags, tag) {
+			return false, nil
+		}
+	}
+
+	users := reactionUsers(t, target.message, emoji)
+	want := t.Reactions
+	if want == 0 {
+		want = 1
+	}
+	if len(users) < want || users[want-1] != ev.User {
+		return false, nil
+	}
+	ev.Reaction, ev.Reactions = t.Reaction, want
+	ev.Message, ev.Tags = target.message, tags
+	return true, nil
+}
+
+// reactedMessage fetches the message the reaction ev is about.
+func (e *Engine) reactedMessage(ctx context.Context, ev *Event) (*flowdock.Message, error) {
+	org, flow, err := e.flowNames(ctx, ev.Flow)
+	if err != nil {
+		return nil, err
+	}
+	m, _, err := e.client.Messages.Get(ctx, org, flow, ev.ID)
+	return m, err
+}
+
+// reactionUsers returns the distinct users whose reactions to m with the
+// canonical emoji count for t, in the order they reacted.
+func reactionUsers(t *Trigger, m *flowdock.Message, emoji string) []string {
+	if m.EmojiReactions == nil {
+		return nil
+	}
+	// The same emoji can be keyed by several shortcodes, such as "+1" and
+	// "thumbsup": walk them in a stable order.
+	var names []string
+	for name := range *m.EmojiReactions {
+		if canonicalEmoji(name) == emoji {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var users []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, user := range (*m.EmojiReactions)[name] {
+			if !seen[user] && countsReaction(t, user) {
+				seen[user] = true
+				users = append(users, user)
+			}
+		}
+	}
+	return users
+}
+
+// countsReaction reports whether the reactions of user count for t.
+func countsReaction(t *Trigger, user string) bool {
+	if len(t.ReactionUsers) == 0 {
+		return true
+	}
+	for _, u := range t.ReactionUsers {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalEmoji returns the character of the emoji e, a shortcode with or
+// without colons, or a character, so that ":thumbsup:", "+1" and "👍" are
+// the same reaction. Shortcodes missing from flowdock.Emoji are returned
+// with colons.
+func canonicalEmoji(e string) string {
+	name := strings.Trim(strings.TrimSpace(e), ":")
+	if char, ok := flowdock.Emoji[name]; ok {
+		return strings.ReplaceAll(char, variationSelector, "")
+	}
+	if nameRegexp.MatchString(name) {
+		return ":" + name + ":"
+	}
+	return strings.ReplaceAll(name, variationSelector, "")
+}
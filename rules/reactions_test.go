@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// reaction returns the event of user reacting to the message 42 with emoji.
+func reaction(user, emoji, typ string) *flowdock.Message {
+	m := message("emoji-reaction", fmt.Sprintf(`{"message":42,"emoji":%q,"type":%q}`, emoji, typ))
+	m.UserID = &user
+	return m
+}
+
+func TestEngine_Handle_reactions(t *testing.T) {
+	ctx := context.Background()
+	var (
+		comments  []url.Values
+		reactions map[string][]string
+		gets      int
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/flows/find", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"flow-id","parameterized_name":"flow","organization":{"parameterized_name":"org"}}`)
+	})
+	mux.HandleFunc("/flows/org/flow/messages/42", func(w http.ResponseWriter, r *http.Request) {
+		gets++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             42,
+			"event":          "message",
+			"tags":           []string{"#deploy"},
+			"emojiReactions": reactions,
+		})
+	})
+	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
+		comments = append(comments, postedForm(r))
+		fmt.Fprint(w, `{}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL + "/")
+	e, err := New(client, []Rule{
+		{
+			When: Trigger{Reaction: ":thumbsup:", Reactions: 2},
+			Then: []Action{{Comment: "approved with {{.Reactions}} {{.Reaction}}"}},
+		},
+		{
+			When: Trigger{Reaction: "rocket", ReactionUsers: []string{"1"}},
+			Then: []Action{{Comment: "shipping"}},
+		},
+		{
+			When: Trigger{Reaction: "🎉", Tags: []string{"deploy"}},
+			Then: []Action{{Comment: "deployed {{index .Tags 0}}"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	for _, step := range []struct {
+		reactions map[string][]string
+		event     *flowdock.Message
+	}{
+		{map[string][]string{"+1": {"3"}}, reaction("3", "+1", "add")},
+		{map[string][]string{"+1": {"3", "4"}}, reaction("4", "thumbsup", "add")},
+		{map[string][]string{"+1": {"4"}}, reaction("3", "+1", "remove")},
+		{map[string][]string{"+1": {"4", "3", "5"}}, reaction("5", "+1", "add")}, // past the count
+		{map[string][]string{"rocket": {"3"}}, reaction("3", "rocket", "add")},   // not counted
+		{map[string][]string{"rocket": {"3", "1"}}, reaction("1", "rocket", "add")},
+		{map[string][]string{"tada": {"1"}}, reaction("1", "tada", "add")},
+	} {
+		reactions = step.reactions
+		if err := e.Handle(ctx, step.event); err != nil {
+			t.Errorf("Engine.Handle returned error: %v", err)
+		}
+	}
+
+	var got []string
+	for _, c := range comments {
+		got = append(got, c.Get("content"))
+	}
+	want := []string{"approved with 2 :thumbsup:", "shipping", "deployed #deploy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.Handle commented %q, want %q", got, want)
+	}
+	if comments[0].Get("message") != "42" {
+		t.Errorf("Engine.Handle commented on message %v, want 42", comments[0].Get("message"))
+	}
+	if gets != 5 {
+		t.Errorf("Engine.Handle fetched the reacted message %d times, want 5", gets)
+	}
+}
+
+func TestReactionUsers(t *testing.T) {
+	m := &flowdock.Message{EmojiReactions: &map[string][]string{
+		"+1":       {"1", "2"},
+		"thumbsup": {"2", "3"},
+		"tada":     {"4"},
+	}}
+	tests := []struct {
+		t    Trigger
+		want []string
+	}{
+		{Trigger{}, []string{"1", "2", "3"}},
+		{Trigger{ReactionUsers: []string{"3", "2"}}, []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		if got := reactionUsers(&tt.t, m, canonicalEmoji("👍")); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("reactionUsers(%+v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_reactions(t *testing.T) {
+	rules, err := Load([]byte(`
+- when:
+    reaction: ":+1:"
+    reactions: 3
+    reaction_users: ["1", "2"]
+  then:
+    - comment: approved
+`))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := Trigger{Reaction: ":+1:", Reactions: 3, ReactionUsers: []string{"1", "2"}}
+	if len(rules) != 1 || !reflect.DeepEqual(rules[0].When, want) {
+		t.Errorf("Load returned %+v, want a trigger %+v", rules, want)
+	}
+	data, _ := json.Marshal(&Event{Reaction: ":+1:", Reactions: 3})
+	var ev map[string]interface{}
+	json.Unmarshal(data, &ev)
+	if ev["reaction"] != ":+1:" || ev["reactions"] != 3.0 {
+		t.Errorf("Event encoded as %s", data)
+	}
+}
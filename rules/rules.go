@@ -0,0 +1,461 @@
+// Package rules runs declarative automation rules against flow streams:
+// when an event with some tags shows up in a flow, post a message, comment on
+// it or call a webhook.
+//
+// Rules are Go values, or loaded from YAML (or JSON) such as:
+//
+//	# rules.yaml
+//	- name: ship approved changes
+//	  when:
+//	    event: tag-change
+//	    tags: [approved]
+//	  then:
+//	    - comment: "Shipping {{.ID}}, thanks {{.User}}!"
+//	    - webhook: https://ci.example.com/deploy
+//
+// Rules can wait for reactions to a message too, such as approvals:
+//
+//	# approvals.yaml
+//	- when:
+//	    reaction: ":+1:"
+//	    reactions: 3
+//	  then:
+//	    - comment: "Approved, shipping!"
+//
+// Rules with ack set react to their events with an emoji while their
+// actions run, and replace it with another one for the outcome, as bots
+// acknowledge commands.
+//
+// Contents are text/template templates executed with the triggering Event.
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Rule runs actions on the events matching a trigger.
+type Rule struct {
+	Name string   `yaml:"name" json:"name"`
+	When Trigger  `yaml:"when" json:"when"`
+	Then []Action `yaml:"then" json:"then"`
+
+	// Ack, if set, acknowledges the events of the rule, such as commands,
+	// with a reaction to their message while the actions run, replaced
+	// once they are done or failed. See the Ack fields of Engine.
+	Ack bool `yaml:"ack" json:"ack"`
+}
+
+// Trigger selects the events of a Rule. Empty fields match anything.
+type Trigger struct {
+	// Event is the event type, such as "message", "comment" or
+	// "tag-change".
+	Event string `yaml:"event" json:"event"`
+
+	// Flow is the ID of the flow of the event.
+	Flow string `yaml:"flow" json:"flow"`
+
+	// Tags must all be carried by the message, the reacted one for
+	// Reaction triggers, or added by a tag-change event. Case and leading
+	// "#" are ignored.
+	Tags []string `yaml:"tags" json:"tags"`
+
+	// Reaction, if set, is an emoji the event must add as a reaction to a
+	// message, such as ":+1:" or "👍". The rule then runs once per reacted
+	// message, on the reaction reaching Reactions, with the reacted message
+	// as the Message of the Event.
+	Reaction string `yaml:"reaction" json:"reaction"`
+
+	// Reactions is the number of users who must react with Reaction.
+	// Defaults to 1.
+	Reactions int `yaml:"reactions" json:"reactions"`
+
+	// ReactionUsers, if set, are the IDs of the only users whose
+	// reactions count, such as the admins approving changes.
+	ReactionUsers []string `yaml:"reaction_users" json:"reaction_users"`
+}
+
+// Action is one of: posting a message in the flow of the event, commenting
+// on the message of the event, or POSTing the event as JSON to a webhook.
+type Action struct {
+	Post    string   `yaml:"post" json:"post"`
+	Comment string   `yaml:"comment" json:"comment"`
+	Webhook string   `yaml:"webhook" json:"webhook"`
+	Tags    []string `yaml:"tags" json:"tags"` // of the posted message or comment
+}
+
+// Event is what templates and webhooks get about the triggering event.
+type Event struct {
+	ID      int               `json:"id"`    // of the message, or of the tagged message for tag changes
+	Flow    string            `json:"flow"`  // ID of the flow
+	User    string            `json:"user"`  // ID of the author
+	Type    string            `json:"event"` // type of the event
+	Text    string            `json:"text"`  // content of the message
+	Tags    []string          `json:"tags"`  // of the message, or added by a tag change
+	Message *flowdock.Message `json:"message"`
+
+	// Reaction and Reactions are the emoji and the number of users who
+	// reacted with it, for the rules triggered by reactions.
+	Reaction  string `json:"reaction,omitempty"`
+	Reactions int    `json:"reactions,omitempty"`
+}
+
+// Load parses rules written in YAML, or JSON.
+func Load(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadFile parses the rules of a YAML, or JSON, file.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// Engine runs rules with the services of a Client.
+type Engine struct {
+	client *flowdock.Client
+	rules  []compiledRule
+
+	// HTTPClient calls the webhooks. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// OnError, if set, is called by Run for the actions that failed.
+	// Errors are logged through the Client's logger otherwise.
+	OnError func(rule *Rule, ev *Event, err error)
+
+	// AckWorking, AckDone and AckFailed are the emoji the rules with Ack
+	// set react with, as shortcodes: while the actions run, and once they
+	// all succeeded or some failed. They default to DefaultAckWorking,
+	// DefaultAckDone and DefaultAckFailed.
+	AckWorking, AckDone, AckFailed string
+
+	mu    sync.Mutex
+	flows map[string]flowNames // by flow ID
+}
+
+// flowNames are the parameterized names of a flow and of its organization,
+// which the message endpoints take.
+type flowNames struct {
+	org, flow string
+}
+
+type compiledRule struct {
+	Rule
+	templates []actionTemplates
+}
+
+type actionTemplates struct {
+	post, comment *template.Template
+}
+
+// New returns an Engine running rules through client. It fails on rules
+// without actions or with invalid templates.
+func New(client *flowdock.Client, rules []Rule) (*Engine, error) {
+	e := &Engine{
+		client:     client,
+		HTTPClient: http.DefaultClient,
+		AckWorking: DefaultAckWorking,
+		AckDone:    DefaultAckDone,
+		AckFailed:  DefaultAckFailed,
+		flows:      make(map[string]flowNames),
+	}
+	for i, r := range rules {
+		if len(r.Then) == 0 {
+			return nil, fmt.Errorf("rules: rule %q has no action", name(r, i))
+		}
+		if r.When.Reactions < 0 {
+			return nil, fmt.Errorf("rules: rule %q has negative reactions", name(r, i))
+		}
+		cr := compiledRule{Rule: r}
+		for _, a := range r.Then {
+			var (
+				t   actionTemplates
+				err error
+			)
+			switch {
+			case a.Post != "":
+				t.post, err = template.New("post").Parse(a.Post)
+			case a.Comment != "":
+				t.comment, err = template.New("comment").Parse(a.Comment)
+			case a.Webhook == "":
+				err = fmt.Errorf("empty action")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("rules: rule %q: %v", name(r, i), err)
+			}
+			cr.templates = append(cr.templates, t)
+		}
+		e.rules = append(e.rules, cr)
+	}
+	return e, nil
+}
+
+// flowNames returns the parameterized names of the organization and of the
+// flow with the given ID. They are looked up once per flow.
+func (e *Engine) flowNames(ctx context.Context, id string) (org, flow string, err error) {
+	e.mu.Lock()
+	names, ok := e.flows[id]
+	e.mu.Unlock()
+	if ok {
+		return names.org, names.flow, nil
+	}
+
+	f, _, err := e.client.Flows.GetByID(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	if f.ParameterizedName == nil || f.Organization == nil || f.Organization.ParameterizedName == nil {
+		return "", "", fmt.Errorf("flow %s has no name", id)
+	}
+	names = flowNames{org: *f.Organization.ParameterizedName, flow: *f.ParameterizedName}
+	e.mu.Lock()
+	e.flows[id] = names
+	e.mu.Unlock()
+	return names.org, names.flow, nil
+}
+
+func name(r Rule, i int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// Run handles the messages of msgs, such as a flow Stream, until it is
+// closed or ctx is done. Actions are run with ctx. A panicking action is
+// reported as a *flowdock.PanicError, and ends Run unless the Client's
+// RestartOnPanic is set.
+func (e *Engine) Run(ctx context.Context, msgs <-chan flowdock.Message) {
+	for {
+		var m flowdock.Message
+		var ok bool
+		select {
+		case m, ok = <-msgs:
+		case <-ctx.Done():
+			return
+		}
+		if !ok {
+			return
+		}
+		panicked := false
+		e.handle(ctx, &m, func(r *Rule, i int, ev *Event, err error) {
+			if _, ok := err.(*flowdock.PanicError); ok {
+				panicked = true
+			}
+			if e.OnError != nil {
+				e.OnError(r, ev, err)
+			} else {
+				e.client.Logf("rule %s failed on message %d: %v", name(*r, i), ev.ID, err)
+			}
+		})
+		if panicked && !e.client.RestartOnPanic {
+			return
+		}
+	}
+}
+
+// Handle runs the actions of the rules matching m with ctx, and returns the
+// first error. A failed action does not stop the others.
+func (e *Engine) Handle(ctx context.Context, m *flowdock.Message) error {
+	var first error
+	e.handle(ctx, m, func(r *Rule, i int, ev *Event, err error) {
+		if first == nil {
+			first = fmt.Errorf("rules: rule %s: %v", name(*r, i), err)
+		}
+	})
+	return first
+}
+
+// handle runs the rules matching m. Their requests are interactive, and
+// sent with PriorityHigh before background traffic sharing the Limiter.
+func (e *Engine) handle(ctx context.Context, m *flowdock.Message, fail func(*Rule, int, *Event, error)) {
+	ctx = flowdock.WithPriority(ctx, flowdock.PriorityHigh)
+	ev := newEvent(m)
+	var target reacted
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.When.matches(ev) {
+			continue
+		}
+		if ok, err := e.reached(ctx, &r.When, ev, &target); err != nil {
+			fail(&r.Rule, i, ev, fmt.Errorf("reactions: %v", err))
+			continue
+		} else if !ok {
+			continue
+		}
+		var reply *ack
+		if r.Ack {
+			var err error
+			if reply, err = e.acknowledge(ctx, ev); err != nil {
+				fail(&r.Rule, i, ev, fmt.Errorf("acknowledgment: %v", err))
+			}
+		}
+		failed := false
+		for j, a := range r.Then {
+			if err := e.runProtected(ctx, a, r.templates[j], ev); err != nil {
+				fail(&r.Rule, i, ev, err)
+				failed = true
+			}
+		}
+		if reply != nil {
+			if err := e.done(ctx, reply, failed); err != nil {
+				fail(&r.Rule, i, ev, fmt.Errorf("acknowledgment: %v", err))
+			}
+		}
+	}
+}
+
+// newEvent describes m to triggers, templates and webhooks. Malformed
+// content leaves the Text empty.
+func newEvent(m *flowdock.Message) *Event {
+	ev := &Event{Message: m}
+	if m.ID != nil {
+		ev.ID = *m.ID
+	}
+	if m.FlowID != nil {
+		ev.Flow = *m.FlowID
+	}
+	if m.UserID != nil {
+		ev.User = *m.UserID
+	}
+	if m.Event != nil {
+		ev.Type = *m.Event
+	}
+	if m.Tags != nil {
+		ev.Tags = *m.Tags
+	}
+
+	if c, ok := m.TagChange(); ok {
+		ev.ID = c.MessageID
+		ev.Tags = c.Added
+	} else if c, ok := m.EmojiReaction(); ok {
+		ev.ID = c.MessageID
+		ev.Text = c.String()
+	} else if m.RawContent != nil {
+		if c, err := m.ContentE(); err == nil {
+			ev.Text = c.String()
+		}
+	}
+	return ev
+}
+
+func (t *Trigger) matches(ev *Event) bool {
+	if t.Event != "" && t.Event != ev.Type {
+		return false
+	}
+	if t.Flow != "" && t.Flow != ev.Flow {
+		return false
+	}
+	if t.Reaction != "" {
+		return true // the tags are the reacted message's, see reached
+	}
+	for _, tag := range t.Tags {
+		if !hasTag(ev.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(strings.TrimPrefix(t, "#"), strings.TrimPrefix(tag, "#")) {
+			return true
+		}
+	}
+	return false
+}
+
+// runProtected runs the action a on ev, returning its panic as a
+// *flowdock.PanicError.
+func (e *Engine) runProtected(ctx context.Context, a Action, t actionTemplates, ev *Event) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &flowdock.PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return e.run(ctx, a, t, ev)
+}
+
+// run runs the action a on ev.
+func (e *Engine) run(ctx context.Context, a Action, t actionTemplates, ev *Event) error {
+	switch {
+	case t.post != nil:
+		content, err := execute(t.post, ev)
+		if err != nil {
+			return err
+		}
+		_, _, err = e.client.Messages.Create(ctx, &flowdock.MessagesCreateOptions{
+			FlowID:  ev.Flow,
+			Event:   "message",
+			Content: content,
+			Tags:    a.Tags,
+		})
+		return err
+	case t.comment != nil:
+		content, err := execute(t.comment, ev)
+		if err != nil {
+			return err
+		}
+		parent := ev.ID
+		if ev.Type == "comment" && ev.Message.MessageID != nil {
+			parent = *ev.Message.MessageID
+		}
+		_, _, err = e.client.Messages.CreateComment(ctx, &flowdock.MessagesCreateOptions{
+			FlowID:    ev.Flow,
+			MessageID: parent,
+			Event:     "comment",
+			Content:   content,
+			Tags:      a.Tags,
+		})
+		return err
+	}
+	return e.callWebhook(ctx, a.Webhook, ev)
+}
+
+func execute(t *template.Template, ev *Event) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := t.Execute(buf, ev); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// callWebhook POSTs ev as JSON to url.
+func (e *Engine) callWebhook(ctx context.Context, url string, ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", flowdock.Redact(url), resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"go/parser"
+	"go/token"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+- name: ship approved changes
+  when:
+    event: tag-change
+    tags: ["#Approved"]
+  then:
+    - comment: "Shipping {{.ID}}, thanks {{.User}}!"
+      tags: [shipped]
+    - webhook: WEBHOOK
+- when:
+    event: message
+    flow: flow-id
+    tags: [todo]
+  then:
+    - post: "noted: {{.Text}}"
+`
+
+func TestLoad(t *testing.T) {
+	rules, err := Load([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	want := []Rule{
+		{
+			Name: "ship approved changes",
+			When: Trigger{Event: "tag-change", Tags: []string{"#Approved"}},
+			Then: []Action{
+				{Comment: "Shipping {{.ID}}, thanks {{.User}}!", Tags: []string{"shipped"}},
+				{Webhook: "WEBHOOK"},
+			},
+		},
+		{
+			When: Trigger{Event: "message", Flow: "flow-id", Tags: []string{"todo"}},
+			Then: []Action{{Post: "noted: {{.Text}}"}},
+		},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("Load returned %+v, want %+v", rules, want)
+	}
+}
+
+// TestLoad_packageDoc loads the examples of the package documentation,
+// which must stay valid rules.
+func TestLoad_packageDoc(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "rules.go", nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var examples []string
+	block := ""
+	for _, line := range strings.Split(f.Doc.Text(), "\n") {
+		if strings.HasPrefix(line, "\t") {
+			block += line[1:] + "\n"
+			continue
+		}
+		if block != "" {
+			examples = append(examples, block)
+			block = ""
+		}
+	}
+	if len(examples) != 2 {
+		t.Fatalf("package doc has %d examples, want 2", len(examples))
+	}
+
+	var rules []Rule
+	for _, example := range examples {
+		r, err := Load([]byte(example))
+		if err != nil {
+			t.Fatalf("Load returned error for %q: %v", example, err)
+		}
+		rules = append(rules, r...)
+	}
+	if _, err := New(flowdock.NewClient(nil), rules); err != nil {
+		t.Errorf("New returned error: %v", err)
+	}
+	want := []Rule{
+		{
+			Name: "ship approved changes",
+			When: Trigger{Event: "tag-change", Tags: []string{"approved"}},
+			Then: []Action{
+				{Comment: "Shipping {{.ID}}, thanks {{.User}}!"},
+				{Webhook: "https://ci.example.com/deploy"},
+			},
+		},
+		{
+			When: Trigger{Reaction: ":+1:", Reactions: 3},
+			Then: []Action{{Comment: "Approved, shipping!"}},
+		},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("package doc examples load as %+v, want %+v", rules, want)
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	for _, r := range []Rule{
+		{Name: "no action"},
+		{Then: []Action{{}}},
+		{Then: []Action{{Post: "{{.Broken"}}},
+	} {
+		if _, err := New(flowdock.NewClient(nil), []Rule{r}); err == nil {
+			t.Errorf("New accepted %+v", r)
+		}
+	}
+}
+
+func message(event, content string, tags ...string) *flowdock.Message {
+	id, flow, user := 7, "flow-id", "3"
+	raw := json.RawMessage(content)
+	return &flowdock.Message{ID: &id, FlowID: &flow, UserID: &user, Event: &event, RawContent: &raw, Tags: &tags}
+}
+
+// postedForm returns the options of the message posted, or edited, by r, as
+// query parameters.
+func postedForm(r *http.Request) url.Values {
+	opt := new(flowdock.MessagesCreateOptions)
+	json.NewDecoder(r.Body).Decode(opt)
+	form, _ := flowdock.DefaultQueryEncoder.Encode(opt)
+	return form
+}
+
+func TestEngine_Handle(t *testing.T) {
+	ctx := context.Background()
+	var (
+		comments, posts []url.Values
+		hooks           []Event
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/comments", func(w http.ResponseWriter, r *http.Request) {
+		comments = append(comments, postedForm(r))
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		posts = append(posts, postedForm(r))
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		var ev Event
+		json.NewDecoder(r.Body).Decode(&ev)
+		hooks = append(hooks, ev)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL + "/")
+
+	rules, _ := Load([]byte(testRules))
+	rules[0].Then[1].Webhook = server.URL + "/hook"
+	e, err := New(client, rules)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+
+	msgs := []*flowdock.Message{
+		message("tag-change", `{"message":42,"add":["approved"]}`),
+		message("tag-change", `{"message":43,"add":["other"],"remove":["approved"]}`),
+		message("message", `"buy milk"`, "#todo"),
+		message("message", `"nothing to do"`),
+	}
+	for _, m := range msgs {
+		if err := e.Handle(ctx, m); err != nil {
+			t.Errorf("Engine.Handle returned error: %v", err)
+		}
+	}
+
+	if len(comments) != 1 || comments[0].Get("message") != "42" ||
+		comments[0].Get("content") != "Shipping 42, thanks 3!" || comments[0].Get("tags") != "shipped" {
+		t.Errorf("Engine.Handle commented %v", comments)
+	}
+	if len(hooks) != 1 || hooks[0].ID != 42 || hooks[0].Type != "tag-change" {
+		t.Errorf("Engine.Handle called the webhook with %+v", hooks)
+	}
+	if len(posts) != 1 || posts[0].Get("flow") != "flow-id" || posts[0].Get("content") != "noted: buy milk" {
+		t.Errorf("Engine.Handle posted %v", posts)
+	}
+}
+
+func TestEngine_Handle_webhookFailure(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	e, _ := New(flowdock.NewClient(nil), []Rule{{Then: []Action{{Webhook: server.URL}}}})
+	if err := e.Handle(ctx, message("message", `"hi"`)); err == nil {
+		t.Errorf("Engine.Handle returned no error for a failed webhook")
+	}
+}
+
+// panicTransport panics on every request.
+type panicTransport struct{}
+
+func (panicTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	panic("boom")
+}
+
+func TestEngine_Run_panic(t *testing.T) {
+	for _, restart := range []bool{false, true} {
+		client := flowdock.NewClient(nil)
+		client.RestartOnPanic = restart
+		e, _ := New(client, []Rule{{Then: []Action{{Webhook: "http://hook.example.com"}}}})
+		e.HTTPClient = &http.Client{Transport: panicTransport{}}
+		var errs []error
+		e.OnError = func(r *Rule, ev *Event, err error) { errs = append(errs, err) }
+
+		msgs := make(chan flowdock.Message, 2)
+		msgs <- *message("message", `"one"`)
+		msgs <- *message("message", `"two"`)
+		close(msgs)
+		e.Run(context.Background(), msgs)
+
+		want := 1
+		if restart {
+			want = 2
+		}
+		if len(errs) != want {
+			t.Fatalf("restart %v: Engine.Run reported %v, want %d errors", restart, errs, want)
+		}
+		if perr, ok := errs[0].(*flowdock.PanicError); !ok || perr.Value != "boom" {
+			t.Errorf("restart %v: Engine.Run reported %v, want a *PanicError", restart, errs[0])
+		}
+	}
+}
+
+func TestEngine_Run_log(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	buf := new(bytes.Buffer)
+	client.Log = log.New(buf, "", 0)
+	client.Redactor = func(s string) string { return strings.ReplaceAll(s, "hook-secret", "REDACTED") }
+	e, _ := New(client, []Rule{{Then: []Action{{Webhook: server.URL + "/hook-secret"}}}})
+
+	msgs := make(chan flowdock.Message, 1)
+	msgs <- *message("message", `"hi"`)
+	close(msgs)
+	e.Run(context.Background(), msgs)
+
+	got := buf.String()
+	if !strings.HasPrefix(got, "rule #1 failed on message 7: ") || strings.Contains(got, "hook-secret") {
+		t.Errorf("Engine.Run logged %q, want the failure of rule #1 redacted", got)
+	}
+}
@@ -0,0 +1,260 @@
+// Package slackimport replays Slack export archives into Flowdock flows.
+//
+// A Slack export is a directory holding a users.json file, a channels.json
+// file and one directory per channel containing a JSON file per day of
+// history. Each Slack message is posted to the mapped flow with its author as
+// the external_user_name.
+package slackimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExternalUserName is the longest external_user_name Flowdock accepts.
+const maxExternalUserName = 16
+
+// User is a Slack user as found in users.json.
+type User struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+}
+
+// Channel is a Slack channel as found in channels.json.
+type Channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Message is a single Slack message from a channel's daily history file.
+type Message struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+}
+
+// State records the last imported message of each channel so an interrupted
+// import can resume where it stopped.
+type State struct {
+	Channels map[string]string `json:"channels"` // channel name => last imported ts
+}
+
+// LoadState reads the import state stored at path. A missing file yields an
+// empty state.
+func LoadState(path string) (*State, error) {
+	state := &State{Channels: make(map[string]string)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Channels == nil {
+		state.Channels = make(map[string]string)
+	}
+	return state, nil
+}
+
+// Save writes the import state to path.
+func (s *State) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// An Importer replays a Slack export into Flowdock flows.
+type Importer struct {
+	Client *flowdock.Client
+
+	// Flows maps Slack channel names to the Flowdock flow IDs (i.e.
+	// "org:flow") they are imported into. Channels without a mapping are
+	// skipped.
+	Flows map[string]string
+
+	// Interval is the minimum delay between two posted messages.
+	Interval time.Duration
+
+	// Clock paces the posted messages. Defaults to the Client's Clock.
+	Clock flowdock.Clock
+
+	// Tags are added to every imported message.
+	Tags []string
+
+	// StateFile, if set, is where the import progress is loaded from and
+	// saved to after every message, making the import resumable.
+	StateFile string
+}
+
+// NewImporter returns an Importer posting through client into the given
+// channel to flow mapping.
+func NewImporter(client *flowdock.Client, flows map[string]string) *Importer {
+	return &Importer{
+		Client:   client,
+		Flows:    flows,
+		Interval: time.Second,
+		Clock:    client.Clock,
+	}
+}
+
+// Import replays the Slack export found in dir, posting with ctx. An
+// interrupted import resumes from its StateFile.
+func (imp *Importer) Import(ctx context.Context, dir string) error {
+	state := &State{Channels: make(map[string]string)}
+	if imp.StateFile != "" {
+		var err error
+		if state, err = LoadState(imp.StateFile); err != nil {
+			return err
+		}
+	}
+
+	var users []User
+	if err := readJSON(filepath.Join(dir, "users.json"), &users); err != nil {
+		return err
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+
+	var channels []Channel
+	if err := readJSON(filepath.Join(dir, "channels.json"), &channels); err != nil {
+		return err
+	}
+
+	var last time.Time
+	for _, ch := range channels {
+		flowID, ok := imp.Flows[ch.Name]
+		if !ok {
+			continue
+		}
+
+		days, err := filepath.Glob(filepath.Join(dir, ch.Name, "*.json"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(days)
+
+		for _, day := range days {
+			var messages []Message
+			if err := readJSON(day, &messages); err != nil {
+				return err
+			}
+
+			for _, m := range messages {
+				if !importable(m) || !tsAfter(m.TS, state.Channels[ch.Name]) {
+					continue
+				}
+
+				if wait := imp.Interval - imp.Clock.Now().Sub(last); wait > 0 {
+					select {
+					case <-imp.Clock.After(wait):
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				last = imp.Clock.Now()
+
+				opt := &flowdock.MessagesCreateOptions{
+					FlowID:           flowID,
+					Event:            "message",
+					Content:          m.Text,
+					Tags:             imp.Tags,
+					ExternalUserName: externalUserName(m, names),
+				}
+				if _, _, err := imp.Client.Messages.Create(ctx, opt); err != nil {
+					return fmt.Errorf("slackimport: %s message %s: %v", ch.Name, m.TS, err)
+				}
+
+				state.Channels[ch.Name] = m.TS
+				if imp.StateFile != "" {
+					if err := state.Save(imp.StateFile); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// importable reports whether m is a message authored by a user or a bot, as
+// opposed to channel join/leave notices and the like.
+func importable(m Message) bool {
+	return m.Type == "message" && m.Text != "" &&
+		(m.Subtype == "" || m.Subtype == "bot_message")
+}
+
+// externalUserName derives a Flowdock external_user_name from the author of
+// m, which may not contain whitespace.
+func externalUserName(m Message, names map[string]string) string {
+	name := names[m.User]
+	if name == "" {
+		name = m.Username
+	}
+	if name == "" {
+		name = "slack"
+	}
+
+	name = strings.Join(strings.Fields(name), "_")
+	if len(name) > maxExternalUserName {
+		name = name[:maxExternalUserName]
+	}
+	return name
+}
+
+// tsAfter reports whether the Slack timestamp a is later than b. An empty b
+// is earlier than any timestamp.
+func tsAfter(a, b string) bool {
+	if b == "" {
+		return true
+	}
+	as, au := splitTS(a)
+	bs, bu := splitTS(b)
+	return as > bs || (as == bs && au > bu)
+}
+
+// splitTS splits a Slack "seconds.micros" timestamp into its two parts.
+func splitTS(ts string) (int64, int64) {
+	parts := strings.SplitN(ts, ".", 2)
+	sec, _ := strconv.ParseInt(parts[0], 10, 64)
+	var micro int64
+	if len(parts) == 2 {
+		micro, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+	return sec, micro
+}
@@ -0,0 +1,159 @@
+package slackimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// postedForm returns the options of the message posted, or edited, by r, as
+// query parameters.
+func postedForm(r *http.Request) url.Values {
+	opt := new(flowdock.MessagesCreateOptions)
+	json.NewDecoder(r.Body).Decode(opt)
+	form, _ := flowdock.DefaultQueryEncoder.Encode(opt)
+	return form
+}
+
+// writeExport lays out a small Slack export in a temporary directory.
+func writeExport(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "slackimport")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"users.json":    `[{"id":"U1","name":"jackie"},{"id":"U2","name":"bob the builder"}]`,
+		"channels.json": `[{"id":"C1","name":"general"},{"id":"C2","name":"random"}]`,
+		"general/2014-01-01.json": `[
+			{"type":"message","user":"U1","text":"hello","ts":"1388534400.000002"},
+			{"type":"message","subtype":"channel_join","user":"U2","text":"<@U2> has joined","ts":"1388534401.000001"}
+		]`,
+		"general/2014-01-02.json": `[
+			{"type":"message","user":"U2","text":"world","ts":"1388620800.000001"}
+		]`,
+		"random/2014-01-01.json": `[
+			{"type":"message","user":"U1","text":"not imported","ts":"1388534400.000001"}
+		]`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImporter_Import(t *testing.T) {
+	ctx := context.Background()
+	dir := writeExport(t)
+	defer os.RemoveAll(dir)
+
+	var posted []url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		posted = append(posted, postedForm(r))
+		fmt.Fprint(w, `{}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL)
+
+	imp := NewImporter(client, map[string]string{"general": "org:flow"})
+	imp.Interval = 0
+	imp.StateFile = filepath.Join(dir, "state.json")
+
+	if err := imp.Import(ctx, dir); err != nil {
+		t.Fatalf("Importer.Import returned error: %v", err)
+	}
+
+	want := []url.Values{
+		{"flow": {"org:flow"}, "event": {"message"}, "content": {"hello"}, "external_user_name": {"jackie"}},
+		{"flow": {"org:flow"}, "event": {"message"}, "content": {"world"}, "external_user_name": {"bob_the_builder"}},
+	}
+	if !reflect.DeepEqual(posted, want) {
+		t.Errorf("Importer.Import posted %v, want %v", posted, want)
+	}
+
+	// a second run resumes after the last imported message
+	posted = nil
+	if err := imp.Import(ctx, dir); err != nil {
+		t.Fatalf("Importer.Import returned error: %v", err)
+	}
+	if len(posted) != 0 {
+		t.Errorf("Importer.Import posted %v on resume, want nothing", posted)
+	}
+}
+
+func TestTSAfter(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1388534400.000002", "", true},
+		{"1388534400.000002", "1388534400.000001", true},
+		{"1388534400.000001", "1388534400.000002", false},
+		{"1388534401.000001", "1388534400.999999", true},
+		{"1388534400.000001", "1388534400.000001", false},
+	}
+	for _, tt := range tests {
+		if got := tsAfter(tt.a, tt.b); got != tt.want {
+			t.Errorf("tsAfter(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestImporter_Import_throttled(t *testing.T) {
+	ctx := context.Background()
+	dir := writeExport(t)
+	defer os.RemoveAll(dir)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := flowdock.NewClient(nil)
+	client.RestURL, _ = url.Parse(server.URL)
+
+	clock := flowdock.NewFakeClock(time.Date(2014, time.January, 1, 0, 0, 0, 0, time.UTC))
+	imp := NewImporter(client, map[string]string{"general": "org:flow"})
+	imp.Interval = time.Minute
+	imp.Clock = clock
+
+	done := make(chan error)
+	go func() { done <- imp.Import(ctx, dir) }()
+
+	// the second message waits for a full interval after the first one
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Importer.Import returned %v before the interval elapsed", err)
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("Importer.Import returned error: %v", err)
+	}
+}
@@ -0,0 +1,443 @@
+// Package streamgroup lets several instances of a bot share the stream of a
+// flow, so that the bot keeps running when one of them goes down.
+//
+// The members of a group coordinate through a shared Store. One of them, the
+// leader, holds the single stream connection of the group and partitions the
+// streamed messages between the live members: the messages of a thread all
+// go to the same member. When the leader stops renewing its lease, another
+// member takes over the stream.
+package streamgroup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wm/go-flowdock/flowdock"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long the lease of the leader and the heartbeat of
+	// a member last by default.
+	DefaultTTL = 15 * time.Second
+
+	// DefaultInterval is how often a member renews its heartbeat and
+	// checks its messages by default.
+	DefaultInterval = time.Second
+)
+
+// Store is the state shared by the members of a group. It must update keys
+// atomically, as flowdock.MemoryStore and flowdock.FileStore do.
+type Store interface {
+	flowdock.Store
+	flowdock.CompareAndSwapper
+}
+
+// Stream is the connection held by the leader, such as a *flowdock.Stream.
+type Stream interface {
+	Close()
+}
+
+// OpenFunc opens the stream of the group.
+type OpenFunc func() (<-chan flowdock.Message, Stream, error)
+
+// FlowStream returns the OpenFunc streaming the messages of the flow named
+// flow in the organization org. The stream lasts until the Group closes it.
+func FlowStream(client *flowdock.Client, token, org, flow string) OpenFunc {
+	return func() (<-chan flowdock.Message, Stream, error) {
+		return client.Messages.Stream(context.Background(), token, org, flow)
+	}
+}
+
+// Group is the membership of one instance in a group sharing a stream.
+type Group struct {
+	store  Store
+	name   string
+	member string
+	open   OpenFunc
+
+	// TTL is how long the lease of the leader and the heartbeat of a
+	// member last. Defaults to DefaultTTL.
+	TTL time.Duration
+
+	// Interval is how often the heartbeat is renewed and the messages of
+	// the member are checked. Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Clock defaults to flowdock.SystemClock.
+	Clock flowdock.Clock
+
+	// Key returns the partition key of a message: the messages with the
+	// same key are handled by the same member. Defaults to ThreadKey.
+	Key func(*flowdock.Message) string
+
+	mu     sync.Mutex
+	leader bool
+	stream Stream
+	quit   chan struct{} // closed with the stream
+	live   []string      // members the leader routes to, as of the last step
+	seq    int
+	own    chan flowdock.Message // messages the leader partitioned to itself
+	done   chan struct{}
+	closed bool
+}
+
+// New returns the membership of member in the group named name, whose
+// members share store and open the stream with open. Member names must be
+// unique in the group, and must not contain slashes.
+func New(store Store, name, member string, open OpenFunc) *Group {
+	return &Group{
+		store:    store,
+		name:     name,
+		member:   member,
+		open:     open,
+		TTL:      DefaultTTL,
+		Interval: DefaultInterval,
+		Clock:    flowdock.SystemClock,
+		Key:      ThreadKey,
+		own:      make(chan flowdock.Message, 64),
+		done:     make(chan struct{}),
+	}
+}
+
+// ThreadKey partitions messages by thread: the messages of a thread go
+// together, and comments without one go with the message they comment.
+func ThreadKey(m *flowdock.Message) string {
+	switch {
+	case m.ThreadID != nil && *m.ThreadID != "":
+		return *m.ThreadID
+	case m.MessageID != nil:
+		return strconv.Itoa(*m.MessageID)
+	case m.ID != nil:
+		return strconv.Itoa(*m.ID)
+	case m.UUID != nil:
+		return *m.UUID
+	}
+	return ""
+}
+
+// Leader reports whether the member currently holds the stream of the
+// group.
+func (g *Group) Leader() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.leader
+}
+
+// Run takes part in the group until Close is called, calling handle for
+// each message partitioned to the member. handle is called from the
+// goroutine of Run only. Run returns the first error of the Store, after
+// leaving the group.
+func (g *Group) Run(handle func(*flowdock.Message)) error {
+	defer g.leave()
+	for {
+		if err := g.step(handle); err != nil {
+			return err
+		}
+		select {
+		case m := <-g.own:
+			handle(&m)
+		case <-g.Clock.After(g.Interval):
+		case <-g.done:
+			return nil
+		}
+	}
+}
+
+// Close makes Run leave the group and return.
+func (g *Group) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		g.closed = true
+		close(g.done)
+	}
+}
+
+// step renews the heartbeat of the member, runs the leader election and
+// handles the messages waiting for the member.
+func (g *Group) step(handle func(*flowdock.Message)) error {
+	now := g.Clock.Now()
+	expires, _ := now.Add(g.TTL).MarshalText()
+	if err := g.store.Set(g.key("members", g.member), expires); err != nil {
+		return err
+	}
+
+	leader, err := g.elect(now)
+	if err != nil {
+		return err
+	}
+	if leader {
+		// routing looks the members up once per step, not per message
+		live, err := g.members(now)
+		if err != nil {
+			return err
+		}
+		g.mu.Lock()
+		g.live = live
+		g.mu.Unlock()
+	}
+	if err := g.lead(leader, handle); err != nil {
+		return err
+	}
+	if leader {
+		if err := g.reassign(now, handle); err != nil {
+			return err
+		}
+	}
+
+	g.handleOwn(handle)
+	return g.take(g.member, func(m flowdock.Message) { handle(&m) })
+}
+
+// handleOwn handles the messages the leader partitioned to itself.
+func (g *Group) handleOwn(handle func(*flowdock.Message)) {
+	for {
+		select {
+		case m := <-g.own:
+			handle(&m)
+		default:
+			return
+		}
+	}
+}
+
+// lease is the value of the leader key.
+type lease struct {
+	Member  string    `json:"member"`
+	Expires time.Time `json:"expires"`
+}
+
+// elect takes or renews the lease of the leader, unless another member
+// holds it, and reports whether the member is the leader.
+func (g *Group) elect(now time.Time) (bool, error) {
+	key := g.key("leader")
+	cur, ok, err := g.store.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	var old []byte
+	if ok {
+		var l lease
+		if json.Unmarshal(cur, &l) == nil && l.Member != g.member && now.Before(l.Expires) {
+			return false, nil
+		}
+		old = cur
+	}
+
+	next, err := json.Marshal(lease{Member: g.member, Expires: now.Add(g.TTL)})
+	if err != nil {
+		return false, err
+	}
+	return g.store.CompareAndSwap(key, old, next)
+}
+
+// lead opens the stream when the member becomes the leader, and closes it
+// when the member loses the lease.
+func (g *Group) lead(leader bool, handle func(*flowdock.Message)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.leader = leader
+	switch {
+	case leader && g.stream == nil && !g.closed:
+		msgs, stream, err := g.open()
+		if err != nil {
+			return err
+		}
+		g.stream, g.quit = stream, make(chan struct{})
+		go g.forward(msgs, stream, g.quit)
+	case !leader && g.stream != nil:
+		g.closeStream()
+	}
+	return nil
+}
+
+// closeStream closes the stream of the leader. g.mu must be held.
+func (g *Group) closeStream() {
+	g.stream.Close()
+	close(g.quit)
+	g.stream, g.quit = nil, nil
+}
+
+// forward partitions the messages of the stream until it ends. Once quit
+// is closed, the messages of the leader's own partition are dropped, as
+// Run may no longer receive them.
+func (g *Group) forward(msgs <-chan flowdock.Message, stream Stream, quit chan struct{}) {
+	for m := range msgs {
+		if g.route(m) {
+			continue
+		}
+		select {
+		case g.own <- m:
+		case <-quit:
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stream == stream {
+		g.stream = nil // reopened by the next step
+	}
+}
+
+// route hands m to the member of its partition, among the members alive at
+// the last step, and reports whether it did.
+// The leader handles the messages of its own partition, as well as the ones
+// which can't be handed over.
+func (g *Group) route(m flowdock.Message) bool {
+	g.mu.Lock()
+	members := g.live
+	g.mu.Unlock()
+	if len(members) == 0 {
+		return false
+	}
+	target := members[partition(g.Key(&m), len(members))]
+	return target != g.member && g.send(target, m) == nil
+}
+
+// send queues m in the inbox of member.
+func (g *Group) send(member string, m flowdock.Message) error {
+	data, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.seq++
+	id := fmt.Sprintf("%020d-%010d-%s", g.Clock.Now().UnixNano(), g.seq, g.member)
+	g.mu.Unlock()
+	return g.store.Set(g.key("inbox", member, id), data)
+}
+
+// take removes the messages queued in the inbox of member, in order, and
+// passes them to fn.
+func (g *Group) take(member string, fn func(flowdock.Message)) error {
+	keys, err := g.store.Keys(g.key("inbox", member) + "/")
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		data, ok, err := g.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := g.store.Delete(key); err != nil {
+			return err
+		}
+		var m flowdock.Message
+		if !ok || json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		fn(m)
+	}
+	return nil
+}
+
+// reassign hands the messages queued for members which left the group to
+// the live ones.
+func (g *Group) reassign(now time.Time, handle func(*flowdock.Message)) error {
+	prefix := g.key("members") + "/"
+	keys, err := g.store.Keys(prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		member := strings.TrimPrefix(key, prefix)
+		alive, err := g.alive(key, now)
+		if err != nil {
+			return err
+		}
+		if alive {
+			continue
+		}
+		err = g.take(member, func(m flowdock.Message) {
+			if !g.route(m) {
+				handle(&m)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if err := g.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// members returns the sorted names of the live members.
+func (g *Group) members(now time.Time) ([]string, error) {
+	prefix := g.key("members") + "/"
+	keys, err := g.store.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	for _, key := range keys {
+		alive, err := g.alive(key, now)
+		if err != nil {
+			return nil, err
+		}
+		if alive {
+			members = append(members, strings.TrimPrefix(key, prefix))
+		}
+	}
+	return members, nil
+}
+
+// alive reports whether the heartbeat stored under key is still valid.
+func (g *Group) alive(key string, now time.Time) (bool, error) {
+	data, ok, err := g.store.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	var expires time.Time
+	if err := expires.UnmarshalText(data); err != nil {
+		return false, nil
+	}
+	return now.Before(expires), nil
+}
+
+// leave closes the stream, drops the heartbeat and gives the lease up so
+// that another member takes over without waiting for it to expire.
+func (g *Group) leave() {
+	g.mu.Lock()
+	if g.stream != nil {
+		g.closeStream()
+	}
+	g.leader, g.live = false, nil
+	g.mu.Unlock()
+
+	g.store.Delete(g.key("members", g.member))
+
+	key := g.key("leader")
+	cur, ok, err := g.store.Get(key)
+	var l lease
+	if err != nil || !ok || json.Unmarshal(cur, &l) != nil || l.Member != g.member {
+		return
+	}
+	if next, err := json.Marshal(lease{Member: g.member}); err == nil {
+		g.store.CompareAndSwap(key, cur, next)
+	}
+}
+
+// key returns the Store key of the group made of parts.
+func (g *Group) key(parts ...string) string {
+	return "streamgroup/" + g.name + "/" + strings.Join(parts, "/")
+}
+
+// partition returns the index of the member handling key among n members.
+func partition(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
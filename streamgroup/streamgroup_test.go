@@ -0,0 +1,235 @@
+package streamgroup
+
+import (
+	"github.com/wm/go-flowdock/flowdock"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStream is the stream opened by the leader of a test group.
+type fakeStream struct {
+	msgs   chan flowdock.Message
+	closed bool
+}
+
+func (s *fakeStream) Close() {
+	s.closed = true
+	close(s.msgs)
+}
+
+// member is a test member of a group, recording what it handles.
+type member struct {
+	*Group
+	streams []*fakeStream
+	handled []int
+}
+
+func newMember(store Store, clock flowdock.Clock, name string) *member {
+	m := new(member)
+	m.Group = New(store, "org/flow", name, func() (<-chan flowdock.Message, Stream, error) {
+		s := &fakeStream{msgs: make(chan flowdock.Message)}
+		m.streams = append(m.streams, s)
+		return s.msgs, s, nil
+	})
+	m.Clock = clock
+	return m
+}
+
+func (m *member) step(t *testing.T) {
+	err := m.Group.step(func(msg *flowdock.Message) {
+		m.handled = append(m.handled, *msg.ID)
+	})
+	if err != nil {
+		t.Fatalf("step of %v returned error: %v", m.member, err)
+	}
+}
+
+func message(id int) flowdock.Message {
+	return flowdock.Message{ID: &id}
+}
+
+func TestGroup_partition(t *testing.T) {
+	store := flowdock.NewMemoryStore()
+	clock := flowdock.NewFakeClock(time.Now())
+	a := newMember(store, clock, "a")
+	b := newMember(store, clock, "b")
+
+	a.step(t)
+	b.step(t)
+	if !a.Leader() || b.Leader() {
+		t.Fatalf("Leader() = %v, %v, want only the first member leading", a.Leader(), b.Leader())
+	}
+	if len(a.streams) != 1 || len(b.streams) != 0 {
+		t.Fatalf("opened %d and %d streams, want only one by the leader", len(a.streams), len(b.streams))
+	}
+	a.step(t) // the leader routes to b from its next step
+
+	for id := 1; id <= 20; id++ {
+		if !a.route(message(id)) {
+			a.own <- message(id)
+		}
+	}
+	comment := message(21)
+	comment.MessageID = new(int)
+	*comment.MessageID = 1
+	if !a.route(comment) {
+		a.own <- comment
+	}
+	a.step(t)
+	b.step(t)
+
+	seen := make(map[int]string)
+	for _, m := range []*member{a, b} {
+		if len(m.handled) == 0 {
+			t.Errorf("member %v handled no message", m.member)
+		}
+		for _, id := range m.handled {
+			if other, ok := seen[id]; ok {
+				t.Errorf("message %d handled by %v and %v", id, other, m.member)
+			}
+			seen[id] = m.member
+		}
+	}
+	if len(seen) != 21 {
+		t.Errorf("handled %d messages, want 21", len(seen))
+	}
+	if seen[21] != seen[1] {
+		t.Errorf("comment handled by %v, its thread by %v", seen[21], seen[1])
+	}
+}
+
+func TestGroup_failover(t *testing.T) {
+	store := flowdock.NewMemoryStore()
+	clock := flowdock.NewFakeClock(time.Now())
+	a := newMember(store, clock, "a")
+	b := newMember(store, clock, "b")
+
+	a.step(t)
+	b.step(t)
+	a.step(t)
+
+	// queue messages for b, which then goes down
+	for id := 1; id <= 20; id++ {
+		if !a.route(message(id)) {
+			a.own <- message(id)
+		}
+	}
+	a.step(t)
+
+	// a stops renewing its lease
+	clock.Advance(DefaultTTL)
+	c := newMember(store, clock, "c")
+	c.step(t)
+	if !c.Leader() || len(c.streams) != 1 {
+		t.Fatalf("Leader() = %v after the lease expired, want true", c.Leader())
+	}
+	a.step(t)
+	if a.Leader() || !a.streams[0].closed {
+		t.Errorf("previous leader still holds the stream")
+	}
+
+	// b never came back: c hands its messages over
+	c.step(t)
+	a.step(t)
+	if len(b.handled) != 0 {
+		t.Fatalf("member b handled %v, want nothing", b.handled)
+	}
+	if got := len(a.handled) + len(c.handled); got != 20 {
+		t.Errorf("handled %d messages after failover, want 20", got)
+	}
+}
+
+func TestGroup_Run_leave(t *testing.T) {
+	store := flowdock.NewMemoryStore()
+	clock := flowdock.NewFakeClock(time.Now())
+	a := newMember(store, clock, "a")
+	b := newMember(store, clock, "b")
+
+	done := make(chan error)
+	go func() { done <- a.Run(func(*flowdock.Message) {}) }()
+	for !a.Leader() {
+		time.Sleep(time.Millisecond)
+	}
+	a.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned error: %v", err)
+	}
+	if a.Leader() || !a.streams[0].closed {
+		t.Errorf("Run returned without closing the stream")
+	}
+
+	// the lease was given up, no need to wait for it to expire
+	b.step(t)
+	if !b.Leader() {
+		t.Errorf("Leader() = false after the leader left, want true")
+	}
+}
+
+// countingStore counts the listings of keys.
+type countingStore struct {
+	Store
+	mu   sync.Mutex
+	keys int
+}
+
+func (s *countingStore) Keys(prefix string) ([]string, error) {
+	s.mu.Lock()
+	s.keys++
+	s.mu.Unlock()
+	return s.Store.Keys(prefix)
+}
+
+func TestGroup_route_cachedMembers(t *testing.T) {
+	store := &countingStore{Store: flowdock.NewMemoryStore()}
+	clock := flowdock.NewFakeClock(time.Now())
+	a := newMember(store, clock, "a")
+	b := newMember(store, clock, "b")
+	a.step(t)
+	b.step(t)
+	a.step(t)
+
+	listed := store.keys
+	for id := 1; id <= 20; id++ {
+		a.route(message(id))
+	}
+	if store.keys != listed {
+		t.Errorf("routing listed the Store %d times, want the members of the last step", store.keys-listed)
+	}
+}
+
+func TestGroup_forward_left(t *testing.T) {
+	g := New(flowdock.NewMemoryStore(), "org/flow", "a", nil)
+	for i := 0; i < cap(g.own); i++ {
+		g.own <- message(i)
+	}
+
+	// the leader's own buffer is full, and Run no longer drains it
+	s := &fakeStream{msgs: make(chan flowdock.Message, 1)}
+	s.msgs <- message(cap(g.own))
+	quit := make(chan struct{})
+	g.stream, g.quit = s, quit
+	done := make(chan struct{})
+	go func() {
+		g.forward(s.msgs, s, quit)
+		close(done)
+	}()
+
+	g.leave()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("forward still blocked after the member left")
+	}
+}
+
+func TestThreadKey(t *testing.T) {
+	id, reply, parent, thread := 1, 2, 1, "thread-a"
+	starter := &flowdock.Message{ID: &id, ThreadID: &thread}
+	if got, want := ThreadKey(&flowdock.Message{ID: &reply, ThreadID: &thread}), ThreadKey(starter); got != want {
+		t.Errorf("ThreadKey of a reply = %q, want the key %q of its thread starter", got, want)
+	}
+	if got, want := ThreadKey(&flowdock.Message{ID: &reply, MessageID: &parent}), "1"; got != want {
+		t.Errorf("ThreadKey of a comment = %q, want %q", got, want)
+	}
+}